@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,6 +18,7 @@ import (
 	"stellarfrp/pkg/database"
 	"stellarfrp/pkg/geetest"
 	"stellarfrp/pkg/logger"
+	"stellarfrp/pkg/secret"
 )
 
 func main() {
@@ -44,6 +46,15 @@ func main() {
 	}
 	defer redisClient.Close()
 
+	// 初始化节点凭据加密密钥
+	keyring, err := secret.ParseKeyring(cfg.Secret.Keys)
+	if err != nil {
+		logger.Fatal("节点凭据加密密钥配置错误", "error", err)
+	}
+	if !keyring.Enabled() {
+		logger.Warn("未配置SECRET_KEYS，节点凭据将以明文存储")
+	}
+
 	// 初始化极验验证客户端
 	geetestClient := geetest.NewGeetestClient(
 		cfg.Geetest.CaptchaID,
@@ -58,13 +69,19 @@ func main() {
 	realNameAuthHandler := handler.NewRealNameAuthHandler(cfg, userRepo, logger)
 
 	// 初始化API路由
-	router := api.SetupRouter(cfg, logger, db, redisClient, geetestClient, realNameAuthHandler)
+	router := api.SetupRouter(cfg, logger, db, redisClient, keyring, geetestClient, realNameAuthHandler)
+
+	// 请求的根上下文，关闭服务器时取消，通知隧道状态推送等长连接退出
+	baseCtx, cancelBaseCtx := context.WithCancel(context.Background())
+	defer cancelBaseCtx()
 
 	// 创建HTTP服务器
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.APIPort),
-		Handler: router,
+		Addr:        fmt.Sprintf(":%d", cfg.APIPort),
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
+	server.RegisterOnShutdown(cancelBaseCtx)
 
 	// 启动服务器（非阻塞）
 	go func() {
@@ -18,6 +18,8 @@ type Config struct {
 	Email    EmailConfig
 	Geetest  GeetestConfig
 	AliCloud AliCloudConfig
+	Frps     FrpsConfig
+	Secret   SecretConfig
 }
 
 // DatabaseConfig MySQL数据库配置
@@ -71,6 +73,19 @@ type AliCloudConfig struct {
 	IdentityKey string // 实名认证加密密钥
 }
 
+// FrpsConfig 节点frps配置生成相关配置
+type FrpsConfig struct {
+	PluginURL string // frps插件回调的面板API对外地址，如https://api.example.com
+	// RequirePluginSecret 插件回调必须携带节点ID和正确的密钥，开启后拒绝旧版配置和未生成密钥节点的回调
+	RequirePluginSecret bool
+}
+
+// SecretConfig 节点凭据加密配置
+type SecretConfig struct {
+	// Keys 加密密钥，格式为 "id:base64密钥"，多个用逗号分隔，第一个用于加密，其余用于解密轮换前的数据
+	Keys string
+}
+
 // Load 从环境变量加载配置
 func Load() (*Config, error) {
 	// 加载.env文件
@@ -118,6 +133,7 @@ func Load() (*Config, error) {
 		logFileMaxAge = 30 // 默认保留30天
 	}
 	logFileCompress, _ := strconv.ParseBool(os.Getenv("LOG_FILE_COMPRESS"))
+	requirePluginSecret, _ := strconv.ParseBool(os.Getenv("FRPS_PLUGIN_REQUIRE_SECRET"))
 
 	return &Config{
 		APIPort:  apiPort,
@@ -161,5 +177,12 @@ func Load() (*Config, error) {
 			Path:        os.Getenv("ALICLOUD_PATH"),
 			IdentityKey: os.Getenv("ALICLOUD_IDENTITY_KEY"),
 		},
+		Frps: FrpsConfig{
+			PluginURL:           os.Getenv("FRPS_PLUGIN_URL"),
+			RequirePluginSecret: requirePluginSecret,
+		},
+		Secret: SecretConfig{
+			Keys: os.Getenv("SECRET_KEYS"),
+		},
 	}, nil
 }
@@ -3,31 +3,49 @@ package admin
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"net/http"
 	"stellarfrp/internal/repository"
 	"stellarfrp/internal/service"
 	"stellarfrp/pkg/logger"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // NodeAdminHandler 节点管理处理器
 type NodeAdminHandler struct {
-	nodeService service.NodeService
-	nodeRepo    repository.NodeRepository
-	userService service.UserService
-	logger      *logger.Logger
+	nodeService        service.NodeService
+	nodeRepo           repository.NodeRepository
+	userService        service.UserService
+	heartbeatService   service.NodeHeartbeatService
+	nodeLoadService    service.NodeLoadService
+	maintenanceService service.NodeMaintenanceService
+	validationService  service.NodeValidationService
+	rewardService      service.NodeRewardService
+	reviewService      service.NodeReviewService
+	proxyAlertService  service.ProxyAlertService
+	frpsPluginURL      string
+	logger             *logger.Logger
 }
 
 // NewNodeAdminHandler 创建节点管理处理器实例
-func NewNodeAdminHandler(nodeService service.NodeService, nodeRepo repository.NodeRepository, userService service.UserService, logger *logger.Logger) *NodeAdminHandler {
+func NewNodeAdminHandler(nodeService service.NodeService, nodeRepo repository.NodeRepository, userService service.UserService, heartbeatService service.NodeHeartbeatService, nodeLoadService service.NodeLoadService, maintenanceService service.NodeMaintenanceService, validationService service.NodeValidationService, rewardService service.NodeRewardService, reviewService service.NodeReviewService, proxyAlertService service.ProxyAlertService, frpsPluginURL string, logger *logger.Logger) *NodeAdminHandler {
 	return &NodeAdminHandler{
-		nodeService: nodeService,
-		nodeRepo:    nodeRepo,
-		userService: userService,
-		logger:      logger,
+		nodeService:        nodeService,
+		nodeRepo:           nodeRepo,
+		userService:        userService,
+		heartbeatService:   heartbeatService,
+		nodeLoadService:    nodeLoadService,
+		maintenanceService: maintenanceService,
+		validationService:  validationService,
+		rewardService:      rewardService,
+		reviewService:      reviewService,
+		proxyAlertService:  proxyAlertService,
+		frpsPluginURL:      frpsPluginURL,
+		logger:             logger,
 	}
 }
 
@@ -106,18 +124,28 @@ func (h *NodeAdminHandler) GetNode(c *gin.Context) {
 
 // CreateNodeRequest 创建节点请求
 type CreateNodeRequest struct {
-	NodeName     string  `json:"node_name" binding:"required"`
-	FrpsPort     int     `json:"frps_port" binding:"required"`
-	URL          string  `json:"url" binding:"required"`
-	Token        string  `json:"token" binding:"required"`
-	User         string  `json:"user" binding:"required"`
-	Description  *string `json:"description"`
-	Permission   string  `json:"permission" binding:"required"`    // JSON格式的字符串，如["1","2"]
-	AllowedTypes string  `json:"allowed_types" binding:"required"` // JSON格式的字符串，如["TCP","UDP"]
-	Host         *string `json:"host"`
-	PortRange    string  `json:"port_range" binding:"required"`
-	IP           string  `json:"ip" binding:"required"`
-	Status       int     `json:"status" binding:"required"`
+	NodeName      string   `json:"node_name" binding:"required"`
+	FrpsPort      int      `json:"frps_port" binding:"required"`
+	URL           string   `json:"url" binding:"required"`
+	Token         string   `json:"token" binding:"required"`
+	User          string   `json:"user" binding:"required"`
+	Description   *string  `json:"description"`
+	Permission    string   `json:"permission" binding:"required"`    // JSON格式的字符串，如["1","2"]
+	AllowedTypes  string   `json:"allowed_types" binding:"required"` // JSON格式的字符串，如["TCP","UDP"]
+	Host          *string  `json:"host"`
+	PortRange     string   `json:"port_range" binding:"required"`
+	IP            string   `json:"ip" binding:"required"`
+	Status        int      `json:"status" binding:"required"`
+	Region        string   `json:"region"` // 节点所在地区
+	ISP           string   `json:"isp"`    // 线路运营商，如telecom、unicom、mobile、bgp
+	City          string   `json:"city"`
+	BandwidthTier string   `json:"bandwidth_tier"`
+	Tags          []string `json:"tags"`
+	SortOrder     int      `json:"sort_order"`
+	// 节点容量上限，0或不填表示不限制
+	MaxProxies       int            `json:"max_proxies"`
+	MaxTypeProxies   map[string]int `json:"max_type_proxies"` // 按协议类型的隧道数量上限，如{"tcp":100}
+	MaxOnlineClients int            `json:"max_online_clients"`
 }
 
 // CreateNode 创建节点
@@ -148,19 +176,42 @@ func (h *NodeAdminHandler) CreateNode(c *gin.Context) {
 	}
 
 	node := &repository.Node{
-		NodeName:     req.NodeName,
-		FrpsPort:     req.FrpsPort,
-		URL:          req.URL,
-		Token:        req.Token,
-		User:         req.User,
-		Description:  description,
-		Permission:   req.Permission,
-		AllowedTypes: req.AllowedTypes,
-		Host:         host,
-		PortRange:    req.PortRange,
-		IP:           req.IP,
-		Status:       req.Status,
-		OwnerID:      sql.NullInt64{Int64: 0, Valid: false}, // 系统节点，OwnerID为null
+		NodeName:         req.NodeName,
+		FrpsPort:         req.FrpsPort,
+		URL:              req.URL,
+		Token:            req.Token,
+		User:             req.User,
+		Description:      description,
+		Permission:       req.Permission,
+		AllowedTypes:     req.AllowedTypes,
+		Host:             host,
+		PortRange:        req.PortRange,
+		IP:               req.IP,
+		Status:           req.Status,
+		OwnerID:          sql.NullInt64{Int64: 0, Valid: false}, // 系统节点，OwnerID为null
+		Region:           strings.TrimSpace(req.Region),
+		ISP:              strings.ToLower(strings.TrimSpace(req.ISP)),
+		City:             strings.TrimSpace(req.City),
+		BandwidthTier:    strings.TrimSpace(req.BandwidthTier),
+		SortOrder:        req.SortOrder,
+		MaxProxies:       req.MaxProxies,
+		MaxOnlineClients: req.MaxOnlineClients,
+	}
+	if node.Tags, err = service.FormatNodeTags(req.Tags); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	if node.MaxTypeProxies, err = service.FormatNodeTypeLimits(req.MaxTypeProxies); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	if err := service.ValidateNodeMeta(node); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	if err := service.ValidateNodeCapacity(node); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
 	}
 
 	// 保存节点
@@ -180,20 +231,29 @@ func (h *NodeAdminHandler) CreateNode(c *gin.Context) {
 
 // UpdateNodeRequest 更新节点请求
 type UpdateNodeRequest struct {
-	NodeName     *string `json:"node_name"`
-	FrpsPort     *int    `json:"frps_port"`
-	URL          *string `json:"url"`
-	Token        *string `json:"token"`
-	User         *string `json:"user"`
-	Description  *string `json:"description"`
-	Permission   *string `json:"permission"`
-	AllowedTypes *string `json:"allowed_types"`
-	Host         *string `json:"host"`
-	PortRange    *string `json:"port_range"`
-	IP           *string `json:"ip"`
-	Status       *int    `json:"status"`
-	OwnerID      *int64  `json:"owner_id"` // 节点所属用户ID，可以为null
-	ID           *int64  `json:"id"`
+	NodeName         *string         `json:"node_name"`
+	FrpsPort         *int            `json:"frps_port"`
+	URL              *string         `json:"url"`
+	Token            *string         `json:"token"`
+	User             *string         `json:"user"`
+	Description      *string         `json:"description"`
+	Permission       *string         `json:"permission"`
+	AllowedTypes     *string         `json:"allowed_types"`
+	Host             *string         `json:"host"`
+	PortRange        *string         `json:"port_range"`
+	IP               *string         `json:"ip"`
+	Status           *int            `json:"status"`
+	OwnerID          *int64          `json:"owner_id"` // 节点所属用户ID，可以为null
+	Region           *string         `json:"region"`
+	ISP              *string         `json:"isp"`
+	City             *string         `json:"city"`
+	BandwidthTier    *string         `json:"bandwidth_tier"`
+	Tags             *[]string       `json:"tags"`
+	SortOrder        *int            `json:"sort_order"`
+	MaxProxies       *int            `json:"max_proxies"`
+	MaxTypeProxies   *map[string]int `json:"max_type_proxies"`
+	MaxOnlineClients *int            `json:"max_online_clients"`
+	ID               *int64          `json:"id"`
 }
 
 // UpdateNode 更新节点
@@ -268,6 +328,47 @@ func (h *NodeAdminHandler) UpdateNode(c *gin.Context) {
 	if req.OwnerID != nil {
 		node.OwnerID = sql.NullInt64{Int64: *req.OwnerID, Valid: true}
 	}
+	if req.Region != nil {
+		node.Region = strings.TrimSpace(*req.Region)
+	}
+	if req.ISP != nil {
+		node.ISP = strings.ToLower(strings.TrimSpace(*req.ISP))
+	}
+	if req.City != nil {
+		node.City = strings.TrimSpace(*req.City)
+	}
+	if req.BandwidthTier != nil {
+		node.BandwidthTier = strings.TrimSpace(*req.BandwidthTier)
+	}
+	if req.Tags != nil {
+		if node.Tags, err = service.FormatNodeTags(*req.Tags); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+	}
+	if req.SortOrder != nil {
+		node.SortOrder = *req.SortOrder
+	}
+	if req.MaxProxies != nil {
+		node.MaxProxies = *req.MaxProxies
+	}
+	if req.MaxTypeProxies != nil {
+		if node.MaxTypeProxies, err = service.FormatNodeTypeLimits(*req.MaxTypeProxies); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+	}
+	if req.MaxOnlineClients != nil {
+		node.MaxOnlineClients = *req.MaxOnlineClients
+	}
+	if err := service.ValidateNodeMeta(node); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	if err := service.ValidateNodeCapacity(node); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
 
 	// 保存更新
 	err = h.nodeRepo.Update(context.Background(), node)
@@ -363,10 +464,13 @@ func containsIgnoreCase(s, substr string) bool {
 // ReviewDonatedNodeRequest 审核捐赠节点请求
 type ReviewDonatedNodeRequest struct {
 	ID     int64  `json:"id" binding:"required"`     // 节点ID
-	Action string `json:"action" binding:"required"` // 操作：approve或reject
+	Action string `json:"action" binding:"required"` // 操作：approve、reject、request_changes或recheck（重新自动校验）
+	Reason string `json:"reason"`                    // 拒绝或要求修改的原因，对捐赠者可见
+	Note   string `json:"note"`                      // 审核备注，仅管理员可见
 }
 
 // ReviewDonatedNode 审核捐赠节点
+// 待审核节点可通过、拒绝或要求修改，需修改的节点可直接拒绝，审核结果会通知捐赠者
 func (h *NodeAdminHandler) ReviewDonatedNode(c *gin.Context) {
 	var req ReviewDonatedNodeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -376,8 +480,10 @@ func (h *NodeAdminHandler) ReviewDonatedNode(c *gin.Context) {
 	}
 
 	// 检查操作类型
-	if req.Action != "approve" && req.Action != "reject" {
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的操作类型，只能是approve或reject"})
+	switch req.Action {
+	case service.NodeReviewApprove, service.NodeReviewReject, service.NodeReviewRequestChanges, "recheck":
+	default:
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的操作类型，只能是approve、reject、request_changes或recheck"})
 		return
 	}
 
@@ -388,46 +494,159 @@ func (h *NodeAdminHandler) ReviewDonatedNode(c *gin.Context) {
 		return
 	}
 
-	// 检查节点状态，只有状态为2（待审核）的节点才能审核
-	if node.Status != 2 {
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "只能审核状态为'待审核'的节点"})
+	if req.Action == "recheck" {
+		if node.Status != repository.NodeStatusPending && node.Status != repository.NodeStatusChangesRequested {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "只能重新校验待审核或需修改的节点"})
+			return
+		}
+		h.recheckDonatedNode(c, node)
 		return
 	}
 
-	if req.Action == "approve" {
-		// 更新节点状态为可用
-		node.Status = 1 // 设置为启用状态
+	var reviewerID int64
+	if adminID, ok := c.Get("user_id"); ok {
+		reviewerID, _ = adminID.(int64)
+	}
 
-		// 保存更新
-		err = h.nodeRepo.Update(context.Background(), node)
-		if err != nil {
-			h.logger.Error("更新节点状态失败", "error", err)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "审核节点失败：" + err.Error()})
+	review, err := h.reviewService.Review(context.Background(), node, req.Action, reviewerID, req.Note, req.Reason)
+	if err != nil {
+		if errors.Is(err, service.ErrNodeReviewInvalidState) {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "节点当前状态为" + nodeStatusDesc(node.Status) + "，不能执行该操作"})
 			return
 		}
+		h.logger.Error("审核捐赠节点失败", "error", err, "nodeID", node.ID, "action", req.Action)
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "审核节点失败：" + err.Error()})
+		return
+	}
+	h.logger.Info("已审核捐赠节点", "nodeID", node.ID, "action", req.Action, "reviewerID", reviewerID)
 
-		c.JSON(http.StatusOK, gin.H{
-			"code": 200,
-			"msg":  "节点审核通过",
-			"data": node,
-		})
-	} else {
-		// 对于拒绝操作，直接删除节点
-		err = h.nodeRepo.Delete(context.Background(), req.ID)
-		if err != nil {
-			h.logger.Error("删除捐赠节点失败", "error", err)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "拒绝节点失败：" + err.Error()})
-			return
+	msg := "节点审核通过"
+	switch req.Action {
+	case service.NodeReviewReject:
+		msg = "已拒绝该捐赠节点"
+	case service.NodeReviewRequestChanges:
+		msg = "已要求捐赠者修改节点"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  msg,
+		"data": gin.H{
+			"node":   node,
+			"review": h.nodeReviewView(context.Background(), review),
+		},
+	})
+}
+
+// ListNodeReviews 获取节点的审核记录，包含审核人和内部备注
+func (h *NodeAdminHandler) ListNodeReviews(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return
+	}
+
+	list, err := h.reviewService.List(context.Background(), id)
+	if err != nil {
+		h.logger.Error("获取节点审核记录失败", "error", err, "nodeID", id)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点审核记录失败"})
+		return
+	}
+
+	ctx := context.Background()
+	data := make([]gin.H, 0, len(list))
+	for _, review := range list {
+		data = append(data, h.nodeReviewView(ctx, review))
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": data})
+}
+
+// nodeReviewView 构建管理员查看的审核记录，附带审核人用户名
+func (h *NodeAdminHandler) nodeReviewView(ctx context.Context, review *repository.NodeReview) gin.H {
+	view := gin.H{
+		"id":          review.ID,
+		"node_id":     review.NodeID,
+		"action":      review.Action,
+		"from_status": review.FromStatus,
+		"to_status":   review.ToStatus,
+		"reviewer_id": nil,
+		"reviewer":    "",
+		"note":        review.Note,
+		"reason":      review.Reason,
+		"created_at":  review.CreatedAt,
+	}
+	if review.ReviewerID.Valid {
+		view["reviewer_id"] = review.ReviewerID.Int64
+		if user, err := h.userService.GetByID(ctx, review.ReviewerID.Int64); err == nil && user != nil {
+			view["reviewer"] = user.Username
 		}
+	}
+	return view
+}
 
-		c.JSON(http.StatusOK, gin.H{
-			"code": 200,
-			"msg":  "已拒绝并删除该捐赠节点",
-		})
+// nodeStatusDesc 节点状态描述
+func nodeStatusDesc(status int) string {
+	switch status {
+	case repository.NodeStatusOffline:
+		return "离线"
+	case repository.NodeStatusOnline:
+		return "在线"
+	case repository.NodeStatusPending:
+		return "待审核"
+	case repository.NodeStatusDegraded:
+		return "降级"
+	case repository.NodeStatusPaused:
+		return "已暂停"
+	case repository.NodeStatusRejected:
+		return "审核未通过"
+	case repository.NodeStatusChangesRequested:
+		return "需修改"
+	default:
+		return "未知"
 	}
 }
 
-// ListDonatedNodes 获取待审核的捐赠节点列表
+// recheckDonatedNode 重新自动校验捐赠节点并保存校验记录
+func (h *NodeAdminHandler) recheckDonatedNode(c *gin.Context, node *repository.Node) {
+	var operatorID int64
+	if adminID, ok := c.Get("user_id"); ok {
+		operatorID, _ = adminID.(int64)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	validation, err := h.validationService.Validate(ctx, node, service.NodeValidationTriggerRecheck, operatorID)
+	if err != nil {
+		h.logger.Error("校验捐赠节点失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "校验捐赠节点失败"})
+		return
+	}
+
+	msg := "节点校验通过"
+	if !validation.Passed {
+		msg = "节点校验未通过，请查看各项检查详情"
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": msg, "data": validation})
+}
+
+// ListNodeValidations 获取节点最近的自动校验记录
+func (h *NodeAdminHandler) ListNodeValidations(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return
+	}
+
+	list, err := h.validationService.List(context.Background(), id)
+	if err != nil {
+		h.logger.Error("获取节点校验记录失败", "error", err, "nodeID", id)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点校验记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": list})
+}
+
+// ListDonatedNodes 获取捐赠节点审核列表，默认返回待审核节点，status 可指定为5（审核未通过）或6（需修改）
 func (h *NodeAdminHandler) ListDonatedNodes(c *gin.Context) {
 	// 获取分页参数
 	pageStr := c.DefaultQuery("page", "1")
@@ -445,6 +664,12 @@ func (h *NodeAdminHandler) ListDonatedNodes(c *gin.Context) {
 		return
 	}
 
+	status, err := strconv.Atoi(c.DefaultQuery("status", strconv.Itoa(repository.NodeStatusPending)))
+	if err != nil || !repository.NodeUnapproved(status) {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的审核状态"})
+		return
+	}
+
 	// 获取所有节点
 	allNodes, err := h.nodeService.GetAllNodes(context.Background())
 	if err != nil {
@@ -453,10 +678,10 @@ func (h *NodeAdminHandler) ListDonatedNodes(c *gin.Context) {
 		return
 	}
 
-	// 过滤出状态为2（待审核）的节点
+	// 过滤出指定审核状态的节点
 	var donatedNodes []*repository.Node
 	for _, node := range allNodes {
-		if node.Status == 2 {
+		if node.Status == status {
 			donatedNodes = append(donatedNodes, node)
 		}
 	}
@@ -488,7 +713,6 @@ func (h *NodeAdminHandler) ListDonatedNodes(c *gin.Context) {
 			"NodeName":     node.NodeName,
 			"FrpsPort":     node.FrpsPort,
 			"URL":          node.URL,
-			"Token":        node.Token,
 			"User":         node.User,
 			"Description":  node.Description,
 			"Permission":   node.Permission,
@@ -497,6 +721,7 @@ func (h *NodeAdminHandler) ListDonatedNodes(c *gin.Context) {
 			"PortRange":    node.PortRange,
 			"IP":           node.IP,
 			"Status":       node.Status,
+			"FrpsVersion":  node.FrpsVersion,
 			"CreatedAt":    node.CreatedAt,
 			"UpdatedAt":    node.UpdatedAt,
 		}
@@ -523,6 +748,24 @@ func (h *NodeAdminHandler) ListDonatedNodes(c *gin.Context) {
 			}
 		}
 
+		// 附带最近一次自动校验结果
+		validation, err := h.validationService.Latest(context.Background(), node.ID)
+		if err != nil {
+			h.logger.Error("获取节点校验记录失败", "error", err, "nodeID", node.ID)
+		}
+		nodeData["Validation"] = validation
+
+		// 附带最近一次审核记录
+		review, err := h.reviewService.Latest(context.Background(), node.ID)
+		if err != nil {
+			h.logger.Error("获取节点审核记录失败", "error", err, "nodeID", node.ID)
+		}
+		if review != nil {
+			nodeData["LatestReview"] = h.nodeReviewView(context.Background(), review)
+		} else {
+			nodeData["LatestReview"] = nil
+		}
+
 		nodeList = append(nodeList, nodeData)
 	}
 
@@ -0,0 +1,114 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"stellarfrp/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNodeHeartbeat 获取节点代理最近一次上报的心跳
+func (h *NodeAdminHandler) GetNodeHeartbeat(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	hb, err := h.heartbeatService.Get(context.Background(), node.ID)
+	if err != nil {
+		h.logger.Error("获取节点心跳失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点心跳失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"node_id":       node.ID,
+			"status":        node.Status,
+			"agent_enabled": node.AgentKey != "",
+			"heartbeat":     hb,
+			"fresh":         hb != nil && hb.Fresh(time.Now()),
+		},
+	})
+}
+
+// ResetNodeAgentKey 生成新的节点代理密钥，旧密钥立即失效
+func (h *NodeAdminHandler) ResetNodeAgentKey(c *gin.Context) {
+	var req struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), req.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	agentKey, err := h.nodeService.ResetAgentKey(context.Background(), node)
+	if err != nil {
+		h.logger.Error("生成节点代理密钥失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "生成节点代理密钥失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "节点代理密钥已生成，请妥善保存，此密钥只显示一次",
+		"data": gin.H{
+			"node_id":           node.ID,
+			"agent_key":         agentKey,
+			"heartbeat_timeout": int(service.NodeHeartbeatTimeout.Seconds()),
+		},
+	})
+}
+
+// GetNodeLoadChart 获取任意节点的负载图表数据，参数同用户端接口
+func (h *NodeAdminHandler) GetNodeLoadChart(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return
+	}
+
+	from, to, err := service.ParseNodeLoadChartRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	chart, err := h.nodeLoadService.Chart(context.Background(), node.ID, from, to, c.Query("resolution"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidNodeLoadResolution) {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+		h.logger.Error("获取节点负载数据失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点负载数据失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": chart})
+}
@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"stellarfrp/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNodeFrpsConfig 生成节点的完整frps.toml，节点尚无插件密钥时需先调用重置插件密钥接口生成
+// download=true 时以文件形式返回配置内容
+func (h *NodeAdminHandler) GetNodeFrpsConfig(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	secrets, err := h.nodeService.Secrets(node)
+	if err != nil {
+		h.logger.Error("解密节点凭据失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "解密节点凭据失败"})
+		return
+	}
+	cfg := service.RenderFrpsConfig(node, h.frpsPluginURL, secrets, time.Now())
+
+	if download, _ := strconv.ParseBool(c.Query("download")); download {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=frps-%d.toml", node.ID))
+		c.Data(http.StatusOK, "application/toml; charset=utf-8", []byte(cfg.Content))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "生成成功",
+		"data": gin.H{
+			"node_id":  node.ID,
+			"config":   cfg.Content,
+			"warnings": cfg.Warnings,
+		},
+	})
+}
+
+// ResetNodePluginSecret 重置节点的frps插件回调密钥，旧配置立即失效，需重新生成配置并重启frps
+func (h *NodeAdminHandler) ResetNodePluginSecret(c *gin.Context) {
+	var req struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), req.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	if _, err := h.nodeService.ResetPluginSecret(context.Background(), node); err != nil {
+		h.logger.Error("重置节点插件密钥失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "重置节点插件密钥失败"})
+		return
+	}
+
+	secrets, err := h.nodeService.Secrets(node)
+	if err != nil {
+		h.logger.Error("解密节点凭据失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "解密节点凭据失败"})
+		return
+	}
+	cfg := service.RenderFrpsConfig(node, h.frpsPluginURL, secrets, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "插件密钥已重置，请使用新配置重启节点frps",
+		"data": gin.H{
+			"node_id":  node.ID,
+			"config":   cfg.Content,
+			"warnings": cfg.Warnings,
+		},
+	})
+}
@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleNodeMaintenanceRequest 创建节点维护计划请求，时间使用RFC3339格式
+type ScheduleNodeMaintenanceRequest struct {
+	NodeID  int64     `json:"node_id" binding:"required"`
+	StartAt time.Time `json:"start_at" binding:"required"`
+	EndAt   time.Time `json:"end_at" binding:"required"`
+	Message string    `json:"message" binding:"required"`
+}
+
+// ScheduleNodeMaintenance 创建节点维护计划
+func (h *NodeAdminHandler) ScheduleNodeMaintenance(c *gin.Context) {
+	var req ScheduleNodeMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), req.NodeID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	m := &repository.NodeMaintenance{
+		NodeID:  node.ID,
+		StartAt: req.StartAt,
+		EndAt:   req.EndAt,
+		Message: req.Message,
+	}
+	if adminID, ok := c.Get("user_id"); ok {
+		m.CreatedBy, _ = adminID.(int64)
+	}
+
+	if err := h.maintenanceService.Schedule(context.Background(), m); err != nil {
+		if errors.Is(err, service.ErrNodeMaintenanceOverlap) {
+			c.JSON(http.StatusOK, gin.H{"code": 409, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	h.logger.Info("已创建节点维护计划", "node", node.NodeName, "maintenanceID", m.ID, "start", m.StartAt, "end", m.EndAt)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "维护计划已创建", "data": m})
+}
+
+// ListNodeMaintenances 获取节点维护计划列表，node_id 为空时获取全部节点
+func (h *NodeAdminHandler) ListNodeMaintenances(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的页码"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的每页数量"})
+		return
+	}
+
+	var nodeID int64
+	if nodeIDStr := c.Query("node_id"); nodeIDStr != "" {
+		nodeID, err = strconv.ParseInt(nodeIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+			return
+		}
+	}
+
+	list, total, err := h.maintenanceService.List(context.Background(), nodeID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("获取节点维护计划失败", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点维护计划失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": list,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+			"pages":     (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// CancelNodeMaintenance 取消节点维护计划
+func (h *NodeAdminHandler) CancelNodeMaintenance(c *gin.Context) {
+	var req struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	m, err := h.maintenanceService.Cancel(context.Background(), req.ID)
+	if err != nil {
+		if errors.Is(err, service.ErrNodeMaintenanceNotFound) {
+			c.JSON(http.StatusOK, gin.H{"code": 404, "msg": err.Error()})
+			return
+		}
+		h.logger.Error("取消节点维护计划失败", "error", err, "maintenanceID", req.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "取消节点维护计划失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "维护计划已取消", "data": m})
+}
@@ -0,0 +1,233 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListNodeRewardRules 获取贡献奖励规则列表
+func (h *NodeAdminHandler) ListNodeRewardRules(c *gin.Context) {
+	rules, err := h.rewardService.ListRules(context.Background())
+	if err != nil {
+		h.logger.Error("获取贡献奖励规则失败", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取贡献奖励规则失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": rules})
+}
+
+// SaveNodeRewardRuleRequest 保存贡献奖励规则请求，id 为0时创建
+type SaveNodeRewardRuleRequest struct {
+	ID           int64   `json:"id"`
+	Name         string  `json:"name" binding:"required"`
+	Enabled      bool    `json:"enabled"`
+	MinTraffic   int64   `json:"min_traffic"`
+	MinUptime    float64 `json:"min_uptime"`
+	RewardType   string  `json:"reward_type" binding:"required"`
+	RewardValue  int64   `json:"reward_value" binding:"required"`
+	BaseGroupID  int64   `json:"base_group_id"`
+	DurationDays int     `json:"duration_days"`
+	MonthlyCap   int64   `json:"monthly_cap"`
+}
+
+// SaveNodeRewardRule 创建或更新贡献奖励规则
+func (h *NodeAdminHandler) SaveNodeRewardRule(c *gin.Context) {
+	var req SaveNodeRewardRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	rule := &repository.NodeRewardRule{
+		ID:           req.ID,
+		Name:         req.Name,
+		Enabled:      req.Enabled,
+		MinTraffic:   req.MinTraffic,
+		MinUptime:    req.MinUptime,
+		RewardType:   req.RewardType,
+		RewardValue:  req.RewardValue,
+		BaseGroupID:  req.BaseGroupID,
+		DurationDays: req.DurationDays,
+		MonthlyCap:   req.MonthlyCap,
+	}
+	if err := h.rewardService.SaveRule(context.Background(), rule); err != nil {
+		if errors.Is(err, service.ErrNodeRewardRuleNotFound) {
+			c.JSON(http.StatusOK, gin.H{"code": 404, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	h.logger.Info("已保存贡献奖励规则", "ruleID", rule.ID, "type", rule.RewardType, "enabled", rule.Enabled)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "保存成功", "data": rule})
+}
+
+// DeleteNodeRewardRule 删除贡献奖励规则，已发放的流水保留
+func (h *NodeAdminHandler) DeleteNodeRewardRule(c *gin.Context) {
+	var req struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	if err := h.rewardService.DeleteRule(context.Background(), req.ID); err != nil {
+		if errors.Is(err, service.ErrNodeRewardRuleNotFound) {
+			c.JSON(http.StatusOK, gin.H{"code": 404, "msg": err.Error()})
+			return
+		}
+		h.logger.Error("删除贡献奖励规则失败", "error", err, "ruleID", req.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "删除贡献奖励规则失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "删除成功"})
+}
+
+// ListNodeRewardCredits 获取贡献奖励流水，支持按 node_id、user_id 筛选
+func (h *NodeAdminHandler) ListNodeRewardCredits(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的页码"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的每页数量"})
+		return
+	}
+
+	var nodeID, userID int64
+	if nodeIDStr := c.Query("node_id"); nodeIDStr != "" {
+		if nodeID, err = strconv.ParseInt(nodeIDStr, 10, 64); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+			return
+		}
+	}
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		if userID, err = strconv.ParseInt(userIDStr, 10, 64); err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的用户ID"})
+			return
+		}
+	}
+
+	list, total, err := h.rewardService.ListCredits(context.Background(), userID, nodeID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("获取贡献奖励流水失败", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取贡献奖励流水失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": list,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+			"pages":     (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// SettleNodeRewards 手动结算指定日期的贡献奖励，已结算的不会重复发放
+func (h *NodeAdminHandler) SettleNodeRewards(c *gin.Context) {
+	var req struct {
+		Date string `json:"date" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", req.Date, time.Local)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "日期格式应为 YYYY-MM-DD"})
+		return
+	}
+	today := time.Now()
+	if !date.Before(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)) {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "只能结算今天之前的日期"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	count, err := h.rewardService.Settle(ctx, date)
+	if err != nil {
+		h.logger.Error("手动结算贡献奖励失败", "error", err, "date", req.Date)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "结算失败：" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "结算完成", "data": gin.H{"date": req.Date, "credited": count}})
+}
+
+// ClawbackNodeRewardsRequest 追回节点贡献奖励请求
+type ClawbackNodeRewardsRequest struct {
+	NodeID int64  `json:"node_id" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+	Block  bool   `json:"block"` // 是否同时停止向该节点发放奖励
+}
+
+// ClawbackNodeRewards 追回节点已发放的贡献奖励，用于节点被认定滥用的情况
+func (h *NodeAdminHandler) ClawbackNodeRewards(c *gin.Context) {
+	var req ClawbackNodeRewardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), req.NodeID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	var operatorID int64
+	if adminID, ok := c.Get("user_id"); ok {
+		operatorID, _ = adminID.(int64)
+	}
+
+	count, err := h.rewardService.Clawback(context.Background(), node, req.Reason, operatorID, req.Block)
+	if err != nil {
+		h.logger.Error("追回贡献奖励失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "追回失败：" + err.Error(), "data": gin.H{"clawed_back": count}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "追回完成", "data": gin.H{"clawed_back": count, "reward_blocked": node.RewardBlocked}})
+}
+
+// SetNodeRewardBlocked 设置是否停止向节点发放贡献奖励
+func (h *NodeAdminHandler) SetNodeRewardBlocked(c *gin.Context) {
+	var req struct {
+		NodeID  int64 `json:"node_id" binding:"required"`
+		Blocked bool  `json:"blocked"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), req.NodeID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	if err := h.nodeService.SetRewardBlocked(context.Background(), node, req.Blocked); err != nil {
+		h.logger.Error("设置节点奖励状态失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "设置失败"})
+		return
+	}
+	h.logger.Info("已设置节点贡献奖励状态", "nodeID", node.ID, "blocked", req.Blocked)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "设置成功"})
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"stellarfrp/pkg/secret"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNodeCredentials 查看节点解密后的面板密码、代理密钥和插件密钥，每次查看都会记录操作日志
+func (h *NodeAdminHandler) GetNodeCredentials(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	secrets, err := h.nodeService.Secrets(node)
+	if err != nil {
+		h.logger.Error("解密节点凭据失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "解密节点凭据失败"})
+		return
+	}
+
+	var adminID int64
+	if v, ok := c.Get("user_id"); ok {
+		adminID, _ = v.(int64)
+	}
+	h.logger.Warn("管理员查看节点凭据", "nodeID", node.ID, "adminID", adminID, "ip", c.ClientIP())
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"node_id":       node.ID,
+			"user":          node.User,
+			"token":         secrets.Token,
+			"agent_key":     secrets.AgentKey,
+			"plugin_secret": secrets.PluginSecret,
+		},
+	})
+}
+
+// RotateNodeSecrets 使用当前密钥重新加密所有节点凭据和Webhook签名密钥，用于轮换密钥后立即完成迁移
+func (h *NodeAdminHandler) RotateNodeSecrets(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	count, err := h.nodeService.RotateSecrets(ctx)
+	if err != nil {
+		if errors.Is(err, secret.ErrNoKey) {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "未配置SECRET_KEYS，无法加密节点凭据"})
+			return
+		}
+		h.logger.Error("重新加密节点凭据失败", "error", err, "rotated", count)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "重新加密节点凭据失败：" + err.Error(), "data": gin.H{"rotated": count, "alerts": 0}})
+		return
+	}
+
+	alertCount, err := h.proxyAlertService.RotateSecrets(ctx)
+	if err != nil {
+		h.logger.Error("重新加密Webhook签名密钥失败", "error", err, "rotated", alertCount)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "重新加密Webhook签名密钥失败：" + err.Error(), "data": gin.H{"rotated": count, "alerts": alertCount}})
+		return
+	}
+
+	h.logger.Info("已重新加密节点凭据和Webhook签名密钥", "nodes", count, "alerts", alertCount)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "重新加密完成", "data": gin.H{"rotated": count, "alerts": alertCount}})
+}
@@ -1,38 +1,32 @@
 package admin
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"stellarfrp/internal/repository"
+	"stellarfrp/internal/scheduler"
 	"stellarfrp/internal/service"
+	"stellarfrp/pkg/frps"
 	"stellarfrp/pkg/logger"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 )
 
-// 辅助函数，返回两个整数中的较小值
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 // ProxyAdminHandler 隧道管理处理器
 type ProxyAdminHandler struct {
-	proxyService service.ProxyService
-	nodeService  service.NodeService
-	userService  service.UserService
-	redisCli     *redis.Client
-	logger       *logger.Logger
+	proxyService    service.ProxyService
+	nodeService     service.NodeService
+	userService     service.UserService
+	proxyValidator  *service.ProxyValidator
+	reconciler      *scheduler.ProxyReconciler
+	frpsClient      *frps.Client
+	snapshotService service.ProxyStatusSnapshotService
+	logger          *logger.Logger
 }
 
 // NewProxyAdminHandler 创建隧道管理处理器实例
@@ -40,15 +34,21 @@ func NewProxyAdminHandler(
 	proxyService service.ProxyService,
 	nodeService service.NodeService,
 	userService service.UserService,
-	redisCli *redis.Client,
+	proxyValidator *service.ProxyValidator,
+	reconciler *scheduler.ProxyReconciler,
+	frpsClient *frps.Client,
+	snapshotService service.ProxyStatusSnapshotService,
 	logger *logger.Logger,
 ) *ProxyAdminHandler {
 	return &ProxyAdminHandler{
-		proxyService: proxyService,
-		nodeService:  nodeService,
-		userService:  userService,
-		redisCli:     redisCli,
-		logger:       logger,
+		proxyService:    proxyService,
+		nodeService:     nodeService,
+		userService:     userService,
+		proxyValidator:  proxyValidator,
+		reconciler:      reconciler,
+		frpsClient:      frpsClient,
+		snapshotService: snapshotService,
+		logger:          logger,
 	}
 }
 
@@ -172,7 +172,34 @@ func (h *ProxyAdminHandler) ListProxies(c *gin.Context) {
 	})
 }
 
-// GetProxyStatus 获取隧道状态
+// proxyStatusData 根据轮询快照构建隧道状态数据，没有快照时视为离线
+func proxyStatusData(proxy *repository.Proxy, nodeName string, snapshot *service.ProxyStatusSnapshot) gin.H {
+	data := gin.H{
+		"status":          "offline",
+		"proxy_id":        proxy.ID,
+		"proxy_name":      proxy.ProxyName,
+		"node_id":         proxy.Node,
+		"node_name":       nodeName,
+		"last_start_time": "",
+		"last_close_time": "",
+		"cur_conns":       0,
+		"traffic_in":      int64(0),
+		"traffic_out":     int64(0),
+		"updated_at":      nil,
+	}
+	if snapshot != nil {
+		data["status"] = snapshot.Status
+		data["last_start_time"] = snapshot.LastStartTime
+		data["last_close_time"] = snapshot.LastCloseTime
+		data["cur_conns"] = snapshot.CurConns
+		data["traffic_in"] = snapshot.TodayTrafficIn
+		data["traffic_out"] = snapshot.TodayTrafficOut
+		data["updated_at"] = snapshot.UpdatedAt.Format("2006-01-02 15:04:05")
+	}
+	return data
+}
+
+// GetProxyStatus 获取隧道状态（来自后台轮询快照）
 func (h *ProxyAdminHandler) GetProxyStatus(c *gin.Context) {
 	// 获取隧道ID
 	idStr := c.Param("id")
@@ -184,199 +211,34 @@ func (h *ProxyAdminHandler) GetProxyStatus(c *gin.Context) {
 
 	// 获取隧道信息
 	proxy, err := h.proxyService.GetByID(context.Background(), id)
-	if err != nil {
+	if err != nil || proxy == nil {
 		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道不存在"})
 		return
 	}
 
 	// 获取节点信息
 	node, err := h.nodeService.GetByID(context.Background(), proxy.Node)
-	if err != nil {
+	if err != nil || node == nil {
 		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
 		return
 	}
 
-	// 检查缓存中是否有状态信息
-	cacheKey := fmt.Sprintf("proxy:status:%d", id)
-	cachedStatus, err := h.redisCli.Get(context.Background(), cacheKey).Result()
-	if err == nil {
-		// 缓存命中，解析并返回
-		var statusData gin.H
-		if err := json.Unmarshal([]byte(cachedStatus), &statusData); err == nil {
-			c.JSON(http.StatusOK, gin.H{
-				"code": 200,
-				"msg":  "获取成功",
-				"data": statusData,
-			})
-			return
-		}
-	}
-
-	// 缓存未命中，向节点查询状态
-	// 根据隧道类型构建API URL
-	apiURL := fmt.Sprintf("%s/api/proxy/%s", node.URL, proxy.ProxyType)
-	h.logger.Debug("请求节点API", "url", apiURL, "node_id", node.ID, "proxy_type", proxy.ProxyType)
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	req, err := http.NewRequest("GET", apiURL, nil)
+	snapshot, err := h.snapshotService.Get(context.Background(), proxy.ID)
 	if err != nil {
-		h.logger.Error("创建请求失败", "error", err, "url", apiURL)
-		c.JSON(http.StatusOK, gin.H{
-			"code": 500,
-			"msg":  "获取隧道状态失败",
-			"data": gin.H{
-				"status":          "offline",
-				"proxy_id":        proxy.ID,
-				"proxy_name":      proxy.ProxyName,
-				"node_id":         node.ID,
-				"node_name":       node.NodeName,
-				"last_start_time": "",
-				"last_close_time": "",
-				"cur_conns":       0,
-				"traffic_in":      0,
-				"traffic_out":     0,
-			},
-		})
-		return
-	}
-
-	req.SetBasicAuth(node.User, node.Token)
-	resp, err := client.Do(req)
-	if err != nil {
-		h.logger.Error("请求节点失败", "error", err, "nodeID", node.ID)
-		c.JSON(http.StatusOK, gin.H{
-			"code": 200,
-			"msg":  "获取成功，但节点离线",
-			"data": gin.H{
-				"status":          "offline",
-				"proxy_id":        proxy.ID,
-				"proxy_name":      proxy.ProxyName,
-				"node_id":         node.ID,
-				"node_name":       node.NodeName,
-				"last_start_time": "",
-				"last_close_time": "",
-				"cur_conns":       0,
-				"traffic_in":      0,
-				"traffic_out":     0,
-			},
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	// 读取响应内容
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		h.logger.Error("读取响应内容失败", "error", err)
-		c.JSON(http.StatusOK, gin.H{
-			"code": 500,
-			"msg":  "读取节点响应失败",
-		})
-		return
-	}
-
-	// 记录原始响应数据进行调试
-	h.logger.Debug("节点原始响应", "nodeID", node.ID, "body_length", len(body))
-
-	// 解析响应
-	var proxyTypeResponse struct {
-		Proxies []map[string]interface{} `json:"proxies"`
-	}
-	if err := json.Unmarshal(body, &proxyTypeResponse); err != nil {
-		h.logger.Error("解析节点响应失败", "error", err, "body_preview", string(body[:min(100, len(body))]))
-		c.JSON(http.StatusOK, gin.H{
-			"code": 200,
-			"msg":  "获取成功，但无法解析节点响应",
-			"data": gin.H{
-				"status":          "offline",
-				"proxy_id":        proxy.ID,
-				"proxy_name":      proxy.ProxyName,
-				"node_id":         node.ID,
-				"node_name":       node.NodeName,
-				"last_start_time": "",
-				"last_close_time": "",
-				"cur_conns":       0,
-				"traffic_in":      0,
-				"traffic_out":     0,
-			},
-		})
+		h.logger.Error("获取隧道状态快照失败", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道状态失败"})
 		return
 	}
 
-	// 预期的隧道名称格式
-	expectedProxyName := proxy.Username + "." + proxy.ProxyName
-
-	// 获取隧道状态信息
-	status := "offline"
-	lastStartTime := ""
-	lastCloseTime := ""
-	curConns := 0
-	trafficIn := int64(0)
-	trafficOut := int64(0)
-
-	// 在返回的隧道列表中查找目标隧道
-	for _, proxyInfo := range proxyTypeResponse.Proxies {
-		name, ok := proxyInfo["name"].(string)
-		if !ok || name != expectedProxyName {
-			continue
-		}
-
-		// 找到目标隧道
-		if statusStr, ok := proxyInfo["status"].(string); ok && statusStr == "online" {
-			status = "online"
-		}
-
-		if start, ok := proxyInfo["lastStartTime"].(string); ok {
-			lastStartTime = start
-		}
-
-		if close, ok := proxyInfo["lastCloseTime"].(string); ok {
-			lastCloseTime = close
-		}
-
-		if conns, ok := proxyInfo["curConns"].(float64); ok {
-			curConns = int(conns)
-		}
-
-		if in, ok := proxyInfo["todayTrafficIn"].(float64); ok {
-			trafficIn = int64(in)
-		}
-
-		if out, ok := proxyInfo["todayTrafficOut"].(float64); ok {
-			trafficOut = int64(out)
-		}
-
-		break
-	}
-
-	// 构建响应数据
-	statusData := gin.H{
-		"status":          status,
-		"proxy_id":        proxy.ID,
-		"proxy_name":      proxy.ProxyName,
-		"node_id":         node.ID,
-		"node_name":       node.NodeName,
-		"last_start_time": lastStartTime,
-		"last_close_time": lastCloseTime,
-		"cur_conns":       curConns,
-		"traffic_in":      trafficIn,
-		"traffic_out":     trafficOut,
-	}
-
-	// 缓存状态数据（30秒）
-	statusJSON, _ := json.Marshal(statusData)
-	h.redisCli.Set(context.Background(), cacheKey, statusJSON, 30*time.Second)
-
 	c.JSON(http.StatusOK, gin.H{
-		"code": 200,
-		"msg":  "获取成功",
-		"data": statusData,
+		"code":          200,
+		"msg":           "获取成功",
+		"data":          proxyStatusData(proxy, node.NodeName, snapshot),
+		"poll_interval": int(service.ProxyStatusPollInterval.Seconds()),
 	})
 }
 
-// ListAllProxiesStatus 批量获取所有隧道状态（带分页，优化查询）
+// ListAllProxiesStatus 批量获取所有隧道状态（带分页，来自后台轮询快照）
 func (h *ProxyAdminHandler) ListAllProxiesStatus(c *gin.Context) {
 	// 可选的查询参数：仅查询在线隧道
 	onlineOnly := c.DefaultQuery("online_only", "false") == "true"
@@ -449,18 +311,6 @@ func (h *ProxyAdminHandler) ListAllProxiesStatus(c *gin.Context) {
 		return
 	}
 
-	// 按节点ID分组隧道
-	nodeProxiesMap := make(map[int64][]*repository.Proxy)
-	for _, proxy := range proxies {
-		nodeProxiesMap[proxy.Node] = append(nodeProxiesMap[proxy.Node], proxy)
-	}
-
-	// 获取所有涉及的节点信息
-	nodeIDs := make([]int64, 0, len(nodeProxiesMap))
-	for nodeID := range nodeProxiesMap {
-		nodeIDs = append(nodeIDs, nodeID)
-	}
-
 	// 获取节点信息
 	allNodes, err := h.nodeService.GetAllNodes(context.Background())
 	if err != nil {
@@ -469,185 +319,36 @@ func (h *ProxyAdminHandler) ListAllProxiesStatus(c *gin.Context) {
 		return
 	}
 
-	// 创建节点ID到节点的映射
-	nodeMap := make(map[int64]*repository.Node)
+	// 创建节点ID到节点名称的映射
+	nodeNames := make(map[int64]string)
 	for _, node := range allNodes {
-		nodeMap[node.ID] = node
+		nodeNames[node.ID] = node.NodeName
 	}
 
-	// 同步获取所有节点上的隧道状态
-	var mu sync.Mutex
-	proxyStatusMap := make(map[int64]gin.H)
-	var wg sync.WaitGroup
-
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+	proxyIDs := make([]int64, len(proxies))
+	for i, proxy := range proxies {
+		proxyIDs[i] = proxy.ID
 	}
-
-	for nodeID, nodeProxies := range nodeProxiesMap {
-		node, exists := nodeMap[nodeID]
-		if !exists {
-			// 节点不存在，标记所有相关隧道为离线
-			for _, proxy := range nodeProxies {
-				proxyStatusMap[proxy.ID] = gin.H{
-					"status":          "offline",
-					"proxy_id":        proxy.ID,
-					"proxy_name":      proxy.ProxyName,
-					"node_id":         nodeID,
-					"node_name":       "未知节点",
-					"last_start_time": "",
-					"last_close_time": "",
-					"cur_conns":       0,
-					"traffic_in":      0,
-					"traffic_out":     0,
-				}
-			}
-			continue
-		}
-
-		wg.Add(1)
-		go func(node *repository.Node, proxies []*repository.Proxy) {
-			defer wg.Done()
-
-			// 构建批量查询URL
-			// 需要按照隧道类型分别查询
-			proxyTypeMap := make(map[string][]*repository.Proxy)
-			for _, proxy := range proxies {
-				proxyType := proxy.ProxyType
-				proxyTypeMap[proxyType] = append(proxyTypeMap[proxyType], proxy)
-			}
-
-			// 存储所有隧道的状态信息
-			proxyStatusInfoMap := make(map[string]map[string]interface{})
-
-			// 对每种隧道类型分别查询
-			for proxyType, _ := range proxyTypeMap {
-				apiURL := fmt.Sprintf("%s/api/proxy/%s", node.URL, proxyType)
-				h.logger.Debug("请求节点API", "url", apiURL, "node_id", node.ID, "proxy_type", proxyType)
-
-				req, err := http.NewRequest("GET", apiURL, nil)
-				if err != nil {
-					h.logger.Error("创建请求失败", "error", err, "url", apiURL)
-					continue
-				}
-
-				req.SetBasicAuth(node.User, node.Token)
-				resp, err := client.Do(req)
-				if err != nil {
-					h.logger.Error("请求节点失败", "error", err, "nodeID", node.ID, "proxy_type", proxyType)
-					continue
-				}
-
-				body, err := io.ReadAll(resp.Body)
-				resp.Body.Close()
-
-				if err != nil {
-					h.logger.Error("读取响应内容失败", "error", err, "nodeID", node.ID, "proxy_type", proxyType)
-					continue
-				}
-
-				// 记录原始响应数据进行调试
-				h.logger.Debug("节点原始响应", "nodeID", node.ID, "proxy_type", proxyType, "body_length", len(body))
-
-				// 解析节点响应
-				var proxyTypeResponse struct {
-					Proxies []map[string]interface{} `json:"proxies"`
-				}
-
-				if err := json.Unmarshal(body, &proxyTypeResponse); err != nil {
-					h.logger.Error("解析节点响应失败", "error", err, "nodeID", node.ID, "proxy_type", proxyType, "body_preview", string(body[:min(100, len(body))]))
-					continue
-				}
-
-				// 处理该类型的所有隧道
-				for _, proxyInfo := range proxyTypeResponse.Proxies {
-					name, ok := proxyInfo["name"].(string)
-					if !ok {
-						continue
-					}
-					proxyStatusInfoMap[name] = proxyInfo
-				}
-			}
-
-			// 处理节点返回的隧道状态
-			mu.Lock()
-			for _, proxy := range proxies {
-				// 预期的隧道名称格式
-				expectedProxyName := proxy.Username + "." + proxy.ProxyName
-
-				// 默认离线状态
-				status := "offline"
-				lastStartTime := ""
-				lastCloseTime := ""
-				curConns := 0
-				trafficIn := int64(0)
-				trafficOut := int64(0)
-
-				// 查找并解析隧道状态
-				if proxyInfo, found := proxyStatusInfoMap[expectedProxyName]; found {
-					if statusStr, ok := proxyInfo["status"].(string); ok && statusStr == "online" {
-						status = "online"
-					}
-
-					if start, ok := proxyInfo["lastStartTime"].(string); ok {
-						lastStartTime = start
-					}
-
-					if close, ok := proxyInfo["lastCloseTime"].(string); ok {
-						lastCloseTime = close
-					}
-
-					if conns, ok := proxyInfo["curConns"].(float64); ok {
-						curConns = int(conns)
-					}
-
-					if in, ok := proxyInfo["todayTrafficIn"].(float64); ok {
-						trafficIn = int64(in)
-					}
-
-					if out, ok := proxyInfo["todayTrafficOut"].(float64); ok {
-						trafficOut = int64(out)
-					}
-				}
-
-				// 保存状态结果
-				proxyStatusMap[proxy.ID] = gin.H{
-					"status":          status,
-					"proxy_id":        proxy.ID,
-					"proxy_name":      proxy.ProxyName,
-					"node_id":         node.ID,
-					"node_name":       node.NodeName,
-					"last_start_time": lastStartTime,
-					"last_close_time": lastCloseTime,
-					"cur_conns":       curConns,
-					"traffic_in":      trafficIn,
-					"traffic_out":     trafficOut,
-				}
-
-				// 缓存该隧道状态（30秒）
-				cacheKey := fmt.Sprintf("proxy:status:%d", proxy.ID)
-				statusJSON, _ := json.Marshal(proxyStatusMap[proxy.ID])
-				h.redisCli.Set(context.Background(), cacheKey, statusJSON, 30*time.Second)
-			}
-			mu.Unlock()
-		}(node, nodeProxies)
+	snapshots, err := h.snapshotService.GetMany(context.Background(), proxyIDs)
+	if err != nil {
+		h.logger.Error("获取隧道状态快照失败", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道状态失败"})
+		return
 	}
 
-	// 等待所有查询完成
-	wg.Wait()
-
 	// 按原隧道顺序构建结果
 	var result []gin.H
 	for _, proxy := range proxies {
-		if status, exists := proxyStatusMap[proxy.ID]; exists {
-			// 如果要筛选仅显示在线隧道
-			if onlineOnly {
-				if statusStr, ok := status["status"].(string); ok && statusStr != "online" {
-					continue
-				}
-			}
-			result = append(result, status)
+		nodeName, exists := nodeNames[proxy.Node]
+		if !exists {
+			nodeName = "未知节点"
+		}
+		status := proxyStatusData(proxy, nodeName, snapshots[proxy.ID])
+		// 如果要筛选仅显示在线隧道
+		if onlineOnly && status["status"] != "online" {
+			continue
 		}
+		result = append(result, status)
 	}
 
 	// 如果进行了状态过滤，更新总数
@@ -665,7 +366,8 @@ func (h *ProxyAdminHandler) ListAllProxiesStatus(c *gin.Context) {
 			"pages":     pages,
 			"total":     filteredTotal,
 		},
-		"proxies": result,
+		"proxies":       result,
+		"poll_interval": int(service.ProxyStatusPollInterval.Seconds()),
 	})
 }
 
@@ -789,73 +491,34 @@ func (h *ProxyAdminHandler) CloseProxy(c *gin.Context) {
 		return
 	}
 
-	// 构建关闭隧道的请求
-	requestBody, err := json.Marshal(map[string]string{
-		"runid": proxy.RunID,
-	})
-	if err != nil {
-		h.logger.Error("构建请求体失败", "error", err)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-		return
-	}
-
 	// 发送关闭请求到节点
-	apiURL := fmt.Sprintf("%s/api/client/kick", node.URL)
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	req2, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(requestBody))
-	if err != nil {
-		h.logger.Error("创建请求失败", "error", err, "url", apiURL)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-		return
-	}
-
-	req2.Header.Set("Content-Type", "application/json")
-	req2.SetBasicAuth(node.User, node.Token)
-
-	resp, err := client.Do(req2)
-	if err != nil {
-		h.logger.Error("发送请求失败", "error", err, "url", apiURL)
+	if err := h.frpsClient.KickClient(context.Background(), service.NodeEndpoint(node), proxy.RunID); err != nil {
+		h.logger.Error("关闭隧道请求失败", "error", err, "nodeID", node.ID)
+		var statusErr *frps.StatusError
+		if errors.As(err, &statusErr) {
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "节点返回错误: " + statusErr.Body})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "关闭隧道请求失败"})
 		return
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		h.logger.Error("读取响应失败", "error", err)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "读取响应失败"})
-		return
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		h.logger.Error("解析响应失败", "error", err, "body", string(body))
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "解析响应失败"})
-		return
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		h.logger.Error("节点返回错误", "statusCode", resp.StatusCode, "response", string(body))
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "节点返回错误: " + string(body)})
-		return
-	}
 
 	// 更新隧道状态
 	proxy.RunID = ""
 	proxy.Status = "offline"
 	proxy.LastUpdate = time.Now().Format("2006-01-02 15:04:05")
-	if err := h.proxyService.Update(context.Background(), proxy); err != nil {
+	err = h.proxyService.UpdateBy(context.Background(), proxy, service.ProxyOperator{
+		Type:   service.ProxyOperatorAdmin,
+		Reason: service.ProxyEventReasonAdminKick,
+	})
+	if err != nil {
 		h.logger.Error("更新隧道状态失败", "error", err)
 		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "隧道已关闭，但更新数据库状态失败"})
 		return
 	}
 
 	// 清除状态缓存
-	cacheKey := fmt.Sprintf("proxy:status:%d", proxy.ID)
-	h.redisCli.Del(context.Background(), cacheKey)
+	h.snapshotService.Delete(context.Background(), proxy.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
@@ -930,10 +593,6 @@ func (h *ProxyAdminHandler) CloseUserProxies(c *gin.Context) {
 	var mu sync.Mutex
 	result := make(map[int64]gin.H)
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
 	for _, proxy := range proxies {
 		// 只处理在线的隧道
 		if proxy.Status != "online" || proxy.RunID == "" {
@@ -967,79 +626,27 @@ func (h *ProxyAdminHandler) CloseUserProxies(c *gin.Context) {
 		go func(proxy *repository.Proxy, node *repository.Node) {
 			defer wg.Done()
 
-			// 构建关闭请求
-			requestBody, err := json.Marshal(map[string]string{
-				"runid": proxy.RunID,
-			})
-			if err != nil {
-				h.logger.Error("构建请求体失败", "error", err, "proxy_id", proxy.ID)
-				mu.Lock()
-				result[proxy.ID] = gin.H{
-					"id":            proxy.ID,
-					"proxy_name":    proxy.ProxyName,
-					"node_id":       proxy.Node,
-					"node_name":     node.NodeName,
-					"status":        "构建请求失败",
-					"close_success": false,
-					"error":         err.Error(),
-				}
-				mu.Unlock()
-				return
-			}
-
 			// 发送关闭请求
-			apiURL := fmt.Sprintf("%s/api/client/kick", node.URL)
-			req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(requestBody))
-			if err != nil {
-				h.logger.Error("创建请求失败", "error", err, "url", apiURL)
-				mu.Lock()
-				result[proxy.ID] = gin.H{
-					"id":            proxy.ID,
-					"proxy_name":    proxy.ProxyName,
-					"node_id":       proxy.Node,
-					"node_name":     node.NodeName,
-					"status":        "创建请求失败",
-					"close_success": false,
-					"error":         err.Error(),
-				}
-				mu.Unlock()
-				return
-			}
-
-			req.Header.Set("Content-Type", "application/json")
-			req.SetBasicAuth(node.User, node.Token)
-
-			resp, err := client.Do(req)
+			err := h.frpsClient.KickClient(context.Background(), service.NodeEndpoint(node), proxy.RunID)
 			if err != nil {
-				h.logger.Error("发送请求失败", "error", err, "url", apiURL)
-				mu.Lock()
-				result[proxy.ID] = gin.H{
-					"id":            proxy.ID,
-					"proxy_name":    proxy.ProxyName,
-					"node_id":       proxy.Node,
-					"node_name":     node.NodeName,
-					"status":        "发送请求失败",
-					"close_success": false,
-					"error":         err.Error(),
-				}
-				mu.Unlock()
-				return
+				h.logger.Error("发送请求失败", "error", err, "nodeID", node.ID, "proxy_id", proxy.ID)
 			}
-			defer resp.Body.Close()
 
 			// 处理响应
-			success := resp.StatusCode == http.StatusOK
+			success := err == nil
 
 			// 更新隧道状态
 			if success {
 				proxy.RunID = ""
 				proxy.Status = "offline"
 				proxy.LastUpdate = time.Now().Format("2006-01-02 15:04:05")
-				err = h.proxyService.Update(context.Background(), proxy)
+				err = h.proxyService.UpdateBy(context.Background(), proxy, service.ProxyOperator{
+					Type:   service.ProxyOperatorAdmin,
+					Reason: service.ProxyEventReasonAdminKick,
+				})
 
 				// 清除状态缓存
-				cacheKey := fmt.Sprintf("proxy:status:%d", proxy.ID)
-				h.redisCli.Del(context.Background(), cacheKey)
+				h.snapshotService.Delete(context.Background(), proxy.ID)
 			}
 
 			mu.Lock()
@@ -1127,53 +734,15 @@ func (h *ProxyAdminHandler) DeleteProxy(c *gin.Context) {
 			return
 		}
 
-		// 构建关闭隧道的请求
-		requestBody, err := json.Marshal(map[string]string{
-			"runid": proxy.RunID,
-		})
-		if err != nil {
-			h.logger.Error("构建请求体失败", "error", err)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-			return
-		}
-
-		// 发送关闭请求到节点
-		apiURL := fmt.Sprintf("%s/api/client/kick", node.URL)
-		client := &http.Client{
-			Timeout: 10 * time.Second,
-		}
-
-		req2, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(requestBody))
-		if err != nil {
-			h.logger.Error("创建请求失败", "error", err, "url", apiURL)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-			return
-		}
-
-		req2.Header.Set("Content-Type", "application/json")
-		req2.SetBasicAuth(node.User, node.Token)
-
-		resp, err := client.Do(req2)
-		if err != nil {
-			h.logger.Error("发送请求失败", "error", err, "url", apiURL)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "关闭隧道请求失败，无法删除"})
-			return
-		}
-		defer resp.Body.Close()
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			h.logger.Error("读取响应失败", "error", err)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "读取响应失败"})
-			return
-		}
-
-		// 尝试解析响应，但即使解析失败也继续执行删除操作
-		var result map[string]interface{}
-		if err := json.Unmarshal(body, &result); err != nil {
-			h.logger.Warn("解析节点响应失败，但将继续删除隧道", "error", err, "body", string(body))
-		} else if resp.StatusCode != http.StatusOK {
-			h.logger.Warn("节点返回非200状态码，但将继续删除隧道", "statusCode", resp.StatusCode, "response", string(body))
+		// 发送关闭请求到节点，节点返回错误时仍继续删除
+		if err := h.frpsClient.KickClient(context.Background(), service.NodeEndpoint(node), proxy.RunID); err != nil {
+			var statusErr *frps.StatusError
+			if !errors.As(err, &statusErr) {
+				h.logger.Error("发送请求失败", "error", err, "nodeID", node.ID)
+				c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "关闭隧道请求失败，无法删除"})
+				return
+			}
+			h.logger.Warn("节点返回非200状态码，但将继续删除隧道", "statusCode", statusErr.StatusCode, "response", statusErr.Body)
 		}
 	}
 
@@ -1186,8 +755,7 @@ func (h *ProxyAdminHandler) DeleteProxy(c *gin.Context) {
 	}
 
 	// 清除状态缓存
-	cacheKey := fmt.Sprintf("proxy:status:%d", proxy.ID)
-	h.redisCli.Del(context.Background(), cacheKey)
+	h.snapshotService.Delete(context.Background(), proxy.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
@@ -1199,3 +767,237 @@ func (h *ProxyAdminHandler) DeleteProxy(c *gin.Context) {
 		},
 	})
 }
+
+// GetProxyHistory 获取隧道配置版本历史
+func (h *ProxyAdminHandler) GetProxyHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的隧道ID"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	proxy, err := h.proxyService.GetByID(context.Background(), id)
+	if err != nil {
+		h.logger.Error("获取隧道信息失败", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道信息失败"})
+		return
+	}
+	if proxy == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道不存在"})
+		return
+	}
+
+	versions, total, err := h.proxyService.ListVersions(context.Background(), id, (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("获取隧道历史版本失败", "error", err, "proxyID", id)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道历史版本失败"})
+		return
+	}
+
+	items := make([]*service.ProxyVersionDetail, 0, len(versions))
+	for _, v := range versions {
+		items = append(items, service.NewProxyVersionDetail(v))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"proxy_id":   proxy.ID,
+			"proxy_name": proxy.ProxyName,
+			"username":   proxy.Username,
+			"versions":   items,
+		},
+		"pagination": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+			"pages":     (total + pageSize - 1) / pageSize,
+		},
+	})
+}
+
+// RollbackProxy 将隧道配置回滚到指定版本，按隧道所属用户的权限重新校验
+func (h *ProxyAdminHandler) RollbackProxy(c *gin.Context) {
+	type RollbackRequest struct {
+		ID      int64 `json:"id" binding:"required"`
+		Version int   `json:"version" binding:"required"`
+	}
+
+	var req RollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误：" + err.Error()})
+		return
+	}
+
+	existingProxy, err := h.proxyService.GetByID(context.Background(), req.ID)
+	if err != nil {
+		h.logger.Error("获取隧道信息失败", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道信息失败"})
+		return
+	}
+	if existingProxy == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道不存在"})
+		return
+	}
+
+	version, err := h.proxyService.GetVersion(context.Background(), req.ID, req.Version)
+	if err != nil {
+		h.logger.Error("获取隧道历史版本失败", "error", err, "proxyID", req.ID, "version", req.Version)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道历史版本失败"})
+		return
+	}
+	if version == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "该版本不存在"})
+		return
+	}
+
+	snapshot, err := service.ParseProxyConfigSnapshot(version)
+	if err != nil {
+		h.logger.Error("解析隧道配置快照失败", "error", err, "proxyID", req.ID, "version", req.Version)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "历史版本数据损坏，无法回滚"})
+		return
+	}
+
+	if len(snapshot.Diff(service.NewProxyConfigSnapshot(existingProxy))) == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "当前配置与该版本一致，无需回滚"})
+		return
+	}
+
+	owner, err := h.userService.GetByUsername(context.Background(), existingProxy.Username)
+	if err != nil || owner == nil {
+		h.logger.Error("获取隧道所属用户失败", "error", err, "username", existingProxy.Username)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道所属用户失败"})
+		return
+	}
+
+	validationErrs, err := h.proxyValidator.Validate(context.Background(), owner, snapshot.ValidationInput(existingProxy))
+	if err != nil {
+		h.logger.Error("校验隧道配置失败", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return
+	}
+	if len(validationErrs) > 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": validationErrs[0].Msg, "errors": validationErrs})
+		return
+	}
+
+	operatorName := ""
+	if admin, err := h.userService.GetByID(context.Background(), c.GetInt64("user_id")); err == nil && admin != nil {
+		operatorName = admin.Username
+	}
+
+	proxy := *existingProxy
+	snapshot.ApplyTo(&proxy)
+
+	err = h.proxyService.UpdateBy(context.Background(), &proxy, service.ProxyOperator{
+		Type:   service.ProxyOperatorAdmin,
+		Name:   operatorName,
+		Action: service.ProxyVersionActionRollback,
+		Remark: fmt.Sprintf("回滚到版本 %d", req.Version),
+	})
+	if err != nil {
+		h.logger.Error("回滚隧道配置失败", "error", err, "proxyID", req.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "回滚隧道配置失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "回滚成功", "data": gin.H{"id": req.ID, "version": req.Version}})
+}
+
+// GetReconcileReport 获取最近一次隧道状态校正的结果
+func (h *ProxyAdminHandler) GetReconcileReport(c *gin.Context) {
+	report := h.reconciler.LastReport()
+	if report == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "尚未执行过隧道状态校正", "data": nil})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": report})
+}
+
+// RunReconcile 立即执行一次隧道状态校正
+func (h *ProxyAdminHandler) RunReconcile(c *gin.Context) {
+	report := h.reconciler.RunOnce(context.Background())
+	if report == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 409, "msg": "隧道状态校正正在执行中，请稍后查看结果"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "校正完成", "data": report})
+}
+
+// GetProxyTimeline 获取隧道上下线时间线与24小时/7天/30天在线率
+func (h *ProxyAdminHandler) GetProxyTimeline(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的隧道ID"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	proxy, err := h.proxyService.GetByID(context.Background(), id)
+	if err != nil {
+		h.logger.Error("获取隧道信息失败", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道信息失败"})
+		return
+	}
+	if proxy == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道不存在"})
+		return
+	}
+
+	events, total, err := h.proxyService.ListEvents(context.Background(), id, (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("获取隧道时间线失败", "error", err, "proxyID", id)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道时间线失败"})
+		return
+	}
+	if events == nil {
+		events = []*repository.ProxyEvent{}
+	}
+
+	uptime, err := h.proxyService.GetUptime(context.Background(), id)
+	if err != nil {
+		h.logger.Error("获取隧道在线率失败", "error", err, "proxyID", id)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道在线率失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"proxy_id":   proxy.ID,
+			"proxy_name": proxy.ProxyName,
+			"username":   proxy.Username,
+			"node_id":    proxy.Node,
+			"status":     proxy.Status,
+			"uptime":     uptime,
+			"events":     events,
+		},
+		"pagination": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+			"pages":     (total + pageSize - 1) / pageSize,
+		},
+	})
+}
@@ -44,6 +44,30 @@ func RegisterAdminRoutes(router *gin.RouterGroup, userAdminHandler *UserAdminHan
 		// 捐赠节点相关路由
 		nodes.GET("/donated", nodeAdminHandler.ListDonatedNodes)
 		nodes.POST("/review", nodeAdminHandler.ReviewDonatedNode)
+		nodes.GET("/validations/:id", nodeAdminHandler.ListNodeValidations)
+		nodes.GET("/reviews/:id", nodeAdminHandler.ListNodeReviews)
+		// 节点代理心跳相关路由
+		nodes.GET("/heartbeat/:id", nodeAdminHandler.GetNodeHeartbeat)
+		nodes.POST("/agent-key", nodeAdminHandler.ResetNodeAgentKey)
+		nodes.GET("/load/chart/:id", nodeAdminHandler.GetNodeLoadChart)
+		// 节点frps配置生成相关路由
+		nodes.GET("/frps-config/:id", nodeAdminHandler.GetNodeFrpsConfig)
+		nodes.POST("/plugin-secret", nodeAdminHandler.ResetNodePluginSecret)
+		// 节点凭据相关路由
+		nodes.GET("/credentials/:id", nodeAdminHandler.GetNodeCredentials)
+		nodes.POST("/credentials/rotate", nodeAdminHandler.RotateNodeSecrets)
+		// 节点维护计划相关路由
+		nodes.GET("/maintenance", nodeAdminHandler.ListNodeMaintenances)
+		nodes.POST("/maintenance", nodeAdminHandler.ScheduleNodeMaintenance)
+		nodes.POST("/maintenance/cancel", nodeAdminHandler.CancelNodeMaintenance)
+		// 捐赠节点贡献奖励相关路由
+		nodes.GET("/rewards/rules", nodeAdminHandler.ListNodeRewardRules)
+		nodes.POST("/rewards/rules", nodeAdminHandler.SaveNodeRewardRule)
+		nodes.POST("/rewards/rules/delete", nodeAdminHandler.DeleteNodeRewardRule)
+		nodes.GET("/rewards/credits", nodeAdminHandler.ListNodeRewardCredits)
+		nodes.POST("/rewards/settle", nodeAdminHandler.SettleNodeRewards)
+		nodes.POST("/rewards/clawback", nodeAdminHandler.ClawbackNodeRewards)
+		nodes.POST("/rewards/block", nodeAdminHandler.SetNodeRewardBlocked)
 	}
 
 	// 用户组管理路由
@@ -90,5 +114,10 @@ func RegisterAdminRoutes(router *gin.RouterGroup, userAdminHandler *UserAdminHan
 		proxies.POST("/close", proxyAdminHandler.CloseProxy)
 		proxies.POST("/user/close", proxyAdminHandler.CloseUserProxies)
 		proxies.POST("/delete", proxyAdminHandler.DeleteProxy)
+		proxies.GET("/:id/history", proxyAdminHandler.GetProxyHistory)
+		proxies.GET("/:id/timeline", proxyAdminHandler.GetProxyTimeline)
+		proxies.POST("/rollback", proxyAdminHandler.RollbackProxy)
+		proxies.GET("/reconcile", proxyAdminHandler.GetReconcileReport)
+		proxies.POST("/reconcile", proxyAdminHandler.RunReconcile)
 	}
 }
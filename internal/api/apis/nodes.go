@@ -19,7 +19,20 @@ func RegisterNodeRoutes(router *gin.RouterGroup, nodeHandler *handler.NodeHandle
 		nodes.POST("/donate", nodeHandler.DonateNode)
 		// 获取用户自己的节点
 		nodes.GET("/my", nodeHandler.GetUserNodes)
-		// 接收节点负载信息
-		nodes.POST("/load/webhook", nodeHandler.ReceiveNodeLoad)
+		// 所有者管理自己捐赠的节点
+		nodes.POST("/my/:id", nodeHandler.UpdateOwnedNode)
+		nodes.DELETE("/my/:id", nodeHandler.WithdrawOwnedNode)
+		nodes.POST("/my/:id/pause", nodeHandler.PauseOwnedNode)
+		nodes.POST("/my/:id/resume", nodeHandler.ResumeOwnedNode)
+		nodes.POST("/my/:id/rotate-token", nodeHandler.RotateOwnedNodeToken)
+		nodes.POST("/my/:id/resubmit", nodeHandler.ResubmitOwnedNode)
+		nodes.GET("/my/:id/reviews", nodeHandler.ListOwnedNodeReviews)
+		nodes.GET("/my/:id/stats", nodeHandler.GetOwnedNodeStats)
+		// 获取捐赠节点的贡献奖励流水
+		nodes.GET("/rewards", nodeHandler.GetMyNodeRewards)
+		// 获取推荐节点
+		nodes.GET("/recommend", nodeHandler.GetRecommendedNodes)
+		// 获取节点负载图表数据
+		nodes.GET("/load/chart", nodeHandler.GetNodeLoadChart)
 	}
 }
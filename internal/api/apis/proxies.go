@@ -15,6 +15,8 @@ func RegisterProxyRoutes(router *gin.RouterGroup, proxyHandler *handler.ProxyHan
 		proxies.POST("/create", proxyHandler.CreateProxy)
 		// 更新隧道
 		proxies.POST("/edit", proxyHandler.UpdateProxy)
+		// 校验隧道参数（不落库）
+		proxies.POST("/validate", proxyHandler.ValidateProxy)
 		// 删除隧道
 		proxies.POST("/delete", proxyHandler.DeleteProxy)
 		// 获取隧道
@@ -24,6 +26,34 @@ func RegisterProxyRoutes(router *gin.RouterGroup, proxyHandler *handler.ProxyHan
 		proxies.POST("/status", proxyHandler.GetProxyStatus)
 		// 关闭隧道
 		proxies.POST("/close", proxyHandler.CloseProxy)
+		// 隧道上下线时间线与在线率
+		proxies.GET("/timeline", proxyHandler.GetProxyTimeline)
+		// 隧道公网入口探测历史
+		proxies.GET("/probes", proxyHandler.GetProxyProbes)
+		// 隧道配置历史版本
+		proxies.GET("/history", proxyHandler.GetProxyHistory)
+		// 回滚隧道配置到指定版本
+		proxies.POST("/rollback", proxyHandler.RollbackProxy)
+		// 复制隧道
+		proxies.POST("/clone", proxyHandler.CloneProxy)
+		// 隧道预设
+		proxies.GET("/presets", proxyHandler.ListProxyPresets)
+		proxies.POST("/presets/create", proxyHandler.CreateProxyPreset)
+		proxies.POST("/presets/edit", proxyHandler.UpdateProxyPreset)
+		proxies.POST("/presets/delete", proxyHandler.DeleteProxyPreset)
+		// 隧道在线时段
+		proxies.GET("/schedule", proxyHandler.GetProxySchedule)
+		proxies.POST("/schedule", proxyHandler.SetProxySchedule)
+		// 隧道离线告警
+		proxies.GET("/alert", proxyHandler.GetProxyAlert)
+		proxies.POST("/alert", proxyHandler.SetProxyAlert)
+		proxies.POST("/alert/test", proxyHandler.TestProxyAlert)
+		// 禁用/启用隧道
+		proxies.POST("/disable", proxyHandler.SetProxyDisabled)
+		// 批量导出隧道
+		proxies.GET("/export", proxyHandler.ExportProxies)
+		// 批量导入隧道
+		proxies.POST("/import", proxyHandler.ImportProxies)
 		// 注册FRP隧道鉴权路由已移至公共路由，这里不再注册
 		// proxies.POST("/auth", proxyAuthHandler.HandleProxyAuth)
 	}
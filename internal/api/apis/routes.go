@@ -14,6 +14,8 @@ func RegisterPublicRoutes(
 	announcementHandler *handler.AnnouncementHandler,
 	adHandler *handler.AdHandler,
 	proxyAuthHandler *handler.ProxyAuthHandler,
+	proxyStreamHandler *handler.ProxyStreamHandler,
+	nodeAgentHandler *handler.NodeAgentHandler,
 	productHandler *handler.ProductHandler,
 ) {
 	// 用户登录注册相关路由
@@ -38,6 +40,13 @@ func RegisterPublicRoutes(
 	// 隧道鉴权路由（不需要认证）
 	router.POST("/proxy/auth", proxyAuthHandler.HandleProxyAuth)
 
+	// 隧道状态实时推送（处理器内自行鉴权，兼容无法设置请求头的EventSource）
+	router.GET("/proxy/stream", proxyStreamHandler.StreamProxyStatus)
+
+	// 节点代理心跳与负载上报（使用节点代理密钥签名鉴权）
+	router.POST("/nodes/heartbeat", nodeAgentHandler.Heartbeat)
+	router.POST("/nodes/load/webhook", nodeAgentHandler.ReceiveNodeLoad)
+
 	// 商品相关公开路由
 	RegisterShopPublicRoutes(router, productHandler)
 }
@@ -47,6 +56,7 @@ func RegisterAuthRoutes(
 	router *gin.RouterGroup,
 	userHandler *handler.UserHandler,
 	userCheckinHandler *handler.UserCheckinHandler,
+	userNoticeHandler *handler.UserNoticeHandler,
 	nodeHandler *handler.NodeHandler,
 	proxyHandler *handler.ProxyHandler,
 	proxyAuthHandler *handler.ProxyAuthHandler,
@@ -54,8 +64,8 @@ func RegisterAuthRoutes(
 	productHandler *handler.ProductHandler,
 ) {
 	// 用户信息、签到、实名认证等路由 (需要认证)
-	usersGroup := router.Group("/users")                                                 // 创建 /users 子分组
-	RegisterUserRoutes(usersGroup, userHandler, userCheckinHandler, realNameAuthHandler) // 调用users.go中的函数
+	usersGroup := router.Group("/users")                                                                    // 创建 /users 子分组
+	RegisterUserRoutes(usersGroup, userHandler, userCheckinHandler, userNoticeHandler, realNameAuthHandler) // 调用users.go中的函数
 
 	// 异步任务路由 (从原来的 users.go 中移到这里，或者保持独立)
 	// 如果 tasks 路由也是认证路由，且希望在 /api/v1/tasks 下
@@ -80,6 +90,7 @@ func RegisterRoutes(
 	router *gin.RouterGroup,
 	userHandler *handler.UserHandler,
 	userCheckinHandler *handler.UserCheckinHandler,
+	userNoticeHandler *handler.UserNoticeHandler,
 	nodeHandler *handler.NodeHandler,
 	proxyHandler *handler.ProxyHandler,
 	proxyAuthHandler *handler.ProxyAuthHandler,
@@ -87,13 +98,15 @@ func RegisterRoutes(
 	announcementHandler *handler.AnnouncementHandler,
 	systemHandler *handler.SystemHandler,
 	realNameAuthHandler *handler.RealNameAuthHandler,
+	proxyStreamHandler *handler.ProxyStreamHandler,
+	nodeAgentHandler *handler.NodeAgentHandler,
 	productHandler *handler.ProductHandler,
 ) {
 	// 注册公共路由
-	RegisterPublicRoutes(router, userHandler, systemHandler, announcementHandler, adHandler, proxyAuthHandler, productHandler)
+	RegisterPublicRoutes(router, userHandler, systemHandler, announcementHandler, adHandler, proxyAuthHandler, proxyStreamHandler, nodeAgentHandler, productHandler)
 
 	// 注册需要认证的路由
-	RegisterAuthRoutes(router, userHandler, userCheckinHandler, nodeHandler, proxyHandler, proxyAuthHandler, realNameAuthHandler, productHandler)
+	RegisterAuthRoutes(router, userHandler, userCheckinHandler, userNoticeHandler, nodeHandler, proxyHandler, proxyAuthHandler, realNameAuthHandler, productHandler)
 }
 
 // RegisterAdRoutes 注册广告相关路由
@@ -8,7 +8,7 @@ import (
 
 // RegisterUserRoutes 注册用户相关路由 (认证后)
 // 注意：这个函数应该由 RegisterAuthRoutes 调用，传入的 router 已经是认证过的 group
-func RegisterUserRoutes(router *gin.RouterGroup, userHandler *handler.UserHandler, userCheckinHandler *handler.UserCheckinHandler, realNameAuthHandler *handler.RealNameAuthHandler) {
+func RegisterUserRoutes(router *gin.RouterGroup, userHandler *handler.UserHandler, userCheckinHandler *handler.UserCheckinHandler, userNoticeHandler *handler.UserNoticeHandler, realNameAuthHandler *handler.RealNameAuthHandler) {
 	// 用户相关路由，这里的 router 已经是父级 group (例如 /api/v1/users)
 	// 所以不需要再 router.Group("/users")
 	router.GET("/info", userHandler.GetUserInfo)
@@ -19,6 +19,10 @@ func RegisterUserRoutes(router *gin.RouterGroup, userHandler *handler.UserHandle
 	router.GET("/checkin/status", userCheckinHandler.GetCheckinStatus)
 	router.GET("/checkin/logs", userCheckinHandler.GetCheckinLogs)
 
+	// 站内通知路由
+	router.GET("/notices", userNoticeHandler.ListNotices)
+	router.POST("/notices/read", userNoticeHandler.MarkNoticesRead)
+
 	// 实名认证路由
 	router.POST("/realname", realNameAuthHandler.RealNameAuth)
 	// router.GET("/realname/ping", realNameAuthHandler.Ping) // 测试路由
@@ -4,13 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"stellarfrp/internal/repository"
 	"stellarfrp/internal/service"
+	"stellarfrp/internal/utils"
+	"stellarfrp/pkg/frps"
 	"stellarfrp/pkg/logger"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,23 +23,59 @@ import (
 
 // NodeHandler 节点处理器
 type NodeHandler struct {
-	nodeService service.NodeService
-	userService service.UserService
-	logger      *logger.Logger
-	redisClient *redis.Client
+	nodeService          service.NodeService
+	proxyService         service.ProxyService
+	userService          service.UserService
+	nodeLoadService      service.NodeLoadService
+	nodeRecommendService service.NodeRecommendService
+	maintenanceService   service.NodeMaintenanceService
+	capacityService      service.NodeCapacityService
+	validationService    service.NodeValidationService
+	rewardService        service.NodeRewardService
+	reviewService        service.NodeReviewService
+	frpsPluginURL        string
+	logger               *logger.Logger
+	redisClient          *redis.Client
+	frpsClient           *frps.Client
 }
 
 // NewNodeHandler 创建节点处理器实例
-func NewNodeHandler(nodeService service.NodeService, userService service.UserService, logger *logger.Logger, redisClient *redis.Client) *NodeHandler {
+func NewNodeHandler(
+	nodeService service.NodeService,
+	proxyService service.ProxyService,
+	userService service.UserService,
+	nodeLoadService service.NodeLoadService,
+	nodeRecommendService service.NodeRecommendService,
+	maintenanceService service.NodeMaintenanceService,
+	capacityService service.NodeCapacityService,
+	validationService service.NodeValidationService,
+	rewardService service.NodeRewardService,
+	reviewService service.NodeReviewService,
+	frpsPluginURL string,
+	logger *logger.Logger,
+	redisClient *redis.Client,
+	frpsClient *frps.Client,
+) *NodeHandler {
 	return &NodeHandler{
-		nodeService: nodeService,
-		userService: userService,
-		logger:      logger,
-		redisClient: redisClient,
+		nodeService:          nodeService,
+		proxyService:         proxyService,
+		userService:          userService,
+		nodeLoadService:      nodeLoadService,
+		nodeRecommendService: nodeRecommendService,
+		maintenanceService:   maintenanceService,
+		capacityService:      capacityService,
+		validationService:    validationService,
+		rewardService:        rewardService,
+		reviewService:        reviewService,
+		frpsPluginURL:        frpsPluginURL,
+		logger:               logger,
+		redisClient:          redisClient,
+		frpsClient:           frpsClient,
 	}
 }
 
 // GetAccessibleNodes 获取用户可访问的节点列表
+// 支持按 region、city、isp、bandwidth、tag 筛选，group_by 指定分组字段时额外返回各分组的节点ID
 func (h *NodeHandler) GetAccessibleNodes(c *gin.Context) {
 	// 从请求头获取token
 	token := c.GetHeader("Authorization")
@@ -52,6 +91,12 @@ func (h *NodeHandler) GetAccessibleNodes(c *gin.Context) {
 		return
 	}
 
+	filter, groupBy, err := parseNodeListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
 	// 获取用户组可访问的节点列表
 	nodes, err := h.nodeService.GetAccessibleNodes(context.Background(), user.GroupID)
 	if err != nil {
@@ -59,14 +104,12 @@ func (h *NodeHandler) GetAccessibleNodes(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
 		return
 	}
+	// 跳过待审核的节点和不满足筛选条件的节点
+	nodes = filterListedNodes(nodes, filter)
 
 	// 构建返回数据 - 使用对象格式，节点ID作为键
 	nodeMap := make(map[string]gin.H)
 	for _, node := range nodes {
-		// 跳过状态为2（待审核）的节点
-		if node.Status == 2 {
-			continue
-		}
 
 		// 解析AllowedTypes字段，它是JSON格式的字符串
 		var allowedTypes []string
@@ -97,20 +140,67 @@ func (h *NodeHandler) GetAccessibleNodes(c *gin.Context) {
 			"Description":  description,
 			"ID":           nodeID,
 		}
+		for k, v := range nodeMetaFields(node) {
+			nodeMap[nodeID][k] = v
+		}
+		nodeMap[nodeID]["Capacity"] = h.nodeCapacity(context.Background(), node)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": nodeMap})
+	resp := gin.H{"code": 200, "msg": "获取成功", "data": nodeMap}
+	if groupBy != "" {
+		resp["groups"] = service.GroupNodeIDs(nodes, groupBy)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
-// NodeInfoResponse 节点服务器信息响应
-type NodeInfoResponse struct {
-	Version         string         `json:"version"`
-	BindPort        int            `json:"bindPort"`
-	TotalTrafficIn  int64          `json:"totalTrafficIn"`
-	TotalTrafficOut int64          `json:"totalTrafficOut"`
-	CurConns        int            `json:"curConns"`
-	ClientCounts    int            `json:"clientCounts"`
-	ProxyTypeCount  map[string]int `json:"proxyTypeCount"`
+// parseNodeListQuery 解析节点列表的筛选与分组参数
+func parseNodeListQuery(c *gin.Context) (*service.NodeFilter, string, error) {
+	filter := &service.NodeFilter{
+		Region:        c.Query("region"),
+		City:          c.Query("city"),
+		ISP:           c.Query("isp"),
+		BandwidthTier: c.Query("bandwidth"),
+		Tag:           c.Query("tag"),
+	}
+	groupBy := c.Query("group_by")
+	if groupBy != "" && !service.NodeGroupFields[groupBy] {
+		return nil, "", service.ErrInvalidNodeGroupField
+	}
+	return filter, groupBy, nil
+}
+
+// filterListedNodes 过滤出对用户展示的节点：排除未通过审核、已暂停节点和不满足筛选条件的节点
+func filterListedNodes(nodes []*repository.Node, filter *service.NodeFilter) []*repository.Node {
+	filtered := make([]*repository.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if repository.NodeUnapproved(node.Status) || node.Status == repository.NodeStatusPaused || !filter.Match(node) {
+			continue
+		}
+		filtered = append(filtered, node)
+	}
+	return filtered
+}
+
+// nodeMetaFields 节点地区、线路、标签等展示字段
+func nodeMetaFields(node *repository.Node) gin.H {
+	return gin.H{
+		"Region":    node.Region,
+		"City":      node.City,
+		"ISP":       node.ISP,
+		"Bandwidth": node.BandwidthTier,
+		"Tags":      service.ParseNodeTags(node),
+		"SortOrder": node.SortOrder,
+	}
+}
+
+// nodeCapacity 获取节点剩余容量，统计失败时返回nil，不影响节点列表展示
+func (h *NodeHandler) nodeCapacity(ctx context.Context, node *repository.Node) *service.NodeCapacity {
+	capacity, err := h.capacityService.Get(ctx, node)
+	if err != nil {
+		h.logger.Error("Failed to get node capacity", "error", err, "node", node.NodeName)
+		return nil
+	}
+	return capacity
 }
 
 // 格式化流量大小为带单位的字符串
@@ -134,7 +224,7 @@ func formatTraffic(bytes int64) string {
 	return fmt.Sprintf("%.2f TB", float64(bytes)/TB)
 }
 
-// GetNodesInfo 获取所有节点的信息
+// GetNodesInfo 获取所有节点的信息，筛选与分组参数同 GetAccessibleNodes
 func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 	// 从请求头获取token
 	token := c.GetHeader("Authorization")
@@ -150,6 +240,12 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 		return
 	}
 
+	filter, groupBy, err := parseNodeListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
 	// 获取所有节点，不再根据用户权限过滤
 	nodes, err := h.nodeService.GetAllNodes(context.Background())
 	if err != nil {
@@ -157,6 +253,8 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点列表失败"})
 		return
 	}
+	// 跳过待审核的节点和不满足筛选条件的节点
+	nodes = filterListedNodes(nodes, filter)
 
 	// 获取每个节点的总流量数据
 	nodeTrafficMap := make(map[string]*repository.NodeTrafficLog)
@@ -169,6 +267,12 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 		// 错误不中断流程，继续处理
 	}
 
+	// 获取正在维护的节点，出错时按无维护处理
+	maintenances, err := h.maintenanceService.ActiveByNode(ctx, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to get node maintenances", "error", err)
+	}
+
 	// 格式化总流量
 	totalInFormatted := formatTraffic(totalTrafficIn)
 	totalOutFormatted := formatTraffic(totalTrafficOut)
@@ -187,55 +291,18 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 		}
 	}
 
-	// 创建HTTP客户端，设置超时
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
 	// 并发获取每个节点的信息
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	results := make(map[string]gin.H)
 
 	for _, node := range nodes {
-		// 跳过状态为2（待审核）的节点
-		if node.Status == 2 {
-			continue
-		}
-
 		wg.Add(1)
 		go func(node *repository.Node) {
 			defer wg.Done()
 
-			// 构建节点API URL
-			apiURL := fmt.Sprintf("%s/api/serverinfo", node.URL)
-
-			// 创建请求
-			req, err := http.NewRequest("GET", apiURL, nil)
-			if err != nil {
-				h.logger.Error("Failed to create request", "error", err, "url", apiURL)
-				mu.Lock()
-				results[strconv.FormatInt(node.ID, 10)] = gin.H{
-					"NodeName":        node.NodeName,
-					"Status":          "offline",
-					"Error":           "请求创建失败",
-					"Version":         "",
-					"Clients":         0,
-					"TrafficIn":       "",
-					"TrafficOut":      "",
-					"TotalTrafficIn":  "",
-					"TotalTrafficOut": "",
-					"Load":            "0.00%",
-				}
-				mu.Unlock()
-				return
-			}
-
-			// 设置Basic认证
-			req.SetBasicAuth(node.User, node.Token)
-
-			// 发送请求
-			resp, err := client.Do(req)
+			// 获取节点服务器信息
+			nodeInfo, err := h.frpsClient.ServerInfo(ctx, service.NodeEndpoint(node))
 			if err != nil {
 				h.logger.Error("Failed to get node info", "error", err, "node", node.NodeName)
 				mu.Lock()
@@ -254,48 +321,8 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 				mu.Unlock()
 				return
 			}
-			defer resp.Body.Close()
-
-			// 读取响应
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				h.logger.Error("Failed to read response", "error", err, "node", node.NodeName)
-				mu.Lock()
-				results[strconv.FormatInt(node.ID, 10)] = gin.H{
-					"NodeName":        node.NodeName,
-					"Status":          "offline",
-					"Error":           "响应读取失败",
-					"Version":         "",
-					"Clients":         0,
-					"TrafficIn":       "",
-					"TrafficOut":      "",
-					"TotalTrafficIn":  "",
-					"TotalTrafficOut": "",
-					"Load":            "0.00%",
-				}
-				mu.Unlock()
-				return
-			}
-
-			// 解析JSON响应
-			var nodeInfo NodeInfoResponse
-			if err := json.Unmarshal(body, &nodeInfo); err != nil {
-				h.logger.Error("Failed to parse response", "error", err, "node", node.NodeName)
-				mu.Lock()
-				results[strconv.FormatInt(node.ID, 10)] = gin.H{
-					"NodeName":        node.NodeName,
-					"Status":          "offline",
-					"Error":           "响应解析失败",
-					"Version":         "",
-					"Clients":         0,
-					"TrafficIn":       "",
-					"TrafficOut":      "",
-					"TotalTrafficIn":  "",
-					"TotalTrafficOut": "",
-					"Load":            "0.00%",
-				}
-				mu.Unlock()
-				return
+			if err := h.nodeService.UpdateFrpsVersion(ctx, node, nodeInfo.Version); err != nil {
+				h.logger.Error("Failed to update frps version", "error", err, "node", node.NodeName)
 			}
 
 			// 格式化流量数据
@@ -311,10 +338,9 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 			}
 
 			// 获取节点负载信息
-			loadKey := fmt.Sprintf("node:load:%s", node.IP)
-			loadValue, err := h.redisClient.Get(ctx, loadKey).Result()
+			loadValue, err := h.redisClient.Get(ctx, service.NodeLoadCacheKey(node.ID)).Result()
 			if err != nil && err != redis.Nil {
-				h.logger.Error("Failed to get node load from Redis", "error", err, "ip", node.IP)
+				h.logger.Error("Failed to get node load from Redis", "error", err, "node", node.NodeName)
 			}
 
 			// 如果没有找到负载信息，设置为默认值
@@ -322,11 +348,17 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 				loadValue = "N/A"
 			}
 
+			// 负载过高或心跳超时的节点标记为降级
+			status := "online"
+			if node.Status == repository.NodeStatusDegraded {
+				status = "degraded"
+			}
+
 			// 保存结果
 			mu.Lock()
 			results[strconv.FormatInt(node.ID, 10)] = gin.H{
 				"NodeName":        node.NodeName,
-				"Status":          "online",
+				"Status":          status,
 				"Version":         nodeInfo.Version,
 				"Clients":         nodeInfo.ClientCounts,
 				"TrafficIn":       trafficIn,
@@ -342,7 +374,27 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 	// 等待所有goroutine完成
 	wg.Wait()
 
-	c.JSON(http.StatusOK, gin.H{
+	for _, node := range nodes {
+		result, ok := results[strconv.FormatInt(node.ID, 10)]
+		if !ok {
+			continue
+		}
+		for k, v := range nodeMetaFields(node) {
+			result[k] = v
+		}
+		result["Capacity"] = h.nodeCapacity(ctx, node)
+		// 维护中的节点无论能否连通都显示为维护状态
+		if m := maintenances[node.ID]; m != nil {
+			result["Status"] = "maintenance"
+			result["Maintenance"] = gin.H{
+				"Message": m.Message,
+				"StartAt": m.StartAt,
+				"EndAt":   m.EndAt,
+			}
+		}
+	}
+
+	resp := gin.H{
 		"code": 200,
 		"msg":  "获取成功",
 		"data": results,
@@ -350,7 +402,11 @@ func (h *NodeHandler) GetNodesInfo(c *gin.Context) {
 			"AllNodesTrafficIn":  totalInFormatted,
 			"AllNodesTrafficOut": totalOutFormatted,
 		},
-	})
+	}
+	if groupBy != "" {
+		resp["groups"] = service.GroupNodeIDs(nodes, groupBy)
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // DonateNodeRequest 捐赠节点请求参数
@@ -368,6 +424,9 @@ type DonateNodeRequest struct {
 	User         string   `json:"user" binding:"required"`          // frps的用户名
 }
 
+// nodeDonateValidationTimeout 提交捐赠时自动校验的最长耗时
+const nodeDonateValidationTimeout = 20 * time.Second
+
 // DonateNode 捐赠节点
 func (h *NodeHandler) DonateNode(c *gin.Context) {
 	// 从请求头获取token
@@ -460,14 +519,39 @@ func (h *NodeHandler) DonateNode(c *gin.Context) {
 		return
 	}
 
+	if err := h.reviewService.Submit(context.Background(), node, repository.NodeStatusPending, ""); err != nil {
+		h.logger.Error("保存节点审核记录失败", "error", err, "nodeID", node.ID)
+	}
+
+	data := gin.H{
+		"node_id":   node.ID,
+		"node_name": node.NodeName,
+		"status":    "待审核",
+	}
+
+	// 生成插件密钥和frps配置，捐赠者按该配置部署后管理员重新校验即可确认插件回调
+	if _, err := h.nodeService.ResetPluginSecret(context.Background(), node); err != nil {
+		h.logger.Error("生成捐赠节点插件密钥失败", "error", err, "nodeID", node.ID)
+	} else if cfg, err := h.renderFrpsConfig(node); err != nil {
+		h.logger.Error("生成frps配置失败", "error", err, "nodeID", node.ID)
+	} else {
+		data["frps_config"] = cfg
+	}
+
+	// 立即校验节点连通性，结果附在审核记录中供管理员参考，校验失败不影响提交
+	ctx, cancel := context.WithTimeout(context.Background(), nodeDonateValidationTimeout)
+	defer cancel()
+	validation, err := h.validationService.Validate(ctx, node, service.NodeValidationTriggerSubmit, 0)
+	if err != nil {
+		h.logger.Error("校验捐赠节点失败", "error", err, "nodeID", node.ID)
+	} else {
+		data["validation"] = validation
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"msg":  "节点捐赠成功，请等待管理员审核",
-		"data": gin.H{
-			"node_id":   node.ID,
-			"node_name": node.NodeName,
-			"status":    "待审核",
-		},
+		"data": data,
 	})
 }
 
@@ -561,10 +645,29 @@ func (h *NodeHandler) GetUserNodes(c *gin.Context) {
 			statusDesc = "启用"
 		case 2:
 			statusDesc = "待审核"
+		case 3:
+			statusDesc = "降级"
+		case 4:
+			statusDesc = "已暂停"
+		case 5:
+			statusDesc = "审核未通过"
+		case 6:
+			statusDesc = "需修改"
 		default:
 			statusDesc = "未知"
 		}
 
+		// 审核未通过或需修改时附带管理员给出的原因
+		reviewReason := ""
+		if node.Status == repository.NodeStatusRejected || node.Status == repository.NodeStatusChangesRequested {
+			review, err := h.reviewService.Latest(context.Background(), node.ID)
+			if err != nil {
+				h.logger.Error("获取节点审核记录失败", "error", err, "nodeID", node.ID)
+			} else if review != nil {
+				reviewReason = review.Reason
+			}
+		}
+
 		nodeList = append(nodeList, gin.H{
 			"id":            node.ID,
 			"node_name":     node.NodeName,
@@ -573,6 +676,7 @@ func (h *NodeHandler) GetUserNodes(c *gin.Context) {
 			"description":   description,
 			"status":        node.Status,
 			"status_desc":   statusDesc,
+			"review_reason": reviewReason,
 			"created_at":    node.CreatedAt.Format("2006-01-02 15:04:05"),
 		})
 	}
@@ -590,45 +694,103 @@ func (h *NodeHandler) GetUserNodes(c *gin.Context) {
 	})
 }
 
-// NodeLoadInfo 节点负载信息
-type NodeLoadInfo struct {
-	IP                string  `json:"ip"`
-	LoadScore         float64 `json:"load_score"`
-	CurrentConns      int     `json:"current_conns"`
-	PeakConns         int     `json:"peak_conns"`
-	CurrentTraffic    int64   `json:"current_traffic"`
-	PeakTraffic       int64   `json:"peak_traffic"`
-	CPUUsage          float64 `json:"cpu_usage"`
-	MemUsage          float64 `json:"mem_usage"`
-	ConnGrowthRate    float64 `json:"conn_growth_rate"`
-	TrafficGrowthRate float64 `json:"traffic_growth_rate"`
-	Timestamp         int64   `json:"timestamp"`
-}
+// GetRecommendedNodes 按负载、状态、端口余量和地区运营商偏好为用户推荐节点
+// 查询参数均为可选：type 隧道类型，remote_port 期望的远程端口，region 偏好地区，isp 偏好运营商
+func (h *NodeHandler) GetRecommendedNodes(c *gin.Context) {
+	// 从请求头获取token
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return
+	}
 
-// ReceiveNodeLoad 接收节点负载信息
-func (h *NodeHandler) ReceiveNodeLoad(c *gin.Context) {
-	var nodeLoad NodeLoadInfo
-	if err := c.ShouldBindJSON(&nodeLoad); err != nil {
-		h.logger.Error("Failed to bind node load info", "error", err)
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的请求数据"})
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
 		return
 	}
 
-	// 将负载百分比转换为字符串形式，如0.11118003913894325 -> "0.11%"
-	loadPercentage := fmt.Sprintf("%.2f%%", nodeLoad.LoadScore*100)
+	req := &service.NodeRecommendRequest{
+		ProxyType: strings.ToLower(c.Query("type")),
+		Region:    c.Query("region"),
+		ISP:       c.Query("isp"),
+	}
+	if portStr := c.Query("remote_port"); portStr != "" {
+		req.RemotePort, err = strconv.Atoi(portStr)
+		if err != nil || req.RemotePort < 0 || req.RemotePort > 65535 {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的远程端口"})
+			return
+		}
+	}
+
+	recommendations, err := h.nodeRecommendService.Recommend(context.Background(), user, req)
+	if err != nil {
+		h.logger.Error("Failed to recommend nodes", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取推荐节点失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": recommendations})
+}
+
+// GetNodeLoadChart 获取节点负载图表数据
+// from、to 为Unix时间戳（秒），默认最近24小时；resolution 可选 raw、5m、1h，为空时自动选择
+func (h *NodeHandler) GetNodeLoadChart(c *gin.Context) {
+	// 从请求头获取token
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return
+	}
 
-	// 存储到Redis中，使用IP作为键
 	ctx := context.Background()
-	key := fmt.Sprintf("node:load:%s", nodeLoad.IP)
+	user, err := h.userService.GetByToken(ctx, token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return
+	}
+
+	nodeID, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil || nodeID <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return
+	}
+
+	from, to, err := service.ParseNodeLoadChartRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
 
-	// 设置过期时间为60秒，确保数据不会过期
-	err := h.redisClient.Set(ctx, key, loadPercentage, 60*time.Second).Err()
+	node, err := h.nodeService.GetByID(ctx, nodeID)
+	if err != nil || node == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return
+	}
+
+	// 节点所有者或有权限使用节点的用户组可查看
+	allowed := node.OwnerID.Valid && node.OwnerID.Int64 == user.ID
+	if !allowed {
+		allowed, err = utils.IsGroupInPermission(user.GroupID, node.Permission)
+		if err != nil {
+			h.logger.Error("Failed to check node permission", "error", err, "nodeID", nodeID)
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusOK, gin.H{"code": 403, "msg": "无权查看该节点"})
+		return
+	}
+
+	chart, err := h.nodeLoadService.Chart(ctx, nodeID, from, to, c.Query("resolution"))
 	if err != nil {
-		h.logger.Error("Failed to store node load in Redis", "error", err, "ip", nodeLoad.IP)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "存储负载信息失败"})
+		if errors.Is(err, service.ErrInvalidNodeLoadResolution) {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to get node load chart", "error", err, "nodeID", nodeID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取负载数据失败"})
 		return
 	}
 
-	h.logger.Info("Node load info received", "ip", nodeLoad.IP, "load", loadPercentage)
-	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "负载信息已接收"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": chart})
 }
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"stellarfrp/pkg/webhook"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 节点代理请求头
+const (
+	HeaderNodeID = "X-StellarFrp-Node"
+)
+
+// maxAgentRequestSize 节点代理请求体大小上限
+const maxAgentRequestSize = 64 << 10
+
+// NodeAgentHandler 节点代理接口处理器，使用节点代理密钥签名鉴权
+type NodeAgentHandler struct {
+	agentAuthService service.NodeAgentAuthService
+	heartbeatService service.NodeHeartbeatService
+	nodeLoadService  service.NodeLoadService
+	logger           *logger.Logger
+}
+
+// NewNodeAgentHandler 创建节点代理接口处理器实例
+func NewNodeAgentHandler(
+	agentAuthService service.NodeAgentAuthService,
+	heartbeatService service.NodeHeartbeatService,
+	nodeLoadService service.NodeLoadService,
+	logger *logger.Logger,
+) *NodeAgentHandler {
+	return &NodeAgentHandler{
+		agentAuthService: agentAuthService,
+		heartbeatService: heartbeatService,
+		nodeLoadService:  nodeLoadService,
+		logger:           logger,
+	}
+}
+
+// authenticate 校验节点代理请求的签名，成功时返回节点、时间戳和请求体，失败时已写入响应
+// 请求头需携带节点ID、时间戳以及以节点代理密钥对 "时间戳.请求体" 计算的签名
+func (h *NodeAgentHandler) authenticate(c *gin.Context) (*repository.Node, int64, []byte, bool) {
+	nodeID, err := strconv.ParseInt(c.GetHeader(HeaderNodeID), 10, 64)
+	if err != nil || nodeID <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return nil, 0, nil, false
+	}
+	timestamp, err := strconv.ParseInt(c.GetHeader(webhook.HeaderTimestamp), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的时间戳"})
+		return nil, 0, nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAgentRequestSize))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "读取请求失败"})
+		return nil, 0, nil, false
+	}
+
+	node, err := h.agentAuthService.Authenticate(context.Background(), nodeID, timestamp, c.GetHeader(webhook.HeaderSignature), body)
+	if err != nil {
+		if errors.Is(err, service.ErrNodeAgentUnauthorized) || errors.Is(err, service.ErrNodeAgentExpired) {
+			c.JSON(http.StatusOK, gin.H{"code": 401, "msg": err.Error()})
+			return nil, 0, nil, false
+		}
+		h.logger.Error("Failed to authenticate node agent", "error", err, "nodeID", nodeID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return nil, 0, nil, false
+	}
+	return node, timestamp, body, true
+}
+
+// Heartbeat 接收节点代理上报的心跳
+func (h *NodeAgentHandler) Heartbeat(c *gin.Context) {
+	node, timestamp, body, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	var hb service.NodeHeartbeat
+	if err := json.Unmarshal(body, &hb); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的请求数据"})
+		return
+	}
+	hb.Timestamp = timestamp
+
+	if err := h.heartbeatService.Receive(context.Background(), node, &hb); err != nil {
+		h.logger.Error("Failed to save node heartbeat", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "保存心跳失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "心跳已接收",
+		"data": gin.H{
+			"status":            node.Status,
+			"heartbeat_timeout": int(service.NodeHeartbeatTimeout.Seconds()),
+		},
+	})
+}
+
+// ReceiveNodeLoad 接收节点代理上报的负载信息并写入时序数据
+func (h *NodeAgentHandler) ReceiveNodeLoad(c *gin.Context) {
+	node, timestamp, body, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	var load service.NodeLoadInfo
+	if err := json.Unmarshal(body, &load); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的请求数据"})
+		return
+	}
+	load.Timestamp = timestamp
+
+	if err := h.nodeLoadService.Record(context.Background(), node, &load); err != nil {
+		h.logger.Error("Failed to save node load", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "存储负载信息失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "负载信息已接收"})
+}
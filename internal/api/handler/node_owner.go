@@ -0,0 +1,485 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 节点所有者统计参数
+const (
+	nodeOwnerStatsDefaultDays = 7
+	nodeOwnerStatsMaxDays     = 30
+)
+
+// ownedNode 校验登录状态并获取路径中当前用户捐赠的节点，失败时已写入响应
+func (h *NodeHandler) ownedNode(c *gin.Context) (*repository.Node, bool) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return nil, false
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的节点ID"})
+		return nil, false
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), id)
+	if err != nil || node == nil || !node.OwnerID.Valid || node.OwnerID.Int64 != user.ID {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "节点不存在"})
+		return nil, false
+	}
+	return node, true
+}
+
+// renderFrpsConfig 解密节点凭据并生成供所有者部署的frps配置
+func (h *NodeHandler) renderFrpsConfig(node *repository.Node) (*service.FrpsConfig, error) {
+	secrets, err := h.nodeService.Secrets(node)
+	if err != nil {
+		return nil, err
+	}
+	return service.RenderFrpsConfig(node, h.frpsPluginURL, secrets, time.Now()), nil
+}
+
+// UpdateOwnedNodeRequest 所有者修改节点请求参数，未填写的字段保持不变
+type UpdateOwnedNodeRequest struct {
+	Description  *string  `json:"description"`
+	PortRange    *string  `json:"port_range"`
+	AllowedTypes []string `json:"allowed_types"`
+}
+
+// UpdateOwnedNode 所有者修改节点描述、端口范围和开放类型
+// 扩大端口范围或新增开放类型时节点重新进入待审核状态，缩小范围时不能影响已有隧道
+// 需修改的节点保持原状态，修改完成后由所有者重新提交审核
+func (h *NodeHandler) UpdateOwnedNode(c *gin.Context) {
+	node, ok := h.ownedNode(c)
+	if !ok {
+		return
+	}
+	if node.Status == repository.NodeStatusRejected {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "节点审核未通过，无法修改"})
+		return
+	}
+
+	var req UpdateOwnedNodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误: " + err.Error()})
+		return
+	}
+
+	proxies, err := h.proxyService.ListByNode(context.Background(), node.ID)
+	if err != nil {
+		h.logger.Error("获取节点隧道失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return
+	}
+
+	needReview := false
+
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		if description == "" {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "节点描述不能为空"})
+			return
+		}
+		descriptionBytes, err := json.Marshal([]string{description})
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+			return
+		}
+		node.Description = sql.NullString{String: string(descriptionBytes), Valid: true}
+	}
+
+	if req.PortRange != nil {
+		minPort, maxPort, err := service.ParsePortRange(*req.PortRange)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+		for _, proxy := range proxies {
+			port, err := strconv.Atoi(proxy.RemotePort)
+			if err != nil || port == 0 {
+				continue
+			}
+			if port < minPort || port > maxPort {
+				c.JSON(http.StatusOK, gin.H{"code": 400, "msg": fmt.Sprintf("隧道 %s 使用的远程端口 %d 不在新的端口范围内", proxy.ProxyName, port)})
+				return
+			}
+		}
+		oldMin, oldMax, err := service.ParsePortRange(node.PortRange)
+		if err != nil || minPort < oldMin || maxPort > oldMax {
+			needReview = true
+		}
+		node.PortRange = fmt.Sprintf("%d-%d", minPort, maxPort)
+	}
+
+	if req.AllowedTypes != nil {
+		if len(req.AllowedTypes) == 0 {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "至少需要开放一种隧道类型"})
+			return
+		}
+		newTypes := make(map[string]bool, len(req.AllowedTypes))
+		for _, t := range req.AllowedTypes {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if !service.NodeProxyTypes[t] {
+				c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "不支持的隧道类型 " + t})
+				return
+			}
+			newTypes[t] = true
+		}
+		for _, proxy := range proxies {
+			if !newTypes[strings.ToLower(proxy.ProxyType)] {
+				c.JSON(http.StatusOK, gin.H{"code": 400, "msg": fmt.Sprintf("节点上仍有 %s 类型的隧道 %s，不能关闭该类型", strings.ToUpper(proxy.ProxyType), proxy.ProxyName)})
+				return
+			}
+		}
+		for t := range newTypes {
+			allowed, err := service.NodeAllowsType(node, t)
+			if err != nil || !allowed {
+				needReview = true
+			}
+		}
+		allowedTypesBytes, err := json.Marshal(req.AllowedTypes)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+			return
+		}
+		node.AllowedTypes = string(allowedTypesBytes)
+	}
+
+	msg := "节点信息已更新"
+	fromStatus := node.Status
+	if needReview && !repository.NodeUnapproved(node.Status) {
+		node.Status = repository.NodeStatusPending
+		msg = "节点信息已更新，扩大了端口范围或开放类型，需重新等待管理员审核"
+	}
+
+	if err := h.nodeService.Update(context.Background(), node); err != nil {
+		h.logger.Error("更新捐赠节点失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "更新节点失败"})
+		return
+	}
+	h.logger.Info("节点所有者更新节点", "nodeID", node.ID, "ownerID", node.OwnerID.Int64, "review", needReview)
+	if node.Status != fromStatus {
+		if err := h.reviewService.Submit(context.Background(), node, fromStatus, "扩大了端口范围或开放类型"); err != nil {
+			h.logger.Error("保存节点审核记录失败", "error", err, "nodeID", node.ID)
+		}
+	}
+
+	data := gin.H{
+		"node_id": node.ID,
+		"status":  node.Status,
+	}
+	if node.PluginSecret != "" {
+		if cfg, err := h.renderFrpsConfig(node); err != nil {
+			h.logger.Error("生成frps配置失败", "error", err, "nodeID", node.ID)
+		} else {
+			data["frps_config"] = cfg
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": msg, "data": data})
+}
+
+// PauseOwnedNode 所有者暂停节点，暂停期间节点不再展示，拒绝新隧道和客户端登录
+func (h *NodeHandler) PauseOwnedNode(c *gin.Context) {
+	node, ok := h.ownedNode(c)
+	if !ok {
+		return
+	}
+
+	if repository.NodeUnapproved(node.Status) {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "节点尚未通过审核，无法暂停"})
+		return
+	}
+	if node.Status == repository.NodeStatusPaused {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "节点已处于暂停状态"})
+		return
+	}
+
+	if err := h.nodeService.UpdateStatus(context.Background(), node, repository.NodeStatusPaused); err != nil {
+		h.logger.Error("暂停节点失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "暂停节点失败"})
+		return
+	}
+	h.logger.Info("节点所有者暂停节点", "nodeID", node.ID, "ownerID", node.OwnerID.Int64)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "节点已暂停"})
+}
+
+// ResumeOwnedNode 所有者恢复已暂停的节点，节点先置为离线，由状态检查更新为实际状态
+func (h *NodeHandler) ResumeOwnedNode(c *gin.Context) {
+	node, ok := h.ownedNode(c)
+	if !ok {
+		return
+	}
+
+	if node.Status != repository.NodeStatusPaused {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "节点未处于暂停状态"})
+		return
+	}
+
+	if err := h.nodeService.UpdateStatus(context.Background(), node, repository.NodeStatusOffline); err != nil {
+		h.logger.Error("恢复节点失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "恢复节点失败"})
+		return
+	}
+	h.logger.Info("节点所有者恢复节点", "nodeID", node.ID, "ownerID", node.OwnerID.Int64)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "节点已恢复，状态将在下次检查后更新"})
+}
+
+// WithdrawOwnedNode 所有者撤回节点，节点上仍有隧道时需先暂停并等待用户迁移
+func (h *NodeHandler) WithdrawOwnedNode(c *gin.Context) {
+	node, ok := h.ownedNode(c)
+	if !ok {
+		return
+	}
+
+	proxies, err := h.proxyService.ListByNode(context.Background(), node.ID)
+	if err != nil {
+		h.logger.Error("获取节点隧道失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return
+	}
+	if len(proxies) > 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": fmt.Sprintf("节点上还有 %d 条隧道，请先暂停节点并等待用户迁移，或联系管理员处理", len(proxies))})
+		return
+	}
+
+	if err := h.nodeService.Delete(context.Background(), node.ID); err != nil {
+		h.logger.Error("撤回节点失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "撤回节点失败"})
+		return
+	}
+	h.logger.Info("节点所有者撤回节点", "nodeID", node.ID, "ownerID", node.OwnerID.Int64, "node", node.NodeName)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "节点已撤回"})
+}
+
+// ResubmitOwnedNode 所有者按管理员要求修改后重新提交审核，并重新执行自动校验
+func (h *NodeHandler) ResubmitOwnedNode(c *gin.Context) {
+	node, ok := h.ownedNode(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"` // 给审核管理员的修改说明
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误: " + err.Error()})
+		return
+	}
+
+	if err := h.reviewService.Resubmit(context.Background(), node, req.Note); err != nil {
+		if errors.Is(err, service.ErrNodeReviewInvalidState) {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "只有需修改的节点可以重新提交审核"})
+			return
+		}
+		h.logger.Error("重新提交节点审核失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "重新提交失败：" + err.Error()})
+		return
+	}
+	h.logger.Info("节点所有者重新提交审核", "nodeID", node.ID, "ownerID", node.OwnerID.Int64)
+
+	data := gin.H{"node_id": node.ID, "status": node.Status}
+	ctx, cancel := context.WithTimeout(context.Background(), nodeDonateValidationTimeout)
+	defer cancel()
+	validation, err := h.validationService.Validate(ctx, node, service.NodeValidationTriggerSubmit, 0)
+	if err != nil {
+		h.logger.Error("校验捐赠节点失败", "error", err, "nodeID", node.ID)
+	} else {
+		data["validation"] = validation
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "已重新提交，请等待管理员审核", "data": data})
+}
+
+// ListOwnedNodeReviews 获取所有者节点的审核记录，不包含审核人和管理员内部备注
+func (h *NodeHandler) ListOwnedNodeReviews(c *gin.Context) {
+	node, ok := h.ownedNode(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.reviewService.List(context.Background(), node.ID)
+	if err != nil {
+		h.logger.Error("获取节点审核记录失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点审核记录失败"})
+		return
+	}
+
+	data := make([]gin.H, 0, len(list))
+	for _, review := range list {
+		note := ""
+		if !review.ReviewerID.Valid {
+			note = review.Note
+		}
+		data = append(data, gin.H{
+			"id":          review.ID,
+			"action":      review.Action,
+			"from_status": review.FromStatus,
+			"to_status":   review.ToStatus,
+			"note":        note,
+			"reason":      review.Reason,
+			"created_at":  review.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": data})
+}
+
+// RotateOwnedNodeToken 所有者重置节点Dashboard密码，返回使用新密码的frps配置
+func (h *NodeHandler) RotateOwnedNodeToken(c *gin.Context) {
+	node, ok := h.ownedNode(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.nodeService.ResetToken(context.Background(), node); err != nil {
+		h.logger.Error("重置节点面板密码失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "重置面板密码失败"})
+		return
+	}
+	if node.PluginSecret == "" {
+		if _, err := h.nodeService.ResetPluginSecret(context.Background(), node); err != nil {
+			h.logger.Error("生成节点插件密钥失败", "error", err, "nodeID", node.ID)
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "生成插件密钥失败"})
+			return
+		}
+	}
+	h.logger.Info("节点所有者重置面板密码", "nodeID", node.ID, "ownerID", node.OwnerID.Int64)
+
+	cfg, err := h.renderFrpsConfig(node)
+	if err != nil {
+		h.logger.Error("生成frps配置失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "面板密码已重置，但生成frps配置失败，请联系管理员"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "面板密码已重置，请使用新配置重启 frps，重启前面板无法同步节点状态",
+		"data": gin.H{
+			"node_id":     node.ID,
+			"frps_config": cfg,
+		},
+	})
+}
+
+// GetOwnedNodeStats 获取所有者节点承载的隧道数、用户数和最近每天的流量
+// 查询参数 days 指定统计天数，默认7天，最多30天
+func (h *NodeHandler) GetOwnedNodeStats(c *gin.Context) {
+	node, ok := h.ownedNode(c)
+	if !ok {
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(nodeOwnerStatsDefaultDays)))
+	if err != nil || days < 1 || days > nodeOwnerStatsMaxDays {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": fmt.Sprintf("统计天数需在1到%d之间", nodeOwnerStatsMaxDays)})
+		return
+	}
+
+	proxies, err := h.proxyService.ListByNode(context.Background(), node.ID)
+	if err != nil {
+		h.logger.Error("获取节点隧道失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return
+	}
+
+	types := make(map[string]int)
+	users := make(map[string]bool)
+	onlineProxies := 0
+	for _, proxy := range proxies {
+		types[strings.ToLower(proxy.ProxyType)]++
+		users[proxy.Username] = true
+		if proxy.Status == "online" {
+			onlineProxies++
+		}
+	}
+
+	traffic, err := h.nodeService.GetDailyTraffic(context.Background(), node, time.Now(), days)
+	if err != nil {
+		h.logger.Error("获取节点每日流量失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"node_id":        node.ID,
+			"node_name":      node.NodeName,
+			"status":         node.Status,
+			"proxies":        len(proxies),
+			"online_proxies": onlineProxies,
+			"proxy_types":    types,
+			"users":          len(users),
+			"daily_traffic":  traffic,
+		},
+	})
+}
+
+// GetMyNodeRewards 分页获取当前用户捐赠节点获得的贡献奖励流水
+func (h *NodeHandler) GetMyNodeRewards(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的页码"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的每页数量"})
+		return
+	}
+
+	credits, total, err := h.rewardService.ListCredits(context.Background(), user.ID, 0, (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("获取贡献奖励流水失败", "error", err, "userID", user.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": credits,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+			"pages":     (total + pageSize - 1) / pageSize,
+		},
+	})
+}
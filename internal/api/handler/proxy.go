@@ -1,44 +1,82 @@
 package handler
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"stellarfrp/internal/repository"
 	"stellarfrp/internal/service"
+	"stellarfrp/pkg/frps"
 	"stellarfrp/pkg/logger"
 	"strconv"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ProxyHandler 隧道处理器
 type ProxyHandler struct {
-	proxyService service.ProxyService
-	nodeService  service.NodeService
-	userService  service.UserService
-	logger       *logger.Logger
+	proxyService         service.ProxyService
+	nodeService          service.NodeService
+	userService          service.UserService
+	proxyValidator       *service.ProxyValidator
+	proxyScheduleService service.ProxyScheduleService
+	proxyPresetService   service.ProxyPresetService
+	proxyAlertService    service.ProxyAlertService
+	proxyProbeService    service.ProxyProbeService
+	nodeRecommendService service.NodeRecommendService
+	snapshotService      service.ProxyStatusSnapshotService
+	logger               *logger.Logger
 }
 
 // NewProxyHandler 创建隧道处理器实例
-func NewProxyHandler(proxyService service.ProxyService, nodeService service.NodeService, userService service.UserService, logger *logger.Logger) *ProxyHandler {
+func NewProxyHandler(proxyService service.ProxyService, nodeService service.NodeService, userService service.UserService, proxyValidator *service.ProxyValidator, proxyScheduleService service.ProxyScheduleService, proxyPresetService service.ProxyPresetService, proxyAlertService service.ProxyAlertService, proxyProbeService service.ProxyProbeService, nodeRecommendService service.NodeRecommendService, snapshotService service.ProxyStatusSnapshotService, logger *logger.Logger) *ProxyHandler {
 	return &ProxyHandler{
-		proxyService: proxyService,
-		nodeService:  nodeService,
-		userService:  userService,
-		logger:       logger,
+		proxyService:         proxyService,
+		nodeService:          nodeService,
+		userService:          userService,
+		proxyValidator:       proxyValidator,
+		proxyScheduleService: proxyScheduleService,
+		proxyPresetService:   proxyPresetService,
+		proxyAlertService:    proxyAlertService,
+		proxyProbeService:    proxyProbeService,
+		nodeRecommendService: nodeRecommendService,
+		snapshotService:      snapshotService,
+		logger:               logger,
 	}
 }
 
+// ProxyNodeID 创建隧道时指定的节点，可以是节点ID或 "auto"（由系统推荐节点）
+type ProxyNodeID struct {
+	ID   int64
+	Auto bool
+}
+
+// UnmarshalJSON 兼容数字、数字字符串和 "auto"，空值视为未选择节点
+func (n *ProxyNodeID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return json.Unmarshal(data, &n.ID)
+	}
+	if s == "" {
+		return nil
+	}
+	if strings.EqualFold(s, "auto") {
+		n.Auto = true
+		return nil
+	}
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的节点ID: %s", s)
+	}
+	n.ID = id
+	return nil
+}
+
 // CreateProxy 创建隧道
+// nodeId 为 "auto" 时按隧道类型、远程端口和 nodeRegion、nodeIsp 偏好选择推荐得分最高的节点
 func (h *ProxyHandler) CreateProxy(c *gin.Context) {
 	token := c.GetHeader("Authorization")
 	if token == "" {
@@ -53,18 +91,21 @@ func (h *ProxyHandler) CreateProxy(c *gin.Context) {
 	}
 
 	type ProxyRequest struct {
-		NodeID               int64  `json:"nodeId" binding:"required"`
-		ProxyName            string `json:"proxyName" binding:"required"`
-		LocalIP              string `json:"localIp" binding:"required"`
-		LocalPort            int    `json:"localPort" binding:"required"`
-		RemotePort           int    `json:"remotePort"`
-		Domain               string `json:"domain"`
-		ProxyType            string `json:"proxyType" binding:"required"`
-		HostHeaderRewrite    string `json:"hostHeaderRewrite"`
-		HeaderXFromWhere     string `json:"headerXFromWhere"`
-		ProxyProtocolVersion string `json:"proxyProtocolVersion"`
-		UseEncryption        bool   `json:"useEncryption"`
-		UseCompression       bool   `json:"useCompression"`
+		NodeID               ProxyNodeID `json:"nodeId"`
+		NodeRegion           string      `json:"nodeRegion"`
+		NodeISP              string      `json:"nodeIsp"`
+		ProxyName            string      `json:"proxyName" binding:"required"`
+		PresetID             int64       `json:"presetId"`
+		LocalIP              string      `json:"localIp"`
+		LocalPort            int         `json:"localPort"`
+		RemotePort           int         `json:"remotePort"`
+		Domain               string      `json:"domain"`
+		ProxyType            string      `json:"proxyType"`
+		HostHeaderRewrite    string      `json:"hostHeaderRewrite"`
+		HeaderXFromWhere     string      `json:"headerXFromWhere"`
+		ProxyProtocolVersion string      `json:"proxyProtocolVersion"`
+		UseEncryption        *bool       `json:"useEncryption"`
+		UseCompression       *bool       `json:"useCompression"`
 	}
 
 	var req ProxyRequest
@@ -73,141 +114,59 @@ func (h *ProxyHandler) CreateProxy(c *gin.Context) {
 		return
 	}
 
-	node, err := h.nodeService.GetByID(context.Background(), req.NodeID)
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "节点不存在或已下线"})
-		return
-	}
-
-	nodes, err := h.nodeService.GetAccessibleNodes(context.Background(), user.GroupID)
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点权限失败"})
-		return
-	}
-
-	nodeAccessible := false
-	for _, n := range nodes {
-		if n.ID == req.NodeID {
-			nodeAccessible = true
-			break
-		}
-	}
-
-	if !nodeAccessible {
-		c.JSON(http.StatusOK, gin.H{"code": 403, "msg": "您没有权限使用该节点"})
-		return
-	}
-
-	var allowedTypes []string
-	if err := json.Unmarshal([]byte(node.AllowedTypes), &allowedTypes); err != nil {
-		h.logger.Error("Failed to parse allowed_types", "error", err, "value", node.AllowedTypes)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-		return
-	}
-
-	typeAllowed := false
-	for _, t := range allowedTypes {
-		if strings.EqualFold(t, req.ProxyType) {
-			typeAllowed = true
-			break
-		}
-	}
-
-	if !typeAllowed {
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "该节点不支持 " + req.ProxyType + " 类型的隧道"})
-		return
-	}
-
-	if req.ProxyType != "http" && req.ProxyType != "https" && req.RemotePort != 0 {
-		isUsed, err := h.proxyService.IsRemotePortUsed(context.Background(), req.NodeID, req.ProxyType, strconv.Itoa(req.RemotePort))
+	// 使用预设时，请求中未填写的字段由预设补全
+	if req.PresetID > 0 {
+		preset, err := h.proxyPresetService.GetByID(context.Background(), req.PresetID)
 		if err != nil {
-			h.logger.Error("Failed to check remote port usage", "error", err)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "检查端口占用失败"})
+			h.logger.Error("Failed to get proxy preset", "error", err)
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道预设失败"})
 			return
 		}
-		if isUsed {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "该节点下已有相同协议类型的隧道使用了端口 " + strconv.Itoa(req.RemotePort) + "，请更换端口"})
+		if preset == nil || preset.Username != user.Username {
+			c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道预设不存在"})
 			return
 		}
-	}
-
-	if req.ProxyType == "http" || req.ProxyType == "https" {
-		if req.Domain == "" {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "HTTP/HTTPS类型的隧道必须填写域名"})
-			return
+		if req.ProxyType == "" {
+			req.ProxyType = preset.ProxyType
 		}
-
-		expectedPort := 80
-		if req.ProxyType == "https" {
-			expectedPort = 443
+		if req.LocalIP == "" {
+			req.LocalIP = preset.LocalIP
 		}
-		if req.RemotePort != expectedPort {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": req.ProxyType + "类型的隧道远程端口必须为" + strconv.Itoa(expectedPort)})
-			return
+		if req.LocalPort == 0 {
+			req.LocalPort = preset.LocalPort
 		}
-	} else if req.ProxyType == "tcp" || req.ProxyType == "udp" {
-		if req.RemotePort == 0 {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "TCP/UDP类型的隧道必须填写远程端口"})
-			return
+		if req.UseEncryption == nil {
+			req.UseEncryption = &preset.UseEncryption
 		}
-
-		portRange := strings.Split(node.PortRange, "-")
-		if len(portRange) != 2 {
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "节点端口范围配置错误"})
-			return
+		if req.UseCompression == nil {
+			req.UseCompression = &preset.UseCompression
 		}
+		if req.HostHeaderRewrite == "" {
+			req.HostHeaderRewrite = preset.HostHeaderRewrite
+		}
+		if req.HeaderXFromWhere == "" {
+			req.HeaderXFromWhere = preset.HeaderXFromWhere
+		}
+	}
 
-		minPort, err1 := strconv.Atoi(portRange[0])
-		maxPort, err2 := strconv.Atoi(portRange[1])
-		remotePort := req.RemotePort
-
-		if err1 != nil || err2 != nil {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "端口格式错误"})
+	nodeID := req.NodeID.ID
+	if req.NodeID.Auto {
+		recommendations, err := h.nodeRecommendService.Recommend(context.Background(), user, &service.NodeRecommendRequest{
+			ProxyType:  strings.ToLower(req.ProxyType),
+			RemotePort: req.RemotePort,
+			Region:     req.NodeRegion,
+			ISP:        req.NodeISP,
+		})
+		if err != nil {
+			h.logger.Error("Failed to recommend node", "error", err)
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "自动选择节点失败"})
 			return
 		}
-
-		if remotePort < minPort || remotePort > maxPort {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "远程端口必须在" + node.PortRange + "范围内"})
+		if len(recommendations) == 0 {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "没有满足条件的可用节点，请手动选择节点"})
 			return
 		}
-	}
-
-	existingProxy, err := h.proxyService.GetByUsernameAndName(context.Background(), user.Username, req.ProxyName)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		h.logger.Error("Failed to check existing proxy", "error", err)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-		return
-	}
-
-	if existingProxy != nil {
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "您已有同名隧道，请更换隧道名称"})
-		return
-	}
-
-	proxyCount, err := h.proxyService.GetUserProxyCount(context.Background(), user.Username)
-	if err != nil {
-		h.logger.Error("Failed to get user proxy count", "error", err)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道数量失败"})
-		return
-	}
-
-	tunnelLimit, err := h.userService.GetGroupTunnelLimit(context.Background(), user.GroupID)
-	if err != nil {
-		h.logger.Error("Failed to get group tunnel limit", "error", err)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取用户组隧道限制失败"})
-		return
-	}
-
-	additionalTunnels := 0
-	if user.TunnelCount != nil {
-		additionalTunnels = *user.TunnelCount
-	}
-
-	totalTunnelLimit := tunnelLimit + additionalTunnels
-
-	if proxyCount >= totalTunnelLimit {
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "您已达到可创建的数量上限"})
-		return
+		nodeID = recommendations[0].NodeID
 	}
 
 	proxy := &repository.Proxy{
@@ -216,24 +175,54 @@ func (h *ProxyHandler) CreateProxy(c *gin.Context) {
 		ProxyType:         req.ProxyType,
 		LocalIP:           req.LocalIP,
 		LocalPort:         req.LocalPort,
-		UseEncryption:     strconv.FormatBool(req.UseEncryption),
-		UseCompression:    strconv.FormatBool(req.UseCompression),
+		UseEncryption:     strconv.FormatBool(req.UseEncryption != nil && *req.UseEncryption),
+		UseCompression:    strconv.FormatBool(req.UseCompression != nil && *req.UseCompression),
 		Domain:            req.Domain,
 		HostHeaderRewrite: req.HostHeaderRewrite,
 		RemotePort:        strconv.Itoa(req.RemotePort),
 		HeaderXFromWhere:  req.HeaderXFromWhere,
-		Node:              req.NodeID,
+		Node:              nodeID,
 		Status:            "offline",
 	}
 
+	id, ok := h.createProxyForUser(c, user, proxy)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "创建成功", "data": gin.H{"Id": id, "NodeId": nodeID}})
+}
+
+// createProxyForUser 按创建隧道的规则（含数量上限）校验并写入隧道，失败时直接写入响应
+func (h *ProxyHandler) createProxyForUser(c *gin.Context, user *repository.User, proxy *repository.Proxy) (int64, bool) {
+	remotePort, _ := strconv.Atoi(proxy.RemotePort)
+	validationErrs, err := h.proxyValidator.Validate(context.Background(), user, &service.ProxyValidationInput{
+		NodeID:     proxy.Node,
+		ProxyName:  proxy.ProxyName,
+		ProxyType:  proxy.ProxyType,
+		LocalIP:    proxy.LocalIP,
+		LocalPort:  proxy.LocalPort,
+		RemotePort: remotePort,
+		Domain:     proxy.Domain,
+	})
+	if err != nil {
+		h.logger.Error("Failed to validate proxy", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return 0, false
+	}
+	if len(validationErrs) > 0 {
+		c.JSON(http.StatusOK, proxyValidationFailedResponse(validationErrs))
+		return 0, false
+	}
+
 	id, err := h.proxyService.Create(context.Background(), proxy)
 	if err != nil {
 		h.logger.Error("Failed to create proxy", "error", err)
 		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "创建隧道失败: " + err.Error()})
-		return
+		return 0, false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "创建成功", "data": gin.H{"Id": id}})
+	return id, true
 }
 
 // UpdateProxy 更新隧道
@@ -288,128 +277,26 @@ func (h *ProxyHandler) UpdateProxy(c *gin.Context) {
 		return
 	}
 
-	node, err := h.nodeService.GetByID(context.Background(), req.NodeID)
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "节点不存在或已下线"})
-		return
-	}
-
-	if existingProxy.Node != req.NodeID {
-		originalNode, err := h.nodeService.GetByID(context.Background(), existingProxy.Node)
-		if err == nil {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "该隧道属于 " + originalNode.NodeName + " 节点，不能修改为其他节点"})
-		} else {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "该隧道不属于请求中指定的节点"})
-		}
-		return
-	}
-
-	nodes, err := h.nodeService.GetAccessibleNodes(context.Background(), user.GroupID)
+	validationErrs, err := h.proxyValidator.Validate(context.Background(), user, &service.ProxyValidationInput{
+		NodeID:     req.NodeID,
+		ProxyName:  req.ProxyName,
+		ProxyType:  req.ProxyType,
+		LocalIP:    req.LocalIP,
+		LocalPort:  req.LocalPort,
+		RemotePort: req.RemotePort,
+		Domain:     req.Domain,
+		Existing:   existingProxy,
+	})
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点权限失败"})
-		return
-	}
-
-	nodeAccessible := false
-	for _, n := range nodes {
-		if n.ID == req.NodeID {
-			nodeAccessible = true
-			break
-		}
-	}
-
-	if !nodeAccessible {
-		c.JSON(http.StatusOK, gin.H{"code": 403, "msg": "您没有权限使用该节点"})
-		return
-	}
-
-	var allowedTypes []string
-	if err := json.Unmarshal([]byte(node.AllowedTypes), &allowedTypes); err != nil {
-		h.logger.Error("Failed to parse allowed_types", "error", err, "value", node.AllowedTypes)
+		h.logger.Error("Failed to validate proxy", "error", err)
 		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
 		return
 	}
-
-	typeAllowed := false
-	for _, t := range allowedTypes {
-		if strings.EqualFold(t, req.ProxyType) {
-			typeAllowed = true
-			break
-		}
-	}
-
-	if !typeAllowed {
-		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "该节点不支持 " + req.ProxyType + " 类型的隧道"})
+	if len(validationErrs) > 0 {
+		c.JSON(http.StatusOK, proxyValidationFailedResponse(validationErrs))
 		return
 	}
 
-	if req.ProxyType != "http" && req.ProxyType != "https" && req.RemotePort != 0 {
-		isUsed, err := h.proxyService.IsRemotePortUsed(context.Background(), req.NodeID, req.ProxyType, strconv.Itoa(req.RemotePort))
-		if err != nil {
-			h.logger.Error("Failed to check remote port usage", "error", err)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "检查端口占用失败"})
-			return
-		}
-		if isUsed && (existingProxy.RemotePort != strconv.Itoa(req.RemotePort) || existingProxy.Node != req.NodeID) {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "该节点下已有相同协议类型的隧道使用了端口 " + strconv.Itoa(req.RemotePort) + "，请更换端口"})
-			return
-		}
-	}
-
-	if req.ProxyType == "http" || req.ProxyType == "https" {
-		if req.Domain == "" {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "HTTP/HTTPS类型的隧道必须填写域名"})
-			return
-		}
-
-		expectedPort := 80
-		if req.ProxyType == "https" {
-			expectedPort = 443
-		}
-		if req.RemotePort != expectedPort {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": req.ProxyType + "类型的隧道远程端口必须为" + strconv.Itoa(expectedPort)})
-			return
-		}
-	} else if req.ProxyType == "tcp" || req.ProxyType == "udp" {
-		if req.RemotePort == 0 {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "TCP/UDP类型的隧道必须填写远程端口"})
-			return
-		}
-
-		portRange := strings.Split(node.PortRange, "-")
-		if len(portRange) != 2 {
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "节点端口范围配置错误"})
-			return
-		}
-
-		minPort, err1 := strconv.Atoi(portRange[0])
-		maxPort, err2 := strconv.Atoi(portRange[1])
-		remotePort := req.RemotePort
-
-		if err1 != nil || err2 != nil {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "端口格式错误"})
-			return
-		}
-
-		if remotePort < minPort || remotePort > maxPort {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "远程端口必须在" + node.PortRange + "范围内"})
-			return
-		}
-	}
-
-	if existingProxy.ProxyName != req.ProxyName {
-		otherProxy, err := h.proxyService.GetByUsernameAndName(context.Background(), user.Username, req.ProxyName)
-		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			h.logger.Error("Failed to check existing proxy", "error", err)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-			return
-		}
-		if otherProxy != nil {
-			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "您已有同名隧道，请更换隧道名称"})
-			return
-		}
-	}
-
 	proxy := &repository.Proxy{
 		ID:                req.ID,
 		Username:          user.Username,
@@ -427,7 +314,11 @@ func (h *ProxyHandler) UpdateProxy(c *gin.Context) {
 		Status:            existingProxy.Status,
 	}
 
-	err = h.proxyService.Update(context.Background(), proxy)
+	err = h.proxyService.UpdateBy(context.Background(), proxy, service.ProxyOperator{
+		Type:   service.ProxyOperatorUser,
+		Name:   user.Username,
+		Action: service.ProxyVersionActionUpdate,
+	})
 	if err != nil {
 		h.logger.Error("Failed to update proxy", "error", err)
 		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "更新隧道失败: " + err.Error()})
@@ -757,7 +648,7 @@ func (h *ProxyHandler) GetProxyByID(c *gin.Context) {
 	})
 }
 
-// GetProxyStatus 获取隧道状态
+// GetProxyStatus 获取隧道状态，数据来自后台轮询器写入的快照
 func (h *ProxyHandler) GetProxyStatus(c *gin.Context) {
 	token := c.GetHeader("Authorization")
 	if token == "" {
@@ -781,291 +672,99 @@ func (h *ProxyHandler) GetProxyStatus(c *gin.Context) {
 		return
 	}
 
-	if len(reqData.IDs) == 0 {
-		proxies, err := h.proxyService.GetByUsername(context.Background(), user.Username)
-		if err != nil {
-			h.logger.Error("Failed to get user's proxies", "error", err)
-			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取用户隧道列表失败"})
-			return
-		}
-
-		if len(proxies) == 0 {
-			c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": gin.H{}})
-			return
-		}
-
-		reqData.IDs = make([]string, len(proxies))
-		for i, proxy := range proxies {
-			reqData.IDs[i] = strconv.FormatInt(proxy.ID, 10)
-		}
+	proxies, err := h.proxyService.GetByUsername(context.Background(), user.Username)
+	if err != nil {
+		h.logger.Error("Failed to get user's proxies", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取用户隧道列表失败"})
+		return
 	}
 
-	proxyNodeMap := make(map[int64]int64)
-	proxyNameMap := make(map[int64]string)
-	nodeNameMap := make(map[int64]string)
-	nodeMap := make(map[int64]*repository.Node)
-
-	for _, idStr := range reqData.IDs {
-		id, err := strconv.ParseInt(idStr, 10, 64)
-		if err != nil {
-			h.logger.Error("Invalid proxy ID in status request", "id", idStr, "error", err)
-			continue
-		}
-
-		proxy, err := h.proxyService.GetByID(context.Background(), id)
-		if err != nil {
-			h.logger.Error("Failed to get proxy for status", "id", id, "error", err)
-			continue
-		}
-
-		if proxy == nil {
-			continue
-		}
+	if len(reqData.IDs) == 0 && len(proxies) == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": gin.H{}})
+		return
+	}
 
-		if proxy.Username != user.Username {
-			continue
+	// 未指定ID时返回用户的全部隧道，否则只返回属于该用户的指定隧道
+	targets := proxies
+	if len(reqData.IDs) > 0 {
+		userProxies := make(map[int64]*repository.Proxy, len(proxies))
+		for _, proxy := range proxies {
+			userProxies[proxy.ID] = proxy
 		}
-
-		node, err := h.nodeService.GetByID(context.Background(), proxy.Node)
-		if err != nil {
-			h.logger.Error("Failed to get node for status", "nodeID", proxy.Node, "error", err)
-			continue
+		targets = make([]*repository.Proxy, 0, len(reqData.IDs))
+		for _, idStr := range reqData.IDs {
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				h.logger.Error("Invalid proxy ID in status request", "id", idStr, "error", err)
+				continue
+			}
+			if proxy, ok := userProxies[id]; ok {
+				targets = append(targets, proxy)
+			}
 		}
-
-		nodeNameMap[proxy.Node] = node.NodeName
-		nodeMap[proxy.Node] = node
-
-		proxyNodeMap[proxy.ID] = proxy.Node
-		proxyNameMap[proxy.ID] = proxy.ProxyName
 	}
 
-	if len(proxyNodeMap) == 0 {
+	if len(targets) == 0 {
 		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "未找到有效的隧道"})
 		return
 	}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	proxyIDs := make([]int64, len(targets))
+	for i, proxy := range targets {
+		proxyIDs[i] = proxy.ID
 	}
 
-	var mu sync.Mutex
-	results := make(map[string]gin.H)
-
-	nodeProxiesMap := make(map[int64][]int64)
-
-	for proxyID, nodeID := range proxyNodeMap {
-		nodeProxiesMap[nodeID] = append(nodeProxiesMap[nodeID], proxyID)
-	}
-
-	var wg sync.WaitGroup
-
-	for nodeID, proxyIDs := range nodeProxiesMap {
-		wg.Add(1)
-		go func(nodeID int64, proxyIDs []int64) {
-			defer wg.Done()
-
-			node := nodeMap[nodeID]
-			nodeName := nodeNameMap[nodeID]
-
-			apiURL := fmt.Sprintf("%s/api/proxy/", node.URL)
-
-			httpReq, err := http.NewRequest("GET", apiURL, nil)
-			if err != nil {
-				h.logger.Error("Failed to create request for node status", "error", err, "url", apiURL)
-				mu.Lock()
-				for _, proxyID := range proxyIDs {
-					results[strconv.FormatInt(proxyID, 10)] = gin.H{
-						"status":          "offline",
-						"proxyId":         proxyID,
-						"proxyName":       proxyNameMap[proxyID],
-						"nodeId":          nodeID,
-						"nodeName":        nodeName,
-						"lastStartTime":   "",
-						"lastCloseTime":   "",
-						"curConns":        0,
-						"todayTrafficIn":  0,
-						"todayTrafficOut": 0,
-					}
-				}
-				mu.Unlock()
-				return
-			}
-
-			httpReq.SetBasicAuth(node.User, node.Token)
-
-			resp, err := client.Do(httpReq)
-			if err != nil {
-				h.logger.Error("Failed to get proxy status from node", "error", err, "nodeID", nodeID)
-				mu.Lock()
-				for _, proxyID := range proxyIDs {
-					results[strconv.FormatInt(proxyID, 10)] = gin.H{
-						"status":          "offline",
-						"proxyId":         proxyID,
-						"proxyName":       proxyNameMap[proxyID],
-						"nodeId":          nodeID,
-						"nodeName":        nodeName,
-						"lastStartTime":   "",
-						"lastCloseTime":   "",
-						"curConns":        0,
-						"todayTrafficIn":  0,
-						"todayTrafficOut": 0,
-					}
-				}
-				mu.Unlock()
-				return
-			}
-			defer resp.Body.Close()
-
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				h.logger.Error("Failed to read response from node status", "error", err, "nodeID", nodeID)
-				mu.Lock()
-				for _, proxyID := range proxyIDs {
-					results[strconv.FormatInt(proxyID, 10)] = gin.H{
-						"status":          "offline",
-						"proxyId":         proxyID,
-						"proxyName":       proxyNameMap[proxyID],
-						"nodeId":          nodeID,
-						"nodeName":        nodeName,
-						"lastStartTime":   "",
-						"lastCloseTime":   "",
-						"curConns":        0,
-						"todayTrafficIn":  0,
-						"todayTrafficOut": 0,
-					}
-				}
-				mu.Unlock()
-				return
-			}
-
-			var allProxiesStatus map[string]interface{}
-			if err := json.Unmarshal(body, &allProxiesStatus); err != nil {
-				h.logger.Error("Failed to parse response from node status", "error", err, "body", string(body), "nodeID", nodeID)
-				mu.Lock()
-				for _, proxyID := range proxyIDs {
-					results[strconv.FormatInt(proxyID, 10)] = gin.H{
-						"status":          "offline",
-						"proxyId":         proxyID,
-						"proxyName":       proxyNameMap[proxyID],
-						"nodeId":          nodeID,
-						"nodeName":        nodeName,
-						"lastStartTime":   "",
-						"lastCloseTime":   "",
-						"curConns":        0,
-						"todayTrafficIn":  0,
-						"todayTrafficOut": 0,
-					}
-				}
-				mu.Unlock()
-				return
-			}
-
-			mu.Lock()
-			defer mu.Unlock()
-
-			allProxies := make([]interface{}, 0)
-			proxyTypes := []string{"tcp", "udp", "http", "https", "stcp", "xtcp"}
-
-			for _, pType := range proxyTypes {
-				if typeProxiesList, ok := allProxiesStatus[pType].(map[string]interface{}); ok {
-					if proxiesList, ok := typeProxiesList["proxies"].([]interface{}); ok {
-						allProxies = append(allProxies, proxiesList...)
-					}
-				}
-			}
-
-			proxyStatusMap := make(map[string]interface{})
-			for _, proxyData := range allProxies {
-				proxyInfo, ok := proxyData.(map[string]interface{})
-				if !ok {
-					continue
-				}
-
-				name, ok := proxyInfo["name"].(string)
-				if !ok {
-					continue
+	snapshots, err := h.snapshotService.GetMany(context.Background(), proxyIDs)
+	if err != nil {
+		h.logger.Error("Failed to get proxy status snapshots", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道状态失败"})
+		return
+	}
+
+	nodeNames := make(map[int64]string)
+	results := make(map[string]gin.H, len(targets))
+	for _, proxy := range targets {
+		result := gin.H{
+			"status":          "offline",
+			"proxyId":         proxy.ID,
+			"proxyName":       proxy.ProxyName,
+			"nodeId":          proxy.Node,
+			"nodeName":        "",
+			"lastStartTime":   "",
+			"lastCloseTime":   "",
+			"curConns":        0,
+			"todayTrafficIn":  0,
+			"todayTrafficOut": 0,
+			"updatedAt":       "",
+		}
+
+		if snapshot, ok := snapshots[proxy.ID]; ok {
+			result["status"] = snapshot.Status
+			result["nodeName"] = snapshot.NodeName
+			result["lastStartTime"] = snapshot.LastStartTime
+			result["lastCloseTime"] = snapshot.LastCloseTime
+			result["curConns"] = snapshot.CurConns
+			result["todayTrafficIn"] = snapshot.TodayTrafficIn
+			result["todayTrafficOut"] = snapshot.TodayTrafficOut
+			result["updatedAt"] = snapshot.UpdatedAt.Format("2006-01-02 15:04:05")
+		} else {
+			// 没有快照时仅补全节点名称
+			if _, ok := nodeNames[proxy.Node]; !ok {
+				if node, err := h.nodeService.GetByID(context.Background(), proxy.Node); err == nil {
+					nodeNames[proxy.Node] = node.NodeName
 				}
-
-				proxyStatusMap[name] = proxyInfo
 			}
+			result["nodeName"] = nodeNames[proxy.Node]
+		}
 
-			for _, proxyID := range proxyIDs {
-				proxyName := proxyNameMap[proxyID]
-				expectedProxyName := user.Username + "." + proxyName
-
-				proxyStatus, found := proxyStatusMap[expectedProxyName]
-				if found {
-					proxyInfo, ok := proxyStatus.(map[string]interface{})
-					if !ok {
-						proxyInfo = make(map[string]interface{})
-					}
-
-					status := "offline"
-					if statusStr, ok := proxyInfo["status"].(string); ok && statusStr == "online" {
-						status = "online"
-					}
-
-					lastStartTime := ""
-					if start, ok := proxyInfo["lastStartTime"].(string); ok {
-						lastStartTime = start
-					}
-
-					lastCloseTime := ""
-					if close, ok := proxyInfo["lastCloseTime"].(string); ok {
-						lastCloseTime = close
-					}
-
-					curConns := 0
-					if conns, ok := proxyInfo["curConns"].(float64); ok {
-						curConns = int(conns)
-					}
-
-					trafficIn := int64(0)
-					if in, ok := proxyInfo["todayTrafficIn"].(float64); ok {
-						trafficIn = int64(in)
-					}
-
-					trafficOut := int64(0)
-					if out, ok := proxyInfo["todayTrafficOut"].(float64); ok {
-						trafficOut = int64(out)
-					}
-
-					results[strconv.FormatInt(proxyID, 10)] = gin.H{
-						"status":          status,
-						"proxyId":         proxyID,
-						"proxyName":       proxyName,
-						"nodeId":          nodeID,
-						"nodeName":        nodeName,
-						"lastStartTime":   lastStartTime,
-						"lastCloseTime":   lastCloseTime,
-						"curConns":        curConns,
-						"todayTrafficIn":  trafficIn,
-						"todayTrafficOut": trafficOut,
-					}
-				} else {
-					results[strconv.FormatInt(proxyID, 10)] = gin.H{
-						"status":          "offline",
-						"proxyId":         proxyID,
-						"proxyName":       proxyName,
-						"nodeId":          nodeID,
-						"nodeName":        nodeName,
-						"lastStartTime":   "",
-						"lastCloseTime":   "",
-						"curConns":        0,
-						"todayTrafficIn":  0,
-						"todayTrafficOut": 0,
-					}
-				}
-			}
-		}(nodeID, proxyIDs)
+		results[strconv.FormatInt(proxy.ID, 10)] = result
 	}
 
-	wg.Wait()
-
 	c.JSON(http.StatusOK, gin.H{
-		"code": 200,
-		"msg":  "获取成功",
-		"data": results,
+		"code":         200,
+		"msg":          "获取成功",
+		"data":         results,
+		"pollInterval": int(service.ProxyStatusPollInterval.Seconds()),
 	})
 }
 
@@ -1115,71 +814,16 @@ func (h *ProxyHandler) CloseProxy(c *gin.Context) {
 		return
 	}
 
-	node, err := h.nodeService.GetByID(context.Background(), proxy.Node)
-	if err != nil {
-		h.logger.Error("获取节点信息失败", "error", err, "nodeID", proxy.Node)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取节点信息失败"})
-		return
-	}
-
-	requestBody, err := json.Marshal(map[string]string{
-		"runid": proxy.RunID,
-	})
-	if err != nil {
-		h.logger.Error("构建请求体失败", "error", err)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-		return
-	}
-
-	apiURL := fmt.Sprintf("%s/api/client/kick", node.URL)
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	req2, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(requestBody))
-	if err != nil {
-		h.logger.Error("创建请求失败", "error", err, "url", apiURL)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
-		return
-	}
-
-	req2.Header.Set("Content-Type", "application/json")
-	req2.SetBasicAuth(node.User, node.Token)
-
-	resp, err := client.Do(req2)
-	if err != nil {
-		h.logger.Error("发送请求失败", "error", err, "url", apiURL)
+	if err := h.proxyService.KickProxy(context.Background(), proxy, service.ProxyEventReasonUserKick); err != nil {
+		h.logger.Error("关闭隧道请求失败", "error", err, "proxyID", proxy.ID, "nodeID", proxy.Node)
+		var statusErr *frps.StatusError
+		if errors.As(err, &statusErr) {
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "节点返回错误"})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "关闭隧道请求失败"})
 		return
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		h.logger.Error("读取响应失败", "error", err)
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "读取响应失败"})
-		return
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		h.logger.Error("解析响应失败", "error", err, "body", string(body))
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "解析响应失败"})
-		return
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		h.logger.Error("节点返回错误", "statusCode", resp.StatusCode, "response", string(body))
-		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "节点返回错误"})
-		return
-	}
-
-	proxy.RunID = ""
-	proxy.Status = "offline"
-	if err := h.proxyService.Update(context.Background(), proxy); err != nil {
-		h.logger.Error("更新隧道状态失败", "error", err)
-	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
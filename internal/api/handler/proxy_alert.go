@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"stellarfrp/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProxyAlert 获取隧道的离线告警配置
+func (h *ProxyHandler) GetProxyAlert(c *gin.Context) {
+	proxyID, _ := strconv.ParseInt(c.Query("id"), 10, 64)
+	_, proxy := h.getOwnedProxy(c, proxyID)
+	if proxy == nil {
+		return
+	}
+
+	settings, err := h.proxyAlertService.Get(context.Background(), proxy)
+	if err != nil {
+		h.logger.Error("Failed to get proxy alert", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道告警配置失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": settings})
+}
+
+// SetProxyAlert 设置隧道的离线告警，阈值为0表示关闭对应规则
+func (h *ProxyHandler) SetProxyAlert(c *gin.Context) {
+	type AlertRequest struct {
+		ID                 int64  `json:"id" binding:"required"`
+		OfflineMinutes     int    `json:"offline_minutes"`
+		FlapThreshold      int    `json:"flap_threshold"`
+		NotifyEmail        bool   `json:"notify_email"`
+		WebhookURL         string `json:"webhook_url"`
+		ResetWebhookSecret bool   `json:"reset_webhook_secret"`
+	}
+
+	var req AlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	user, proxy := h.getOwnedProxy(c, req.ID)
+	if proxy == nil {
+		return
+	}
+
+	if req.NotifyEmail && user.Email == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "请先绑定邮箱后再开启邮件通知"})
+		return
+	}
+
+	settings := &service.ProxyAlertSettings{
+		ProxyID:        proxy.ID,
+		OfflineMinutes: req.OfflineMinutes,
+		FlapThreshold:  req.FlapThreshold,
+		NotifyEmail:    req.NotifyEmail,
+		WebhookURL:     req.WebhookURL,
+	}
+	if err := service.ValidateProxyAlertSettings(settings); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	saved, err := h.proxyAlertService.Save(context.Background(), proxy, settings, req.ResetWebhookSecret)
+	if err != nil {
+		h.logger.Error("Failed to set proxy alert", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "设置隧道告警失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "设置成功", "data": saved})
+}
+
+// TestProxyAlert 向已配置的通知方式发送测试告警
+func (h *ProxyHandler) TestProxyAlert(c *gin.Context) {
+	type TestRequest struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+
+	var req TestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	_, proxy := h.getOwnedProxy(c, req.ID)
+	if proxy == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := h.proxyAlertService.SendTest(ctx, proxy); err != nil {
+		if errors.Is(err, service.ErrProxyAlertTestTooFrequent) {
+			c.JSON(http.StatusOK, gin.H{"code": 429, "msg": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "发送测试告警失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "测试告警已发送"})
+}
@@ -2,9 +2,11 @@ package handler
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"stellarfrp/internal/constants"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,19 +34,31 @@ type FrpPluginResponse struct {
 
 // ProxyAuthHandler 隧道鉴权处理器
 type ProxyAuthHandler struct {
-	proxyService       service.ProxyService
-	userService        service.UserService
-	userTrafficService service.UserTrafficLogService
-	logger             *logger.Logger
+	proxyService         service.ProxyService
+	nodeService          service.NodeService
+	userService          service.UserService
+	userTrafficService   service.UserTrafficLogService
+	proxyScheduleService service.ProxyScheduleService
+	maintenanceService   service.NodeMaintenanceService
+	capacityService      service.NodeCapacityService
+	validationService    service.NodeValidationService
+	requireSecret        bool
+	logger               *logger.Logger
 }
 
 // NewProxyAuthHandler 创建隧道鉴权处理器实例
-func NewProxyAuthHandler(proxyService service.ProxyService, userService service.UserService, userTrafficService service.UserTrafficLogService, logger *logger.Logger) *ProxyAuthHandler {
+func NewProxyAuthHandler(proxyService service.ProxyService, nodeService service.NodeService, userService service.UserService, userTrafficService service.UserTrafficLogService, proxyScheduleService service.ProxyScheduleService, maintenanceService service.NodeMaintenanceService, capacityService service.NodeCapacityService, validationService service.NodeValidationService, requireSecret bool, logger *logger.Logger) *ProxyAuthHandler {
 	return &ProxyAuthHandler{
-		proxyService:       proxyService,
-		userService:        userService,
-		userTrafficService: userTrafficService,
-		logger:             logger,
+		proxyService:         proxyService,
+		nodeService:          nodeService,
+		userService:          userService,
+		userTrafficService:   userTrafficService,
+		proxyScheduleService: proxyScheduleService,
+		maintenanceService:   maintenanceService,
+		capacityService:      capacityService,
+		validationService:    validationService,
+		requireSecret:        requireSecret,
+		logger:               logger,
 	}
 }
 
@@ -61,14 +75,19 @@ func (h *ProxyAuthHandler) HandleProxyAuth(c *gin.Context) {
 		return
 	}
 
+	pluginNode, ok := h.resolvePluginNode(c)
+	if !ok {
+		return
+	}
+
 	// 根据操作类型处理请求
 	switch req.Op {
 	case "Login":
-		h.handleLoginAuth(c, req)
+		h.handleLoginAuth(c, req, pluginNode)
 	case "NewProxy":
-		h.handleNewProxyAuth(c, req)
+		h.handleNewProxyAuth(c, req, pluginNode)
 	case "CloseProxy":
-		h.handleCloseProxyAuth(c, req)
+		h.handleCloseProxyAuth(c, req, pluginNode)
 	default:
 		c.JSON(http.StatusOK, FrpPluginResponse{
 			Reject:       true,
@@ -77,8 +96,79 @@ func (h *ProxyAuthHandler) HandleProxyAuth(c *gin.Context) {
 	}
 }
 
-// handleLoginAuth 处理登录鉴权
-func (h *ProxyAuthHandler) handleLoginAuth(c *gin.Context, req FrpPluginRequest) {
+// resolvePluginNode 解析插件地址中的节点ID（如 /proxy/auth?node=1&secret=xxx）并校验回调密钥
+// 未携带节点ID的旧版配置返回nil，开启强制校验后拒绝；节点已生成密钥时必须携带正确的密钥
+func (h *ProxyAuthHandler) resolvePluginNode(c *gin.Context) (*repository.Node, bool) {
+	nodeIDStr := c.Query("node")
+	if nodeIDStr == "" {
+		if !h.requireSecret {
+			return nil, true
+		}
+		h.logger.Warn("插件回调未携带节点ID", "ip", c.ClientIP())
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrPluginSecretInvalid,
+		})
+		return nil, false
+	}
+
+	nodeID, err := strconv.ParseInt(nodeIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrInvalidRequest,
+		})
+		return nil, false
+	}
+
+	node, err := h.nodeService.GetByID(context.Background(), nodeID)
+	if err != nil {
+		h.logger.Warn("插件回调的节点不存在", "nodeID", nodeID, "ip", c.ClientIP())
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrPluginNodeInvalid,
+		})
+		return nil, false
+	}
+
+	secrets, err := h.nodeService.Secrets(node)
+	if err != nil {
+		h.logger.Error("解密节点插件密钥失败", "error", err, "nodeID", node.ID)
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrPluginSecretInvalid,
+		})
+		return nil, false
+	}
+	if secrets.PluginSecret == "" && h.requireSecret {
+		h.logger.Warn("节点未生成插件密钥，拒绝插件回调", "nodeID", nodeID, "ip", c.ClientIP())
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrPluginSecretInvalid,
+		})
+		return nil, false
+	}
+	if secrets.PluginSecret != "" && subtle.ConstantTimeCompare([]byte(c.Query("secret")), []byte(secrets.PluginSecret)) != 1 {
+		h.logger.Warn("插件回调密钥错误", "nodeID", nodeID, "ip", c.ClientIP())
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrPluginSecretInvalid,
+		})
+		return nil, false
+	}
+
+	// 记录携带正确密钥的回调，供捐赠节点校验确认插件配置
+	if node.PluginSecret != "" {
+		if err := h.validationService.RecordPluginCallback(context.Background(), node.ID); err != nil {
+			h.logger.Error("记录插件回调失败", "error", err, "nodeID", node.ID)
+		}
+	}
+
+	return node, true
+}
+
+// handleLoginAuth 处理登录鉴权，pluginNode 为发起回调的节点，旧版配置为nil
+func (h *ProxyAuthHandler) handleLoginAuth(c *gin.Context, req FrpPluginRequest, pluginNode *repository.Node) {
 	content := req.Content
 	username, _ := content["user"].(string)
 
@@ -177,14 +267,44 @@ func (h *ProxyAuthHandler) handleLoginAuth(c *gin.Context, req FrpPluginRequest)
 		return
 	}
 
+	// 已知发起回调的节点时检查节点是否暂停及在线客户端数量上限
+	if pluginNode != nil && pluginNode.Status == repository.NodeStatusPaused {
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrNodePaused,
+		})
+		return
+	}
+
+	if pluginNode != nil {
+		runID, _ := content["run_id"].(string)
+		reason, err := h.capacityService.CheckClient(context.Background(), pluginNode.ID, runID)
+		if err != nil {
+			h.logger.Error("检查节点在线客户端数量失败", "error", err, "nodeID", pluginNode.ID)
+			c.JSON(http.StatusOK, FrpPluginResponse{
+				Reject:       true,
+				RejectReason: constants.ErrInternalServer,
+			})
+			return
+		}
+
+		if reason != "" {
+			c.JSON(http.StatusOK, FrpPluginResponse{
+				Reject:       true,
+				RejectReason: reason,
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, FrpPluginResponse{
 		Reject:   false,
 		Unchange: true,
 	})
 }
 
-// handleNewProxyAuth 处理新隧道创建鉴权
-func (h *ProxyAuthHandler) handleNewProxyAuth(c *gin.Context, req FrpPluginRequest) {
+// handleNewProxyAuth 处理新隧道创建鉴权，pluginNode 为发起回调的节点，旧版配置为nil
+func (h *ProxyAuthHandler) handleNewProxyAuth(c *gin.Context, req FrpPluginRequest, pluginNode *repository.Node) {
 	content := req.Content
 
 	userInfo, ok := content["user"].(map[string]interface{})
@@ -312,6 +432,72 @@ func (h *ProxyAuthHandler) handleNewProxyAuth(c *gin.Context, req FrpPluginReque
 		return
 	}
 
+	// 隧道只能在所属节点上启动
+	if pluginNode != nil && proxy.Node != pluginNode.ID {
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrProxyNodeMismatch,
+		})
+		return
+	}
+
+	// 节点被所有者暂停时拒绝启动隧道，旧版配置未携带节点信息时按隧道所属节点判断
+	proxyNode := pluginNode
+	if proxyNode == nil {
+		if proxyNode, err = h.nodeService.GetByID(context.Background(), proxy.Node); err != nil {
+			h.logger.Error("获取隧道所属节点失败", "error", err, "nodeID", proxy.Node)
+		}
+	}
+	if proxyNode != nil && proxyNode.Status == repository.NodeStatusPaused {
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrNodePaused,
+		})
+		return
+	}
+
+	// 节点维护期间拒绝启动新隧道
+	maintenance, err := h.maintenanceService.GetActive(context.Background(), proxy.Node, time.Now())
+	if err != nil {
+		h.logger.Error("检查节点维护计划失败", "error", err)
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrInternalServer,
+		})
+		return
+	}
+
+	if maintenance != nil {
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: service.NodeMaintenanceReason(maintenance),
+		})
+		return
+	}
+
+	// 检查隧道是否被禁用或处于允许的在线时段之外
+	allowed, reason, err := h.proxyScheduleService.CheckAllowed(context.Background(), proxy.ID, time.Now())
+	if err != nil {
+		h.logger.Error("检查隧道在线时段失败", "error", err)
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrInternalServer,
+		})
+		return
+	}
+
+	if !allowed {
+		rejectReason := constants.ErrProxyOutOfWindow
+		if reason == "disabled" {
+			rejectReason = constants.ErrProxyDisabled
+		}
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: rejectReason,
+		})
+		return
+	}
+
 	// 检查用户是否有权限使用该节点
 	hasAccess, err := h.proxyService.CheckUserNodeAccess(context.Background(), username, proxy.Node)
 	if err != nil {
@@ -335,14 +521,37 @@ func (h *ProxyAuthHandler) handleNewProxyAuth(c *gin.Context, req FrpPluginReque
 		return
 	}
 
+	// 检查节点在线客户端数量上限，该客户端已有在线隧道时不重复计数
+	runID, hasRunID := userInfo["run_id"].(string)
+	capacityReason, err := h.capacityService.CheckClient(context.Background(), proxy.Node, runID)
+	if err != nil {
+		h.logger.Error("检查节点在线客户端数量失败", "error", err)
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: constants.ErrInternalServer,
+		})
+		return
+	}
+
+	if capacityReason != "" {
+		c.JSON(http.StatusOK, FrpPluginResponse{
+			Reject:       true,
+			RejectReason: capacityReason,
+		})
+		return
+	}
+
 	// 鉴权通过，更新隧道状态
 	proxy.Status = "online"
 	proxy.LastUpdate = time.Now().Format("2006-01-02 15:04:05")
-	if runID, ok := userInfo["run_id"].(string); ok {
+	if hasRunID {
 		proxy.RunID = runID
 	}
 
-	err = h.proxyService.Update(context.Background(), proxy)
+	err = h.proxyService.UpdateBy(context.Background(), proxy, service.ProxyOperator{
+		Type:   service.ProxyOperatorSystem,
+		Reason: service.ProxyEventReasonPluginNewProxy,
+	})
 	if err != nil {
 		h.logger.Error("更新隧道状态失败", "error", err)
 	}
@@ -503,8 +712,8 @@ func (h *ProxyAuthHandler) verifyTransportParams(c *gin.Context, content map[str
 	return true
 }
 
-// handleCloseProxyAuth 处理关闭隧道鉴权
-func (h *ProxyAuthHandler) handleCloseProxyAuth(c *gin.Context, req FrpPluginRequest) {
+// handleCloseProxyAuth 处理关闭隧道鉴权，携带节点信息时忽略不属于该节点的隧道
+func (h *ProxyAuthHandler) handleCloseProxyAuth(c *gin.Context, req FrpPluginRequest, pluginNode *repository.Node) {
 	content := req.Content
 	userInfo, ok := content["user"].(map[string]interface{})
 	if !ok {
@@ -534,12 +743,18 @@ func (h *ProxyAuthHandler) handleCloseProxyAuth(c *gin.Context, req FrpPluginReq
 
 	// 更新隧道状态为非活跃
 	proxy, err := h.proxyService.GetByUsernameAndName(context.Background(), username, proxyName)
-	if err == nil && proxy != nil {
+	if err == nil && proxy != nil && pluginNode != nil && proxy.Node != pluginNode.ID {
+		// 只允许隧道所在节点报告关闭，避免其他节点伪造回调让隧道下线
+		h.logger.Warn("忽略其他节点的隧道关闭回调", "proxyID", proxy.ID, "proxyNode", proxy.Node, "nodeID", pluginNode.ID, "ip", c.ClientIP())
+	} else if err == nil && proxy != nil {
 		proxy.Status = "offline"
 		proxy.LastUpdate = time.Now().Format("2006-01-02 15:04:05")
 		proxy.RunID = ""
 
-		err = h.proxyService.Update(context.Background(), proxy)
+		err = h.proxyService.UpdateBy(context.Background(), proxy, service.ProxyOperator{
+			Type:   service.ProxyOperatorSystem,
+			Reason: service.ProxyEventReasonPluginCloseProxy,
+		})
 		if err != nil {
 			h.logger.Error("更新隧道状态失败", "error", err)
 		}
@@ -0,0 +1,426 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 批量导入单次允许的最大行数
+const maxProxyImportRows = 500
+
+// proxyBulkCSVHeader 导出/导入CSV文件的列顺序
+var proxyBulkCSVHeader = []string{
+	"nodeId", "proxyName", "proxyType", "localIp", "localPort", "remotePort",
+	"domain", "hostHeaderRewrite", "headerXFromWhere", "useEncryption", "useCompression",
+}
+
+// ProxyBulkRow 批量导入导出的隧道行
+type ProxyBulkRow struct {
+	NodeID            int64  `json:"nodeId"`
+	ProxyName         string `json:"proxyName"`
+	ProxyType         string `json:"proxyType"`
+	LocalIP           string `json:"localIp"`
+	LocalPort         int    `json:"localPort"`
+	RemotePort        int    `json:"remotePort"`
+	Domain            string `json:"domain"`
+	HostHeaderRewrite string `json:"hostHeaderRewrite"`
+	HeaderXFromWhere  string `json:"headerXFromWhere"`
+	UseEncryption     bool   `json:"useEncryption"`
+	UseCompression    bool   `json:"useCompression"`
+}
+
+// ProxyImportResult 单行导入结果
+type ProxyImportResult struct {
+	Row       int                       `json:"row"`
+	ProxyName string                    `json:"proxyName"`
+	Action    string                    `json:"action"` // create 或 update
+	Success   bool                      `json:"success"`
+	Msg       string                    `json:"msg"`
+	ID        int64                     `json:"id,omitempty"`
+	Errors    []service.ProxyFieldError `json:"errors,omitempty"`
+}
+
+// ExportProxies 导出用户的全部隧道（JSON或CSV）
+func (h *ProxyHandler) ExportProxies(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "不支持的导出格式，只能是json或csv"})
+		return
+	}
+
+	proxies, err := h.proxyService.GetByUsername(context.Background(), user.Username)
+	if err != nil {
+		h.logger.Error("Failed to get proxies for export", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道列表失败"})
+		return
+	}
+
+	rows := make([]ProxyBulkRow, 0, len(proxies))
+	for _, proxy := range proxies {
+		remotePort, _ := strconv.Atoi(proxy.RemotePort)
+		useEncryption, _ := strconv.ParseBool(proxy.UseEncryption)
+		useCompression, _ := strconv.ParseBool(proxy.UseCompression)
+		rows = append(rows, ProxyBulkRow{
+			NodeID:            proxy.Node,
+			ProxyName:         proxy.ProxyName,
+			ProxyType:         proxy.ProxyType,
+			LocalIP:           proxy.LocalIP,
+			LocalPort:         proxy.LocalPort,
+			RemotePort:        remotePort,
+			Domain:            proxy.Domain,
+			HostHeaderRewrite: proxy.HostHeaderRewrite,
+			HeaderXFromWhere:  proxy.HeaderXFromWhere,
+			UseEncryption:     useEncryption,
+			UseCompression:    useCompression,
+		})
+	}
+
+	fileName := fmt.Sprintf("proxies-%s-%s.%s", user.Username, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+
+	if format == "json" {
+		c.JSON(http.StatusOK, rows)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write(proxyBulkCSVHeader)
+	for _, row := range rows {
+		_ = writer.Write([]string{
+			strconv.FormatInt(row.NodeID, 10),
+			row.ProxyName,
+			row.ProxyType,
+			row.LocalIP,
+			strconv.Itoa(row.LocalPort),
+			strconv.Itoa(row.RemotePort),
+			row.Domain,
+			row.HostHeaderRewrite,
+			row.HeaderXFromWhere,
+			strconv.FormatBool(row.UseEncryption),
+			strconv.FormatBool(row.UseCompression),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		h.logger.Error("Failed to write csv export", "error", err)
+	}
+}
+
+// ImportProxies 批量导入隧道，同名隧道更新，不存在则创建
+func (h *ProxyHandler) ImportProxies(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", c.DefaultPostForm("dry_run", "false")))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "请上传导入文件"})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultPostForm("format", strings.TrimPrefix(filepath.Ext(fileHeader.Filename), ".")))
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "不支持的导入格式，只能是json或csv"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "读取导入文件失败"})
+		return
+	}
+	defer file.Close()
+
+	var rows []ProxyBulkRow
+	if format == "json" {
+		rows, err = parseProxyJSONRows(file)
+	} else {
+		rows, err = parseProxyCSVRows(file)
+	}
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "解析导入文件失败: " + err.Error()})
+		return
+	}
+
+	if len(rows) == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "导入文件中没有隧道"})
+		return
+	}
+	if len(rows) > maxProxyImportRows {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": fmt.Sprintf("单次最多导入%d条隧道", maxProxyImportRows)})
+		return
+	}
+
+	ctx := context.Background()
+
+	existingProxies, err := h.proxyService.GetByUsername(ctx, user.Username)
+	if err != nil {
+		h.logger.Error("Failed to get user's proxies", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取用户隧道列表失败"})
+		return
+	}
+	existingByName := make(map[string]*repository.Proxy, len(existingProxies))
+	for _, p := range existingProxies {
+		existingByName[p.ProxyName] = p
+	}
+
+	remainingTunnels, err := h.proxyValidator.RemainingTunnels(ctx, user)
+	if err != nil {
+		h.logger.Error("Failed to get remaining tunnels", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取用户组隧道限制失败"})
+		return
+	}
+
+	seenNames := make(map[string]int)
+	claimedPorts := make(map[string]int)
+	results := make([]ProxyImportResult, 0, len(rows))
+	successCount := 0
+
+	for i, row := range rows {
+		rowNum := i + 1
+		result := ProxyImportResult{Row: rowNum, ProxyName: row.ProxyName, Action: "create"}
+
+		existing := existingByName[row.ProxyName]
+		if existing != nil {
+			result.Action = "update"
+			result.ID = existing.ID
+		}
+
+		if prev, ok := seenNames[row.ProxyName]; ok && row.ProxyName != "" {
+			result.Msg = fmt.Sprintf("与第%d行隧道名称重复", prev)
+			results = append(results, result)
+			continue
+		}
+		seenNames[row.ProxyName] = rowNum
+
+		validationErrs, err := h.proxyValidator.ValidateConfig(ctx, user, &service.ProxyValidationInput{
+			NodeID:     row.NodeID,
+			ProxyName:  row.ProxyName,
+			ProxyType:  row.ProxyType,
+			LocalIP:    row.LocalIP,
+			LocalPort:  row.LocalPort,
+			RemotePort: row.RemotePort,
+			Domain:     row.Domain,
+			Existing:   existing,
+		})
+		if err != nil {
+			h.logger.Error("Failed to validate proxy on import", "error", err, "proxyName", row.ProxyName)
+			result.Msg = "服务器内部错误"
+			results = append(results, result)
+			continue
+		}
+		if len(validationErrs) > 0 {
+			result.Msg = validationErrs[0].Msg
+			result.Errors = validationErrs
+			results = append(results, result)
+			continue
+		}
+
+		if row.ProxyType != "http" && row.ProxyType != "https" {
+			portKey := fmt.Sprintf("%d/%s/%d", row.NodeID, row.ProxyType, row.RemotePort)
+			if prev, ok := claimedPorts[portKey]; ok {
+				result.Msg = fmt.Sprintf("与第%d行使用了相同的远程端口", prev)
+				results = append(results, result)
+				continue
+			}
+			claimedPorts[portKey] = rowNum
+		}
+
+		if existing == nil {
+			if remainingTunnels <= 0 {
+				result.Msg = "您已达到可创建的数量上限"
+				results = append(results, result)
+				continue
+			}
+			remainingTunnels--
+		}
+
+		if dryRun {
+			result.Success = true
+			result.Msg = "校验通过"
+			results = append(results, result)
+			successCount++
+			continue
+		}
+
+		proxy := &repository.Proxy{
+			Username:          user.Username,
+			ProxyName:         row.ProxyName,
+			ProxyType:         row.ProxyType,
+			LocalIP:           row.LocalIP,
+			LocalPort:         row.LocalPort,
+			UseEncryption:     strconv.FormatBool(row.UseEncryption),
+			UseCompression:    strconv.FormatBool(row.UseCompression),
+			Domain:            row.Domain,
+			HostHeaderRewrite: row.HostHeaderRewrite,
+			RemotePort:        strconv.Itoa(row.RemotePort),
+			HeaderXFromWhere:  row.HeaderXFromWhere,
+			Node:              row.NodeID,
+			Status:            "offline",
+		}
+
+		if existing != nil {
+			proxy.ID = existing.ID
+			proxy.Status = existing.Status
+			proxy.RunID = existing.RunID
+			proxy.TrafficQuota = existing.TrafficQuota
+			err := h.proxyService.UpdateBy(ctx, proxy, service.ProxyOperator{
+				Type:   service.ProxyOperatorUser,
+				Name:   user.Username,
+				Action: service.ProxyVersionActionImport,
+			})
+			if err != nil {
+				h.logger.Error("Failed to update proxy on import", "error", err, "proxyName", row.ProxyName)
+				result.Msg = "更新隧道失败"
+				results = append(results, result)
+				continue
+			}
+			result.Msg = "更新成功"
+		} else {
+			id, err := h.proxyService.Create(ctx, proxy)
+			if err != nil {
+				h.logger.Error("Failed to create proxy on import", "error", err, "proxyName", row.ProxyName)
+				remainingTunnels++
+				result.Msg = "创建隧道失败"
+				results = append(results, result)
+				continue
+			}
+			result.ID = id
+			result.Msg = "创建成功"
+		}
+
+		result.Success = true
+		results = append(results, result)
+		successCount++
+	}
+
+	msg := fmt.Sprintf("导入完成：成功 %d 条，失败 %d 条", successCount, len(rows)-successCount)
+	if dryRun {
+		msg = fmt.Sprintf("预检完成：通过 %d 条，失败 %d 条", successCount, len(rows)-successCount)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  msg,
+		"data": gin.H{
+			"dry_run":       dryRun,
+			"total":         len(rows),
+			"success_count": successCount,
+			"fail_count":    len(rows) - successCount,
+			"results":       results,
+		},
+	})
+}
+
+// parseProxyJSONRows 解析JSON格式的导入文件
+func parseProxyJSONRows(r io.Reader) ([]ProxyBulkRow, error) {
+	var rows []ProxyBulkRow
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// parseProxyCSVRows 解析CSV格式的导入文件，首行必须为表头
+func parseProxyCSVRows(r io.Reader) ([]ProxyBulkRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range []string{"nodeId", "proxyName", "proxyType", "localIp", "localPort"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("缺少列 %s", name)
+		}
+	}
+
+	get := func(record []string, name string) string {
+		if idx, ok := columns[name]; ok && idx < len(record) {
+			return strings.TrimSpace(record[idx])
+		}
+		return ""
+	}
+
+	rows := make([]ProxyBulkRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		nodeID, err := strconv.ParseInt(get(record, "nodeId"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("第%d行 nodeId 格式错误", i+1)
+		}
+		localPort, err := strconv.Atoi(get(record, "localPort"))
+		if err != nil {
+			return nil, fmt.Errorf("第%d行 localPort 格式错误", i+1)
+		}
+		remotePort := 0
+		if v := get(record, "remotePort"); v != "" {
+			remotePort, err = strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("第%d行 remotePort 格式错误", i+1)
+			}
+		}
+		useEncryption, _ := strconv.ParseBool(get(record, "useEncryption"))
+		useCompression, _ := strconv.ParseBool(get(record, "useCompression"))
+
+		rows = append(rows, ProxyBulkRow{
+			NodeID:            nodeID,
+			ProxyName:         get(record, "proxyName"),
+			ProxyType:         get(record, "proxyType"),
+			LocalIP:           get(record, "localIp"),
+			LocalPort:         localPort,
+			RemotePort:        remotePort,
+			Domain:            get(record, "domain"),
+			HostHeaderRewrite: get(record, "hostHeaderRewrite"),
+			HeaderXFromWhere:  get(record, "headerXFromWhere"),
+			UseEncryption:     useEncryption,
+			UseCompression:    useCompression,
+		})
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"stellarfrp/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProxyHistory 获取隧道配置版本历史
+func (h *ProxyHandler) GetProxyHistory(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return
+	}
+
+	proxyID, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil || proxyID <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的隧道ID"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	proxy, err := h.proxyService.GetByID(context.Background(), proxyID)
+	if err != nil {
+		h.logger.Error("Failed to get proxy", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道失败"})
+		return
+	}
+	if proxy == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道不存在"})
+		return
+	}
+	if proxy.Username != user.Username {
+		c.JSON(http.StatusOK, gin.H{"code": 403, "msg": "您没有权限查看此隧道"})
+		return
+	}
+
+	versions, total, err := h.proxyService.ListVersions(context.Background(), proxyID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to list proxy versions", "error", err, "proxyID", proxyID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道历史版本失败"})
+		return
+	}
+
+	items := make([]*service.ProxyVersionDetail, 0, len(versions))
+	for _, v := range versions {
+		items = append(items, service.NewProxyVersionDetail(v))
+	}
+
+	pages := (total + pageSize - 1) / pageSize
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"proxy_id": proxyID,
+			"versions": items,
+		},
+		"pagination": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+			"pages":     pages,
+		},
+	})
+}
+
+// RollbackProxy 将隧道配置回滚到指定版本，回滚前按当前规则重新校验
+func (h *ProxyHandler) RollbackProxy(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return
+	}
+
+	type RollbackRequest struct {
+		ID      int64 `json:"id" binding:"required"`
+		Version int   `json:"version" binding:"required"`
+	}
+
+	var req RollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	existingProxy, err := h.proxyService.GetByID(context.Background(), req.ID)
+	if err != nil {
+		h.logger.Error("Failed to get proxy", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道失败"})
+		return
+	}
+	if existingProxy == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道不存在"})
+		return
+	}
+	if existingProxy.Username != user.Username {
+		c.JSON(http.StatusOK, gin.H{"code": 403, "msg": "您没有权限修改此隧道"})
+		return
+	}
+
+	version, err := h.proxyService.GetVersion(context.Background(), req.ID, req.Version)
+	if err != nil {
+		h.logger.Error("Failed to get proxy version", "error", err, "proxyID", req.ID, "version", req.Version)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道历史版本失败"})
+		return
+	}
+	if version == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "该版本不存在"})
+		return
+	}
+
+	snapshot, err := service.ParseProxyConfigSnapshot(version)
+	if err != nil {
+		h.logger.Error("Failed to parse proxy snapshot", "error", err, "proxyID", req.ID, "version", req.Version)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "历史版本数据损坏，无法回滚"})
+		return
+	}
+
+	if len(snapshot.Diff(service.NewProxyConfigSnapshot(existingProxy))) == 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "当前配置与该版本一致，无需回滚"})
+		return
+	}
+
+	validationErrs, err := h.proxyValidator.Validate(context.Background(), user, snapshot.ValidationInput(existingProxy))
+	if err != nil {
+		h.logger.Error("Failed to validate proxy", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return
+	}
+	if len(validationErrs) > 0 {
+		c.JSON(http.StatusOK, proxyValidationFailedResponse(validationErrs))
+		return
+	}
+
+	proxy := *existingProxy
+	snapshot.ApplyTo(&proxy)
+
+	err = h.proxyService.UpdateBy(context.Background(), &proxy, service.ProxyOperator{
+		Type:   service.ProxyOperatorUser,
+		Name:   user.Username,
+		Action: service.ProxyVersionActionRollback,
+		Remark: fmt.Sprintf("回滚到版本 %d", req.Version),
+	})
+	if err != nil {
+		h.logger.Error("Failed to rollback proxy", "error", err, "proxyID", req.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "回滚隧道失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "回滚成功", "data": gin.H{"id": req.ID, "version": req.Version}})
+}
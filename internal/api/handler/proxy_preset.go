@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyPresetRequest 隧道预设请求参数
+type ProxyPresetRequest struct {
+	ID                int64  `json:"id"`
+	Name              string `json:"name" binding:"required"`
+	ProxyType         string `json:"proxyType"`
+	LocalIP           string `json:"localIp"`
+	LocalPort         int    `json:"localPort"`
+	UseEncryption     bool   `json:"useEncryption"`
+	UseCompression    bool   `json:"useCompression"`
+	HostHeaderRewrite string `json:"hostHeaderRewrite"`
+	HeaderXFromWhere  string `json:"headerXFromWhere"`
+}
+
+// validate 校验预设参数，返回错误提示
+func (r *ProxyPresetRequest) validate() string {
+	if utf8.RuneCountInString(r.Name) > 50 {
+		return "预设名称不能超过50个字符"
+	}
+	switch r.ProxyType {
+	case "", "tcp", "udp", "http", "https", "stcp", "xtcp", "sudp":
+	default:
+		return "不支持的隧道类型"
+	}
+	if r.LocalPort < 0 || r.LocalPort > 65535 {
+		return "本地端口必须在0-65535范围内"
+	}
+	return ""
+}
+
+// authUser 校验登录状态，失败时直接写入响应并返回nil
+func (h *ProxyHandler) authUser(c *gin.Context) *repository.User {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return nil
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return nil
+	}
+	return user
+}
+
+// ListProxyPresets 获取当前用户的隧道预设
+func (h *ProxyHandler) ListProxyPresets(c *gin.Context) {
+	user := h.authUser(c)
+	if user == nil {
+		return
+	}
+
+	presets, err := h.proxyPresetService.ListByUsername(context.Background(), user.Username)
+	if err != nil {
+		h.logger.Error("Failed to list proxy presets", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道预设失败"})
+		return
+	}
+	if presets == nil {
+		presets = []*repository.ProxyPreset{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "获取成功", "data": presets})
+}
+
+// CreateProxyPreset 保存隧道预设
+func (h *ProxyHandler) CreateProxyPreset(c *gin.Context) {
+	user := h.authUser(c)
+	if user == nil {
+		return
+	}
+
+	var req ProxyPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": msg})
+		return
+	}
+
+	id, err := h.proxyPresetService.Create(context.Background(), &repository.ProxyPreset{
+		Username:          user.Username,
+		Name:              req.Name,
+		ProxyType:         req.ProxyType,
+		LocalIP:           req.LocalIP,
+		LocalPort:         req.LocalPort,
+		UseEncryption:     req.UseEncryption,
+		UseCompression:    req.UseCompression,
+		HostHeaderRewrite: req.HostHeaderRewrite,
+		HeaderXFromWhere:  req.HeaderXFromWhere,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrProxyPresetLimit) || errors.Is(err, service.ErrProxyPresetNameExists) {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to create proxy preset", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "保存隧道预设失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "创建成功", "data": gin.H{"id": id}})
+}
+
+// UpdateProxyPreset 更新隧道预设
+func (h *ProxyHandler) UpdateProxyPreset(c *gin.Context) {
+	user := h.authUser(c)
+	if user == nil {
+		return
+	}
+
+	var req ProxyPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": msg})
+		return
+	}
+
+	preset, err := h.proxyPresetService.GetByID(context.Background(), req.ID)
+	if err != nil {
+		h.logger.Error("Failed to get proxy preset", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道预设失败"})
+		return
+	}
+	if preset == nil || preset.Username != user.Username {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道预设不存在"})
+		return
+	}
+
+	preset.Name = req.Name
+	preset.ProxyType = req.ProxyType
+	preset.LocalIP = req.LocalIP
+	preset.LocalPort = req.LocalPort
+	preset.UseEncryption = req.UseEncryption
+	preset.UseCompression = req.UseCompression
+	preset.HostHeaderRewrite = req.HostHeaderRewrite
+	preset.HeaderXFromWhere = req.HeaderXFromWhere
+
+	if err := h.proxyPresetService.Update(context.Background(), preset); err != nil {
+		if errors.Is(err, service.ErrProxyPresetNameExists) {
+			c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to update proxy preset", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "更新隧道预设失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "更新成功"})
+}
+
+// DeleteProxyPreset 删除隧道预设
+func (h *ProxyHandler) DeleteProxyPreset(c *gin.Context) {
+	user := h.authUser(c)
+	if user == nil {
+		return
+	}
+
+	type DeleteRequest struct {
+		ID int64 `json:"id" binding:"required"`
+	}
+
+	var req DeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	preset, err := h.proxyPresetService.GetByID(context.Background(), req.ID)
+	if err != nil {
+		h.logger.Error("Failed to get proxy preset", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道预设失败"})
+		return
+	}
+	if preset == nil || preset.Username != user.Username {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道预设不存在"})
+		return
+	}
+
+	if err := h.proxyPresetService.Delete(context.Background(), preset.ID); err != nil {
+		h.logger.Error("Failed to delete proxy preset", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "删除隧道预设失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "删除成功"})
+}
+
+// CloneProxy 以新名称和端口复制已有隧道，按创建隧道的规则校验
+func (h *ProxyHandler) CloneProxy(c *gin.Context) {
+	type CloneRequest struct {
+		ID         int64  `json:"id" binding:"required"`
+		ProxyName  string `json:"proxyName" binding:"required"`
+		RemotePort int    `json:"remotePort"`
+		Domain     string `json:"domain"`
+	}
+
+	var req CloneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	user, source := h.getOwnedProxy(c, req.ID)
+	if source == nil {
+		return
+	}
+
+	proxy := &repository.Proxy{
+		Username:          user.Username,
+		ProxyName:         req.ProxyName,
+		ProxyType:         source.ProxyType,
+		LocalIP:           source.LocalIP,
+		LocalPort:         source.LocalPort,
+		UseEncryption:     source.UseEncryption,
+		UseCompression:    source.UseCompression,
+		Domain:            source.Domain,
+		HostHeaderRewrite: source.HostHeaderRewrite,
+		RemotePort:        source.RemotePort,
+		HeaderXFromWhere:  source.HeaderXFromWhere,
+		Node:              source.Node,
+		Status:            "offline",
+	}
+	if req.RemotePort != 0 {
+		proxy.RemotePort = strconv.Itoa(req.RemotePort)
+	}
+	if req.Domain != "" {
+		proxy.Domain = req.Domain
+	}
+
+	id, ok := h.createProxyForUser(c, user, proxy)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "复制成功", "data": gin.H{"Id": id}})
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 隧道健康判断结果
+const (
+	probeDiagnosisHealthy     = "healthy"
+	probeDiagnosisUnreachable = "local_service_unreachable"
+	probeDiagnosisOffline     = "offline"
+	probeDiagnosisUnknown     = "unknown"
+)
+
+// GetProxyProbes 获取隧道公网入口的探测历史、成功率与延迟
+// 隧道在线但最近一次探测失败，通常说明客户端已连接而本地服务不可用
+func (h *ProxyHandler) GetProxyProbes(c *gin.Context) {
+	proxyID, _ := strconv.ParseInt(c.Query("id"), 10, 64)
+	_, proxy := h.getOwnedProxy(c, proxyID)
+	if proxy == nil {
+		return
+	}
+
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 168 {
+		hours = 24
+	}
+
+	probes, summary, err := h.proxyProbeService.GetHistory(context.Background(), proxy.ID, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		h.logger.Error("Failed to get proxy probes", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道探测记录失败"})
+		return
+	}
+
+	var diagnosis string
+	switch {
+	case proxy.Status != "online":
+		diagnosis = probeDiagnosisOffline
+	case summary.Last == nil:
+		diagnosis = probeDiagnosisUnknown
+	case summary.Last.Success:
+		diagnosis = probeDiagnosisHealthy
+	default:
+		diagnosis = probeDiagnosisUnreachable
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"proxy_id":  proxy.ID,
+			"status":    proxy.Status,
+			"diagnosis": diagnosis,
+			"summary":   summary,
+			"probes":    probes,
+		},
+	})
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getOwnedProxy 校验登录状态并获取当前用户拥有的隧道，失败时直接写入响应并返回nil
+func (h *ProxyHandler) getOwnedProxy(c *gin.Context, proxyID int64) (*repository.User, *repository.Proxy) {
+	user := h.authUser(c)
+	if user == nil {
+		return nil, nil
+	}
+
+	if proxyID <= 0 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的隧道ID"})
+		return nil, nil
+	}
+
+	proxy, err := h.proxyService.GetByID(context.Background(), proxyID)
+	if err != nil {
+		h.logger.Error("Failed to get proxy", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道失败"})
+		return nil, nil
+	}
+	if proxy == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道不存在"})
+		return nil, nil
+	}
+	if proxy.Username != user.Username {
+		c.JSON(http.StatusOK, gin.H{"code": 403, "msg": "您没有权限操作此隧道"})
+		return nil, nil
+	}
+
+	return user, proxy
+}
+
+// GetProxySchedule 获取隧道的禁用状态与在线时段
+func (h *ProxyHandler) GetProxySchedule(c *gin.Context) {
+	proxyID, _ := strconv.ParseInt(c.Query("id"), 10, 64)
+	_, proxy := h.getOwnedProxy(c, proxyID)
+	if proxy == nil {
+		return
+	}
+
+	info, err := h.proxyScheduleService.Get(context.Background(), proxy.ID)
+	if err != nil {
+		h.logger.Error("Failed to get proxy schedule", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道在线时段失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"proxy_id":    info.ProxyID,
+			"disabled":    info.Disabled,
+			"windows":     info.Windows,
+			"allowed_now": !info.Disabled && service.InScheduleWindows(info.Windows, time.Now()),
+		},
+	})
+}
+
+// SetProxySchedule 设置隧道每周允许在线的时段，windows为空表示不限制
+func (h *ProxyHandler) SetProxySchedule(c *gin.Context) {
+	type ScheduleRequest struct {
+		ID      int64                    `json:"id" binding:"required"`
+		Windows []service.ScheduleWindow `json:"windows"`
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	_, proxy := h.getOwnedProxy(c, req.ID)
+	if proxy == nil {
+		return
+	}
+
+	if err := service.ValidateScheduleWindows(req.Windows); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	if err := h.proxyScheduleService.SetWindows(context.Background(), proxy.ID, req.Windows); err != nil {
+		h.logger.Error("Failed to set proxy schedule", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "设置隧道在线时段失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "设置成功，超出时段的在线隧道将在下一分钟内被关闭"})
+}
+
+// SetProxyDisabled 禁用或启用隧道，禁用时立即关闭在线的隧道
+func (h *ProxyHandler) SetProxyDisabled(c *gin.Context) {
+	type DisableRequest struct {
+		ID       int64 `json:"id" binding:"required"`
+		Disabled bool  `json:"disabled"`
+	}
+
+	var req DisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	_, proxy := h.getOwnedProxy(c, req.ID)
+	if proxy == nil {
+		return
+	}
+
+	if err := h.proxyScheduleService.SetDisabled(context.Background(), proxy.ID, req.Disabled); err != nil {
+		h.logger.Error("Failed to set proxy disabled", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "设置隧道状态失败"})
+		return
+	}
+
+	if !req.Disabled {
+		c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "隧道已启用"})
+		return
+	}
+
+	if proxy.Status == "online" {
+		if err := h.proxyService.KickProxy(context.Background(), proxy, service.ProxyEventReasonUserDisabled); err != nil {
+			h.logger.Error("Failed to kick disabled proxy", "error", err, "proxyID", proxy.ID)
+			c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "隧道已禁用，但关闭在线连接失败，将在下一分钟内重试"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "隧道已禁用"})
+}
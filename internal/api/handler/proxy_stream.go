@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"stellarfrp/internal/constants"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// proxyStreamHeartbeat 推送连接心跳间隔，避免被反向代理判定为空闲连接
+const proxyStreamHeartbeat = 25 * time.Second
+
+// ProxyStreamHandler 隧道状态实时推送处理器
+type ProxyStreamHandler struct {
+	userService     service.UserService
+	proxyService    service.ProxyService
+	snapshotService service.ProxyStatusSnapshotService
+	streamHub       service.ProxyStreamHub
+	logger          *logger.Logger
+}
+
+// NewProxyStreamHandler 创建隧道状态实时推送处理器实例
+func NewProxyStreamHandler(
+	userService service.UserService,
+	proxyService service.ProxyService,
+	snapshotService service.ProxyStatusSnapshotService,
+	streamHub service.ProxyStreamHub,
+	logger *logger.Logger,
+) *ProxyStreamHandler {
+	return &ProxyStreamHandler{
+		userService:     userService,
+		proxyService:    proxyService,
+		snapshotService: snapshotService,
+		streamHub:       streamHub,
+		logger:          logger,
+	}
+}
+
+// StreamProxyStatus 以SSE方式推送当前用户的隧道上下线事件和流量、连接数更新
+// 浏览器原生EventSource无法设置请求头，因此同时支持通过token查询参数鉴权
+func (h *ProxyStreamHandler) StreamProxyStatus(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": constants.ErrUnauthorized})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil || user == nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": constants.ErrInvalidToken})
+		return
+	}
+
+	isBlacklisted, err := h.userService.IsUserBlacklistedByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": constants.ErrInternalServer})
+		return
+	}
+	if isBlacklisted {
+		c.JSON(http.StatusOK, gin.H{"code": 403, "msg": constants.ErrBlacklisted})
+		return
+	}
+
+	events, unsubscribe, err := h.streamHub.Subscribe(user.Username)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 429, "msg": err.Error()})
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// 建立连接后先推送一次当前快照，客户端无需再单独请求状态接口
+	c.SSEvent(service.ProxyStreamEventStats, h.initialStats(user.Username))
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(proxyStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event.Data)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
+
+// initialStats 获取用户所有隧道的当前快照
+func (h *ProxyStreamHandler) initialStats(username string) []service.ProxyStreamStats {
+	stats := []service.ProxyStreamStats{}
+
+	proxies, err := h.proxyService.GetByUsername(context.Background(), username)
+	if err != nil {
+		h.logger.Error("获取用户隧道列表失败", "error", err, "username", username)
+		return stats
+	}
+
+	proxyIDs := make([]int64, len(proxies))
+	for i, proxy := range proxies {
+		proxyIDs[i] = proxy.ID
+	}
+	snapshots, err := h.snapshotService.GetMany(context.Background(), proxyIDs)
+	if err != nil {
+		h.logger.Error("获取隧道状态快照失败", "error", err, "username", username)
+		return stats
+	}
+
+	for _, proxy := range proxies {
+		snapshot, ok := snapshots[proxy.ID]
+		if !ok {
+			stats = append(stats, service.ProxyStreamStats{
+				ProxyID:   proxy.ID,
+				ProxyName: proxy.ProxyName,
+				Status:    "offline",
+			})
+			continue
+		}
+		stats = append(stats, service.NewProxyStreamStats(snapshot))
+	}
+	return stats
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/repository/repositorytest"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/frps"
+	"stellarfrp/pkg/frps/frpstest"
+	"stellarfrp/pkg/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeUserService 按Token返回固定用户的用户服务
+type fakeUserService struct {
+	service.UserService
+	users map[string]*repository.User
+}
+
+func (s *fakeUserService) GetByToken(ctx context.Context, token string) (*repository.User, error) {
+	if user, ok := s.users[token]; ok {
+		return user, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+func TestCloseProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := frpstest.NewServer("admin", "password")
+	defer s.Close()
+
+	proxyRepo := repositorytest.NewProxyRepository(
+		&repository.Proxy{ID: 1, Node: 1, Username: "alice", ProxyName: "web", ProxyType: "tcp", Status: "online", RunID: "r1"},
+		&repository.Proxy{ID: 2, Node: 1, Username: "alice", ProxyName: "ssh", ProxyType: "tcp", Status: "offline"},
+		&repository.Proxy{ID: 3, Node: 1, Username: "bob", ProxyName: "game", ProxyType: "tcp", Status: "online", RunID: "r3"},
+		&repository.Proxy{ID: 4, Node: 1, Username: "alice", ProxyName: "api", ProxyType: "tcp", Status: "online", RunID: "r4"},
+	)
+	nodeService := service.NewNodeService(repositorytest.NewNodeRepository(
+		&repository.Node{ID: 1, Status: repository.NodeStatusOnline, URL: s.URL, User: s.User, Token: s.Token},
+	), nil, nil)
+	// 缓存不可用时服务只记录日志，不影响测试的主流程
+	redisCli := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	log := logger.NewLogger("error")
+	frpsClient := frps.NewClient(frps.Options{Timeout: time.Second, BreakerThreshold: 5, BreakerCooldown: time.Minute})
+	proxyService := service.NewProxyService(proxyRepo, nil, repositorytest.NewProxyEventRepository(), nil, nil, nil,
+		nodeService, nil, frpsClient, service.NewProxyStreamHub(), redisCli, log)
+	userService := &fakeUserService{users: map[string]*repository.User{"alice-token": {Username: "alice"}}}
+	h := NewProxyHandler(proxyService, nodeService, userService, nil, nil, nil, nil, nil, nil, nil, log)
+
+	r := gin.New()
+	r.POST("/proxy/close", h.CloseProxy)
+	closeProxy := func(token, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/proxy/close", strings.NewReader(body))
+		req.Header.Set("Authorization", token)
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var resp struct {
+			Code int `json:"code"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Code
+	}
+
+	if code := closeProxy("alice-token", `{"id":1}`); code != 200 {
+		t.Fatalf("code = %d, want 200", code)
+	}
+	if kicked := s.Kicked(); len(kicked) != 1 || kicked[0] != "r1" {
+		t.Errorf("Kicked = %v, want [r1]", kicked)
+	}
+	if proxy, _ := proxyRepo.GetByID(context.Background(), 1); proxy.Status != "offline" || proxy.RunID != "" {
+		t.Errorf("proxy = %s/%q, want offline without run id", proxy.Status, proxy.RunID)
+	}
+
+	s.FailNext(1, http.StatusInternalServerError)
+	if code := closeProxy("alice-token", `{"id":4}`); code != 500 {
+		t.Errorf("节点返回错误时 code = %d, want 500", code)
+	}
+	if proxy, _ := proxyRepo.GetByID(context.Background(), 4); proxy.Status != "online" || proxy.RunID != "r4" {
+		t.Errorf("踢下失败时不应修改隧道状态, got %s/%q", proxy.Status, proxy.RunID)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		body  string
+		want  int
+	}{
+		{"unauthorized", "", `{"id":1}`, 401},
+		{"invalid token", "bad", `{"id":1}`, 401},
+		{"not found", "alice-token", `{"id":9}`, 404},
+		{"other user", "alice-token", `{"id":3}`, 403},
+		{"not running", "alice-token", `{"id":2}`, 400},
+	}
+	for _, tt := range tests {
+		if code := closeProxy(tt.token, tt.body); code != tt.want {
+			t.Errorf("%s: code = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+	if kicked := s.Kicked(); len(kicked) != 1 {
+		t.Errorf("被拒绝的请求不应踢下客户端, Kicked = %v", kicked)
+	}
+}
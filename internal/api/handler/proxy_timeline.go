@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProxyTimeline 获取隧道上下线时间线与24小时/7天/30天在线率
+func (h *ProxyHandler) GetProxyTimeline(c *gin.Context) {
+	proxyID, _ := strconv.ParseInt(c.Query("id"), 10, 64)
+	_, proxy := h.getOwnedProxy(c, proxyID)
+	if proxy == nil {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	events, total, err := h.proxyService.ListEvents(context.Background(), proxy.ID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to list proxy events", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道时间线失败"})
+		return
+	}
+	if events == nil {
+		events = []*repository.ProxyEvent{}
+	}
+
+	uptime, err := h.proxyService.GetUptime(context.Background(), proxy.ID)
+	if err != nil {
+		h.logger.Error("Failed to get proxy uptime", "error", err, "proxyID", proxy.ID)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取隧道在线率失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"proxy_id": proxy.ID,
+			"status":   proxy.Status,
+			"uptime":   uptime,
+			"events":   events,
+		},
+		"pagination": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+			"pages":     (total + pageSize - 1) / pageSize,
+		},
+	})
+}
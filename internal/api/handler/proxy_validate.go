@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// proxyValidationFailedResponse 将字段校验错误转换为统一响应，msg取第一条错误
+func proxyValidationFailedResponse(errs []service.ProxyFieldError) gin.H {
+	code := 400
+	if errs[0].Code == service.ProxyErrNodeForbidden {
+		code = 403
+	}
+	return gin.H{"code": code, "msg": errs[0].Msg, "errors": errs}
+}
+
+// ValidateProxy 校验隧道创建/编辑参数，一次性返回所有字段错误
+func (h *ProxyHandler) ValidateProxy(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "未授权，请先登录"})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的token"})
+		return
+	}
+
+	type ValidateRequest struct {
+		ID         int64  `json:"id"`
+		NodeID     int64  `json:"nodeId"`
+		ProxyName  string `json:"proxyName"`
+		LocalIP    string `json:"localIp"`
+		LocalPort  int    `json:"localPort"`
+		RemotePort int    `json:"remotePort"`
+		Domain     string `json:"domain"`
+		ProxyType  string `json:"proxyType"`
+	}
+
+	var req ValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	var existingProxy *repository.Proxy
+	if req.ID > 0 {
+		existingProxy, err = h.proxyService.GetByID(context.Background(), req.ID)
+		if err != nil {
+			h.logger.Error("Failed to check proxy existence", "error", err)
+			c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "检查隧道失败"})
+			return
+		}
+		if existingProxy == nil {
+			c.JSON(http.StatusOK, gin.H{"code": 404, "msg": "隧道不存在"})
+			return
+		}
+		if existingProxy.Username != user.Username {
+			c.JSON(http.StatusOK, gin.H{"code": 403, "msg": "您没有权限修改此隧道"})
+			return
+		}
+	}
+
+	validationErrs, err := h.proxyValidator.Validate(context.Background(), user, &service.ProxyValidationInput{
+		NodeID:     req.NodeID,
+		ProxyName:  req.ProxyName,
+		ProxyType:  req.ProxyType,
+		LocalIP:    req.LocalIP,
+		LocalPort:  req.LocalPort,
+		RemotePort: req.RemotePort,
+		Domain:     req.Domain,
+		Existing:   existingProxy,
+	})
+	if err != nil {
+		h.logger.Error("Failed to validate proxy", "error", err)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "服务器内部错误"})
+		return
+	}
+
+	if validationErrs == nil {
+		validationErrs = []service.ProxyFieldError{}
+	}
+
+	msg := "校验通过"
+	if len(validationErrs) > 0 {
+		msg = "校验未通过"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  msg,
+		"data": gin.H{
+			"valid":  len(validationErrs) == 0,
+			"errors": validationErrs,
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserNoticeHandler 用户站内通知处理器
+type UserNoticeHandler struct {
+	userService       service.UserService
+	userNoticeService service.UserNoticeService
+	logger            *logger.Logger
+}
+
+// NewUserNoticeHandler 创建用户站内通知处理器实例
+func NewUserNoticeHandler(
+	userService service.UserService,
+	userNoticeService service.UserNoticeService,
+	logger *logger.Logger,
+) *UserNoticeHandler {
+	return &UserNoticeHandler{
+		userService:       userService,
+		userNoticeService: userNoticeService,
+		logger:            logger,
+	}
+}
+
+// ListNotices 分页获取当前用户的站内通知
+func (h *UserNoticeHandler) ListNotices(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "请先登录"})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的认证信息"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的页码"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "无效的每页数量"})
+		return
+	}
+
+	notices, total, unread, err := h.userNoticeService.List(context.Background(), user.Username, (page-1)*pageSize, pageSize)
+	if err != nil {
+		h.logger.Error("获取站内通知失败", "error", err, "username", user.Username)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "获取站内通知失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "获取成功",
+		"data": gin.H{
+			"notices": notices,
+			"unread":  unread,
+			"pagination": gin.H{
+				"page":      page,
+				"page_size": pageSize,
+				"total":     total,
+				"pages":     (total + pageSize - 1) / pageSize,
+			},
+		},
+	})
+}
+
+// MarkNoticesRead 将站内通知标记为已读，ids 为空时标记全部
+func (h *UserNoticeHandler) MarkNoticesRead(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "请先登录"})
+		return
+	}
+
+	user, err := h.userService.GetByToken(context.Background(), token)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 401, "msg": "无效的认证信息"})
+		return
+	}
+
+	var req struct {
+		IDs []int64 `json:"ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, gin.H{"code": 400, "msg": "参数错误"})
+		return
+	}
+
+	if err := h.userNoticeService.MarkRead(context.Background(), user.Username, req.IDs); err != nil {
+		h.logger.Error("标记站内通知已读失败", "error", err, "username", user.Username)
+		c.JSON(http.StatusOK, gin.H{"code": 500, "msg": "操作失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "msg": "已标记为已读"})
+}
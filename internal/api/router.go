@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"stellarfrp/config"
 	"stellarfrp/internal/api/admin"
 	"stellarfrp/internal/api/apis"
@@ -11,8 +12,12 @@ import (
 	"stellarfrp/internal/service"
 	"stellarfrp/pkg/async"
 	"stellarfrp/pkg/email"
+	"stellarfrp/pkg/frps"
 	"stellarfrp/pkg/geetest"
 	"stellarfrp/pkg/logger"
+	"stellarfrp/pkg/secret"
+	"stellarfrp/pkg/webhook"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
@@ -20,7 +25,7 @@ import (
 )
 
 // SetupRouter 设置API路由
-func SetupRouter(cfg *config.Config, logger *logger.Logger, db *sqlx.DB, redisClient *redis.Client, geetestClient *geetest.GeetestClient, realNameAuthHandler *handler.RealNameAuthHandler) *gin.Engine {
+func SetupRouter(cfg *config.Config, logger *logger.Logger, db *sqlx.DB, redisClient *redis.Client, keyring *secret.Keyring, geetestClient *geetest.GeetestClient, realNameAuthHandler *handler.RealNameAuthHandler) *gin.Engine {
 	// 创建Gin引擎
 	if cfg.LogLevel != "debug" {
 		gin.SetMode(gin.ReleaseMode)
@@ -40,9 +45,21 @@ func SetupRouter(cfg *config.Config, logger *logger.Logger, db *sqlx.DB, redisCl
 	// 初始化存储库
 	userRepo := repository.NewUserRepository(db)
 	groupRepo := repository.NewGroupRepository(db)
-	nodeRepo := repository.NewNodeRepository(db)
+	nodeRepo := repository.NewNodeRepository(db, keyring)
 	nodeTrafficRepo := repository.NewNodeTrafficRepository(db)
 	proxyRepo := repository.NewProxyRepository(db)
+	proxyVersionRepo := repository.NewProxyVersionRepository(db)
+	proxyEventRepo := repository.NewProxyEventRepository(db)
+	proxyScheduleRepo := repository.NewProxyScheduleRepository(db)
+	proxyPresetRepo := repository.NewProxyPresetRepository(db)
+	proxyAlertRepo := repository.NewProxyAlertRepository(db, keyring)
+	proxyProbeRepo := repository.NewProxyProbeRepository(db)
+	nodeLoadRepo := repository.NewNodeLoadRepository(db)
+	nodeMaintenanceRepo := repository.NewNodeMaintenanceRepository(db)
+	nodeValidationRepo := repository.NewNodeValidationRepository(db)
+	nodeRewardRepo := repository.NewNodeRewardRepository(db)
+	nodeReviewRepo := repository.NewNodeReviewRepository(db)
+	userNoticeRepo := repository.NewUserNoticeRepository(db)
 	userCheckinRepo := repository.NewUserCheckinRepository(db)
 	userTrafficLogRepo := repository.NewUserTrafficLogRepository(db)
 	adRepo := repository.NewAdRepository(db)
@@ -61,33 +78,120 @@ func SetupRouter(cfg *config.Config, logger *logger.Logger, db *sqlx.DB, redisCl
 		FromName: cfg.Email.FromName,
 	}, logger)
 
+	// 初始化节点API客户端，所有节点共享连接池，节点密码在请求时解密
+	frpsOpts := frps.DefaultOptions()
+	frpsOpts.DecryptToken = keyring.Decrypt
+	frpsClient := frps.NewClient(frpsOpts)
+
+	// 初始化Webhook发送器
+	webhookSender := webhook.NewSender(10 * time.Second)
+
+	// 初始化隧道事件推送中心
+	proxyStreamHub := service.NewProxyStreamHub()
+
 	// 初始化服务
 	userService := service.NewUserService(userRepo, groupRepo, userTrafficLogRepo, redisClient, worker, emailService, logger)
-	nodeService := service.NewNodeService(nodeRepo, nodeTrafficRepo)
-	proxyService := service.NewProxyService(proxyRepo, nodeService, userService, redisClient, logger)
-	nodeTrafficService := service.NewNodeTrafficService(nodeRepo, nodeTrafficRepo, logger)
+	nodeService := service.NewNodeService(nodeRepo, nodeTrafficRepo, keyring)
+	proxyService := service.NewProxyService(proxyRepo, proxyVersionRepo, proxyEventRepo, proxyScheduleRepo, proxyAlertRepo, proxyProbeRepo, nodeService, userService, frpsClient, proxyStreamHub, redisClient, logger)
+	proxyScheduleService := service.NewProxyScheduleService(proxyScheduleRepo, logger)
+	proxyPresetService := service.NewProxyPresetService(proxyPresetRepo, logger)
+	proxyStatusSnapshotService := service.NewProxyStatusSnapshotService(redisClient, logger)
+	proxyAlertService := service.NewProxyAlertService(proxyAlertRepo, proxyEventRepo, proxyService, userService, emailService, webhookSender, keyring, redisClient, logger)
+	proxyProbeService := service.NewProxyProbeService(proxyProbeRepo, proxyService, nodeService, logger)
+	nodeHeartbeatService := service.NewNodeHeartbeatService(nodeService, redisClient, logger)
+	nodeAgentAuthService := service.NewNodeAgentAuthService(nodeService, redisClient)
+	nodeLoadService := service.NewNodeLoadService(nodeLoadRepo, redisClient, logger)
+	userNoticeService := service.NewUserNoticeService(userNoticeRepo, proxyStreamHub, logger)
+	nodeMaintenanceService := service.NewNodeMaintenanceService(nodeMaintenanceRepo, nodeService, proxyService, userService, userNoticeService, emailService, logger)
+	nodeCapacityService := service.NewNodeCapacityService(nodeService, proxyService)
+	nodeValidationService := service.NewNodeValidationService(nodeValidationRepo, redisClient, keyring, logger)
+	nodeRewardService := service.NewNodeRewardService(nodeRewardRepo, groupRepo, nodeService, userService, userNoticeService, redisClient, logger)
+	nodeReviewService := service.NewNodeReviewService(nodeReviewRepo, nodeService, userService, userNoticeService, emailService, logger)
+	nodeRecommendService := service.NewNodeRecommendService(nodeService, proxyService, nodeLoadService, nodeMaintenanceService, nodeCapacityService, logger)
+	nodeTrafficService := service.NewNodeTrafficService(nodeRepo, nodeTrafficRepo, frpsClient, nodeHeartbeatService, logger)
 	userCheckinService := service.NewUserCheckinService(userRepo, groupRepo, userCheckinRepo, redisClient, logger)
-	userTrafficLogService := service.NewUserTrafficLogService(nodeRepo, userTrafficLogRepo, redisClient, logger)
+	userTrafficLogService := service.NewUserTrafficLogService(nodeRepo, userTrafficLogRepo, redisClient, frpsClient, logger)
 	adService := service.NewAdService(adRepo, redisClient, logger)
 	announcementService := service.NewAnnouncementService(announcementRepo, redisClient, logger)
 	systemService := service.NewSystemService(systemRepo, redisClient, logger)
 	groupService := service.NewGroupService(groupRepo, logger)
 	productService := service.NewProductService(productRepo, orderRepo, userService, redisClient, logger)
+	proxyValidator := service.NewProxyValidator(proxyService, nodeService, userService, nodeCapacityService)
+
+	// 使用当前密钥重新加密节点凭据和Webhook签名密钥，完成密钥轮换或迁移启用加密前写入的明文
+	if keyring.Enabled() {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			count, err := nodeService.RotateSecrets(ctx)
+			if err != nil {
+				logger.Error("重新加密节点凭据失败", "error", err, "rotated", count)
+			} else if count > 0 {
+				logger.Info("已使用当前密钥重新加密节点凭据", "keyID", keyring.CurrentKeyID(), "nodes", count)
+			}
+
+			count, err = proxyAlertService.RotateSecrets(ctx)
+			if err != nil {
+				logger.Error("重新加密Webhook签名密钥失败", "error", err, "rotated", count)
+			} else if count > 0 {
+				logger.Info("已使用当前密钥重新加密Webhook签名密钥", "keyID", keyring.CurrentKeyID(), "alerts", count)
+			}
+		}()
+	}
 
 	// 初始化节点调度器
-	nodeScheduler := scheduler.NewNodeScheduler(nodeTrafficService, logger)
+	nodeScheduler := scheduler.NewNodeScheduler(nodeTrafficService, nodeHeartbeatService, logger)
 	nodeScheduler.Start() // 启动节点调度
 
+	// 初始化节点负载时序数据调度器
+	nodeLoadScheduler := scheduler.NewNodeLoadScheduler(nodeLoadService, logger)
+	nodeLoadScheduler.Start() // 启动节点负载降采样与清理
+
+	// 初始化节点维护通知调度器
+	nodeMaintenanceScheduler := scheduler.NewNodeMaintenanceScheduler(nodeMaintenanceService, logger)
+	nodeMaintenanceScheduler.Start() // 启动节点维护通知
+
+	// 初始化节点贡献奖励调度器
+	nodeRewardScheduler := scheduler.NewNodeRewardScheduler(nodeRewardService, logger)
+	nodeRewardScheduler.Start() // 启动捐赠节点在线采样与每日奖励结算
+
 	// 初始化流量记录调度器
 	trafficScheduler := scheduler.NewTrafficScheduler(userTrafficLogService, userService, proxyService, nodeService, logger)
 	trafficScheduler.Start() // 启动流量记录调度
 
+	// 初始化隧道在线时段调度器
+	proxyScheduleScheduler := scheduler.NewProxyScheduleScheduler(proxyService, proxyScheduleService, logger)
+	proxyScheduleScheduler.Start() // 启动隧道在线时段调度
+
+	// 初始化隧道状态校正器
+	proxyReconciler := scheduler.NewProxyReconciler(proxyService, nodeService, frpsClient, logger)
+	proxyReconciler.Start() // 启动隧道状态校正
+
+	// 初始化隧道状态轮询器
+	proxyStatusPoller := scheduler.NewProxyStatusPoller(proxyService, nodeService, proxyStatusSnapshotService, proxyStreamHub, frpsClient, logger)
+	proxyStatusPoller.Start() // 启动隧道状态轮询
+
+	// 初始化隧道离线告警调度器
+	proxyAlertScheduler := scheduler.NewProxyAlertScheduler(proxyAlertService, logger)
+	proxyAlertScheduler.Start() // 启动隧道离线告警检查
+
+	// 初始化隧道公网可达性探测调度器
+	proxyProbeScheduler := scheduler.NewProxyProbeScheduler(proxyProbeService, logger)
+	proxyProbeScheduler.Start() // 启动隧道公网可达性探测
+
+	// 初始化隧道事件清理调度器
+	proxyEventScheduler := scheduler.NewProxyEventScheduler(proxyService, logger)
+	proxyEventScheduler.Start() // 启动过期隧道事件清理
+
 	// 初始化处理器
 	userHandler := handler.NewUserHandler(userService, redisClient, emailService, logger, geetestClient, proxyService)
 	userCheckinHandler := handler.NewUserCheckinHandler(userService, userCheckinService, logger)
-	nodeHandler := handler.NewNodeHandler(nodeService, userService, logger, redisClient)
-	proxyHandler := handler.NewProxyHandler(proxyService, nodeService, userService, logger)
-	proxyAuthHandler := handler.NewProxyAuthHandler(proxyService, userService, userTrafficLogService, logger)
+	userNoticeHandler := handler.NewUserNoticeHandler(userService, userNoticeService, logger)
+	nodeHandler := handler.NewNodeHandler(nodeService, proxyService, userService, nodeLoadService, nodeRecommendService, nodeMaintenanceService, nodeCapacityService, nodeValidationService, nodeRewardService, nodeReviewService, cfg.Frps.PluginURL, logger, redisClient, frpsClient)
+	nodeAgentHandler := handler.NewNodeAgentHandler(nodeAgentAuthService, nodeHeartbeatService, nodeLoadService, logger)
+	proxyStreamHandler := handler.NewProxyStreamHandler(userService, proxyService, proxyStatusSnapshotService, proxyStreamHub, logger)
+	proxyHandler := handler.NewProxyHandler(proxyService, nodeService, userService, proxyValidator, proxyScheduleService, proxyPresetService, proxyAlertService, proxyProbeService, nodeRecommendService, proxyStatusSnapshotService, logger)
+	proxyAuthHandler := handler.NewProxyAuthHandler(proxyService, nodeService, userService, userTrafficLogService, proxyScheduleService, nodeMaintenanceService, nodeCapacityService, nodeValidationService, cfg.Frps.RequirePluginSecret, logger)
 	adHandler := handler.NewAdHandler(adService, logger)
 	announcementHandler := handler.NewAnnouncementHandler(announcementService, logger)
 	systemHandler := handler.NewSystemHandler(systemService, logger)
@@ -96,10 +200,10 @@ func SetupRouter(cfg *config.Config, logger *logger.Logger, db *sqlx.DB, redisCl
 	// 初始化管理员处理器
 	userAdminHandler := admin.NewUserAdminHandler(userService, logger)
 	announcementAdminHandler := admin.NewAnnouncementAdminHandler(announcementService, logger)
-	nodeAdminHandler := admin.NewNodeAdminHandler(nodeService, nodeRepo, userService, logger)
+	nodeAdminHandler := admin.NewNodeAdminHandler(nodeService, nodeRepo, userService, nodeHeartbeatService, nodeLoadService, nodeMaintenanceService, nodeValidationService, nodeRewardService, nodeReviewService, proxyAlertService, cfg.Frps.PluginURL, logger)
 	groupAdminHandler := admin.NewGroupAdminHandler(groupService, logger)
 	productAdminHandler := admin.NewProductAdminHandler(productService, userService, logger)
-	proxyAdminHandler := admin.NewProxyAdminHandler(proxyService, nodeService, userService, redisClient, logger)
+	proxyAdminHandler := admin.NewProxyAdminHandler(proxyService, nodeService, userService, proxyValidator, proxyReconciler, frpsClient, proxyStatusSnapshotService, logger)
 
 	// 健康检查
 	router.GET("/health", func(c *gin.Context) {
@@ -115,10 +219,10 @@ func SetupRouter(cfg *config.Config, logger *logger.Logger, db *sqlx.DB, redisCl
 	authRouter.Use(middleware.UserAuth(userService))
 
 	// 注册不需要认证的路由（如登录、注册、发送验证码等）
-	apis.RegisterPublicRoutes(v1, userHandler, systemHandler, announcementHandler, adHandler, proxyAuthHandler, productHandler)
+	apis.RegisterPublicRoutes(v1, userHandler, systemHandler, announcementHandler, adHandler, proxyAuthHandler, proxyStreamHandler, nodeAgentHandler, productHandler)
 
 	// 注册需要认证的API路由
-	apis.RegisterAuthRoutes(authRouter, userHandler, userCheckinHandler, nodeHandler, proxyHandler, proxyAuthHandler, realNameAuthHandler, productHandler)
+	apis.RegisterAuthRoutes(authRouter, userHandler, userCheckinHandler, userNoticeHandler, nodeHandler, proxyHandler, proxyAuthHandler, realNameAuthHandler, productHandler)
 
 	// 注册管理员API路由
 	adminRouter := v1.Group("/admin")
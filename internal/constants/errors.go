@@ -28,6 +28,14 @@ const (
 	ErrNoNodeAccess      = "您无权使用此节点"
 	ErrProxyNameFormat   = "隧道名称格式错误，应为：用户名.隧道名"
 	ErrProxyNameEmpty    = "隧道名称不能为空"
+	ErrProxyDisabled     = "隧道已被禁用"
+	ErrProxyOutOfWindow  = "当前不在隧道允许的在线时段内"
+	ErrProxyNodeMismatch = "隧道不属于当前节点，请检查客户端配置"
+
+	// 节点插件相关错误
+	ErrPluginNodeInvalid   = "插件回调的节点不存在"
+	ErrPluginSecretInvalid = "插件回调密钥错误"
+	ErrNodePaused          = "节点已被所有者暂停，请更换节点"
 
 	// 系统错误
 	ErrInternalServer       = "服务器内部错误"
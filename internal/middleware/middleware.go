@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"net/url"
 	"stellarfrp/pkg/logger"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,7 +24,7 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 		latency := timeStamp.Sub(start)
 
 		if raw != "" {
-			path = path + "?" + raw
+			path = path + "?" + redactQuery(raw)
 		}
 
 		log.Info("访问日志",
@@ -35,6 +37,28 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 	}
 }
 
+// sensitiveQueryKeys 记录日志时需要隐藏值的查询参数
+var sensitiveQueryKeys = map[string]bool{
+	"secret": true, // frps插件回调密钥
+	"token":  true, // 隧道状态推送的用户令牌，EventSource无法设置请求头
+}
+
+// redactQuery 隐藏查询字符串中敏感参数的值，避免密钥写入访问日志
+func redactQuery(raw string) string {
+	params := strings.Split(raw, "&")
+	for i, param := range params {
+		key, _, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil || sensitiveQueryKeys[strings.ToLower(name)] {
+			params[i] = key + "=***"
+		}
+	}
+	return strings.Join(params, "&")
+}
+
 // Recovery 恢复中间件
 func Recovery(log *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
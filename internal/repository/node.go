@@ -4,30 +4,67 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"stellarfrp/internal/utils"
+	"stellarfrp/pkg/secret"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// 节点状态
+const (
+	NodeStatusOffline          = 0 // 离线
+	NodeStatusOnline           = 1 // 在线
+	NodeStatusPending          = 2 // 待审核
+	NodeStatusDegraded         = 3 // 降级：可用但负载过高或心跳超时
+	NodeStatusPaused           = 4 // 暂停：所有者暂停接入，不接受新的隧道与客户端登录
+	NodeStatusRejected         = 5 // 审核未通过
+	NodeStatusChangesRequested = 6 // 需修改：管理员要求捐赠者修改后重新提交审核
+)
+
+// NodeReachable 节点的frps是否仍在提供服务，可以通过Dashboard API查询隧道状态
+// 暂停的节点只拒绝新隧道，已有隧道仍在运行
+func NodeReachable(status int) bool {
+	return status == NodeStatusOnline || status == NodeStatusDegraded || status == NodeStatusPaused
+}
+
+// NodeUnapproved 节点是否尚未通过审核（待审核、需修改或审核未通过），此类节点不接入服务
+func NodeUnapproved(status int) bool {
+	return status == NodeStatusPending || status == NodeStatusRejected || status == NodeStatusChangesRequested
+}
+
 // Node FRP节点模型
 type Node struct {
-	ID           int64          `db:"id"`
-	NodeName     string         `db:"node_name"`
-	FrpsPort     int            `db:"frps_port"`
-	URL          string         `db:"url"`
-	Token        string         `db:"token"`
-	User         string         `db:"user"`
-	Description  sql.NullString `db:"description"`
-	Permission   string         `db:"permission"`    // JSON格式的字符串，如["1","2"]表示权限组IDs
-	AllowedTypes string         `db:"allowed_types"` // JSON格式的字符串，如["TCP","UDP"]
-	Host         sql.NullString `db:"host"`
-	PortRange    string         `db:"port_range"`
-	IP           string         `db:"ip"`
-	Status       int            `db:"status"`
-	OwnerID      sql.NullInt64  `db:"owner_id"` // 节点所属的用户ID，系统节点为null
-	CreatedAt    time.Time      `db:"created_at"`
-	UpdatedAt    time.Time      `db:"updated_at"`
+	ID               int64          `db:"id"`
+	NodeName         string         `db:"node_name"`
+	FrpsPort         int            `db:"frps_port"`
+	URL              string         `db:"url"`
+	Token            string         `db:"token" json:"-"` // frps Dashboard密码，加密存储
+	User             string         `db:"user"`
+	Description      sql.NullString `db:"description"`
+	Permission       string         `db:"permission"`    // JSON格式的字符串，如["1","2"]表示权限组IDs
+	AllowedTypes     string         `db:"allowed_types"` // JSON格式的字符串，如["TCP","UDP"]
+	Host             sql.NullString `db:"host"`
+	PortRange        string         `db:"port_range"`
+	IP               string         `db:"ip"`
+	Status           int            `db:"status"`
+	OwnerID          sql.NullInt64  `db:"owner_id"`               // 节点所属的用户ID，系统节点为null
+	FrpsVersion      string         `db:"frps_version"`           // 节点运行的frps版本，由serverinfo自动识别
+	AgentKey         string         `db:"agent_key" json:"-"`     // 节点代理心跳签名密钥，加密存储
+	Region           string         `db:"region"`                 // 节点所在地区，如"华东"
+	ISP              string         `db:"isp"`                    // 节点线路运营商，如"telecom"、"unicom"、"bgp"
+	City             string         `db:"city"`                   // 节点所在城市
+	BandwidthTier    string         `db:"bandwidth_tier"`         // 带宽档位，如"100M"、"1G"
+	Tags             string         `db:"tags"`                   // JSON格式的字符串，如["game","web"]
+	SortOrder        int            `db:"sort_order"`             // 排序值，越小越靠前
+	MaxProxies       int            `db:"max_proxies"`            // 隧道总数上限，0表示不限制
+	MaxTypeProxies   string         `db:"max_type_proxies"`       // JSON格式的字符串，如{"tcp":100}，按协议类型限制隧道数量
+	MaxOnlineClients int            `db:"max_online_clients"`     // 同时在线客户端数量上限，0表示不限制
+	PluginSecret     string         `db:"plugin_secret" json:"-"` // frps插件回调密钥，加密存储
+	RewardBlocked    bool           `db:"reward_blocked"`         // 是否停止向所有者发放贡献奖励
+	CreatedAt        time.Time      `db:"created_at"`
+	UpdatedAt        time.Time      `db:"updated_at"`
 }
 
 // NodeRepository 节点仓库接口
@@ -39,28 +76,52 @@ type NodeRepository interface {
 	GetByPermission(ctx context.Context, permission int64) ([]*Node, error)
 	GetByOwnerID(ctx context.Context, ownerID int64) ([]*Node, error)
 	Update(ctx context.Context, node *Node) error
+	UpdateFrpsVersion(ctx context.Context, id int64, version string) error
+	UpdateStatus(ctx context.Context, id int64, status int) error
+	UpdateAgentKey(ctx context.Context, id int64, agentKey string) error
+	UpdatePluginSecret(ctx context.Context, id int64, secret string) error
+	UpdateRewardBlocked(ctx context.Context, id int64, blocked bool) error
+	RotateSecrets(ctx context.Context) (int, error)
 	Delete(ctx context.Context, id int64) error
 	List(ctx context.Context, offset, limit int) ([]*Node, error)
 }
 
 // nodeRepository 节点仓库实现
 type nodeRepository struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	keyring *secret.Keyring
 }
 
-// NewNodeRepository 创建节点仓库实例
-func NewNodeRepository(db *sqlx.DB) NodeRepository {
-	return &nodeRepository{db: db}
+// NewNodeRepository 创建节点仓库实例，节点面板密码、代理密钥和插件密钥使用 keyring 加密后写库
+func NewNodeRepository(db *sqlx.DB, keyring *secret.Keyring) NodeRepository {
+	return &nodeRepository{db: db, keyring: keyring}
+}
+
+// sealSecrets 加密节点的面板密码、代理密钥和插件密钥，已加密的值保持不变
+func (r *nodeRepository) sealSecrets(node *Node) error {
+	for _, field := range []*string{&node.Token, &node.AgentKey, &node.PluginSecret} {
+		sealed, err := r.keyring.Encrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = sealed
+	}
+	return nil
 }
 
 // Create 创建节点
 func (r *nodeRepository) Create(ctx context.Context, node *Node) error {
-	query := `INSERT INTO nodes (node_name, frps_port, url, token, user, description, permission, allowed_types, host, port_range, ip, status, owner_id, created_at, updated_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	if err := r.sealSecrets(node); err != nil {
+		return err
+	}
+	query := `INSERT INTO nodes (node_name, frps_port, url, token, user, description, permission, allowed_types, host, port_range, ip, status, owner_id, region, isp, city, bandwidth_tier, tags, sort_order, max_proxies, max_type_proxies, max_online_clients, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
 	result, err := r.db.ExecContext(ctx, query,
 		node.NodeName, node.FrpsPort, node.URL, node.Token, node.User,
 		node.Description, node.Permission, node.AllowedTypes, node.Host,
-		node.PortRange, node.IP, node.Status, node.OwnerID)
+		node.PortRange, node.IP, node.Status, node.OwnerID, node.Region, node.ISP,
+		node.City, node.BandwidthTier, node.Tags, node.SortOrder,
+		node.MaxProxies, node.MaxTypeProxies, node.MaxOnlineClients)
 	if err != nil {
 		return err
 	}
@@ -116,7 +177,7 @@ func (r *nodeRepository) GetByUser(ctx context.Context, user string) ([]*Node, e
 func (r *nodeRepository) GetByPermission(ctx context.Context, permission int64) ([]*Node, error) {
 	nodes := []*Node{}
 	// 首先获取所有节点
-	query := `SELECT * FROM nodes`
+	query := `SELECT * FROM nodes ORDER BY sort_order ASC, id ASC`
 	err := r.db.SelectContext(ctx, &nodes, query)
 	if err != nil {
 		return nil, err
@@ -143,7 +204,7 @@ func (r *nodeRepository) GetByPermission(ctx context.Context, permission int64)
 // GetByOwnerID 根据所属用户ID获取节点列表
 func (r *nodeRepository) GetByOwnerID(ctx context.Context, ownerID int64) ([]*Node, error) {
 	nodes := []*Node{}
-	query := `SELECT * FROM nodes WHERE owner_id = ?`
+	query := `SELECT * FROM nodes WHERE owner_id = ? ORDER BY sort_order ASC, id ASC`
 	err := r.db.SelectContext(ctx, &nodes, query, ownerID)
 	if err != nil {
 		return nil, err
@@ -153,15 +214,121 @@ func (r *nodeRepository) GetByOwnerID(ctx context.Context, ownerID int64) ([]*No
 
 // Update 更新节点信息
 func (r *nodeRepository) Update(ctx context.Context, node *Node) error {
+	if err := r.sealSecrets(node); err != nil {
+		return err
+	}
 	query := `UPDATE nodes SET node_name = ?, frps_port = ?, url = ?, token = ?, user = ?, 
-		description = ?, permission = ?, allowed_types = ?, host = ?, port_range = ?, ip = ?, status = ?, owner_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+		description = ?, permission = ?, allowed_types = ?, host = ?, port_range = ?, ip = ?, status = ?, owner_id = ?, region = ?, isp = ?, city = ?, bandwidth_tier = ?, tags = ?, sort_order = ?, max_proxies = ?, max_type_proxies = ?, max_online_clients = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
 	_, err := r.db.ExecContext(ctx, query,
 		node.NodeName, node.FrpsPort, node.URL, node.Token, node.User,
 		node.Description, node.Permission, node.AllowedTypes, node.Host,
-		node.PortRange, node.IP, node.Status, node.OwnerID, node.ID)
+		node.PortRange, node.IP, node.Status, node.OwnerID, node.Region, node.ISP,
+		node.City, node.BandwidthTier, node.Tags, node.SortOrder,
+		node.MaxProxies, node.MaxTypeProxies, node.MaxOnlineClients, node.ID)
+	return err
+}
+
+// UpdateFrpsVersion 更新节点的frps版本
+func (r *nodeRepository) UpdateFrpsVersion(ctx context.Context, id int64, version string) error {
+	query := `UPDATE nodes SET frps_version = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, version, id)
+	return err
+}
+
+// UpdateStatus 更新节点状态
+func (r *nodeRepository) UpdateStatus(ctx context.Context, id int64, status int) error {
+	query := `UPDATE nodes SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, status, id)
+	return err
+}
+
+// UpdateAgentKey 更新节点代理的心跳签名密钥
+func (r *nodeRepository) UpdateAgentKey(ctx context.Context, id int64, agentKey string) error {
+	sealed, err := r.keyring.Encrypt(agentKey)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE nodes SET agent_key = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err = r.db.ExecContext(ctx, query, sealed, id)
+	return err
+}
+
+// UpdatePluginSecret 更新节点frps插件回调密钥
+func (r *nodeRepository) UpdatePluginSecret(ctx context.Context, id int64, pluginSecret string) error {
+	sealed, err := r.keyring.Encrypt(pluginSecret)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE nodes SET plugin_secret = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err = r.db.ExecContext(ctx, query, sealed, id)
+	return err
+}
+
+// UpdateRewardBlocked 更新节点是否停止发放贡献奖励
+func (r *nodeRepository) UpdateRewardBlocked(ctx context.Context, id int64, blocked bool) error {
+	query := `UPDATE nodes SET reward_blocked = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, blocked, id)
 	return err
 }
 
+// RotateSecrets 用当前密钥重新加密明文或使用旧密钥加密的节点凭据，返回更新的节点数
+// 更新时校验凭据未被并发修改，被修改的节点已由修改操作使用当前密钥加密，直接跳过
+func (r *nodeRepository) RotateSecrets(ctx context.Context) (int, error) {
+	if !r.keyring.Enabled() {
+		return 0, secret.ErrNoKey
+	}
+
+	var rows []struct {
+		ID           int64  `db:"id"`
+		Token        string `db:"token"`
+		AgentKey     string `db:"agent_key"`
+		PluginSecret string `db:"plugin_secret"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, `SELECT id, token, agent_key, plugin_secret FROM nodes`); err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, row := range rows {
+		values, changed, err := rotateSecretValues(r.keyring, [3]string{row.Token, row.AgentKey, row.PluginSecret})
+		if err != nil {
+			return rotated, fmt.Errorf("解密节点 %d 的凭据失败: %w", row.ID, err)
+		}
+		if !changed {
+			continue
+		}
+
+		query := `UPDATE nodes SET token = ?, agent_key = ?, plugin_secret = ?
+			WHERE id = ? AND token = ? AND agent_key = ? AND plugin_secret = ?`
+		result, err := r.db.ExecContext(ctx, query, values[0], values[1], values[2],
+			row.ID, row.Token, row.AgentKey, row.PluginSecret)
+		if err != nil {
+			return rotated, err
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			rotated++
+		}
+	}
+	return rotated, nil
+}
+
+// rotateSecretValues 用当前密钥重新加密需要轮换的凭据，返回轮换后的值及是否有值发生变化
+func rotateSecretValues(keyring *secret.Keyring, values [3]string) ([3]string, bool, error) {
+	changed := false
+	for i, v := range values {
+		if !keyring.NeedsRotation(v) {
+			continue
+		}
+		rotated, err := keyring.Rotate(v)
+		if err != nil {
+			return values, false, err
+		}
+		values[i] = rotated
+		changed = true
+	}
+	return values, changed, nil
+}
+
 // Delete 删除节点
 func (r *nodeRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM nodes WHERE id = ?`
@@ -172,7 +339,7 @@ func (r *nodeRepository) Delete(ctx context.Context, id int64) error {
 // List 获取节点列表
 func (r *nodeRepository) List(ctx context.Context, offset, limit int) ([]*Node, error) {
 	nodes := []*Node{}
-	query := `SELECT * FROM nodes LIMIT ? OFFSET ?`
+	query := `SELECT * FROM nodes ORDER BY sort_order ASC, id ASC LIMIT ? OFFSET ?`
 	err := r.db.SelectContext(ctx, &nodes, query, limit, offset)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NodeLoadSample 节点代理上报的原始负载数据
+type NodeLoadSample struct {
+	ID                int64     `db:"id" json:"id"`
+	NodeID            int64     `db:"node_id" json:"node_id"`
+	LoadScore         float64   `db:"load_score" json:"load_score"`
+	CurrentConns      int       `db:"current_conns" json:"current_conns"`
+	PeakConns         int       `db:"peak_conns" json:"peak_conns"`
+	CurrentTraffic    int64     `db:"current_traffic" json:"current_traffic"`
+	PeakTraffic       int64     `db:"peak_traffic" json:"peak_traffic"`
+	CPUUsage          float64   `db:"cpu_usage" json:"cpu_usage"`
+	MemUsage          float64   `db:"mem_usage" json:"mem_usage"`
+	ConnGrowthRate    float64   `db:"conn_growth_rate" json:"conn_growth_rate"`
+	TrafficGrowthRate float64   `db:"traffic_growth_rate" json:"traffic_growth_rate"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+}
+
+// NodeLoadPoint 负载图表中的一个数据点，原始数据的 Samples 为1
+type NodeLoadPoint struct {
+	Time              time.Time `db:"bucket_time" json:"time"`
+	Samples           int       `db:"samples" json:"samples"`
+	LoadScore         float64   `db:"load_score" json:"load_score"`
+	CurrentConns      float64   `db:"current_conns" json:"current_conns"`
+	PeakConns         int       `db:"peak_conns" json:"peak_conns"`
+	CurrentTraffic    float64   `db:"current_traffic" json:"current_traffic"`
+	PeakTraffic       int64     `db:"peak_traffic" json:"peak_traffic"`
+	CPUUsage          float64   `db:"cpu_usage" json:"cpu_usage"`
+	MemUsage          float64   `db:"mem_usage" json:"mem_usage"`
+	ConnGrowthRate    float64   `db:"conn_growth_rate" json:"conn_growth_rate"`
+	TrafficGrowthRate float64   `db:"traffic_growth_rate" json:"traffic_growth_rate"`
+}
+
+// NodeLoadRepository 节点负载时序数据仓库接口
+type NodeLoadRepository interface {
+	CreateSample(ctx context.Context, sample *NodeLoadSample) error
+	ListSamples(ctx context.Context, nodeID int64, from, to time.Time) ([]*NodeLoadPoint, error)
+	ListRollups(ctx context.Context, nodeID int64, resolution int, from, to time.Time) ([]*NodeLoadPoint, error)
+	RollupSamples(ctx context.Context, resolution int, from, to time.Time) error
+	RollupRollups(ctx context.Context, sourceResolution, resolution int, from, to time.Time) error
+	DeleteSamplesBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteRollupsBefore(ctx context.Context, resolution int, before time.Time) (int64, error)
+}
+
+// nodeLoadRepository 节点负载时序数据仓库实现
+type nodeLoadRepository struct {
+	db *sqlx.DB
+}
+
+// NewNodeLoadRepository 创建节点负载时序数据仓库实例
+func NewNodeLoadRepository(db *sqlx.DB) NodeLoadRepository {
+	return &nodeLoadRepository{db: db}
+}
+
+// CreateSample 写入原始负载数据
+func (r *nodeLoadRepository) CreateSample(ctx context.Context, sample *NodeLoadSample) error {
+	query := `INSERT INTO node_load_samples (node_id, load_score, current_conns, peak_conns, current_traffic, peak_traffic,
+		cpu_usage, mem_usage, conn_growth_rate, traffic_growth_rate, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if sample.CreatedAt.IsZero() {
+		sample.CreatedAt = time.Now()
+	}
+	result, err := r.db.ExecContext(ctx, query,
+		sample.NodeID, sample.LoadScore, sample.CurrentConns, sample.PeakConns, sample.CurrentTraffic, sample.PeakTraffic,
+		sample.CPUUsage, sample.MemUsage, sample.ConnGrowthRate, sample.TrafficGrowthRate, sample.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sample.ID = id
+	return nil
+}
+
+// ListSamples 获取时间范围内的原始负载数据（按时间正序）
+func (r *nodeLoadRepository) ListSamples(ctx context.Context, nodeID int64, from, to time.Time) ([]*NodeLoadPoint, error) {
+	query := `SELECT created_at AS bucket_time, 1 AS samples, load_score, current_conns, peak_conns, current_traffic, peak_traffic,
+		cpu_usage, mem_usage, conn_growth_rate, traffic_growth_rate
+		FROM node_load_samples WHERE node_id = ? AND created_at >= ? AND created_at < ? ORDER BY created_at ASC, id ASC`
+	var points []*NodeLoadPoint
+	err := r.db.SelectContext(ctx, &points, query, nodeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// ListRollups 获取时间范围内指定粒度的降采样数据（按时间正序）
+func (r *nodeLoadRepository) ListRollups(ctx context.Context, nodeID int64, resolution int, from, to time.Time) ([]*NodeLoadPoint, error) {
+	query := `SELECT bucket_time, samples, load_score, current_conns, peak_conns, current_traffic, peak_traffic,
+		cpu_usage, mem_usage, conn_growth_rate, traffic_growth_rate
+		FROM node_load_rollups WHERE node_id = ? AND resolution = ? AND bucket_time >= ? AND bucket_time < ? ORDER BY bucket_time ASC`
+	var points []*NodeLoadPoint
+	err := r.db.SelectContext(ctx, &points, query, nodeID, resolution, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// RollupSamples 将 [from, to) 内的原始数据按粒度聚合，重复执行会覆盖为最新结果
+func (r *nodeLoadRepository) RollupSamples(ctx context.Context, resolution int, from, to time.Time) error {
+	query := `INSERT INTO node_load_rollups (node_id, resolution, bucket_time, samples, load_score, current_conns, peak_conns,
+			current_traffic, peak_traffic, cpu_usage, mem_usage, conn_growth_rate, traffic_growth_rate)
+		SELECT node_id, ?, FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(created_at) / ?) * ?) AS bucket, COUNT(*),
+			AVG(load_score), AVG(current_conns), MAX(peak_conns), AVG(current_traffic), MAX(peak_traffic),
+			AVG(cpu_usage), AVG(mem_usage), AVG(conn_growth_rate), AVG(traffic_growth_rate)
+		FROM node_load_samples WHERE created_at >= ? AND created_at < ?
+		GROUP BY node_id, bucket
+		ON DUPLICATE KEY UPDATE samples = VALUES(samples), load_score = VALUES(load_score),
+			current_conns = VALUES(current_conns), peak_conns = VALUES(peak_conns),
+			current_traffic = VALUES(current_traffic), peak_traffic = VALUES(peak_traffic),
+			cpu_usage = VALUES(cpu_usage), mem_usage = VALUES(mem_usage),
+			conn_growth_rate = VALUES(conn_growth_rate), traffic_growth_rate = VALUES(traffic_growth_rate)`
+	_, err := r.db.ExecContext(ctx, query, resolution, resolution, resolution, from, to)
+	return err
+}
+
+// RollupRollups 将 [from, to) 内的细粒度聚合数据按样本数加权聚合为粗粒度数据
+func (r *nodeLoadRepository) RollupRollups(ctx context.Context, sourceResolution, resolution int, from, to time.Time) error {
+	query := `INSERT INTO node_load_rollups (node_id, resolution, bucket_time, samples, load_score, current_conns, peak_conns,
+			current_traffic, peak_traffic, cpu_usage, mem_usage, conn_growth_rate, traffic_growth_rate)
+		SELECT node_id, ?, FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(bucket_time) / ?) * ?) AS bucket, SUM(samples),
+			SUM(load_score * samples) / SUM(samples), SUM(current_conns * samples) / SUM(samples), MAX(peak_conns),
+			SUM(current_traffic * samples) / SUM(samples), MAX(peak_traffic),
+			SUM(cpu_usage * samples) / SUM(samples), SUM(mem_usage * samples) / SUM(samples),
+			SUM(conn_growth_rate * samples) / SUM(samples), SUM(traffic_growth_rate * samples) / SUM(samples)
+		FROM node_load_rollups WHERE resolution = ? AND bucket_time >= ? AND bucket_time < ? AND samples > 0
+		GROUP BY node_id, bucket
+		ON DUPLICATE KEY UPDATE samples = VALUES(samples), load_score = VALUES(load_score),
+			current_conns = VALUES(current_conns), peak_conns = VALUES(peak_conns),
+			current_traffic = VALUES(current_traffic), peak_traffic = VALUES(peak_traffic),
+			cpu_usage = VALUES(cpu_usage), mem_usage = VALUES(mem_usage),
+			conn_growth_rate = VALUES(conn_growth_rate), traffic_growth_rate = VALUES(traffic_growth_rate)`
+	_, err := r.db.ExecContext(ctx, query, resolution, resolution, resolution, sourceResolution, from, to)
+	return err
+}
+
+// DeleteSamplesBefore 删除指定时间之前的原始负载数据，返回删除条数
+func (r *nodeLoadRepository) DeleteSamplesBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM node_load_samples WHERE created_at < ?`
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteRollupsBefore 删除指定粒度在指定时间之前的聚合数据，返回删除条数
+func (r *nodeLoadRepository) DeleteRollupsBefore(ctx context.Context, resolution int, before time.Time) (int64, error) {
+	query := `DELETE FROM node_load_rollups WHERE resolution = ? AND bucket_time < ?`
+	result, err := r.db.ExecContext(ctx, query, resolution, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
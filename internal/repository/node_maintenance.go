@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NodeMaintenance 节点维护计划
+type NodeMaintenance struct {
+	ID         int64        `db:"id" json:"id"`
+	NodeID     int64        `db:"node_id" json:"node_id"`
+	StartAt    time.Time    `db:"start_at" json:"start_at"`
+	EndAt      time.Time    `db:"end_at" json:"end_at"`
+	Message    string       `db:"message" json:"message"`
+	Canceled   bool         `db:"canceled" json:"canceled"`
+	NotifiedAt sql.NullTime `db:"notified_at" json:"notified_at"`
+	CreatedBy  int64        `db:"created_by" json:"created_by"`
+	CreatedAt  time.Time    `db:"created_at" json:"created_at"`
+}
+
+// Active 判断维护计划在指定时间是否生效
+func (m *NodeMaintenance) Active(now time.Time) bool {
+	return !m.Canceled && !now.Before(m.StartAt) && now.Before(m.EndAt)
+}
+
+// NodeMaintenanceRepository 节点维护计划仓库接口
+type NodeMaintenanceRepository interface {
+	Create(ctx context.Context, m *NodeMaintenance) error
+	GetByID(ctx context.Context, id int64) (*NodeMaintenance, error)
+	List(ctx context.Context, nodeID int64, offset, limit int) ([]*NodeMaintenance, int, error)
+	ListActive(ctx context.Context, now time.Time) ([]*NodeMaintenance, error)
+	ListOverlapping(ctx context.Context, nodeID int64, start, end time.Time) ([]*NodeMaintenance, error)
+	ListToNotify(ctx context.Context, now, before time.Time) ([]*NodeMaintenance, error)
+	MarkNotified(ctx context.Context, id int64, at time.Time) (bool, error)
+	Cancel(ctx context.Context, id int64) error
+}
+
+// nodeMaintenanceRepository 节点维护计划仓库实现
+type nodeMaintenanceRepository struct {
+	db *sqlx.DB
+}
+
+// NewNodeMaintenanceRepository 创建节点维护计划仓库实例
+func NewNodeMaintenanceRepository(db *sqlx.DB) NodeMaintenanceRepository {
+	return &nodeMaintenanceRepository{db: db}
+}
+
+// Create 创建维护计划
+func (r *nodeMaintenanceRepository) Create(ctx context.Context, m *NodeMaintenance) error {
+	query := `INSERT INTO node_maintenances (node_id, start_at, end_at, message, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	result, err := r.db.ExecContext(ctx, query, m.NodeID, m.StartAt, m.EndAt, m.Message, m.CreatedBy, m.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = id
+	return nil
+}
+
+// GetByID 根据ID获取维护计划
+func (r *nodeMaintenanceRepository) GetByID(ctx context.Context, id int64) (*NodeMaintenance, error) {
+	query := `SELECT * FROM node_maintenances WHERE id = ?`
+	var m NodeMaintenance
+	err := r.db.GetContext(ctx, &m, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// List 分页获取维护计划（按开始时间倒序），nodeID 为0时获取全部节点
+func (r *nodeMaintenanceRepository) List(ctx context.Context, nodeID int64, offset, limit int) ([]*NodeMaintenance, int, error) {
+	where := ``
+	args := []interface{}{}
+	if nodeID > 0 {
+		where = ` WHERE node_id = ?`
+		args = append(args, nodeID)
+	}
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM node_maintenances`+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	var list []*NodeMaintenance
+	query := `SELECT * FROM node_maintenances` + where + ` ORDER BY start_at DESC, id DESC LIMIT ? OFFSET ?`
+	if err := r.db.SelectContext(ctx, &list, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ListActive 获取在指定时间生效的维护计划
+func (r *nodeMaintenanceRepository) ListActive(ctx context.Context, now time.Time) ([]*NodeMaintenance, error) {
+	query := `SELECT * FROM node_maintenances WHERE canceled = 0 AND start_at <= ? AND end_at > ? ORDER BY start_at ASC`
+	var list []*NodeMaintenance
+	err := r.db.SelectContext(ctx, &list, query, now, now)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListOverlapping 获取节点与 [start, end) 重叠且未取消的维护计划
+func (r *nodeMaintenanceRepository) ListOverlapping(ctx context.Context, nodeID int64, start, end time.Time) ([]*NodeMaintenance, error) {
+	query := `SELECT * FROM node_maintenances WHERE node_id = ? AND canceled = 0 AND start_at < ? AND end_at > ?`
+	var list []*NodeMaintenance
+	err := r.db.SelectContext(ctx, &list, query, nodeID, end, start)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListToNotify 获取尚未通知、未结束且在 before 之前开始的维护计划
+func (r *nodeMaintenanceRepository) ListToNotify(ctx context.Context, now, before time.Time) ([]*NodeMaintenance, error) {
+	query := `SELECT * FROM node_maintenances WHERE canceled = 0 AND notified_at IS NULL AND start_at <= ? AND end_at > ? ORDER BY start_at ASC`
+	var list []*NodeMaintenance
+	err := r.db.SelectContext(ctx, &list, query, before, now)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// MarkNotified 记录维护计划已通知，已被标记过时返回false
+func (r *nodeMaintenanceRepository) MarkNotified(ctx context.Context, id int64, at time.Time) (bool, error) {
+	query := `UPDATE node_maintenances SET notified_at = ? WHERE id = ? AND notified_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Cancel 取消维护计划
+func (r *nodeMaintenanceRepository) Cancel(ctx context.Context, id int64) error {
+	query := `UPDATE node_maintenances SET canceled = 1 WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NodeReview 捐赠节点审核记录
+type NodeReview struct {
+	ID         int64         `db:"id" json:"id"`
+	NodeID     int64         `db:"node_id" json:"node_id"`
+	Action     string        `db:"action" json:"action"`
+	FromStatus int           `db:"from_status" json:"from_status"`
+	ToStatus   int           `db:"to_status" json:"to_status"`
+	ReviewerID sql.NullInt64 `db:"reviewer_id" json:"-"`
+	Note       string        `db:"note" json:"note"`
+	Reason     string        `db:"reason" json:"reason"`
+	CreatedAt  time.Time     `db:"created_at" json:"created_at"`
+}
+
+// NodeReviewRepository 节点审核记录仓库接口
+type NodeReviewRepository interface {
+	Create(ctx context.Context, review *NodeReview) error
+	ListByNode(ctx context.Context, nodeID int64) ([]*NodeReview, error)
+	GetLatest(ctx context.Context, nodeID int64) (*NodeReview, error)
+}
+
+// nodeReviewRepository 节点审核记录仓库实现
+type nodeReviewRepository struct {
+	db *sqlx.DB
+}
+
+// NewNodeReviewRepository 创建节点审核记录仓库实例
+func NewNodeReviewRepository(db *sqlx.DB) NodeReviewRepository {
+	return &nodeReviewRepository{db: db}
+}
+
+// Create 保存审核记录
+func (r *nodeReviewRepository) Create(ctx context.Context, review *NodeReview) error {
+	query := `INSERT INTO node_reviews (node_id, action, from_status, to_status, reviewer_id, note, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if review.CreatedAt.IsZero() {
+		review.CreatedAt = time.Now()
+	}
+	result, err := r.db.ExecContext(ctx, query, review.NodeID, review.Action, review.FromStatus, review.ToStatus,
+		review.ReviewerID, review.Note, review.Reason, review.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	review.ID = id
+	return nil
+}
+
+// ListByNode 获取节点的全部审核记录，按时间倒序
+func (r *nodeReviewRepository) ListByNode(ctx context.Context, nodeID int64) ([]*NodeReview, error) {
+	list := []*NodeReview{}
+	query := `SELECT * FROM node_reviews WHERE node_id = ? ORDER BY id DESC`
+	if err := r.db.SelectContext(ctx, &list, query, nodeID); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetLatest 获取节点最近一次审核记录，没有记录时返回nil
+func (r *nodeReviewRepository) GetLatest(ctx context.Context, nodeID int64) (*NodeReview, error) {
+	var review NodeReview
+	err := r.db.GetContext(ctx, &review, `SELECT * FROM node_reviews WHERE node_id = ? ORDER BY id DESC LIMIT 1`, nodeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &review, nil
+}
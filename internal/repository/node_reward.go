@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NodeRewardRule 捐赠节点贡献奖励规则
+type NodeRewardRule struct {
+	ID           int64     `db:"id" json:"id"`
+	Name         string    `db:"name" json:"name"`
+	Enabled      bool      `db:"enabled" json:"enabled"`
+	MinTraffic   int64     `db:"min_traffic" json:"min_traffic"`     // 当日最低承载流量(字节)
+	MinUptime    float64   `db:"min_uptime" json:"min_uptime"`       // 当日最低在线率(百分比)
+	RewardType   string    `db:"reward_type" json:"reward_type"`     // traffic/tunnel/group
+	RewardValue  int64     `db:"reward_value" json:"reward_value"`   // 流量字节数、隧道数或目标用户组ID
+	BaseGroupID  int64     `db:"base_group_id" json:"base_group_id"` // 用户组升级仅对该用户组生效
+	DurationDays int       `db:"duration_days" json:"duration_days"` // 用户组升级每次发放的天数
+	MonthlyCap   int64     `db:"monthly_cap" json:"monthly_cap"`     // 每个用户每月上限，0表示不限制
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// NodeRewardCredit 贡献奖励流水
+type NodeRewardCredit struct {
+	ID          int64      `db:"id" json:"id"`
+	NodeID      int64      `db:"node_id" json:"node_id"`
+	UserID      int64      `db:"user_id" json:"user_id"`
+	RuleID      int64      `db:"rule_id" json:"rule_id"`
+	RewardDate  time.Time  `db:"reward_date" json:"reward_date"`
+	RewardType  string     `db:"reward_type" json:"reward_type"`
+	Amount      int64      `db:"amount" json:"amount"`
+	GroupID     *int64     `db:"group_id" json:"group_id"`
+	PrevGroupID *int64     `db:"prev_group_id" json:"prev_group_id"`
+	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at"`
+	Traffic     int64      `db:"traffic" json:"traffic"`
+	Uptime      float64    `db:"uptime" json:"uptime"`
+	Status      string     `db:"status" json:"status"`
+	Reason      string     `db:"reason" json:"reason"`
+	OperatorID  *int64     `db:"operator_id" json:"operator_id"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// NodeRewardRepository 贡献奖励仓库接口
+type NodeRewardRepository interface {
+	CreateRule(ctx context.Context, rule *NodeRewardRule) error
+	UpdateRule(ctx context.Context, rule *NodeRewardRule) error
+	DeleteRule(ctx context.Context, id int64) error
+	GetRule(ctx context.Context, id int64) (*NodeRewardRule, error)
+	ListRules(ctx context.Context, enabledOnly bool) ([]*NodeRewardRule, error)
+	CreateCredit(ctx context.Context, credit *NodeRewardCredit) error
+	CreditExists(ctx context.Context, nodeID, ruleID int64, date time.Time) (bool, error)
+	SumCredited(ctx context.Context, userID, ruleID int64, from, to time.Time) (int64, error)
+	ListCredits(ctx context.Context, userID, nodeID int64, offset, limit int) ([]*NodeRewardCredit, int, error)
+	ListCreditedByNode(ctx context.Context, nodeID int64) ([]*NodeRewardCredit, error)
+	ListExpiredUpgrades(ctx context.Context, now time.Time) ([]*NodeRewardCredit, error)
+	UpdateCreditStatus(ctx context.Context, id int64, status, reason string, operatorID *int64) error
+}
+
+// nodeRewardRepository 贡献奖励仓库实现
+type nodeRewardRepository struct {
+	db *sqlx.DB
+}
+
+// NewNodeRewardRepository 创建贡献奖励仓库实例
+func NewNodeRewardRepository(db *sqlx.DB) NodeRewardRepository {
+	return &nodeRewardRepository{db: db}
+}
+
+// CreateRule 创建奖励规则
+func (r *nodeRewardRepository) CreateRule(ctx context.Context, rule *NodeRewardRule) error {
+	query := `INSERT INTO node_reward_rules (name, enabled, min_traffic, min_uptime, reward_type, reward_value, base_group_id, duration_days, monthly_cap, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`
+	result, err := r.db.ExecContext(ctx, query, rule.Name, rule.Enabled, rule.MinTraffic, rule.MinUptime,
+		rule.RewardType, rule.RewardValue, rule.BaseGroupID, rule.DurationDays, rule.MonthlyCap)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rule.ID = id
+	return nil
+}
+
+// UpdateRule 更新奖励规则
+func (r *nodeRewardRepository) UpdateRule(ctx context.Context, rule *NodeRewardRule) error {
+	query := `UPDATE node_reward_rules SET name = ?, enabled = ?, min_traffic = ?, min_uptime = ?, reward_type = ?, reward_value = ?,
+		base_group_id = ?, duration_days = ?, monthly_cap = ?, updated_at = NOW() WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, rule.Name, rule.Enabled, rule.MinTraffic, rule.MinUptime,
+		rule.RewardType, rule.RewardValue, rule.BaseGroupID, rule.DurationDays, rule.MonthlyCap, rule.ID)
+	return err
+}
+
+// DeleteRule 删除奖励规则，已发放的流水保留
+func (r *nodeRewardRepository) DeleteRule(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM node_reward_rules WHERE id = ?`, id)
+	return err
+}
+
+// GetRule 根据ID获取奖励规则，不存在时返回nil
+func (r *nodeRewardRepository) GetRule(ctx context.Context, id int64) (*NodeRewardRule, error) {
+	var rule NodeRewardRule
+	err := r.db.GetContext(ctx, &rule, `SELECT * FROM node_reward_rules WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListRules 获取奖励规则列表
+func (r *nodeRewardRepository) ListRules(ctx context.Context, enabledOnly bool) ([]*NodeRewardRule, error) {
+	query := `SELECT * FROM node_reward_rules`
+	if enabledOnly {
+		query += ` WHERE enabled = 1`
+	}
+	query += ` ORDER BY id ASC`
+	rules := []*NodeRewardRule{}
+	if err := r.db.SelectContext(ctx, &rules, query); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreateCredit 创建奖励流水，同一节点同一规则同一天只能发放一次
+func (r *nodeRewardRepository) CreateCredit(ctx context.Context, credit *NodeRewardCredit) error {
+	query := `INSERT INTO node_reward_credits (node_id, user_id, rule_id, reward_date, reward_type, amount, group_id, prev_group_id, expires_at, traffic, uptime, status, reason, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`
+	result, err := r.db.ExecContext(ctx, query, credit.NodeID, credit.UserID, credit.RuleID, credit.RewardDate.Format("2006-01-02"),
+		credit.RewardType, credit.Amount, credit.GroupID, credit.PrevGroupID, credit.ExpiresAt,
+		credit.Traffic, credit.Uptime, credit.Status, credit.Reason)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	credit.ID = id
+	return nil
+}
+
+// CreditExists 检查节点在指定日期是否已按规则发放过奖励
+func (r *nodeRewardRepository) CreditExists(ctx context.Context, nodeID, ruleID int64, date time.Time) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM node_reward_credits WHERE node_id = ? AND rule_id = ? AND reward_date = ?`
+	if err := r.db.GetContext(ctx, &count, query, nodeID, ruleID, date.Format("2006-01-02")); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SumCredited 统计用户在日期范围 [from, to] 内从规则获得且未被追回的奖励数量
+func (r *nodeRewardRepository) SumCredited(ctx context.Context, userID, ruleID int64, from, to time.Time) (int64, error) {
+	var total int64
+	query := `SELECT COALESCE(SUM(amount), 0) FROM node_reward_credits
+		WHERE user_id = ? AND rule_id = ? AND reward_date >= ? AND reward_date <= ? AND status IN ('credited', 'expired')`
+	err := r.db.GetContext(ctx, &total, query, userID, ruleID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	return total, err
+}
+
+// ListCredits 分页获取奖励流水（按时间倒序），userID、nodeID 为0时不限制
+func (r *nodeRewardRepository) ListCredits(ctx context.Context, userID, nodeID int64, offset, limit int) ([]*NodeRewardCredit, int, error) {
+	var conds []string
+	args := []interface{}{}
+	if userID > 0 {
+		conds = append(conds, `user_id = ?`)
+		args = append(args, userID)
+	}
+	if nodeID > 0 {
+		conds = append(conds, `node_id = ?`)
+		args = append(args, nodeID)
+	}
+	where := ``
+	if len(conds) > 0 {
+		where = ` WHERE ` + strings.Join(conds, ` AND `)
+	}
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM node_reward_credits`+where, args...); err != nil {
+		return nil, 0, err
+	}
+
+	list := []*NodeRewardCredit{}
+	query := `SELECT * FROM node_reward_credits` + where + ` ORDER BY reward_date DESC, id DESC LIMIT ? OFFSET ?`
+	if err := r.db.SelectContext(ctx, &list, query, append(args, limit, offset)...); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ListCreditedByNode 获取节点已发放且仍有效的奖励流水
+func (r *nodeRewardRepository) ListCreditedByNode(ctx context.Context, nodeID int64) ([]*NodeRewardCredit, error) {
+	list := []*NodeRewardCredit{}
+	query := `SELECT * FROM node_reward_credits WHERE node_id = ? AND status = 'credited' ORDER BY id ASC`
+	if err := r.db.SelectContext(ctx, &list, query, nodeID); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListExpiredUpgrades 获取已到期但尚未处理的用户组升级奖励
+func (r *nodeRewardRepository) ListExpiredUpgrades(ctx context.Context, now time.Time) ([]*NodeRewardCredit, error) {
+	list := []*NodeRewardCredit{}
+	query := `SELECT * FROM node_reward_credits WHERE reward_type = 'group' AND status = 'credited' AND expires_at <= ? ORDER BY expires_at ASC`
+	if err := r.db.SelectContext(ctx, &list, query, now); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateCreditStatus 更新奖励流水状态
+func (r *nodeRewardRepository) UpdateCreditStatus(ctx context.Context, id int64, status, reason string, operatorID *int64) error {
+	query := `UPDATE node_reward_credits SET status = ?, reason = ?, operator_id = ?, updated_at = NOW() WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, status, reason, operatorID, id)
+	return err
+}
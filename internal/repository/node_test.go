@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+	"stellarfrp/pkg/secret"
+	"strings"
+	"testing"
+)
+
+// newKeyrings 生成 k1、k2 两个随机密钥，返回只含k1的旧密钥环和以k2为当前密钥、保留k1的新密钥环
+func newKeyrings(t *testing.T) (old, current *secret.Keyring) {
+	t.Helper()
+	k1, err := secret.GenerateKey("k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := secret.GenerateKey("k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old, err = secret.ParseKeyring(k1); err != nil {
+		t.Fatal(err)
+	}
+	if current, err = secret.ParseKeyring(k2 + "," + k1); err != nil {
+		t.Fatal(err)
+	}
+	return old, current
+}
+
+func TestRotateSecretValues(t *testing.T) {
+	old, keyring := newKeyrings(t)
+	oldToken, _ := old.Encrypt("token")
+	currentKey, _ := keyring.Encrypt("agent")
+
+	// 旧密钥密文、当前密钥密文和明文混合
+	values, changed, err := rotateSecretValues(keyring, [3]string{oldToken, currentKey, "plugin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("存在需要轮换的凭据时应返回changed")
+	}
+	if values[1] != currentKey {
+		t.Error("当前密钥的密文不应重新加密")
+	}
+	for i, want := range []string{"token", "agent", "plugin"} {
+		if !strings.HasPrefix(values[i], "enc:v1:k2:") {
+			t.Errorf("values[%d] = %q，应使用当前密钥加密", i, values[i])
+		}
+		if got, err := keyring.Decrypt(values[i]); err != nil || got != want {
+			t.Errorf("values[%d] 解密为 %q, %v; want %q", i, got, err, want)
+		}
+	}
+
+	values, changed, err = rotateSecretValues(keyring, values)
+	if err != nil || changed {
+		t.Errorf("已轮换的凭据不应再次更新, changed = %v, err = %v", changed, err)
+	}
+
+	empty, changed, err := rotateSecretValues(keyring, [3]string{"", "", ""})
+	if err != nil || changed || empty != [3]string{} {
+		t.Errorf("空凭据不应更新, got %v, changed = %v, err = %v", empty, changed, err)
+	}
+}
+
+func TestRotateSecretValuesUnknownKey(t *testing.T) {
+	// 使用k2加密的数据，只含k1的密钥环无法解密
+	old, current := newKeyrings(t)
+	sealed, _ := current.Encrypt("token")
+
+	if _, _, err := rotateSecretValues(old, [3]string{sealed, "", ""}); !errors.Is(err, secret.ErrUnknownKey) {
+		t.Errorf("err = %v, want ErrUnknownKey", err)
+	}
+}
@@ -26,6 +26,7 @@ type NodeTrafficRepository interface {
 	GetDailyRecord(ctx context.Context, nodeName string, date string) (*NodeTrafficLog, error)
 	UpdateRecord(ctx context.Context, id int64, trafficIn, trafficOut int64, onlineCount int) error
 	GetTotalTraffic(ctx context.Context) (int64, int64, error)
+	ListByDateRange(ctx context.Context, nodeName string, startDate, endDate string) ([]*NodeTrafficLog, error)
 }
 
 // nodeTrafficRepository 节点流量仓库实现
@@ -83,6 +84,17 @@ func (r *nodeTrafficRepository) UpdateRecord(ctx context.Context, id int64, traf
 	return err
 }
 
+// ListByDateRange 获取指定节点在日期范围内的流量记录，按日期升序排列
+func (r *nodeTrafficRepository) ListByDateRange(ctx context.Context, nodeName string, startDate, endDate string) ([]*NodeTrafficLog, error) {
+	query := `SELECT * FROM node_traffic_log WHERE node_name = ? AND record_date >= ? AND record_date <= ? ORDER BY record_date ASC`
+	logs := []*NodeTrafficLog{}
+	err := r.db.SelectContext(ctx, &logs, query, nodeName, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
 // 以下是为了兼容旧代码，保留的方法
 // GetTodayIncrement 获取指定节点当天的流量记录 (兼容旧方法)
 func (r *nodeTrafficRepository) GetTodayIncrement(ctx context.Context, nodeName string, date string) (*NodeTrafficLog, error) {
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NodeValidationCheck 单项检查结果
+type NodeValidationCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// NodeValidation 捐赠节点自动校验记录
+type NodeValidation struct {
+	ID         int64                 `db:"id" json:"id"`
+	NodeID     int64                 `db:"node_id" json:"node_id"`
+	Passed     bool                  `db:"passed" json:"passed"`
+	Checks     string                `db:"checks" json:"-"` // JSON格式的检查结果
+	Trigger    string                `db:"trigger_type" json:"trigger"`
+	OperatorID sql.NullInt64         `db:"operator_id" json:"-"`
+	CreatedAt  time.Time             `db:"created_at" json:"created_at"`
+	Results    []NodeValidationCheck `db:"-" json:"checks"`
+}
+
+// NodeValidationRepository 节点校验记录仓库接口
+type NodeValidationRepository interface {
+	Create(ctx context.Context, v *NodeValidation) error
+	GetLatest(ctx context.Context, nodeID int64) (*NodeValidation, error)
+	ListByNode(ctx context.Context, nodeID int64, limit int) ([]*NodeValidation, error)
+}
+
+// nodeValidationRepository 节点校验记录仓库实现
+type nodeValidationRepository struct {
+	db *sqlx.DB
+}
+
+// NewNodeValidationRepository 创建节点校验记录仓库实例
+func NewNodeValidationRepository(db *sqlx.DB) NodeValidationRepository {
+	return &nodeValidationRepository{db: db}
+}
+
+// Create 保存校验记录
+func (r *nodeValidationRepository) Create(ctx context.Context, v *NodeValidation) error {
+	query := `INSERT INTO node_validations (node_id, passed, checks, trigger_type, operator_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now()
+	}
+	result, err := r.db.ExecContext(ctx, query, v.NodeID, v.Passed, v.Checks, v.Trigger, v.OperatorID, v.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	v.ID = id
+	return nil
+}
+
+// GetLatest 获取节点最近一次校验记录，没有记录时返回nil
+func (r *nodeValidationRepository) GetLatest(ctx context.Context, nodeID int64) (*NodeValidation, error) {
+	query := `SELECT * FROM node_validations WHERE node_id = ? ORDER BY id DESC LIMIT 1`
+	var v NodeValidation
+	err := r.db.GetContext(ctx, &v, query, nodeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListByNode 获取节点最近的校验记录，按时间倒序
+func (r *nodeValidationRepository) ListByNode(ctx context.Context, nodeID int64, limit int) ([]*NodeValidation, error) {
+	query := `SELECT * FROM node_validations WHERE node_id = ? ORDER BY id DESC LIMIT ?`
+	var list []*NodeValidation
+	err := r.db.SelectContext(ctx, &list, query, nodeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -40,9 +41,13 @@ type ProxyRepository interface {
 	Delete(ctx context.Context, id int64) error
 	List(ctx context.Context, offset, limit int) ([]*Proxy, error)
 	ListByStatus(ctx context.Context, status string, offset, limit int) ([]*Proxy, error)
+	ListByNode(ctx context.Context, nodeID int64) ([]*Proxy, error)
 	Count(ctx context.Context) (int, error)
 	CountByStatus(ctx context.Context, status string) (int, error)
 	IsRemotePortUsed(ctx context.Context, nodeID int64, proxyType string, remotePort string) (bool, error)
+	CountRemotePorts(ctx context.Context, nodeID int64, proxyType string) (int, error)
+	CountByNodeGroupByType(ctx context.Context, nodeID int64) (map[string]int, error)
+	ListOnlineRunIDs(ctx context.Context, nodeID int64) ([]string, error)
 }
 
 // proxyRepository 隧道仓库实现
@@ -177,6 +182,17 @@ func (r *proxyRepository) ListByStatus(ctx context.Context, status string, offse
 	return proxies, nil
 }
 
+// ListByNode 获取节点下的全部隧道
+func (r *proxyRepository) ListByNode(ctx context.Context, nodeID int64) ([]*Proxy, error) {
+	query := `SELECT * FROM proxy WHERE node = ? ORDER BY id ASC`
+	var proxies []*Proxy
+	err := r.db.SelectContext(ctx, &proxies, query, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return proxies, nil
+}
+
 // Count 获取隧道总数
 func (r *proxyRepository) Count(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM proxy`
@@ -209,3 +225,43 @@ func (r *proxyRepository) IsRemotePortUsed(ctx context.Context, nodeID int64, pr
 	}
 	return count > 0, nil
 }
+
+// CountRemotePorts 统计节点下指定协议类型已占用的远程端口数量
+func (r *proxyRepository) CountRemotePorts(ctx context.Context, nodeID int64, proxyType string) (int, error) {
+	query := `SELECT COUNT(DISTINCT remote_port) FROM proxy WHERE node = ? AND proxy_type = ?`
+	var count int
+	err := r.db.GetContext(ctx, &count, query, nodeID, proxyType)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountByNodeGroupByType 按协议类型统计节点下的隧道数量
+func (r *proxyRepository) CountByNodeGroupByType(ctx context.Context, nodeID int64) (map[string]int, error) {
+	query := `SELECT proxy_type, COUNT(*) AS count FROM proxy WHERE node = ? GROUP BY proxy_type`
+	var rows []struct {
+		ProxyType string `db:"proxy_type"`
+		Count     int    `db:"count"`
+	}
+	err := r.db.SelectContext(ctx, &rows, query, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[strings.ToLower(row.ProxyType)] += row.Count
+	}
+	return counts, nil
+}
+
+// ListOnlineRunIDs 获取节点上在线隧道所属客户端的运行ID，每个运行ID对应一个在线客户端
+func (r *proxyRepository) ListOnlineRunIDs(ctx context.Context, nodeID int64) ([]string, error) {
+	query := `SELECT DISTINCT runID FROM proxy WHERE node = ? AND status = 'online' AND runID IS NOT NULL AND runID <> ''`
+	var runIDs []string
+	err := r.db.SelectContext(ctx, &runIDs, query, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return runIDs, nil
+}
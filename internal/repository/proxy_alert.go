@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"stellarfrp/pkg/secret"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProxyAlert 隧道离线告警配置与告警状态
+type ProxyAlert struct {
+	ProxyID          int64        `db:"proxy_id" json:"proxy_id"`
+	Username         string       `db:"username" json:"username"`
+	OfflineMinutes   int          `db:"offline_minutes" json:"offline_minutes"`
+	FlapThreshold    int          `db:"flap_threshold" json:"flap_threshold"`
+	NotifyEmail      bool         `db:"notify_email" json:"notify_email"`
+	WebhookURL       string       `db:"webhook_url" json:"webhook_url"`
+	WebhookSecret    string       `db:"webhook_secret" json:"-"` // Webhook签名密钥，加密存储
+	OfflineAlertedAt sql.NullTime `db:"offline_alerted_at" json:"offline_alerted_at"`
+	FlapAlertedAt    sql.NullTime `db:"flap_alerted_at" json:"flap_alerted_at"`
+	UpdatedAt        time.Time    `db:"updated_at" json:"updated_at"`
+}
+
+// ProxyAlertRepository 隧道告警仓库接口
+type ProxyAlertRepository interface {
+	GetByProxyID(ctx context.Context, proxyID int64) (*ProxyAlert, error)
+	Upsert(ctx context.Context, alert *ProxyAlert) error
+	ListActive(ctx context.Context) ([]*ProxyAlert, error)
+	UpdateOfflineAlertedAt(ctx context.Context, proxyID int64, alertedAt sql.NullTime) error
+	UpdateFlapAlertedAt(ctx context.Context, proxyID int64, alertedAt sql.NullTime) error
+	RotateSecrets(ctx context.Context) (int, error)
+	DeleteByProxyID(ctx context.Context, proxyID int64) error
+}
+
+// proxyAlertRepository 隧道告警仓库实现
+type proxyAlertRepository struct {
+	db      *sqlx.DB
+	keyring *secret.Keyring
+}
+
+// NewProxyAlertRepository 创建隧道告警仓库实例，Webhook签名密钥使用 keyring 加密后写库
+func NewProxyAlertRepository(db *sqlx.DB, keyring *secret.Keyring) ProxyAlertRepository {
+	return &proxyAlertRepository{db: db, keyring: keyring}
+}
+
+// GetByProxyID 获取隧道的告警配置
+func (r *proxyAlertRepository) GetByProxyID(ctx context.Context, proxyID int64) (*ProxyAlert, error) {
+	query := `SELECT * FROM proxy_alerts WHERE proxy_id = ?`
+	var alert ProxyAlert
+	err := r.db.GetContext(ctx, &alert, query, proxyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// Upsert 创建或更新隧道的告警配置，不修改告警状态
+func (r *proxyAlertRepository) Upsert(ctx context.Context, alert *ProxyAlert) error {
+	sealed, err := r.keyring.Encrypt(alert.WebhookSecret)
+	if err != nil {
+		return err
+	}
+	alert.WebhookSecret = sealed
+
+	query := `INSERT INTO proxy_alerts (proxy_id, username, offline_minutes, flap_threshold, notify_email, webhook_url, webhook_secret, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE username = VALUES(username), offline_minutes = VALUES(offline_minutes),
+			flap_threshold = VALUES(flap_threshold), notify_email = VALUES(notify_email),
+			webhook_url = VALUES(webhook_url), webhook_secret = VALUES(webhook_secret), updated_at = CURRENT_TIMESTAMP`
+	_, err = r.db.ExecContext(ctx, query, alert.ProxyID, alert.Username, alert.OfflineMinutes, alert.FlapThreshold,
+		alert.NotifyEmail, alert.WebhookURL, alert.WebhookSecret)
+	return err
+}
+
+// ListActive 获取开启了告警规则和至少一种通知方式的配置，已删除的隧道不会返回
+func (r *proxyAlertRepository) ListActive(ctx context.Context) ([]*ProxyAlert, error) {
+	query := `SELECT a.* FROM proxy_alerts a
+		INNER JOIN proxy p ON p.id = a.proxy_id AND p.username = a.username
+		WHERE (a.offline_minutes > 0 OR a.flap_threshold > 0) AND (a.notify_email = 1 OR a.webhook_url <> '')`
+	var alerts []*ProxyAlert
+	err := r.db.SelectContext(ctx, &alerts, query)
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// UpdateOfflineAlertedAt 更新离线告警状态
+func (r *proxyAlertRepository) UpdateOfflineAlertedAt(ctx context.Context, proxyID int64, alertedAt sql.NullTime) error {
+	query := `UPDATE proxy_alerts SET offline_alerted_at = ?, updated_at = updated_at WHERE proxy_id = ?`
+	_, err := r.db.ExecContext(ctx, query, alertedAt, proxyID)
+	return err
+}
+
+// UpdateFlapAlertedAt 更新频繁掉线告警状态
+func (r *proxyAlertRepository) UpdateFlapAlertedAt(ctx context.Context, proxyID int64, alertedAt sql.NullTime) error {
+	query := `UPDATE proxy_alerts SET flap_alerted_at = ?, updated_at = updated_at WHERE proxy_id = ?`
+	_, err := r.db.ExecContext(ctx, query, alertedAt, proxyID)
+	return err
+}
+
+// RotateSecrets 用当前密钥重新加密明文或使用旧密钥加密的Webhook签名密钥，返回更新的配置数
+// 更新时校验密钥未被并发修改，被修改的配置已使用当前密钥加密，直接跳过
+func (r *proxyAlertRepository) RotateSecrets(ctx context.Context) (int, error) {
+	if !r.keyring.Enabled() {
+		return 0, secret.ErrNoKey
+	}
+
+	var rows []struct {
+		ProxyID       int64  `db:"proxy_id"`
+		WebhookSecret string `db:"webhook_secret"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, `SELECT proxy_id, webhook_secret FROM proxy_alerts WHERE webhook_secret <> ''`); err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, row := range rows {
+		if !r.keyring.NeedsRotation(row.WebhookSecret) {
+			continue
+		}
+		sealed, err := r.keyring.Rotate(row.WebhookSecret)
+		if err != nil {
+			return rotated, fmt.Errorf("解密隧道 %d 的Webhook签名密钥失败: %w", row.ProxyID, err)
+		}
+
+		query := `UPDATE proxy_alerts SET webhook_secret = ?, updated_at = updated_at WHERE proxy_id = ? AND webhook_secret = ?`
+		result, err := r.db.ExecContext(ctx, query, sealed, row.ProxyID, row.WebhookSecret)
+		if err != nil {
+			return rotated, err
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			rotated++
+		}
+	}
+	return rotated, nil
+}
+
+// DeleteByProxyID 删除隧道的告警配置
+func (r *proxyAlertRepository) DeleteByProxyID(ctx context.Context, proxyID int64) error {
+	query := `DELETE FROM proxy_alerts WHERE proxy_id = ?`
+	_, err := r.db.ExecContext(ctx, query, proxyID)
+	return err
+}
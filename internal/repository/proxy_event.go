@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProxyEvent 隧道上下线事件
+type ProxyEvent struct {
+	ID        int64     `db:"id" json:"id"`
+	ProxyID   int64     `db:"proxy_id" json:"proxy_id"`
+	NodeID    int64     `db:"node_id" json:"node_id"`
+	Username  string    `db:"username" json:"username"`
+	Status    string    `db:"status" json:"status"`
+	Reason    string    `db:"reason" json:"reason"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// ProxyEventRepository 隧道事件仓库接口
+type ProxyEventRepository interface {
+	Create(ctx context.Context, event *ProxyEvent) error
+	ListByProxyID(ctx context.Context, proxyID int64, offset, limit int) ([]*ProxyEvent, error)
+	CountByProxyID(ctx context.Context, proxyID int64) (int, error)
+	ListByProxyIDSince(ctx context.Context, proxyID int64, since time.Time) ([]*ProxyEvent, error)
+	GetLastBefore(ctx context.Context, proxyID int64, before time.Time) (*ProxyEvent, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteByProxyID(ctx context.Context, proxyID int64) error
+}
+
+// proxyEventRepository 隧道事件仓库实现
+type proxyEventRepository struct {
+	db *sqlx.DB
+}
+
+// NewProxyEventRepository 创建隧道事件仓库实例
+func NewProxyEventRepository(db *sqlx.DB) ProxyEventRepository {
+	return &proxyEventRepository{db: db}
+}
+
+// Create 写入隧道事件
+func (r *proxyEventRepository) Create(ctx context.Context, event *ProxyEvent) error {
+	query := `INSERT INTO proxy_events (proxy_id, node_id, username, status, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	result, err := r.db.ExecContext(ctx, query,
+		event.ProxyID, event.NodeID, event.Username, event.Status, event.Reason, event.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	event.ID = id
+	return nil
+}
+
+// ListByProxyID 获取隧道事件列表（按时间倒序）
+func (r *proxyEventRepository) ListByProxyID(ctx context.Context, proxyID int64, offset, limit int) ([]*ProxyEvent, error) {
+	query := `SELECT * FROM proxy_events WHERE proxy_id = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	var events []*ProxyEvent
+	err := r.db.SelectContext(ctx, &events, query, proxyID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// CountByProxyID 获取隧道事件数量
+func (r *proxyEventRepository) CountByProxyID(ctx context.Context, proxyID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM proxy_events WHERE proxy_id = ?`
+	var count int
+	err := r.db.GetContext(ctx, &count, query, proxyID)
+	return count, err
+}
+
+// ListByProxyIDSince 获取指定时间之后的隧道事件（按时间正序）
+func (r *proxyEventRepository) ListByProxyIDSince(ctx context.Context, proxyID int64, since time.Time) ([]*ProxyEvent, error) {
+	query := `SELECT * FROM proxy_events WHERE proxy_id = ? AND created_at >= ? ORDER BY created_at ASC, id ASC`
+	var events []*ProxyEvent
+	err := r.db.SelectContext(ctx, &events, query, proxyID, since)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// GetLastBefore 获取指定时间之前的最后一条隧道事件
+func (r *proxyEventRepository) GetLastBefore(ctx context.Context, proxyID int64, before time.Time) (*ProxyEvent, error) {
+	query := `SELECT * FROM proxy_events WHERE proxy_id = ? AND created_at < ? ORDER BY created_at DESC, id DESC LIMIT 1`
+	var event ProxyEvent
+	err := r.db.GetContext(ctx, &event, query, proxyID, before)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+// DeleteBefore 删除指定时间之前的事件，返回删除条数
+// 每个隧道保留该时间之前的最后一条事件，作为计算在线率时窗口起点的状态
+func (r *proxyEventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE e FROM proxy_events e
+		JOIN (SELECT proxy_id, MAX(created_at) AS last_at FROM proxy_events WHERE created_at < ? GROUP BY proxy_id) k
+		ON e.proxy_id = k.proxy_id
+		WHERE e.created_at < k.last_at`
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByProxyID 删除隧道的全部事件
+func (r *proxyEventRepository) DeleteByProxyID(ctx context.Context, proxyID int64) error {
+	query := `DELETE FROM proxy_events WHERE proxy_id = ?`
+	_, err := r.db.ExecContext(ctx, query, proxyID)
+	return err
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProxyPreset 用户隧道预设
+type ProxyPreset struct {
+	ID                int64     `db:"id" json:"id"`
+	Username          string    `db:"username" json:"username"`
+	Name              string    `db:"name" json:"name"`
+	ProxyType         string    `db:"proxy_type" json:"proxy_type"`
+	LocalIP           string    `db:"local_ip" json:"local_ip"`
+	LocalPort         int       `db:"local_port" json:"local_port"`
+	UseEncryption     bool      `db:"use_encryption" json:"use_encryption"`
+	UseCompression    bool      `db:"use_compression" json:"use_compression"`
+	HostHeaderRewrite string    `db:"host_header_rewrite" json:"host_header_rewrite"`
+	HeaderXFromWhere  string    `db:"header_x_from_where" json:"header_x_from_where"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ProxyPresetRepository 隧道预设仓库接口
+type ProxyPresetRepository interface {
+	Create(ctx context.Context, preset *ProxyPreset) (int64, error)
+	GetByID(ctx context.Context, id int64) (*ProxyPreset, error)
+	GetByUsernameAndName(ctx context.Context, username, name string) (*ProxyPreset, error)
+	ListByUsername(ctx context.Context, username string) ([]*ProxyPreset, error)
+	CountByUsername(ctx context.Context, username string) (int, error)
+	Update(ctx context.Context, preset *ProxyPreset) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// proxyPresetRepository 隧道预设仓库实现
+type proxyPresetRepository struct {
+	db *sqlx.DB
+}
+
+// NewProxyPresetRepository 创建隧道预设仓库实例
+func NewProxyPresetRepository(db *sqlx.DB) ProxyPresetRepository {
+	return &proxyPresetRepository{db: db}
+}
+
+// Create 创建隧道预设
+func (r *proxyPresetRepository) Create(ctx context.Context, preset *ProxyPreset) (int64, error) {
+	query := `INSERT INTO proxy_presets
+		(username, name, proxy_type, local_ip, local_port, use_encryption, use_compression, host_header_rewrite, header_x_from_where)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := r.db.ExecContext(ctx, query,
+		preset.Username, preset.Name, preset.ProxyType, preset.LocalIP, preset.LocalPort,
+		preset.UseEncryption, preset.UseCompression, preset.HostHeaderRewrite, preset.HeaderXFromWhere)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByID 根据ID获取隧道预设
+func (r *proxyPresetRepository) GetByID(ctx context.Context, id int64) (*ProxyPreset, error) {
+	query := `SELECT * FROM proxy_presets WHERE id = ?`
+	var preset ProxyPreset
+	err := r.db.GetContext(ctx, &preset, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// GetByUsernameAndName 根据用户名和预设名称获取隧道预设
+func (r *proxyPresetRepository) GetByUsernameAndName(ctx context.Context, username, name string) (*ProxyPreset, error) {
+	query := `SELECT * FROM proxy_presets WHERE username = ? AND name = ?`
+	var preset ProxyPreset
+	err := r.db.GetContext(ctx, &preset, query, username, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// ListByUsername 获取用户的全部隧道预设
+func (r *proxyPresetRepository) ListByUsername(ctx context.Context, username string) ([]*ProxyPreset, error) {
+	query := `SELECT * FROM proxy_presets WHERE username = ? ORDER BY id ASC`
+	var presets []*ProxyPreset
+	err := r.db.SelectContext(ctx, &presets, query, username)
+	if err != nil {
+		return nil, err
+	}
+	return presets, nil
+}
+
+// CountByUsername 获取用户的隧道预设数量
+func (r *proxyPresetRepository) CountByUsername(ctx context.Context, username string) (int, error) {
+	query := `SELECT COUNT(*) FROM proxy_presets WHERE username = ?`
+	var count int
+	err := r.db.GetContext(ctx, &count, query, username)
+	return count, err
+}
+
+// Update 更新隧道预设
+func (r *proxyPresetRepository) Update(ctx context.Context, preset *ProxyPreset) error {
+	query := `UPDATE proxy_presets SET
+		name = ?, proxy_type = ?, local_ip = ?, local_port = ?, use_encryption = ?, use_compression = ?,
+		host_header_rewrite = ?, header_x_from_where = ?
+		WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query,
+		preset.Name, preset.ProxyType, preset.LocalIP, preset.LocalPort, preset.UseEncryption,
+		preset.UseCompression, preset.HostHeaderRewrite, preset.HeaderXFromWhere, preset.ID)
+	return err
+}
+
+// Delete 删除隧道预设
+func (r *proxyPresetRepository) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM proxy_presets WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
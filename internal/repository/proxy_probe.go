@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProxyProbe 隧道公网可达性探测记录
+type ProxyProbe struct {
+	ID         int64     `db:"id" json:"id"`
+	ProxyID    int64     `db:"proxy_id" json:"proxy_id"`
+	Target     string    `db:"target" json:"target"`
+	Success    bool      `db:"success" json:"success"`
+	LatencyMs  int       `db:"latency_ms" json:"latency_ms"`
+	StatusCode int       `db:"status_code" json:"status_code"`
+	Error      string    `db:"error" json:"error"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// ProxyProbeRepository 隧道探测记录仓库接口
+type ProxyProbeRepository interface {
+	Create(ctx context.Context, probe *ProxyProbe) error
+	ListByProxyIDSince(ctx context.Context, proxyID int64, since time.Time) ([]*ProxyProbe, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteByProxyID(ctx context.Context, proxyID int64) error
+}
+
+// proxyProbeRepository 隧道探测记录仓库实现
+type proxyProbeRepository struct {
+	db *sqlx.DB
+}
+
+// NewProxyProbeRepository 创建隧道探测记录仓库实例
+func NewProxyProbeRepository(db *sqlx.DB) ProxyProbeRepository {
+	return &proxyProbeRepository{db: db}
+}
+
+// Create 写入探测记录
+func (r *proxyProbeRepository) Create(ctx context.Context, probe *ProxyProbe) error {
+	query := `INSERT INTO proxy_probes (proxy_id, target, success, latency_ms, status_code, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if probe.CreatedAt.IsZero() {
+		probe.CreatedAt = time.Now()
+	}
+	result, err := r.db.ExecContext(ctx, query,
+		probe.ProxyID, probe.Target, probe.Success, probe.LatencyMs, probe.StatusCode, probe.Error, probe.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	probe.ID = id
+	return nil
+}
+
+// ListByProxyIDSince 获取指定时间之后的探测记录（按时间正序）
+func (r *proxyProbeRepository) ListByProxyIDSince(ctx context.Context, proxyID int64, since time.Time) ([]*ProxyProbe, error) {
+	query := `SELECT * FROM proxy_probes WHERE proxy_id = ? AND created_at >= ? ORDER BY created_at ASC, id ASC`
+	var probes []*ProxyProbe
+	err := r.db.SelectContext(ctx, &probes, query, proxyID, since)
+	if err != nil {
+		return nil, err
+	}
+	return probes, nil
+}
+
+// DeleteBefore 删除指定时间之前的探测记录，返回删除条数
+func (r *proxyProbeRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM proxy_probes WHERE created_at < ?`
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByProxyID 删除隧道的全部探测记录
+func (r *proxyProbeRepository) DeleteByProxyID(ctx context.Context, proxyID int64) error {
+	query := `DELETE FROM proxy_probes WHERE proxy_id = ?`
+	_, err := r.db.ExecContext(ctx, query, proxyID)
+	return err
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProxySchedule 隧道禁用状态与在线时段
+type ProxySchedule struct {
+	ProxyID   int64     `db:"proxy_id" json:"proxy_id"`
+	Disabled  bool      `db:"disabled" json:"disabled"`
+	Windows   string    `db:"windows" json:"windows"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ProxyScheduleRepository 隧道时段仓库接口
+type ProxyScheduleRepository interface {
+	GetByProxyID(ctx context.Context, proxyID int64) (*ProxySchedule, error)
+	Upsert(ctx context.Context, schedule *ProxySchedule) error
+	ListRestrictedOnline(ctx context.Context) ([]*ProxySchedule, error)
+	DeleteByProxyID(ctx context.Context, proxyID int64) error
+}
+
+// proxyScheduleRepository 隧道时段仓库实现
+type proxyScheduleRepository struct {
+	db *sqlx.DB
+}
+
+// NewProxyScheduleRepository 创建隧道时段仓库实例
+func NewProxyScheduleRepository(db *sqlx.DB) ProxyScheduleRepository {
+	return &proxyScheduleRepository{db: db}
+}
+
+// GetByProxyID 获取隧道的时段配置
+func (r *proxyScheduleRepository) GetByProxyID(ctx context.Context, proxyID int64) (*ProxySchedule, error) {
+	query := `SELECT * FROM proxy_schedules WHERE proxy_id = ?`
+	var schedule ProxySchedule
+	err := r.db.GetContext(ctx, &schedule, query, proxyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// Upsert 创建或更新隧道的时段配置
+func (r *proxyScheduleRepository) Upsert(ctx context.Context, schedule *ProxySchedule) error {
+	query := `INSERT INTO proxy_schedules (proxy_id, disabled, windows, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON DUPLICATE KEY UPDATE disabled = VALUES(disabled), windows = VALUES(windows), updated_at = CURRENT_TIMESTAMP`
+	_, err := r.db.ExecContext(ctx, query, schedule.ProxyID, schedule.Disabled, schedule.Windows)
+	return err
+}
+
+// ListRestrictedOnline 获取当前在线且设置了禁用或时段限制的隧道配置
+func (r *proxyScheduleRepository) ListRestrictedOnline(ctx context.Context) ([]*ProxySchedule, error) {
+	query := `SELECT s.* FROM proxy_schedules s
+		INNER JOIN proxy p ON p.id = s.proxy_id
+		WHERE p.status = 'online' AND (s.disabled = 1 OR (s.windows <> '' AND s.windows <> '[]'))`
+	var schedules []*ProxySchedule
+	err := r.db.SelectContext(ctx, &schedules, query)
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// DeleteByProxyID 删除隧道的时段配置
+func (r *proxyScheduleRepository) DeleteByProxyID(ctx context.Context, proxyID int64) error {
+	query := `DELETE FROM proxy_schedules WHERE proxy_id = ?`
+	_, err := r.db.ExecContext(ctx, query, proxyID)
+	return err
+}
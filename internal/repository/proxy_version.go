@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProxyVersion 隧道配置版本记录
+type ProxyVersion struct {
+	ID            int64     `db:"id" json:"id"`
+	ProxyID       int64     `db:"proxy_id" json:"proxy_id"`
+	Version       int       `db:"version" json:"version"`
+	Username      string    `db:"username" json:"username"`
+	Snapshot      string    `db:"snapshot" json:"snapshot"`
+	ChangedFields string    `db:"changed_fields" json:"changed_fields"`
+	Action        string    `db:"action" json:"action"`
+	OperatorType  string    `db:"operator_type" json:"operator_type"`
+	Operator      string    `db:"operator" json:"operator"`
+	Remark        string    `db:"remark" json:"remark"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+// ProxyVersionRepository 隧道配置版本仓库接口
+type ProxyVersionRepository interface {
+	Create(ctx context.Context, version *ProxyVersion) error
+	GetByVersion(ctx context.Context, proxyID int64, version int) (*ProxyVersion, error)
+	ListByProxyID(ctx context.Context, proxyID int64, offset, limit int) ([]*ProxyVersion, error)
+	CountByProxyID(ctx context.Context, proxyID int64) (int, error)
+	DeleteByProxyID(ctx context.Context, proxyID int64) error
+}
+
+// proxyVersionRepository 隧道配置版本仓库实现
+type proxyVersionRepository struct {
+	db *sqlx.DB
+}
+
+// NewProxyVersionRepository 创建隧道配置版本仓库实例
+func NewProxyVersionRepository(db *sqlx.DB) ProxyVersionRepository {
+	return &proxyVersionRepository{db: db}
+}
+
+// Create 写入一条版本记录，版本号在同一隧道内自动递增
+func (r *proxyVersionRepository) Create(ctx context.Context, version *ProxyVersion) error {
+	query := `INSERT INTO proxy_versions
+		(proxy_id, version, username, snapshot, changed_fields, action, operator_type, operator, remark, created_at)
+		SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP
+		FROM proxy_versions WHERE proxy_id = ?`
+	result, err := r.db.ExecContext(ctx, query,
+		version.ProxyID, version.Username, version.Snapshot, version.ChangedFields,
+		version.Action, version.OperatorType, version.Operator, version.Remark, version.ProxyID)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	version.ID = id
+	return nil
+}
+
+// GetByVersion 获取隧道的指定版本
+func (r *proxyVersionRepository) GetByVersion(ctx context.Context, proxyID int64, version int) (*ProxyVersion, error) {
+	query := `SELECT * FROM proxy_versions WHERE proxy_id = ? AND version = ?`
+	var v ProxyVersion
+	err := r.db.GetContext(ctx, &v, query, proxyID, version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListByProxyID 获取隧道的版本列表（按版本号倒序）
+func (r *proxyVersionRepository) ListByProxyID(ctx context.Context, proxyID int64, offset, limit int) ([]*ProxyVersion, error) {
+	query := `SELECT * FROM proxy_versions WHERE proxy_id = ? ORDER BY version DESC LIMIT ? OFFSET ?`
+	var versions []*ProxyVersion
+	err := r.db.SelectContext(ctx, &versions, query, proxyID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// CountByProxyID 获取隧道的版本数量
+func (r *proxyVersionRepository) CountByProxyID(ctx context.Context, proxyID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM proxy_versions WHERE proxy_id = ?`
+	var count int
+	err := r.db.GetContext(ctx, &count, query, proxyID)
+	return count, err
+}
+
+// DeleteByProxyID 删除隧道的全部版本记录
+func (r *proxyVersionRepository) DeleteByProxyID(ctx context.Context, proxyID int64) error {
+	query := `DELETE FROM proxy_versions WHERE proxy_id = ?`
+	_, err := r.db.ExecContext(ctx, query, proxyID)
+	return err
+}
@@ -0,0 +1,173 @@
+// Package repositorytest 提供内存中的仓库实现，便于离线测试依赖数据库的服务
+// 仓库只实现了测试用到的方法，调用未实现的方法会panic
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"stellarfrp/internal/repository"
+	"sync"
+)
+
+// NodeRepository 内存中的节点仓库
+type NodeRepository struct {
+	repository.NodeRepository
+
+	mu    sync.Mutex
+	nodes map[int64]*repository.Node
+}
+
+// NewNodeRepository 创建包含给定节点的节点仓库
+func NewNodeRepository(nodes ...*repository.Node) *NodeRepository {
+	r := &NodeRepository{nodes: make(map[int64]*repository.Node)}
+	for _, node := range nodes {
+		n := *node
+		r.nodes[node.ID] = &n
+	}
+	return r
+}
+
+// GetByID 根据ID获取节点
+func (r *NodeRepository) GetByID(ctx context.Context, id int64) (*repository.Node, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	node, ok := r.nodes[id]
+	if !ok {
+		return nil, errors.New("node not found")
+	}
+	n := *node
+	return &n, nil
+}
+
+// List 按ID顺序获取节点列表
+func (r *NodeRepository) List(ctx context.Context, offset, limit int) ([]*repository.Node, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nodes := make([]*repository.Node, 0, len(r.nodes))
+	for _, node := range r.nodes {
+		n := *node
+		nodes = append(nodes, &n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return page(nodes, offset, limit), nil
+}
+
+// UpdateFrpsVersion 更新节点的frps版本
+func (r *NodeRepository) UpdateFrpsVersion(ctx context.Context, id int64, version string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if node, ok := r.nodes[id]; ok {
+		node.FrpsVersion = version
+	}
+	return nil
+}
+
+// UpdateStatus 更新节点状态
+func (r *NodeRepository) UpdateStatus(ctx context.Context, id int64, status int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if node, ok := r.nodes[id]; ok {
+		node.Status = status
+	}
+	return nil
+}
+
+// ProxyRepository 内存中的隧道仓库
+type ProxyRepository struct {
+	repository.ProxyRepository
+
+	mu      sync.Mutex
+	proxies map[int64]*repository.Proxy
+}
+
+// NewProxyRepository 创建包含给定隧道的隧道仓库
+func NewProxyRepository(proxies ...*repository.Proxy) *ProxyRepository {
+	r := &ProxyRepository{proxies: make(map[int64]*repository.Proxy)}
+	for _, proxy := range proxies {
+		p := *proxy
+		r.proxies[proxy.ID] = &p
+	}
+	return r
+}
+
+// GetByID 根据ID获取隧道，不存在时返回nil
+func (r *ProxyRepository) GetByID(ctx context.Context, id int64) (*repository.Proxy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	proxy, ok := r.proxies[id]
+	if !ok {
+		return nil, nil
+	}
+	p := *proxy
+	return &p, nil
+}
+
+// Update 更新隧道
+func (r *ProxyRepository) Update(ctx context.Context, proxy *repository.Proxy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.proxies[proxy.ID]; !ok {
+		return errors.New("proxy not found")
+	}
+	p := *proxy
+	r.proxies[proxy.ID] = &p
+	return nil
+}
+
+// ListByNode 按ID顺序获取节点下的隧道
+func (r *ProxyRepository) ListByNode(ctx context.Context, nodeID int64) ([]*repository.Proxy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var proxies []*repository.Proxy
+	for _, proxy := range r.proxies {
+		if proxy.Node == nodeID {
+			p := *proxy
+			proxies = append(proxies, &p)
+		}
+	}
+	sort.Slice(proxies, func(i, j int) bool { return proxies[i].ID < proxies[j].ID })
+	return proxies, nil
+}
+
+// ProxyEventRepository 内存中的隧道事件仓库
+type ProxyEventRepository struct {
+	repository.ProxyEventRepository
+
+	mu     sync.Mutex
+	events []*repository.ProxyEvent
+}
+
+// NewProxyEventRepository 创建空的隧道事件仓库
+func NewProxyEventRepository() *ProxyEventRepository {
+	return &ProxyEventRepository{}
+}
+
+// Create 写入隧道事件
+func (r *ProxyEventRepository) Create(ctx context.Context, event *repository.ProxyEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := *event
+	e.ID = int64(len(r.events) + 1)
+	r.events = append(r.events, &e)
+	return nil
+}
+
+// Events 返回已写入的全部事件
+func (r *ProxyEventRepository) Events() []*repository.ProxyEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*repository.ProxyEvent(nil), r.events...)
+}
+
+// page 按偏移量和数量截取列表
+func page[T any](list []T, offset, limit int) []T {
+	if offset >= len(list) {
+		return nil
+	}
+	list = list[offset:]
+	if limit < len(list) {
+		list = list[:limit]
+	}
+	return list
+}
@@ -0,0 +1,39 @@
+-- 修改节点表，记录节点运行的frps版本
+ALTER TABLE `nodes`
+ADD COLUMN `frps_version` varchar(32) NOT NULL DEFAULT '' COMMENT '节点frps版本(由serverinfo自动识别)';
+
+-- 修改节点表，添加节点代理心跳签名密钥
+ALTER TABLE `nodes`
+ADD COLUMN `agent_key` varchar(64) NOT NULL DEFAULT '' COMMENT '节点代理心跳签名密钥，为空表示未启用心跳';
+
+-- 修改节点表，添加节点地区与线路运营商，用于节点推荐
+ALTER TABLE `nodes`
+ADD COLUMN `region` varchar(32) NOT NULL DEFAULT '' COMMENT '节点所在地区',
+ADD COLUMN `isp` varchar(32) NOT NULL DEFAULT '' COMMENT '节点线路运营商(telecom/unicom/mobile/bgp等)';
+
+-- 修改节点表，添加城市、带宽档位、标签和排序值，用于节点筛选与分组
+ALTER TABLE `nodes`
+ADD COLUMN `city` varchar(32) NOT NULL DEFAULT '' COMMENT '节点所在城市',
+ADD COLUMN `bandwidth_tier` varchar(32) NOT NULL DEFAULT '' COMMENT '带宽档位',
+ADD COLUMN `tags` varchar(512) NOT NULL DEFAULT '[]' COMMENT '节点标签，JSON数组',
+ADD COLUMN `sort_order` int(10) NOT NULL DEFAULT '0' COMMENT '排序值，越小越靠前';
+
+-- 修改节点表，添加节点容量上限，0表示不限制
+ALTER TABLE `nodes`
+ADD COLUMN `max_proxies` int(10) NOT NULL DEFAULT '0' COMMENT '节点隧道总数上限，0表示不限制',
+ADD COLUMN `max_type_proxies` varchar(255) NOT NULL DEFAULT '{}' COMMENT '按协议类型的隧道数量上限，JSON对象，如{"tcp":100}',
+ADD COLUMN `max_online_clients` int(10) NOT NULL DEFAULT '0' COMMENT '同时在线客户端数量上限，0表示不限制';
+
+-- 修改节点表，添加frps插件回调密钥
+ALTER TABLE `nodes`
+ADD COLUMN `plugin_secret` varchar(64) NOT NULL DEFAULT '' COMMENT 'frps插件回调密钥，为空表示未启用校验';
+
+-- 修改节点表，添加贡献奖励封禁标记
+ALTER TABLE `nodes`
+ADD COLUMN `reward_blocked` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否停止向所有者发放贡献奖励';
+
+-- 修改节点表，加大凭据字段长度以存储加密后的密文
+ALTER TABLE `nodes`
+MODIFY COLUMN `token` varchar(512) NOT NULL COMMENT '节点访问令牌，加密存储',
+MODIFY COLUMN `agent_key` varchar(255) NOT NULL DEFAULT '' COMMENT '节点代理心跳签名密钥，加密存储，为空表示未启用心跳',
+MODIFY COLUMN `plugin_secret` varchar(255) NOT NULL DEFAULT '' COMMENT 'frps插件回调密钥，加密存储，为空表示未启用校验';
@@ -0,0 +1,3 @@
+-- 修改隧道告警表，加大签名密钥字段长度以存储加密后的密文
+ALTER TABLE `proxy_alerts`
+MODIFY COLUMN `webhook_secret` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT 'Webhook签名密钥，加密存储';
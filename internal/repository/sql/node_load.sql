@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS `node_load_samples` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '记录ID',
+  `node_id` int(10) NOT NULL COMMENT '节点ID',
+  `load_score` double NOT NULL DEFAULT '0' COMMENT '负载评分(0-1)',
+  `current_conns` int(10) NOT NULL DEFAULT '0' COMMENT '当前连接数',
+  `peak_conns` int(10) NOT NULL DEFAULT '0' COMMENT '峰值连接数',
+  `current_traffic` bigint(20) NOT NULL DEFAULT '0' COMMENT '当前带宽(字节/秒)',
+  `peak_traffic` bigint(20) NOT NULL DEFAULT '0' COMMENT '峰值带宽(字节/秒)',
+  `cpu_usage` double NOT NULL DEFAULT '0' COMMENT 'CPU使用率',
+  `mem_usage` double NOT NULL DEFAULT '0' COMMENT '内存使用率',
+  `conn_growth_rate` double NOT NULL DEFAULT '0' COMMENT '连接数增长率',
+  `traffic_growth_rate` double NOT NULL DEFAULT '0' COMMENT '流量增长率',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '上报时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_node_time` (`node_id`, `created_at`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='节点负载原始上报表';
+
+CREATE TABLE IF NOT EXISTS `node_load_rollups` (
+  `node_id` int(10) NOT NULL COMMENT '节点ID',
+  `resolution` int(10) NOT NULL COMMENT '聚合粒度(秒)',
+  `bucket_time` timestamp NOT NULL COMMENT '聚合区间开始时间',
+  `samples` int(10) NOT NULL DEFAULT '0' COMMENT '区间内原始样本数',
+  `load_score` double NOT NULL DEFAULT '0' COMMENT '平均负载评分',
+  `current_conns` double NOT NULL DEFAULT '0' COMMENT '平均连接数',
+  `peak_conns` int(10) NOT NULL DEFAULT '0' COMMENT '区间峰值连接数',
+  `current_traffic` double NOT NULL DEFAULT '0' COMMENT '平均带宽(字节/秒)',
+  `peak_traffic` bigint(20) NOT NULL DEFAULT '0' COMMENT '区间峰值带宽(字节/秒)',
+  `cpu_usage` double NOT NULL DEFAULT '0' COMMENT '平均CPU使用率',
+  `mem_usage` double NOT NULL DEFAULT '0' COMMENT '平均内存使用率',
+  `conn_growth_rate` double NOT NULL DEFAULT '0' COMMENT '平均连接数增长率',
+  `traffic_growth_rate` double NOT NULL DEFAULT '0' COMMENT '平均流量增长率',
+  PRIMARY KEY (`node_id`, `resolution`, `bucket_time`),
+  KEY `idx_resolution_time` (`resolution`, `bucket_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='节点负载降采样表';
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `node_maintenances` (
+  `id` int(10) NOT NULL AUTO_INCREMENT COMMENT '维护计划ID',
+  `node_id` int(10) NOT NULL COMMENT '节点ID',
+  `start_at` timestamp NOT NULL COMMENT '维护开始时间',
+  `end_at` timestamp NOT NULL COMMENT '维护结束时间',
+  `message` varchar(512) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '维护说明',
+  `canceled` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否已取消',
+  `notified_at` timestamp NULL DEFAULT NULL COMMENT '已通知受影响用户的时间',
+  `created_by` int(10) NOT NULL DEFAULT '0' COMMENT '创建维护计划的管理员ID',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_node_time` (`node_id`, `end_at`),
+  KEY `idx_start_at` (`start_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='节点维护计划表';
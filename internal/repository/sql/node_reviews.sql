@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `node_reviews` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '审核记录ID',
+  `node_id` int(10) NOT NULL COMMENT '节点ID',
+  `action` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '操作(submit/resubmit/approve/reject/request_changes)',
+  `from_status` tinyint(1) NOT NULL COMMENT '操作前的节点状态',
+  `to_status` tinyint(1) NOT NULL COMMENT '操作后的节点状态',
+  `reviewer_id` int(10) DEFAULT NULL COMMENT '审核管理员ID，捐赠者提交时为空',
+  `note` text COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '备注：审核时为管理员内部备注，提交时为捐赠者说明',
+  `reason` varchar(512) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '拒绝或要求修改的原因，对捐赠者可见',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '操作时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_node_created` (`node_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='捐赠节点审核记录表';
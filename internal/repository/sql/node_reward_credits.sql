@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS `node_reward_credits` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '奖励记录ID',
+  `node_id` int(10) NOT NULL COMMENT '节点ID',
+  `user_id` int(10) NOT NULL COMMENT '获得奖励的节点所有者ID',
+  `rule_id` int(10) NOT NULL COMMENT '奖励规则ID',
+  `reward_date` date NOT NULL COMMENT '奖励对应的统计日期',
+  `reward_type` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '奖励类型(traffic/tunnel/group)',
+  `amount` bigint(20) NOT NULL COMMENT '发放数量：流量字节数、隧道数或用户组天数',
+  `group_id` int(10) DEFAULT NULL COMMENT '用户组升级的目标用户组ID',
+  `prev_group_id` int(10) DEFAULT NULL COMMENT '用户组升级到期后恢复的用户组ID',
+  `expires_at` timestamp NULL DEFAULT NULL COMMENT '用户组升级到期时间',
+  `traffic` bigint(20) NOT NULL DEFAULT '0' COMMENT '节点当日承载流量(字节)',
+  `uptime` decimal(5,2) NOT NULL DEFAULT '0.00' COMMENT '节点当日在线率(百分比)',
+  `status` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'credited' COMMENT '状态(credited/expired/clawed_back/failed)',
+  `reason` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '追回或发放失败的原因',
+  `operator_id` int(10) DEFAULT NULL COMMENT '执行追回的管理员ID',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '发放时间',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_node_rule_date` (`node_id`, `rule_id`, `reward_date`),
+  KEY `idx_user_rule_date` (`user_id`, `rule_id`, `reward_date`),
+  KEY `idx_status_expires` (`status`, `expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='捐赠节点贡献奖励流水表';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `node_reward_rules` (
+  `id` int(10) NOT NULL AUTO_INCREMENT COMMENT '规则ID',
+  `name` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '规则名称',
+  `enabled` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否启用',
+  `min_traffic` bigint(20) NOT NULL DEFAULT '0' COMMENT '当日最低承载流量(字节)，入站与出站之和',
+  `min_uptime` decimal(5,2) NOT NULL DEFAULT '0.00' COMMENT '当日最低在线率(百分比)',
+  `reward_type` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '奖励类型(traffic/tunnel/group)',
+  `reward_value` bigint(20) NOT NULL COMMENT '奖励数值：流量字节数、隧道数或升级的目标用户组ID',
+  `base_group_id` int(10) NOT NULL DEFAULT '0' COMMENT '用户组升级奖励仅对该用户组的用户生效',
+  `duration_days` int(10) NOT NULL DEFAULT '0' COMMENT '用户组升级每次发放的天数',
+  `monthly_cap` bigint(20) NOT NULL DEFAULT '0' COMMENT '每个用户每月从该规则获得的上限，0表示不限制',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='捐赠节点贡献奖励规则表';
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `node_validations` (
+  `id` int(10) NOT NULL AUTO_INCREMENT COMMENT '校验记录ID',
+  `node_id` int(10) NOT NULL COMMENT '节点ID',
+  `passed` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否全部检查通过',
+  `checks` text COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '各项检查结果，JSON数组',
+  `trigger_type` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '触发方式(submit/recheck)',
+  `operator_id` int(10) DEFAULT NULL COMMENT '触发重新校验的管理员ID，提交时为空',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '校验时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_node_created` (`node_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='捐赠节点自动校验记录表';
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `proxy_alerts` (
+  `proxy_id` int(10) NOT NULL COMMENT '隧道ID',
+  `username` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '所属用户',
+  `offline_minutes` int(10) NOT NULL DEFAULT '0' COMMENT '离线超过多少分钟告警，0为关闭',
+  `flap_threshold` int(10) NOT NULL DEFAULT '0' COMMENT '一小时内掉线超过多少次告警，0为关闭',
+  `notify_email` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否发送邮件',
+  `webhook_url` varchar(512) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT 'Webhook地址，为空表示不发送',
+  `webhook_secret` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT 'Webhook签名密钥',
+  `offline_alerted_at` timestamp NULL DEFAULT NULL COMMENT '本次离线已告警时间，恢复后清空',
+  `flap_alerted_at` timestamp NULL DEFAULT NULL COMMENT '本次频繁掉线已告警时间，恢复后清空',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`proxy_id`),
+  KEY `idx_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='隧道离线告警配置表';
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `proxy_events` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '记录ID',
+  `proxy_id` int(10) NOT NULL COMMENT '隧道ID',
+  `node_id` int(10) NOT NULL COMMENT '节点ID',
+  `username` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '隧道所属用户名',
+  `status` varchar(10) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '变更后的状态：online/offline',
+  `reason` varchar(50) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '变更原因',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '变更时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_proxy_time` (`proxy_id`, `created_at`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='隧道上下线事件表';
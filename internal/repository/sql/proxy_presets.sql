@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS `proxy_presets` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '预设ID',
+  `username` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '所属用户名',
+  `name` varchar(50) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '预设名称',
+  `proxy_type` varchar(5) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '隧道类型',
+  `local_ip` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '本地IP',
+  `local_port` int(5) NOT NULL DEFAULT '0' COMMENT '本地端口',
+  `use_encryption` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否使用加密',
+  `use_compression` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否使用压缩',
+  `host_header_rewrite` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '重写主机头信息',
+  `header_x_from_where` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '自定义HTTP头信息',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_username_name` (`username`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户隧道预设表';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `proxy_probes` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '记录ID',
+  `proxy_id` int(10) NOT NULL COMMENT '隧道ID',
+  `target` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '探测地址',
+  `success` tinyint(1) NOT NULL COMMENT '是否探测成功',
+  `latency_ms` int(10) NOT NULL DEFAULT '0' COMMENT '耗时(毫秒)',
+  `status_code` int(10) NOT NULL DEFAULT '0' COMMENT 'HTTP状态码，TCP探测为0',
+  `error` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '失败原因',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '探测时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_proxy_time` (`proxy_id`, `created_at`),
+  KEY `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='隧道公网可达性探测记录表';
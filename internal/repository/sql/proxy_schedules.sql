@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS `proxy_schedules` (
+  `proxy_id` int(10) NOT NULL COMMENT '隧道ID',
+  `disabled` tinyint(1) NOT NULL DEFAULT '0' COMMENT '是否被用户禁用',
+  `windows` text COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '每周允许在线时段(JSON数组)，为空表示不限制',
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`proxy_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='隧道禁用状态与在线时段表';
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS `proxy_versions` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '记录ID',
+  `proxy_id` int(10) NOT NULL COMMENT '隧道ID',
+  `version` int(10) unsigned NOT NULL COMMENT '版本号（按隧道递增）',
+  `username` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '隧道所属用户名',
+  `snapshot` text COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '配置快照(JSON)',
+  `changed_fields` varchar(512) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '[]' COMMENT '本次变更的字段(JSON数组)',
+  `action` varchar(20) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '变更动作：create/update/import/rollback',
+  `operator_type` varchar(10) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '操作人类型：user/admin/system',
+  `operator` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '操作人',
+  `remark` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' COMMENT '备注',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_proxy_version` (`proxy_id`, `version`),
+  KEY `idx_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='隧道配置版本历史表';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `user_notices` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '通知ID',
+  `username` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '接收用户',
+  `title` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '标题',
+  `content` text COLLATE utf8mb4_unicode_ci NOT NULL COMMENT '内容',
+  `read_at` timestamp NULL DEFAULT NULL COMMENT '已读时间，未读为NULL',
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_username_time` (`username`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户站内通知表';
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UserNotice 用户站内通知
+type UserNotice struct {
+	ID        int64        `db:"id" json:"id"`
+	Username  string       `db:"username" json:"-"`
+	Title     string       `db:"title" json:"title"`
+	Content   string       `db:"content" json:"content"`
+	ReadAt    sql.NullTime `db:"read_at" json:"read_at"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+}
+
+// UserNoticeRepository 用户站内通知仓库接口
+type UserNoticeRepository interface {
+	Create(ctx context.Context, notice *UserNotice) error
+	ListByUsername(ctx context.Context, username string, offset, limit int) ([]*UserNotice, int, error)
+	CountUnread(ctx context.Context, username string) (int, error)
+	MarkRead(ctx context.Context, username string, ids []int64, at time.Time) error
+	MarkAllRead(ctx context.Context, username string, at time.Time) error
+}
+
+// userNoticeRepository 用户站内通知仓库实现
+type userNoticeRepository struct {
+	db *sqlx.DB
+}
+
+// NewUserNoticeRepository 创建用户站内通知仓库实例
+func NewUserNoticeRepository(db *sqlx.DB) UserNoticeRepository {
+	return &userNoticeRepository{db: db}
+}
+
+// Create 写入站内通知
+func (r *userNoticeRepository) Create(ctx context.Context, notice *UserNotice) error {
+	query := `INSERT INTO user_notices (username, title, content, created_at) VALUES (?, ?, ?, ?)`
+	if notice.CreatedAt.IsZero() {
+		notice.CreatedAt = time.Now()
+	}
+	result, err := r.db.ExecContext(ctx, query, notice.Username, notice.Title, notice.Content, notice.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	notice.ID = id
+	return nil
+}
+
+// ListByUsername 分页获取用户的站内通知（按时间倒序）
+func (r *userNoticeRepository) ListByUsername(ctx context.Context, username string, offset, limit int) ([]*UserNotice, int, error) {
+	var total int
+	if err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM user_notices WHERE username = ?`, username); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT * FROM user_notices WHERE username = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	var notices []*UserNotice
+	if err := r.db.SelectContext(ctx, &notices, query, username, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	return notices, total, nil
+}
+
+// CountUnread 获取用户未读通知数量
+func (r *userNoticeRepository) CountUnread(ctx context.Context, username string) (int, error) {
+	query := `SELECT COUNT(*) FROM user_notices WHERE username = ? AND read_at IS NULL`
+	var count int
+	err := r.db.GetContext(ctx, &count, query, username)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkRead 将用户的指定通知标记为已读
+func (r *userNoticeRepository) MarkRead(ctx context.Context, username string, ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`UPDATE user_notices SET read_at = ? WHERE username = ? AND read_at IS NULL AND id IN (?)`, at, username, ids)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, r.db.Rebind(query), args...)
+	return err
+}
+
+// MarkAllRead 将用户的全部通知标记为已读
+func (r *userNoticeRepository) MarkAllRead(ctx context.Context, username string, at time.Time) error {
+	query := `UPDATE user_notices SET read_at = ? WHERE username = ? AND read_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, at, username)
+	return err
+}
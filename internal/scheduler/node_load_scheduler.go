@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// nodeLoadRollupInterval 负载数据降采样与清理间隔
+const nodeLoadRollupInterval = 5 * time.Minute
+
+// NodeLoadScheduler 节点负载时序数据调度器
+type NodeLoadScheduler struct {
+	nodeLoadService service.NodeLoadService
+	logger          *logger.Logger
+	quit            chan struct{}
+}
+
+// NewNodeLoadScheduler 创建节点负载时序数据调度器实例
+func NewNodeLoadScheduler(nodeLoadService service.NodeLoadService, logger *logger.Logger) *NodeLoadScheduler {
+	return &NodeLoadScheduler{
+		nodeLoadService: nodeLoadService,
+		logger:          logger,
+		quit:            make(chan struct{}),
+	}
+}
+
+// Start 启动节点负载时序数据调度器
+func (s *NodeLoadScheduler) Start() {
+	go s.rollupScheduler()
+	s.logger.Info("节点负载时序数据调度器启动")
+}
+
+// Stop 停止节点负载时序数据调度器
+func (s *NodeLoadScheduler) Stop() {
+	close(s.quit)
+	s.logger.Info("节点负载时序数据调度器停止")
+}
+
+// rollupScheduler 降采样定时器
+func (s *NodeLoadScheduler) rollupScheduler() {
+	ticker := time.NewTicker(nodeLoadRollupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.rollup()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// rollup 聚合最近的负载数据并清理过期数据
+func (s *NodeLoadScheduler) rollup() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	now := time.Now()
+	if err := s.nodeLoadService.Rollup(ctx, now); err != nil {
+		s.logger.Error("节点负载数据降采样失败", "error", err)
+	}
+	if err := s.nodeLoadService.Cleanup(ctx, now); err != nil {
+		s.logger.Error("清理过期节点负载数据失败", "error", err)
+	}
+}
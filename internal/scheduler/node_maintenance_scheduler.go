@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// nodeMaintenanceCheckInterval 维护通知检查间隔
+const nodeMaintenanceCheckInterval = time.Minute
+
+// NodeMaintenanceScheduler 节点维护通知调度器
+// 维护状态由维护时间段实时判断，结束后自动恢复，调度器只负责提前通知受影响用户
+type NodeMaintenanceScheduler struct {
+	maintenanceService service.NodeMaintenanceService
+	logger             *logger.Logger
+	quit               chan struct{}
+}
+
+// NewNodeMaintenanceScheduler 创建节点维护通知调度器实例
+func NewNodeMaintenanceScheduler(maintenanceService service.NodeMaintenanceService, logger *logger.Logger) *NodeMaintenanceScheduler {
+	return &NodeMaintenanceScheduler{
+		maintenanceService: maintenanceService,
+		logger:             logger,
+		quit:               make(chan struct{}),
+	}
+}
+
+// Start 启动节点维护通知调度器
+func (s *NodeMaintenanceScheduler) Start() {
+	go s.notifyScheduler()
+	s.logger.Info("节点维护通知调度器启动")
+}
+
+// Stop 停止节点维护通知调度器
+func (s *NodeMaintenanceScheduler) Stop() {
+	close(s.quit)
+	s.logger.Info("节点维护通知调度器停止")
+}
+
+// notifyScheduler 维护通知定时器
+func (s *NodeMaintenanceScheduler) notifyScheduler() {
+	ticker := time.NewTicker(nodeMaintenanceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.notifyUpcoming()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// notifyUpcoming 通知即将开始维护的节点上的用户
+func (s *NodeMaintenanceScheduler) notifyUpcoming() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := s.maintenanceService.NotifyUpcoming(ctx, time.Now()); err != nil {
+		s.logger.Error("发送节点维护通知失败", "error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// 贡献奖励调度参数
+const (
+	// nodeUptimeSampleInterval 捐赠节点在线采样间隔
+	nodeUptimeSampleInterval = 5 * time.Minute
+	// nodeRewardSettleHour 每天结算前一天奖励的时刻，需晚于23:55的节点流量记录
+	nodeRewardSettleHour = 0
+	// nodeRewardSettleMinute 每天结算前一天奖励的分钟
+	nodeRewardSettleMinute = 30
+)
+
+// NodeRewardScheduler 捐赠节点贡献奖励调度器
+type NodeRewardScheduler struct {
+	rewardService service.NodeRewardService
+	logger        *logger.Logger
+	quit          chan struct{}
+}
+
+// NewNodeRewardScheduler 创建捐赠节点贡献奖励调度器实例
+func NewNodeRewardScheduler(rewardService service.NodeRewardService, logger *logger.Logger) *NodeRewardScheduler {
+	return &NodeRewardScheduler{
+		rewardService: rewardService,
+		logger:        logger,
+		quit:          make(chan struct{}),
+	}
+}
+
+// Start 启动贡献奖励调度器
+func (s *NodeRewardScheduler) Start() {
+	go s.sampleScheduler()
+	go s.settleScheduler()
+	s.logger.Info("节点贡献奖励调度器启动")
+}
+
+// Stop 停止贡献奖励调度器
+func (s *NodeRewardScheduler) Stop() {
+	close(s.quit)
+	s.logger.Info("节点贡献奖励调度器停止")
+}
+
+// sampleScheduler 在线采样与用户组升级到期处理定时器
+func (s *NodeRewardScheduler) sampleScheduler() {
+	ticker := time.NewTicker(nodeUptimeSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sample()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// settleScheduler 每日结算定时器
+func (s *NodeRewardScheduler) settleScheduler() {
+	for {
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), nodeRewardSettleHour, nodeRewardSettleMinute, 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-timer.C:
+			s.settle(next.AddDate(0, 0, -1))
+		case <-s.quit:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// sample 采样捐赠节点在线状态并处理到期的用户组升级
+func (s *NodeRewardScheduler) sample() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	now := time.Now()
+	if err := s.rewardService.SampleUptime(ctx, now); err != nil {
+		s.logger.Error("捐赠节点在线采样失败", "error", err)
+	}
+	if err := s.rewardService.ExpireUpgrades(ctx, now); err != nil {
+		s.logger.Error("处理到期的用户组升级奖励失败", "error", err)
+	}
+}
+
+// settle 结算指定日期的贡献奖励
+func (s *NodeRewardScheduler) settle(date time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if _, err := s.rewardService.Settle(ctx, date); err != nil {
+		s.logger.Error("贡献奖励结算失败", "error", err, "date", date.Format("2006-01-02"))
+	}
+}
@@ -9,20 +9,23 @@ import (
 
 // NodeScheduler 节点调度器
 type NodeScheduler struct {
-	nodeTrafficService service.NodeTrafficService
-	logger             *logger.Logger
-	quit               chan struct{}
+	nodeTrafficService   service.NodeTrafficService
+	nodeHeartbeatService service.NodeHeartbeatService
+	logger               *logger.Logger
+	quit                 chan struct{}
 }
 
 // NewNodeScheduler 创建节点调度器实例
 func NewNodeScheduler(
 	nodeTrafficService service.NodeTrafficService,
+	nodeHeartbeatService service.NodeHeartbeatService,
 	logger *logger.Logger,
 ) *NodeScheduler {
 	return &NodeScheduler{
-		nodeTrafficService: nodeTrafficService,
-		logger:             logger,
-		quit:               make(chan struct{}),
+		nodeTrafficService:   nodeTrafficService,
+		nodeHeartbeatService: nodeHeartbeatService,
+		logger:               logger,
+		quit:                 make(chan struct{}),
 	}
 }
 
@@ -31,6 +34,9 @@ func (s *NodeScheduler) Start() {
 	// 启动定时检查节点状态的goroutine
 	go s.checkNodeStatusScheduler()
 
+	// 启动定时检查节点心跳超时的goroutine
+	go s.checkHeartbeatScheduler()
+
 	// 启动定时记录节点流量的goroutine
 	go s.recordNodeTrafficScheduler()
 
@@ -62,6 +68,21 @@ func (s *NodeScheduler) checkNodeStatusScheduler() {
 	}
 }
 
+// checkHeartbeatScheduler 节点心跳超时检查定时器，检查间隔为心跳超时时间的一半
+func (s *NodeScheduler) checkHeartbeatScheduler() {
+	ticker := time.NewTicker(service.NodeHeartbeatTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkHeartbeatTimeouts()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
 // recordNodeTrafficScheduler 节点流量记录定时器
 func (s *NodeScheduler) recordNodeTrafficScheduler() {
 	// 计算到当天23:55的时间
@@ -108,6 +129,16 @@ func (s *NodeScheduler) checkNodeStatus() {
 	}
 }
 
+// checkHeartbeatTimeouts 检查节点心跳超时的具体实现
+func (s *NodeScheduler) checkHeartbeatTimeouts() {
+	ctx, cancel := context.WithTimeout(context.Background(), service.NodeHeartbeatTimeout/2)
+	defer cancel()
+
+	if err := s.nodeHeartbeatService.CheckTimeouts(ctx); err != nil {
+		s.logger.Error("节点心跳超时检查失败", "error", err)
+	}
+}
+
 // recordNodeTraffic 记录节点流量的具体实现
 func (s *NodeScheduler) recordNodeTraffic() {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// ProxyAlertScheduler 隧道离线告警调度器，每分钟检查一次告警规则
+type ProxyAlertScheduler struct {
+	proxyAlertService service.ProxyAlertService
+	logger            *logger.Logger
+	quit              chan struct{}
+}
+
+// NewProxyAlertScheduler 创建隧道离线告警调度器实例
+func NewProxyAlertScheduler(proxyAlertService service.ProxyAlertService, logger *logger.Logger) *ProxyAlertScheduler {
+	return &ProxyAlertScheduler{
+		proxyAlertService: proxyAlertService,
+		logger:            logger,
+		quit:              make(chan struct{}),
+	}
+}
+
+// Start 启动隧道离线告警调度器
+func (s *ProxyAlertScheduler) Start() {
+	go s.evaluateAlertsScheduler()
+	s.logger.Info("隧道离线告警调度器启动")
+}
+
+// Stop 停止隧道离线告警调度器
+func (s *ProxyAlertScheduler) Stop() {
+	close(s.quit)
+	s.logger.Info("隧道离线告警调度器停止")
+}
+
+// evaluateAlertsScheduler 告警检查定时器
+func (s *ProxyAlertScheduler) evaluateAlertsScheduler() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evaluateAlerts()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// evaluateAlerts 检查所有开启告警的隧道
+func (s *ProxyAlertScheduler) evaluateAlerts() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+	defer cancel()
+
+	s.proxyAlertService.Evaluate(ctx, time.Now())
+}
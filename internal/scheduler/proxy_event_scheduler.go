@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// proxyEventCleanEvery 过期隧道事件清理间隔
+const proxyEventCleanEvery = time.Hour
+
+// ProxyEventScheduler 隧道事件清理调度器
+type ProxyEventScheduler struct {
+	proxyService service.ProxyService
+	logger       *logger.Logger
+	quit         chan struct{}
+}
+
+// NewProxyEventScheduler 创建隧道事件清理调度器实例
+func NewProxyEventScheduler(proxyService service.ProxyService, logger *logger.Logger) *ProxyEventScheduler {
+	return &ProxyEventScheduler{
+		proxyService: proxyService,
+		logger:       logger,
+		quit:         make(chan struct{}),
+	}
+}
+
+// Start 启动隧道事件清理调度器
+func (s *ProxyEventScheduler) Start() {
+	go s.cleanupScheduler()
+	s.logger.Info("隧道事件清理调度器启动")
+}
+
+// Stop 停止隧道事件清理调度器
+func (s *ProxyEventScheduler) Stop() {
+	close(s.quit)
+	s.logger.Info("隧道事件清理调度器停止")
+}
+
+// cleanupScheduler 过期隧道事件清理定时器
+func (s *ProxyEventScheduler) cleanupScheduler() {
+	ticker := time.NewTicker(proxyEventCleanEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanup()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// cleanup 删除超过保留时长的隧道事件
+func (s *ProxyEventScheduler) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deleted, err := s.proxyService.CleanupEvents(ctx, time.Now().Add(-service.ProxyEventRetention))
+	if err != nil {
+		s.logger.Error("清理过期隧道事件失败", "error", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("已清理过期隧道事件", "count", deleted)
+	}
+}
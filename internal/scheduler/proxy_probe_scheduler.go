@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"context"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// 探测调度间隔
+const (
+	proxyProbeInterval   = 5 * time.Minute
+	proxyProbeCleanEvery = time.Hour
+)
+
+// ProxyProbeScheduler 隧道公网可达性探测调度器
+type ProxyProbeScheduler struct {
+	proxyProbeService service.ProxyProbeService
+	logger            *logger.Logger
+	quit              chan struct{}
+}
+
+// NewProxyProbeScheduler 创建隧道公网可达性探测调度器实例
+func NewProxyProbeScheduler(proxyProbeService service.ProxyProbeService, logger *logger.Logger) *ProxyProbeScheduler {
+	return &ProxyProbeScheduler{
+		proxyProbeService: proxyProbeService,
+		logger:            logger,
+		quit:              make(chan struct{}),
+	}
+}
+
+// Start 启动隧道公网可达性探测调度器
+func (s *ProxyProbeScheduler) Start() {
+	go s.probeScheduler()
+	go s.cleanupScheduler()
+	s.logger.Info("隧道公网可达性探测调度器启动")
+}
+
+// Stop 停止隧道公网可达性探测调度器
+func (s *ProxyProbeScheduler) Stop() {
+	close(s.quit)
+	s.logger.Info("隧道公网可达性探测调度器停止")
+}
+
+// probeScheduler 探测定时器
+func (s *ProxyProbeScheduler) probeScheduler() {
+	ticker := time.NewTicker(proxyProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.probeOnline()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// probeOnline 探测所有在线隧道，单轮耗时不超过探测间隔
+func (s *ProxyProbeScheduler) probeOnline() {
+	ctx, cancel := context.WithTimeout(context.Background(), proxyProbeInterval-30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	s.proxyProbeService.ProbeOnline(ctx)
+	s.logger.Debug("隧道公网可达性探测完成", "duration", time.Since(start).String())
+}
+
+// cleanupScheduler 过期探测记录清理定时器
+func (s *ProxyProbeScheduler) cleanupScheduler() {
+	ticker := time.NewTicker(proxyProbeCleanEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanup()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// cleanup 删除超过保留时长的探测记录
+func (s *ProxyProbeScheduler) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deleted, err := s.proxyProbeService.Cleanup(ctx, time.Now().Add(-service.ProxyProbeRetention))
+	if err != nil {
+		s.logger.Error("清理过期探测记录失败", "error", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("已清理过期探测记录", "count", deleted)
+	}
+}
@@ -0,0 +1,257 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/frps"
+	"stellarfrp/pkg/logger"
+	"sync"
+	"time"
+)
+
+// 隧道状态校正动作
+const (
+	DriftMarkedOnline  = "marked_online"
+	DriftMarkedOffline = "marked_offline"
+	DriftClearedRunID  = "cleared_run_id"
+	DriftMissingRunID  = "missing_run_id"
+)
+
+// reconcileGracePeriod 最近刚由插件更新过的隧道跳过校正，避免与上线/下线回调竞争
+const reconcileGracePeriod = time.Minute
+
+// ProxyDrift 一条被校正的隧道状态偏差
+type ProxyDrift struct {
+	ProxyID   int64  `json:"proxy_id"`
+	ProxyName string `json:"proxy_name"`
+	Username  string `json:"username"`
+	NodeID    int64  `json:"node_id"`
+	Action    string `json:"action"`
+	OldStatus string `json:"old_status"`
+	NewStatus string `json:"new_status"`
+	OldRunID  string `json:"old_run_id"`
+}
+
+// ReconcileReport 一次校正的结果汇总
+type ReconcileReport struct {
+	StartedAt      time.Time    `json:"started_at"`
+	FinishedAt     time.Time    `json:"finished_at"`
+	NodesChecked   int          `json:"nodes_checked"`
+	NodesFailed    int          `json:"nodes_failed"`
+	ProxiesChecked int          `json:"proxies_checked"`
+	Drifts         []ProxyDrift `json:"drifts"`
+	Errors         []string     `json:"errors"`
+}
+
+// ProxyReconciler 隧道状态校正器，定期比对节点上的实际隧道状态与数据库记录
+type ProxyReconciler struct {
+	proxyService service.ProxyService
+	nodeService  service.NodeService
+	frpsClient   *frps.Client
+	logger       *logger.Logger
+	interval     time.Duration
+	quit         chan struct{}
+
+	mu         sync.Mutex
+	running    bool
+	lastReport *ReconcileReport
+}
+
+// NewProxyReconciler 创建隧道状态校正器实例
+func NewProxyReconciler(
+	proxyService service.ProxyService,
+	nodeService service.NodeService,
+	frpsClient *frps.Client,
+	logger *logger.Logger,
+) *ProxyReconciler {
+	return &ProxyReconciler{
+		proxyService: proxyService,
+		nodeService:  nodeService,
+		frpsClient:   frpsClient,
+		logger:       logger,
+		interval:     5 * time.Minute,
+		quit:         make(chan struct{}),
+	}
+}
+
+// Start 启动隧道状态校正器
+func (r *ProxyReconciler) Start() {
+	go r.reconcileScheduler()
+	r.logger.Info("隧道状态校正器启动")
+}
+
+// Stop 停止隧道状态校正器
+func (r *ProxyReconciler) Stop() {
+	close(r.quit)
+	r.logger.Info("隧道状态校正器停止")
+}
+
+// LastReport 获取最近一次校正的结果，尚未执行过时返回nil
+func (r *ProxyReconciler) LastReport() *ReconcileReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastReport
+}
+
+// reconcileScheduler 隧道状态校正定时器
+func (r *ProxyReconciler) reconcileScheduler() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.RunOnce(context.Background())
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// RunOnce 立即执行一次校正，若已有校正在执行则返回nil
+func (r *ProxyReconciler) RunOnce(ctx context.Context) *ReconcileReport {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	report := r.reconcile(ctx)
+
+	r.mu.Lock()
+	r.running = false
+	r.lastReport = report
+	r.mu.Unlock()
+
+	if len(report.Drifts) > 0 || report.NodesFailed > 0 {
+		r.logger.Info("隧道状态校正完成",
+			"nodesChecked", report.NodesChecked,
+			"nodesFailed", report.NodesFailed,
+			"proxiesChecked", report.ProxiesChecked,
+			"drifts", len(report.Drifts))
+	}
+
+	return report
+}
+
+// reconcile 遍历所有节点执行校正
+func (r *ProxyReconciler) reconcile(ctx context.Context) *ReconcileReport {
+	report := &ReconcileReport{
+		StartedAt: time.Now(),
+		Drifts:    []ProxyDrift{},
+		Errors:    []string{},
+	}
+
+	nodes, err := r.nodeService.GetAllNodes(ctx)
+	if err != nil {
+		report.Errors = append(report.Errors, "获取节点列表失败: "+err.Error())
+		report.FinishedAt = time.Now()
+		return report
+	}
+
+	for _, node := range nodes {
+		// 未通过审核的节点尚未接入，跳过
+		if repository.NodeUnapproved(node.Status) {
+			continue
+		}
+		report.NodesChecked++
+		if err := r.reconcileNode(ctx, node, report); err != nil {
+			report.NodesFailed++
+			report.Errors = append(report.Errors, fmt.Sprintf("节点 %s: %s", node.NodeName, err.Error()))
+			r.logger.Error("校正节点隧道状态失败", "error", err, "nodeID", node.ID)
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report
+}
+
+// reconcileNode 校正单个节点下的隧道状态
+func (r *ProxyReconciler) reconcileNode(ctx context.Context, node *repository.Node, report *ReconcileReport) error {
+	proxies, err := r.proxyService.ListByNode(ctx, node.ID)
+	if err != nil {
+		return fmt.Errorf("获取节点隧道失败: %w", err)
+	}
+	if len(proxies) == 0 {
+		return nil
+	}
+
+	// 节点已被判定离线时，节点上的隧道不可能在线
+	liveStatus := make(map[string]string)
+	if repository.NodeReachable(node.Status) {
+		types := make(map[string]bool)
+		for _, proxy := range proxies {
+			types[proxy.ProxyType] = true
+		}
+		for proxyType := range types {
+			stats, err := r.frpsClient.ListProxies(ctx, service.NodeEndpoint(node), proxyType)
+			if err != nil {
+				// 节点API不可达时无法区分节点宕机与网络问题，本轮不校正该节点
+				return err
+			}
+			for _, s := range stats {
+				liveStatus[s.Name] = s.Status
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, proxy := range proxies {
+		report.ProxiesChecked++
+
+		if lastUpdate, err := time.ParseInLocation("2006-01-02 15:04:05", proxy.LastUpdate, time.Local); err == nil {
+			if now.Sub(lastUpdate) < reconcileGracePeriod {
+				continue
+			}
+		}
+
+		drift := ProxyDrift{
+			ProxyID:   proxy.ID,
+			ProxyName: proxy.ProxyName,
+			Username:  proxy.Username,
+			NodeID:    node.ID,
+			OldStatus: proxy.Status,
+			OldRunID:  proxy.RunID,
+		}
+
+		liveOnline := liveStatus[proxy.Username+"."+proxy.ProxyName] == "online"
+		switch {
+		case liveOnline && proxy.Status != "online":
+			proxy.Status = "online"
+			drift.Action = DriftMarkedOnline
+			if proxy.RunID == "" {
+				// 节点API不返回运行ID，只能等待客户端下次上线时由插件补全
+				drift.Action = DriftMissingRunID
+			}
+		case !liveOnline && proxy.Status == "online":
+			proxy.Status = "offline"
+			proxy.RunID = ""
+			drift.Action = DriftMarkedOffline
+		case !liveOnline && proxy.RunID != "":
+			proxy.RunID = ""
+			drift.Action = DriftClearedRunID
+		default:
+			continue
+		}
+
+		proxy.LastUpdate = now.Format("2006-01-02 15:04:05")
+		err := r.proxyService.UpdateBy(ctx, proxy, service.ProxyOperator{
+			Type:   service.ProxyOperatorSystem,
+			Reason: service.ProxyEventReasonReconciler,
+		})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("更新隧道 %d 失败: %s", proxy.ID, err.Error()))
+			continue
+		}
+
+		drift.NewStatus = proxy.Status
+		report.Drifts = append(report.Drifts, drift)
+		r.logger.Info("校正隧道状态", "proxyID", proxy.ID, "proxyName", proxy.ProxyName, "action", drift.Action, "oldStatus", drift.OldStatus, "newStatus", drift.NewStatus)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/repository/repositorytest"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/frps"
+	"stellarfrp/pkg/frps/frpstest"
+	"stellarfrp/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testEnv 使用内存仓库和模拟节点API组装的服务
+type testEnv struct {
+	nodeRepo     *repositorytest.NodeRepository
+	proxyRepo    *repositorytest.ProxyRepository
+	eventRepo    *repositorytest.ProxyEventRepository
+	nodeService  service.NodeService
+	proxyService service.ProxyService
+	frpsClient   *frps.Client
+	logger       *logger.Logger
+}
+
+func newTestEnv(nodes []*repository.Node, proxies []*repository.Proxy) *testEnv {
+	env := &testEnv{
+		nodeRepo:   repositorytest.NewNodeRepository(nodes...),
+		proxyRepo:  repositorytest.NewProxyRepository(proxies...),
+		eventRepo:  repositorytest.NewProxyEventRepository(),
+		frpsClient: frps.NewClient(frps.Options{Timeout: time.Second, BreakerThreshold: 5, BreakerCooldown: time.Minute}),
+		logger:     logger.NewLogger("error"),
+	}
+	// 缓存不可用时服务只记录日志，不影响测试的主流程
+	redisCli := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	env.nodeService = service.NewNodeService(env.nodeRepo, nil, nil)
+	env.proxyService = service.NewProxyService(env.proxyRepo, nil, env.eventRepo, nil, nil, nil,
+		env.nodeService, nil, env.frpsClient, service.NewProxyStreamHub(), redisCli, env.logger)
+	return env
+}
+
+func (e *testEnv) proxy(t *testing.T, id int64) *repository.Proxy {
+	t.Helper()
+	proxy, _ := e.proxyRepo.GetByID(context.Background(), id)
+	if proxy == nil {
+		t.Fatalf("proxy %d not found", id)
+	}
+	return proxy
+}
+
+func newTestFrpsServer(t *testing.T) *frpstest.Server {
+	t.Helper()
+	s := frpstest.NewServer("admin", "password")
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testNode(id int64, status int, s *frpstest.Server) *repository.Node {
+	return &repository.Node{ID: id, NodeName: "node", Status: status, URL: s.URL, User: s.User, Token: s.Token}
+}
+
+func TestProxyReconciler(t *testing.T) {
+	live := newTestFrpsServer(t)
+	live.SetProxies("tcp", []frps.ProxyStats{
+		{Name: "alice.web", Status: "online"},
+		{Name: "alice.ssh", Status: "offline"},
+		{Name: "alice.db", Status: "online"},
+		{Name: "alice.fresh", Status: "offline"},
+	})
+	failing := newTestFrpsServer(t)
+	failing.FailNext(10, http.StatusInternalServerError)
+	down := newTestFrpsServer(t)
+	down.Close()
+
+	stale := time.Now().Add(-time.Hour).Format("2006-01-02 15:04:05")
+	fresh := time.Now().Format("2006-01-02 15:04:05")
+	env := newTestEnv(
+		[]*repository.Node{
+			testNode(1, repository.NodeStatusOnline, live),
+			testNode(2, repository.NodeStatusOnline, failing),
+			testNode(3, repository.NodeStatusOffline, down),
+			testNode(4, repository.NodeStatusPending, down),
+		},
+		[]*repository.Proxy{
+			{ID: 1, Node: 1, Username: "alice", ProxyName: "web", ProxyType: "tcp", Status: "offline", LastUpdate: stale},
+			{ID: 2, Node: 1, Username: "alice", ProxyName: "ssh", ProxyType: "tcp", Status: "online", RunID: "r2", LastUpdate: stale},
+			{ID: 3, Node: 1, Username: "bob", ProxyName: "game", ProxyType: "tcp", Status: "offline", RunID: "r3", LastUpdate: stale},
+			{ID: 4, Node: 1, Username: "alice", ProxyName: "db", ProxyType: "tcp", Status: "online", RunID: "r4", LastUpdate: stale},
+			{ID: 5, Node: 1, Username: "alice", ProxyName: "fresh", ProxyType: "tcp", Status: "online", RunID: "r5", LastUpdate: fresh},
+			{ID: 6, Node: 2, Username: "alice", ProxyName: "api", ProxyType: "tcp", Status: "online", RunID: "r6", LastUpdate: stale},
+			{ID: 7, Node: 3, Username: "alice", ProxyName: "mc", ProxyType: "tcp", Status: "online", RunID: "r7", LastUpdate: stale},
+		},
+	)
+	r := NewProxyReconciler(env.proxyService, env.nodeService, env.frpsClient, env.logger)
+
+	report := r.RunOnce(context.Background())
+
+	// 待审核节点不校正，节点API失败时不校正该节点
+	if report.NodesChecked != 3 || report.NodesFailed != 1 {
+		t.Errorf("NodesChecked = %d, NodesFailed = %d; want 3, 1", report.NodesChecked, report.NodesFailed)
+	}
+	actions := make(map[int64]string)
+	for _, drift := range report.Drifts {
+		actions[drift.ProxyID] = drift.Action
+	}
+	want := map[int64]string{
+		1: DriftMissingRunID,
+		2: DriftMarkedOffline,
+		3: DriftClearedRunID,
+		// 节点已离线时不请求节点API，直接判定隧道离线
+		7: DriftMarkedOffline,
+	}
+	if len(actions) != len(want) {
+		t.Errorf("drifts = %v, want %v", actions, want)
+	}
+	for id, action := range want {
+		if actions[id] != action {
+			t.Errorf("proxy %d action = %q, want %q", id, actions[id], action)
+		}
+	}
+
+	tests := []struct {
+		id     int64
+		status string
+		runID  string
+	}{
+		{1, "online", ""},
+		{2, "offline", ""},
+		{3, "offline", ""},
+		{4, "online", "r4"},
+		{5, "online", "r5"},
+		{6, "online", "r6"},
+		{7, "offline", ""},
+	}
+	for _, tt := range tests {
+		proxy := env.proxy(t, tt.id)
+		if proxy.Status != tt.status || proxy.RunID != tt.runID {
+			t.Errorf("proxy %d = %s/%q, want %s/%q", tt.id, proxy.Status, proxy.RunID, tt.status, tt.runID)
+		}
+	}
+
+	// 状态变化记录为校正器产生的事件
+	events := env.eventRepo.Events()
+	if len(events) != 3 {
+		t.Fatalf("events = %d, want 3", len(events))
+	}
+	for _, event := range events {
+		if event.Reason != service.ProxyEventReasonReconciler {
+			t.Errorf("event reason = %q", event.Reason)
+		}
+	}
+
+	if r.LastReport() != report {
+		t.Error("LastReport 应返回最近一次校正结果")
+	}
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"context"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// ProxyScheduleScheduler 隧道在线时段调度器，负责踢下已禁用或超出时段的在线隧道
+type ProxyScheduleScheduler struct {
+	proxyService         service.ProxyService
+	proxyScheduleService service.ProxyScheduleService
+	logger               *logger.Logger
+	quit                 chan struct{}
+}
+
+// NewProxyScheduleScheduler 创建隧道在线时段调度器实例
+func NewProxyScheduleScheduler(
+	proxyService service.ProxyService,
+	proxyScheduleService service.ProxyScheduleService,
+	logger *logger.Logger,
+) *ProxyScheduleScheduler {
+	return &ProxyScheduleScheduler{
+		proxyService:         proxyService,
+		proxyScheduleService: proxyScheduleService,
+		logger:               logger,
+		quit:                 make(chan struct{}),
+	}
+}
+
+// Start 启动隧道在线时段调度器
+func (s *ProxyScheduleScheduler) Start() {
+	go s.enforceSchedulesScheduler()
+	s.logger.Info("隧道在线时段调度器启动")
+}
+
+// Stop 停止隧道在线时段调度器
+func (s *ProxyScheduleScheduler) Stop() {
+	close(s.quit)
+	s.logger.Info("隧道在线时段调度器停止")
+}
+
+// enforceSchedulesScheduler 在线时段检查定时器，对齐到整分钟执行
+func (s *ProxyScheduleScheduler) enforceSchedulesScheduler() {
+	now := time.Now()
+	select {
+	case <-time.After(now.Truncate(time.Minute).Add(time.Minute).Sub(now)):
+		s.enforceSchedules()
+	case <-s.quit:
+		return
+	}
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.enforceSchedules()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// enforceSchedules 踢下当前不允许在线的隧道
+func (s *ProxyScheduleScheduler) enforceSchedules() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+	defer cancel()
+
+	schedules, err := s.proxyScheduleService.ListRestrictedOnline(ctx)
+	if err != nil {
+		s.logger.Error("获取受限隧道列表失败", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, schedule := range schedules {
+		if !schedule.Disabled && service.InScheduleWindows(schedule.Windows, now) {
+			continue
+		}
+
+		proxy, err := s.proxyService.GetByID(ctx, schedule.ProxyID)
+		if err != nil {
+			s.logger.Error("获取隧道信息失败", "error", err, "proxyID", schedule.ProxyID)
+			continue
+		}
+		if proxy == nil || proxy.Status != "online" {
+			continue
+		}
+
+		reason := service.ProxyEventReasonScheduleKick
+		if schedule.Disabled {
+			reason = service.ProxyEventReasonUserDisabled
+		}
+		if err := s.proxyService.KickProxy(ctx, proxy, reason); err != nil {
+			s.logger.Error("关闭超出在线时段的隧道失败", "error", err, "proxyID", proxy.ID, "proxyName", proxy.ProxyName)
+			continue
+		}
+		s.logger.Info("已关闭超出在线时段的隧道", "proxyID", proxy.ID, "proxyName", proxy.ProxyName, "disabled", schedule.Disabled)
+	}
+}
@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"context"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/frps"
+	"stellarfrp/pkg/logger"
+	"sync"
+	"time"
+)
+
+// ProxyStatusPoller 隧道状态轮询器，定期从所有节点采集隧道状态并写入Redis快照
+type ProxyStatusPoller struct {
+	proxyService    service.ProxyService
+	nodeService     service.NodeService
+	snapshotService service.ProxyStatusSnapshotService
+	streamHub       service.ProxyStreamHub
+	frpsClient      *frps.Client
+	logger          *logger.Logger
+	quit            chan struct{}
+
+	mu          sync.Mutex
+	failedNodes map[int64]bool
+}
+
+// NewProxyStatusPoller 创建隧道状态轮询器实例
+func NewProxyStatusPoller(
+	proxyService service.ProxyService,
+	nodeService service.NodeService,
+	snapshotService service.ProxyStatusSnapshotService,
+	streamHub service.ProxyStreamHub,
+	frpsClient *frps.Client,
+	logger *logger.Logger,
+) *ProxyStatusPoller {
+	return &ProxyStatusPoller{
+		proxyService:    proxyService,
+		nodeService:     nodeService,
+		snapshotService: snapshotService,
+		streamHub:       streamHub,
+		frpsClient:      frpsClient,
+		logger:          logger,
+		quit:            make(chan struct{}),
+		failedNodes:     make(map[int64]bool),
+	}
+}
+
+// Start 启动隧道状态轮询器
+func (p *ProxyStatusPoller) Start() {
+	go p.pollScheduler()
+	p.logger.Info("隧道状态轮询器启动")
+}
+
+// Stop 停止隧道状态轮询器
+func (p *ProxyStatusPoller) Stop() {
+	close(p.quit)
+	p.logger.Info("隧道状态轮询器停止")
+}
+
+// pollScheduler 隧道状态轮询定时器
+func (p *ProxyStatusPoller) pollScheduler() {
+	p.poll()
+
+	ticker := time.NewTicker(service.ProxyStatusPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.poll()
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// poll 并发采集所有节点的隧道状态
+func (p *ProxyStatusPoller) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), service.ProxyStatusPollInterval)
+	defer cancel()
+
+	nodes, err := p.nodeService.GetAllNodes(ctx)
+	if err != nil {
+		p.logger.Error("获取节点列表失败", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		// 未通过审核的节点尚未接入，跳过
+		if repository.NodeUnapproved(node.Status) {
+			continue
+		}
+		wg.Add(1)
+		go func(node *repository.Node) {
+			defer wg.Done()
+			p.pollNode(ctx, node)
+		}(node)
+	}
+	wg.Wait()
+}
+
+// pollNode 采集单个节点的隧道状态；节点不可达时保留旧快照，由更新时间体现其新鲜度
+func (p *ProxyStatusPoller) pollNode(ctx context.Context, node *repository.Node) {
+	proxies, err := p.proxyService.ListByNode(ctx, node.ID)
+	if err != nil {
+		p.logger.Error("获取节点隧道失败", "error", err, "nodeID", node.ID)
+		return
+	}
+	if len(proxies) == 0 {
+		return
+	}
+
+	stats := make(map[string]frps.ProxyStats)
+	if repository.NodeReachable(node.Status) {
+		types := make(map[string]bool)
+		for _, proxy := range proxies {
+			types[proxy.ProxyType] = true
+		}
+		for proxyType := range types {
+			typeStats, err := p.frpsClient.ListProxies(ctx, service.NodeEndpoint(node), proxyType)
+			if err != nil {
+				p.markNodeFailed(node, err)
+				return
+			}
+			for _, s := range typeStats {
+				stats[s.Name] = s
+			}
+		}
+		p.markNodeRecovered(node)
+	}
+
+	now := time.Now()
+	snapshots := make([]*service.ProxyStatusSnapshot, 0, len(proxies))
+	for _, proxy := range proxies {
+		snapshot := &service.ProxyStatusSnapshot{
+			ProxyID:   proxy.ID,
+			ProxyName: proxy.ProxyName,
+			Username:  proxy.Username,
+			NodeID:    node.ID,
+			NodeName:  node.NodeName,
+			Status:    "offline",
+			UpdatedAt: now,
+		}
+		if s, ok := stats[proxy.Username+"."+proxy.ProxyName]; ok {
+			if s.Status == "online" {
+				snapshot.Status = "online"
+			}
+			snapshot.CurConns = s.CurConns
+			snapshot.TodayTrafficIn = s.TodayTrafficIn
+			snapshot.TodayTrafficOut = s.TodayTrafficOut
+			snapshot.LastStartTime = s.LastStartTime
+			snapshot.LastCloseTime = s.LastCloseTime
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := p.snapshotService.Save(ctx, snapshots); err != nil {
+		p.logger.Error("写入隧道状态快照失败", "error", err, "nodeID", node.ID)
+	}
+
+	p.publishStats(snapshots)
+}
+
+// publishStats 将本轮采集结果按用户推送给在线的订阅者
+func (p *ProxyStatusPoller) publishStats(snapshots []*service.ProxyStatusSnapshot) {
+	byUser := make(map[string][]service.ProxyStreamStats)
+	for _, snapshot := range snapshots {
+		if !p.streamHub.HasSubscribers(snapshot.Username) {
+			continue
+		}
+		byUser[snapshot.Username] = append(byUser[snapshot.Username], service.NewProxyStreamStats(snapshot))
+	}
+
+	for username, stats := range byUser {
+		p.streamHub.Publish(username, service.ProxyStreamEvent{
+			Type: service.ProxyStreamEventStats,
+			Data: stats,
+		})
+	}
+}
+
+// markNodeFailed 记录节点采集失败，仅在状态变化时输出日志，避免每轮刷屏
+func (p *ProxyStatusPoller) markNodeFailed(node *repository.Node, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.failedNodes[node.ID] {
+		p.logger.Warn("节点隧道状态采集失败，保留旧快照", "error", err, "nodeID", node.ID, "nodeName", node.NodeName)
+	}
+	p.failedNodes[node.ID] = true
+}
+
+// markNodeRecovered 记录节点采集恢复
+func (p *ProxyStatusPoller) markNodeRecovered(node *repository.Node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failedNodes[node.ID] {
+		p.logger.Info("节点隧道状态采集恢复", "nodeID", node.ID, "nodeName", node.NodeName)
+		delete(p.failedNodes, node.ID)
+	}
+}
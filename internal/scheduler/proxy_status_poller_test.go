@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"stellarfrp/pkg/frps"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSnapshotService 保存在内存中的隧道状态快照服务
+type fakeSnapshotService struct {
+	service.ProxyStatusSnapshotService
+
+	mu        sync.Mutex
+	snapshots map[int64]*service.ProxyStatusSnapshot
+}
+
+func (s *fakeSnapshotService) Save(ctx context.Context, snapshots []*service.ProxyStatusSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, snapshot := range snapshots {
+		s.snapshots[snapshot.ProxyID] = snapshot
+	}
+	return nil
+}
+
+func TestProxyStatusPoller(t *testing.T) {
+	live := newTestFrpsServer(t)
+	live.SetProxies("tcp", []frps.ProxyStats{{Name: "alice.web", Status: "online", CurConns: 2, TodayTrafficIn: 10, TodayTrafficOut: 20}})
+	live.SetProxies("udp", []frps.ProxyStats{{Name: "bob.dns", Status: "offline", TodayTrafficIn: 5}})
+	failing := newTestFrpsServer(t)
+	failing.FailNext(1, http.StatusInternalServerError)
+	down := newTestFrpsServer(t)
+	down.Close()
+
+	env := newTestEnv(
+		[]*repository.Node{
+			testNode(1, repository.NodeStatusOnline, live),
+			testNode(2, repository.NodeStatusOnline, failing),
+			testNode(3, repository.NodeStatusOffline, down),
+		},
+		[]*repository.Proxy{
+			{ID: 1, Node: 1, Username: "alice", ProxyName: "web", ProxyType: "tcp"},
+			{ID: 2, Node: 1, Username: "bob", ProxyName: "dns", ProxyType: "udp"},
+			{ID: 3, Node: 1, Username: "alice", ProxyName: "gone", ProxyType: "tcp"},
+			{ID: 4, Node: 2, Username: "alice", ProxyName: "api", ProxyType: "tcp"},
+			{ID: 5, Node: 3, Username: "alice", ProxyName: "mc", ProxyType: "tcp"},
+		},
+	)
+	// 节点API失败时保留上一轮的快照
+	old := &service.ProxyStatusSnapshot{ProxyID: 4, Status: "online"}
+	snapshots := &fakeSnapshotService{snapshots: map[int64]*service.ProxyStatusSnapshot{4: old}}
+	hub := service.NewProxyStreamHub()
+	events, cancel, err := hub.Subscribe("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	p := NewProxyStatusPoller(env.proxyService, env.nodeService, snapshots, hub, env.frpsClient, env.logger)
+	p.poll()
+
+	tests := []struct {
+		id       int64
+		status   string
+		curConns int
+		in       int64
+	}{
+		{1, "online", 2, 10},
+		{2, "offline", 0, 5},
+		{3, "offline", 0, 0},
+		{5, "offline", 0, 0},
+	}
+	for _, tt := range tests {
+		s := snapshots.snapshots[tt.id]
+		if s == nil {
+			t.Errorf("proxy %d 缺少快照", tt.id)
+			continue
+		}
+		if s.Status != tt.status || s.CurConns != tt.curConns || s.TodayTrafficIn != tt.in {
+			t.Errorf("proxy %d snapshot = %+v", tt.id, s)
+		}
+	}
+	if snapshots.snapshots[4] != old {
+		t.Error("节点API失败时不应覆盖旧快照")
+	}
+	if !p.failedNodes[2] {
+		t.Error("应记录采集失败的节点")
+	}
+
+	// 节点1和节点3上alice的隧道分别推送一次
+	got := make(map[int64]string)
+	timeout := time.After(time.Second)
+	for len(got) < 3 {
+		select {
+		case event := <-events:
+			if event.Type != service.ProxyStreamEventStats {
+				t.Fatalf("event type = %q", event.Type)
+			}
+			for _, stats := range event.Data.([]service.ProxyStreamStats) {
+				got[stats.ProxyID] = stats.Status
+			}
+		case <-timeout:
+			t.Fatalf("stats = %v, want proxies 1, 3, 5", got)
+		}
+	}
+	if got[1] != "online" || got[3] != "offline" || got[5] != "offline" {
+		t.Errorf("stats = %v", got)
+	}
+
+	// 节点恢复后清除失败记录
+	p.poll()
+	if p.failedNodes[2] {
+		t.Error("节点恢复后应清除失败记录")
+	}
+}
@@ -1,12 +1,7 @@
 package scheduler
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
 	"stellarfrp/internal/repository"
 	"stellarfrp/internal/service"
 	"stellarfrp/pkg/logger"
@@ -183,12 +178,6 @@ func (s *TrafficScheduler) recordUserTraffic() {
 							s.logger.Error("关闭隧道失败", "username", user.Username, "proxy_name", proxy.ProxyName, "run_id", proxy.RunID, "error", err)
 						} else {
 							closedCount++
-							proxy.Status = "offline"
-							proxy.RunID = ""
-							proxy.LastUpdate = time.Now().Format("2006-01-02 15:04:05")
-							if errUpdate := s.proxyService.Update(ctx, proxy); errUpdate != nil {
-								s.logger.Error("更新隧道状态为offline失败", "username", user.Username, "proxy_name", proxy.ProxyName, "error", errUpdate)
-							}
 						}
 					}
 				}
@@ -201,46 +190,18 @@ func (s *TrafficScheduler) recordUserTraffic() {
 	s.logger.Info("所有用户检查完成")
 }
 
-// closeTunnel 关闭单个隧道，通过调用节点API
+// closeTunnel 通过节点API踢下超额用户的隧道，并将隧道标记为离线
 func (s *TrafficScheduler) closeTunnel(ctx context.Context, proxy *repository.Proxy) error {
 	if proxy.RunID == "" {
 		s.logger.Info("隧道没有有效的 RunID，无需关闭", "proxy_name", proxy.ProxyName)
 		return nil // 没有运行ID，无需关闭
 	}
 
-	node, err := s.nodeService.GetByID(ctx, proxy.Node)
-	if err != nil {
-		return fmt.Errorf("获取节点信息失败: %w", err)
-	}
-	if node == nil {
-		return fmt.Errorf("节点不存在: %d", proxy.Node)
-	}
-
-	apiURL := fmt.Sprintf("%s/api/client/kick", node.URL)
-	requestBody, err := json.Marshal(map[string]string{"runid": proxy.RunID})
-	if err != nil {
-		return fmt.Errorf("构建请求体失败: %w", err)
-	}
-
-	httpClient := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return fmt.Errorf("创建请求失败: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.SetBasicAuth(node.User, node.Token)
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("发送关闭隧道请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("节点返回错误, status: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	runID := proxy.RunID
+	if err := s.proxyService.KickProxy(ctx, proxy, service.ProxyEventReasonTrafficExhausted); err != nil {
+		return err
 	}
 
-	s.logger.Info("成功向节点发送关闭隧道请求", "proxy_name", proxy.ProxyName, "run_id", proxy.RunID, "node_url", apiURL)
+	s.logger.Info("成功向节点发送关闭隧道请求", "proxy_name", proxy.ProxyName, "run_id", runID, "node", proxy.Node)
 	return nil
 }
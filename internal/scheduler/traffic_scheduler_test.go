@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/service"
+	"testing"
+)
+
+func TestTrafficSchedulerCloseTunnel(t *testing.T) {
+	s := newTestFrpsServer(t)
+	env := newTestEnv(
+		[]*repository.Node{testNode(1, repository.NodeStatusOnline, s)},
+		[]*repository.Proxy{
+			{ID: 1, Node: 1, Username: "alice", ProxyName: "web", ProxyType: "tcp", Status: "online", RunID: "r1"},
+			{ID: 2, Node: 1, Username: "alice", ProxyName: "ssh", ProxyType: "tcp", Status: "online", RunID: "r2"},
+		},
+	)
+	scheduler := NewTrafficScheduler(nil, nil, env.proxyService, env.nodeService, env.logger)
+
+	if err := scheduler.closeTunnel(context.Background(), env.proxy(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if kicked := s.Kicked(); len(kicked) != 1 || kicked[0] != "r1" {
+		t.Errorf("Kicked = %v, want [r1]", kicked)
+	}
+	if proxy := env.proxy(t, 1); proxy.Status != "offline" || proxy.RunID != "" {
+		t.Errorf("proxy = %s/%q, want offline without run id", proxy.Status, proxy.RunID)
+	}
+	events := env.eventRepo.Events()
+	if len(events) != 1 || events[0].Reason != service.ProxyEventReasonTrafficExhausted {
+		t.Errorf("events = %+v, want one traffic_exhausted event", events)
+	}
+
+	// 节点拒绝踢下时保留隧道状态，等待下一轮重试
+	s.FailNext(1, http.StatusInternalServerError)
+	if err := scheduler.closeTunnel(context.Background(), env.proxy(t, 2)); err == nil {
+		t.Fatal("节点返回错误时应返回错误")
+	}
+	if proxy := env.proxy(t, 2); proxy.Status != "online" || proxy.RunID != "r2" {
+		t.Errorf("proxy = %s/%q, want unchanged", proxy.Status, proxy.RunID)
+	}
+}
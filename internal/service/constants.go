@@ -1,9 +0,0 @@
-package service
-
-import "time"
-
-// API访问相关常量
-const (
-	// API请求超时时间
-	apiTimeout = 30 * time.Second
-)
@@ -3,6 +3,10 @@ package service
 import (
 	"context"
 	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/frps"
+	"stellarfrp/pkg/secret"
+	"stellarfrp/pkg/webhook"
+	"time"
 )
 
 // NodeService 节点服务接口
@@ -15,21 +19,51 @@ type NodeService interface {
 	List(ctx context.Context, offset, limit int) ([]*repository.Node, error)
 	GetAllNodes(ctx context.Context) ([]*repository.Node, error)
 	CreateNode(ctx context.Context, node *repository.Node) error
+	UpdateFrpsVersion(ctx context.Context, node *repository.Node, version string) error
+	UpdateStatus(ctx context.Context, node *repository.Node, status int) error
+	ResetAgentKey(ctx context.Context, node *repository.Node) (string, error)
+	ResetPluginSecret(ctx context.Context, node *repository.Node) (string, error)
+	ResetToken(ctx context.Context, node *repository.Node) (string, error)
+	SetRewardBlocked(ctx context.Context, node *repository.Node, blocked bool) error
+	Secrets(node *repository.Node) (*NodeSecrets, error)
+	RotateSecrets(ctx context.Context) (int, error)
+	Update(ctx context.Context, node *repository.Node) error
+	Delete(ctx context.Context, id int64) error
+	GetDailyTraffic(ctx context.Context, node *repository.Node, end time.Time, days int) ([]*NodeDailyTraffic, error)
 	GetLatestNodeTraffic(ctx context.Context, nodeName string) (*repository.NodeTrafficLog, error)
 	GetTotalTraffic(ctx context.Context) (int64, int64, error) // 返回总入站流量和总出站流量
 }
 
+// NodeSecrets 解密后的节点凭据，仅在校验签名、生成frps配置或管理员明确查看时使用
+type NodeSecrets struct {
+	Token        string `json:"token"`
+	AgentKey     string `json:"agent_key"`
+	PluginSecret string `json:"plugin_secret"`
+}
+
+// NodeEndpoint 获取节点 Dashboard API 的连接信息，Token 为加密后的值，由 frps 客户端在请求时解密
+func NodeEndpoint(node *repository.Node) frps.Endpoint {
+	return frps.Endpoint{
+		URL:     node.URL,
+		User:    node.User,
+		Token:   node.Token,
+		Version: node.FrpsVersion,
+	}
+}
+
 // nodeService 节点服务实现
 type nodeService struct {
 	nodeRepo        repository.NodeRepository
 	nodeTrafficRepo repository.NodeTrafficRepository
+	keyring         *secret.Keyring
 }
 
 // NewNodeService 创建节点服务实例
-func NewNodeService(nodeRepo repository.NodeRepository, nodeTrafficRepo repository.NodeTrafficRepository) NodeService {
+func NewNodeService(nodeRepo repository.NodeRepository, nodeTrafficRepo repository.NodeTrafficRepository, keyring *secret.Keyring) NodeService {
 	return &nodeService{
 		nodeRepo:        nodeRepo,
 		nodeTrafficRepo: nodeTrafficRepo,
+		keyring:         keyring,
 	}
 }
 
@@ -75,6 +109,160 @@ func (s *nodeService) CreateNode(ctx context.Context, node *repository.Node) err
 	return s.nodeRepo.Create(ctx, node)
 }
 
+// UpdateFrpsVersion 记录节点的frps版本，版本未变化时不写库
+func (s *nodeService) UpdateFrpsVersion(ctx context.Context, node *repository.Node, version string) error {
+	if version == "" || version == node.FrpsVersion {
+		return nil
+	}
+	if err := s.nodeRepo.UpdateFrpsVersion(ctx, node.ID, version); err != nil {
+		return err
+	}
+	node.FrpsVersion = version
+	return nil
+}
+
+// UpdateStatus 更新节点状态，状态未变化时不写库
+func (s *nodeService) UpdateStatus(ctx context.Context, node *repository.Node, status int) error {
+	if node.Status == status {
+		return nil
+	}
+	if err := s.nodeRepo.UpdateStatus(ctx, node.ID, status); err != nil {
+		return err
+	}
+	node.Status = status
+	return nil
+}
+
+// ResetAgentKey 为节点生成新的代理心跳签名密钥，旧密钥立即失效
+func (s *nodeService) ResetAgentKey(ctx context.Context, node *repository.Node) (string, error) {
+	agentKey, err := webhook.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := s.nodeRepo.UpdateAgentKey(ctx, node.ID, agentKey); err != nil {
+		return "", err
+	}
+	node.AgentKey = agentKey
+	return agentKey, nil
+}
+
+// ResetPluginSecret 为节点生成新的frps插件回调密钥，旧密钥立即失效，需重新下发frps配置
+func (s *nodeService) ResetPluginSecret(ctx context.Context, node *repository.Node) (string, error) {
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := s.nodeRepo.UpdatePluginSecret(ctx, node.ID, secret); err != nil {
+		return "", err
+	}
+	node.PluginSecret = secret
+	return secret, nil
+}
+
+// ResetToken 为节点生成新的Dashboard密码，需按新配置重启frps后面板才能重新连接节点
+func (s *nodeService) ResetToken(ctx context.Context, node *repository.Node) (string, error) {
+	token, err := webhook.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	oldToken := node.Token
+	node.Token = token
+	if err := s.nodeRepo.Update(ctx, node); err != nil {
+		node.Token = oldToken
+		return "", err
+	}
+	return token, nil
+}
+
+// SetRewardBlocked 设置节点是否停止向所有者发放贡献奖励
+func (s *nodeService) SetRewardBlocked(ctx context.Context, node *repository.Node, blocked bool) error {
+	if err := s.nodeRepo.UpdateRewardBlocked(ctx, node.ID, blocked); err != nil {
+		return err
+	}
+	node.RewardBlocked = blocked
+	return nil
+}
+
+// Secrets 解密节点凭据
+func (s *nodeService) Secrets(node *repository.Node) (*NodeSecrets, error) {
+	token, err := s.keyring.Decrypt(node.Token)
+	if err != nil {
+		return nil, err
+	}
+	agentKey, err := s.keyring.Decrypt(node.AgentKey)
+	if err != nil {
+		return nil, err
+	}
+	pluginSecret, err := s.keyring.Decrypt(node.PluginSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeSecrets{Token: token, AgentKey: agentKey, PluginSecret: pluginSecret}, nil
+}
+
+// RotateSecrets 使用当前密钥重新加密所有节点凭据，返回更新的节点数
+func (s *nodeService) RotateSecrets(ctx context.Context) (int, error) {
+	return s.nodeRepo.RotateSecrets(ctx)
+}
+
+// Update 更新节点信息
+func (s *nodeService) Update(ctx context.Context, node *repository.Node) error {
+	return s.nodeRepo.Update(ctx, node)
+}
+
+// Delete 删除节点
+func (s *nodeService) Delete(ctx context.Context, id int64) error {
+	return s.nodeRepo.Delete(ctx, id)
+}
+
+// NodeDailyTraffic 节点单日流量统计
+type NodeDailyTraffic struct {
+	Date        string `json:"date"`
+	TrafficIn   int64  `json:"traffic_in"`
+	TrafficOut  int64  `json:"traffic_out"`
+	OnlineCount int    `json:"online_count"`
+}
+
+// GetDailyTraffic 获取节点截至 end 当天的最近 days 天每天的流量，当天或前一天没有记录时流量为0
+// 流量记录保存的是累计值，单日流量为当天与前一天记录之差
+func (s *nodeService) GetDailyTraffic(ctx context.Context, node *repository.Node, end time.Time, days int) ([]*NodeDailyTraffic, error) {
+	start := end.AddDate(0, 0, -days+1)
+	logs, err := s.nodeTrafficRepo.ListByDateRange(ctx, node.NodeName, start.AddDate(0, 0, -1).Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]*repository.NodeTrafficLog, len(logs))
+	for _, log := range logs {
+		byDate[trafficLogDate(log)] = log
+	}
+
+	result := make([]*NodeDailyTraffic, 0, days)
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i)
+		date := day.Format("2006-01-02")
+		item := &NodeDailyTraffic{Date: date}
+		if log := byDate[date]; log != nil {
+			item.OnlineCount = log.OnlineCount
+			// 缺少前一天的记录时无法得知当天的增量，按0计算，不能把累计值当作当天流量
+			if prev := byDate[day.AddDate(0, 0, -1).Format("2006-01-02")]; prev != nil {
+				item.TrafficIn = max(log.TrafficIn-prev.TrafficIn, 0)
+				item.TrafficOut = max(log.TrafficOut-prev.TrafficOut, 0)
+			}
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// trafficLogDate 取流量记录的日期部分，兼容驱动将date列解析为时间字符串的情况
+func trafficLogDate(log *repository.NodeTrafficLog) string {
+	if len(log.RecordDate) > 10 {
+		return log.RecordDate[:10]
+	}
+	return log.RecordDate
+}
+
 // GetLatestNodeTraffic 获取指定节点的最新流量记录
 func (s *nodeService) GetLatestNodeTraffic(ctx context.Context, nodeName string) (*repository.NodeTrafficLog, error) {
 	// 首先检查节点是否存在
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/webhook"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// nodeAgentMaxSkew 节点代理请求时间戳与服务器时间允许的最大偏差
+const nodeAgentMaxSkew = 5 * time.Minute
+
+// 节点代理鉴权错误
+var (
+	ErrNodeAgentUnauthorized = errors.New("节点签名校验失败")
+	ErrNodeAgentExpired      = errors.New("请求时间戳已过期或重复")
+)
+
+// NodeAgentAuthService 节点代理请求鉴权服务接口
+type NodeAgentAuthService interface {
+	Authenticate(ctx context.Context, nodeID, timestamp int64, signature string, body []byte) (*repository.Node, error)
+}
+
+// nodeAgentAuthService 节点代理请求鉴权服务实现
+type nodeAgentAuthService struct {
+	nodeService NodeService
+	redisCli    *redis.Client
+}
+
+// NewNodeAgentAuthService 创建节点代理请求鉴权服务实例
+func NewNodeAgentAuthService(nodeService NodeService, redisCli *redis.Client) NodeAgentAuthService {
+	return &nodeAgentAuthService{
+		nodeService: nodeService,
+		redisCli:    redisCli,
+	}
+}
+
+// Authenticate 校验签名与时间戳，签名内容为 "时间戳.请求体"，密钥为节点的代理密钥
+// 同一签名在允许的时间偏差内只能使用一次，防止请求被重放
+func (s *nodeAgentAuthService) Authenticate(ctx context.Context, nodeID, timestamp int64, signature string, body []byte) (*repository.Node, error) {
+	node, err := s.nodeService.GetByID(ctx, nodeID)
+	if err != nil || node.AgentKey == "" {
+		return nil, ErrNodeAgentUnauthorized
+	}
+	secrets, err := s.nodeService.Secrets(node)
+	if err != nil {
+		return nil, fmt.Errorf("解密节点代理密钥失败: %w", err)
+	}
+	if !webhook.Verify(secrets.AgentKey, timestamp, body, signature) {
+		return nil, ErrNodeAgentUnauthorized
+	}
+
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew > nodeAgentMaxSkew || skew < -nodeAgentMaxSkew {
+		return nil, ErrNodeAgentExpired
+	}
+
+	key := fmt.Sprintf("node:agent:nonce:%d:%s", nodeID, signature)
+	fresh, err := s.redisCli.SetNX(ctx, key, 1, 2*nodeAgentMaxSkew).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrNodeAgentExpired
+	}
+	return node, nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"strings"
+)
+
+// NodeProxyTypes 支持按协议类型限制数量的隧道类型
+var NodeProxyTypes = map[string]bool{
+	"tcp":   true,
+	"udp":   true,
+	"http":  true,
+	"https": true,
+	"stcp":  true,
+	"xtcp":  true,
+	"sudp":  true,
+}
+
+// NodeTypeCapacity 节点单个协议类型的容量使用情况
+type NodeTypeCapacity struct {
+	Max       int `json:"max"`
+	Used      int `json:"used"`
+	Remaining int `json:"remaining"`
+}
+
+// NodeCapacity 节点容量使用情况，上限为0表示不限制，此时剩余量为nil
+type NodeCapacity struct {
+	MaxProxies       int                          `json:"max_proxies"`
+	Proxies          int                          `json:"proxies"`
+	RemainingProxies *int                         `json:"remaining_proxies"`
+	Types            map[string]*NodeTypeCapacity `json:"types"`
+	MaxOnlineClients int                          `json:"max_online_clients"`
+	OnlineClients    int                          `json:"online_clients"`
+	RemainingClients *int                         `json:"remaining_clients"`
+}
+
+// NodeCapacityService 节点容量服务接口
+type NodeCapacityService interface {
+	Get(ctx context.Context, node *repository.Node) (*NodeCapacity, error)
+	CheckProxy(ctx context.Context, node *repository.Node, proxyType string, existing *repository.Proxy) (string, error)
+	CheckClient(ctx context.Context, nodeID int64, runID string) (string, error)
+}
+
+// nodeCapacityService 节点容量服务实现
+type nodeCapacityService struct {
+	nodeService  NodeService
+	proxyService ProxyService
+}
+
+// NewNodeCapacityService 创建节点容量服务实例
+func NewNodeCapacityService(nodeService NodeService, proxyService ProxyService) NodeCapacityService {
+	return &nodeCapacityService{
+		nodeService:  nodeService,
+		proxyService: proxyService,
+	}
+}
+
+// Get 统计节点的隧道数量与在线客户端数量
+func (s *nodeCapacityService) Get(ctx context.Context, node *repository.Node) (*NodeCapacity, error) {
+	counts, err := s.proxyService.CountByNodeGroupByType(ctx, node.ID)
+	if err != nil {
+		return nil, fmt.Errorf("统计节点隧道数量失败: %w", err)
+	}
+
+	capacity := &NodeCapacity{
+		MaxProxies:       node.MaxProxies,
+		Types:            make(map[string]*NodeTypeCapacity),
+		MaxOnlineClients: node.MaxOnlineClients,
+	}
+	for _, count := range counts {
+		capacity.Proxies += count
+	}
+	if node.MaxProxies > 0 {
+		remaining := max(node.MaxProxies-capacity.Proxies, 0)
+		capacity.RemainingProxies = &remaining
+	}
+	for proxyType, limit := range ParseNodeTypeLimits(node) {
+		capacity.Types[proxyType] = &NodeTypeCapacity{
+			Max:       limit,
+			Used:      counts[proxyType],
+			Remaining: max(limit-counts[proxyType], 0),
+		}
+	}
+
+	if node.MaxOnlineClients > 0 {
+		runIDs, err := s.proxyService.ListOnlineRunIDs(ctx, node.ID)
+		if err != nil {
+			return nil, fmt.Errorf("统计节点在线客户端失败: %w", err)
+		}
+		capacity.OnlineClients = len(runIDs)
+		remaining := max(node.MaxOnlineClients-capacity.OnlineClients, 0)
+		capacity.RemainingClients = &remaining
+	}
+	return capacity, nil
+}
+
+// CheckProxy 检查节点是否还能容纳指定类型的隧道，返回拒绝原因，为空表示通过
+// existing 为更新时的原隧道，原隧道已在该节点上时不重复占用名额
+func (s *nodeCapacityService) CheckProxy(ctx context.Context, node *repository.Node, proxyType string, existing *repository.Proxy) (string, error) {
+	typeLimits := ParseNodeTypeLimits(node)
+	if node.MaxProxies <= 0 && len(typeLimits) == 0 {
+		return "", nil
+	}
+
+	counts, err := s.proxyService.CountByNodeGroupByType(ctx, node.ID)
+	if err != nil {
+		return "", fmt.Errorf("统计节点隧道数量失败: %w", err)
+	}
+
+	onNode := existing != nil && existing.Node == node.ID
+	if node.MaxProxies > 0 && !onNode {
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		if total >= node.MaxProxies {
+			return fmt.Sprintf("节点 %s 的隧道数量已达上限（%d 个），请更换节点", node.NodeName, node.MaxProxies), nil
+		}
+	}
+
+	proxyType = strings.ToLower(proxyType)
+	if limit := typeLimits[proxyType]; limit > 0 {
+		sameType := onNode && strings.EqualFold(existing.ProxyType, proxyType)
+		if !sameType && counts[proxyType] >= limit {
+			return fmt.Sprintf("节点 %s 的 %s 隧道数量已达上限（%d 个），请更换节点", node.NodeName, strings.ToUpper(proxyType), limit), nil
+		}
+	}
+	return "", nil
+}
+
+// CheckClient 检查节点在线客户端数量是否已达上限，返回拒绝原因，为空表示通过
+// runID 已有在线隧道的客户端不计为新客户端
+func (s *nodeCapacityService) CheckClient(ctx context.Context, nodeID int64, runID string) (string, error) {
+	node, err := s.nodeService.GetByID(ctx, nodeID)
+	if err != nil {
+		return "", fmt.Errorf("获取节点信息失败: %w", err)
+	}
+	if node.MaxOnlineClients <= 0 {
+		return "", nil
+	}
+
+	runIDs, err := s.proxyService.ListOnlineRunIDs(ctx, nodeID)
+	if err != nil {
+		return "", fmt.Errorf("统计节点在线客户端失败: %w", err)
+	}
+	if runID != "" {
+		for _, id := range runIDs {
+			if id == runID {
+				return "", nil
+			}
+		}
+	}
+	if len(runIDs) >= node.MaxOnlineClients {
+		return fmt.Sprintf("节点 %s 的在线客户端数量已达上限（%d 个），请稍后再试或更换节点", node.NodeName, node.MaxOnlineClients), nil
+	}
+	return "", nil
+}
+
+// ParseNodeTypeLimits 解析节点按协议类型的隧道数量上限，配置为空或无法解析时返回空表
+func ParseNodeTypeLimits(node *repository.Node) map[string]int {
+	limits := map[string]int{}
+	if node.MaxTypeProxies == "" {
+		return limits
+	}
+	var raw map[string]int
+	if err := json.Unmarshal([]byte(node.MaxTypeProxies), &raw); err != nil {
+		return limits
+	}
+	for proxyType, limit := range raw {
+		if limit > 0 {
+			limits[strings.ToLower(proxyType)] = limit
+		}
+	}
+	return limits
+}
+
+// FormatNodeTypeLimits 校验按协议类型的隧道数量上限并格式化为JSON字符串，值为0的类型不限制
+func FormatNodeTypeLimits(limits map[string]int) (string, error) {
+	normalized := make(map[string]int, len(limits))
+	for proxyType, limit := range limits {
+		proxyType = strings.ToLower(strings.TrimSpace(proxyType))
+		if !NodeProxyTypes[proxyType] {
+			return "", fmt.Errorf("不支持的隧道类型 %s", proxyType)
+		}
+		if limit < 0 {
+			return "", fmt.Errorf("%s 隧道数量上限不能为负数", proxyType)
+		}
+		if limit > 0 {
+			normalized[proxyType] = limit
+		}
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ValidateNodeCapacity 校验节点隧道总数与在线客户端数量上限
+func ValidateNodeCapacity(node *repository.Node) error {
+	if node.MaxProxies < 0 {
+		return errors.New("隧道数量上限不能为负数")
+	}
+	if node.MaxOnlineClients < 0 {
+		return errors.New("在线客户端数量上限不能为负数")
+	}
+	return nil
+}
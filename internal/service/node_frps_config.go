@@ -0,0 +1,183 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"stellarfrp/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FrpsPluginPath frps插件回调的面板接口路径
+const FrpsPluginPath = "/api/v1/proxy/auth"
+
+// frpsPluginOps frps插件需要回调的操作
+var frpsPluginOps = []string{"Login", "NewProxy", "CloseProxy"}
+
+// FrpsConfig 生成的frps配置，Warnings 列出需要管理员人工确认的问题
+type FrpsConfig struct {
+	Content  string   `json:"content"`
+	Warnings []string `json:"warnings"`
+}
+
+// RenderFrpsConfig 根据节点信息生成完整的frps.toml
+// pluginURL 为面板API对外地址，secrets 为解密后的节点凭据
+func RenderFrpsConfig(node *repository.Node, pluginURL string, secrets *NodeSecrets, now time.Time) *FrpsConfig {
+	cfg := &FrpsConfig{Warnings: []string{}}
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# StellarFrp 节点 %s (ID %d) 的 frps 配置，由面板生成于 %s\n", node.NodeName, node.ID, now.Format(time.RFC3339))
+	b.WriteString("# 修改节点信息或重置插件密钥后需重新生成并重启 frps\n\n")
+
+	fmt.Fprintf(&b, "bindAddr = \"0.0.0.0\"\nbindPort = %d\n", node.FrpsPort)
+
+	types := map[string]bool{}
+	var allowedTypes []string
+	if err := json.Unmarshal([]byte(node.AllowedTypes), &allowedTypes); err != nil {
+		cfg.Warnings = append(cfg.Warnings, "节点开放类型配置无法解析，未生成HTTP/HTTPS监听端口")
+	}
+	for _, t := range allowedTypes {
+		types[strings.ToLower(t)] = true
+	}
+	if types["http"] || types["https"] {
+		// 节点未记录HTTP/HTTPS监听端口，使用默认端口，需管理员确认
+		b.WriteString("\n# HTTP/HTTPS 隧道监听端口，默认值，请按节点实际情况修改\n")
+		if types["http"] {
+			b.WriteString("vhostHTTPPort = 80\n")
+		}
+		if types["https"] {
+			b.WriteString("vhostHTTPSPort = 443\n")
+		}
+		cfg.Warnings = append(cfg.Warnings, "HTTP/HTTPS监听端口使用默认的80/443，请确认节点上未被占用或按实际端口修改")
+
+		host := strings.TrimSpace(node.Host.String)
+		if node.Host.Valid && host != "" && net.ParseIP(host) == nil {
+			fmt.Fprintf(&b, "subDomainHost = %s\n", tomlString(host))
+		} else {
+			cfg.Warnings = append(cfg.Warnings, "节点未配置域名，未生成 subDomainHost，HTTP/HTTPS隧道只能使用自定义域名")
+		}
+	}
+
+	minPort, maxPort, err := ParsePortRange(node.PortRange)
+	if err != nil {
+		cfg.Warnings = append(cfg.Warnings, "节点端口范围配置错误，未限制可用端口")
+	} else {
+		b.WriteString("\n# TCP/UDP 隧道可用的远程端口\n")
+		fmt.Fprintf(&b, "allowPorts = [\n  { start = %d, end = %d },\n]\n", minPort, maxPort)
+	}
+
+	b.WriteString("\n# Dashboard，面板通过该接口同步节点状态与流量\n")
+	webAddr, webPort, err := frpsDashboardAddr(node.URL)
+	if err != nil {
+		cfg.Warnings = append(cfg.Warnings, "节点面板地址无法解析，请手动填写 webServer.port")
+	}
+	fmt.Fprintf(&b, "webServer.addr = %s\n", tomlString(webAddr))
+	fmt.Fprintf(&b, "webServer.port = %d\n", webPort)
+	fmt.Fprintf(&b, "webServer.user = %s\n", tomlString(node.User))
+	fmt.Fprintf(&b, "webServer.password = %s\n", tomlString(secrets.Token))
+	if strings.HasPrefix(strings.ToLower(node.URL), "https://") {
+		cfg.Warnings = append(cfg.Warnings, "节点面板地址为HTTPS，请为Dashboard配置证书或在前置代理处终止TLS")
+	}
+
+	b.WriteString("\n# 登录、隧道启动与关闭由面板鉴权\n")
+	addr, path, err := frpsPluginAddr(pluginURL)
+	if err != nil {
+		cfg.Warnings = append(cfg.Warnings, "未配置或无法解析 FRPS_PLUGIN_URL，请手动填写插件地址")
+	}
+	query := url.Values{}
+	query.Set("node", strconv.FormatInt(node.ID, 10))
+	if secrets.PluginSecret != "" {
+		query.Set("secret", secrets.PluginSecret)
+	} else {
+		cfg.Warnings = append(cfg.Warnings, "节点尚未生成插件密钥，插件回调不校验密钥，请先重置插件密钥再生成配置")
+	}
+	quotedOps := make([]string, len(frpsPluginOps))
+	for i, op := range frpsPluginOps {
+		quotedOps[i] = tomlString(op)
+	}
+	b.WriteString("[[httpPlugins]]\n")
+	b.WriteString("name = \"stellarfrp-auth\"\n")
+	fmt.Fprintf(&b, "addr = %s\n", tomlString(addr))
+	fmt.Fprintf(&b, "path = %s\n", tomlString(path+"?"+query.Encode()))
+	fmt.Fprintf(&b, "ops = [%s]\n", strings.Join(quotedOps, ", "))
+
+	cfg.Content = b.String()
+	return cfg
+}
+
+// frpsDashboardAddr 从节点面板地址解析Dashboard监听地址和端口，解析失败时返回默认值
+func frpsDashboardAddr(rawURL string) (string, int, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "0.0.0.0", 7500, fmt.Errorf("面板地址格式错误: %s", rawURL)
+	}
+	if u.Port() == "" {
+		if u.Scheme == "https" {
+			return "0.0.0.0", 443, nil
+		}
+		return "0.0.0.0", 80, nil
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return "0.0.0.0", 7500, err
+	}
+	return "0.0.0.0", port, nil
+}
+
+// frpsPluginAddr 将面板API地址转换为frps插件的 addr 与 path
+// HTTP地址使用 host:port 形式，HTTPS地址保留协议前缀，地址中的路径前缀拼接到插件路径前
+func frpsPluginAddr(pluginURL string) (string, string, error) {
+	u, err := url.Parse(strings.TrimSpace(pluginURL))
+	if err != nil || u.Host == "" {
+		return "127.0.0.1:8080", FrpsPluginPath, fmt.Errorf("插件地址格式错误: %s", pluginURL)
+	}
+	path := strings.TrimRight(u.Path, "/") + FrpsPluginPath
+
+	switch u.Scheme {
+	case "https":
+		return "https://" + u.Host, path, nil
+	case "http":
+		if u.Port() == "" {
+			return u.Host + ":80", path, nil
+		}
+		return u.Host, path, nil
+	default:
+		return "127.0.0.1:8080", FrpsPluginPath, fmt.Errorf("插件地址仅支持http或https: %s", pluginURL)
+	}
+}
+
+// tomlString 将字符串格式化为TOML基本字符串
+// TOML不支持 \x、\a、\v 等转义，不能直接使用 strconv.Quote；无效的UTF-8字节替换为U+FFFD
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
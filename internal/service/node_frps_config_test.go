@@ -0,0 +1,23 @@
+package service
+
+import "testing"
+
+func TestTomlString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"admin", `"admin"`},
+		{`pa"ss\word`, `"pa\"ss\\word"`},
+		{"a\tb\nc\rd\be\ff", `"a\tb\nc\rd\be\ff"`},
+		// strconv.Quote 会输出 \a、\v、\x00，TOML只接受 \uXXXX
+		{"\a\v\x00\x7f", `"\u0007\u000B\u0000\u007F"`},
+		{"节点密码", `"节点密码"`},
+		{"bad\xffutf8", "\"bad�utf8\""},
+	}
+	for _, tt := range tests {
+		if got := tomlString(tt.in); got != tt.want {
+			t.Errorf("tomlString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/logger"
+	"stellarfrp/pkg/network"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 心跳参数
+const (
+	// NodeHeartbeatTimeout 超过该时长未收到心跳视为心跳超时
+	NodeHeartbeatTimeout = 30 * time.Second
+	// nodeHeartbeatRetention 心跳记录保留时长，超过后节点退回仅使用TCP端口检查
+	nodeHeartbeatRetention = 24 * time.Hour
+)
+
+// 降级阈值（百分比）
+const (
+	degradedCPUUsage  = 90
+	degradedMemUsage  = 90
+	degradedDiskUsage = 95
+)
+
+// NodeHeartbeat 节点代理上报的心跳，使用率均为0-100的百分比
+type NodeHeartbeat struct {
+	FrpsAlive   bool      `json:"frps_alive"`
+	FrpsVersion string    `json:"frps_version"`
+	Load1       float64   `json:"load1"`
+	CPUUsage    float64   `json:"cpu_usage"`
+	MemUsage    float64   `json:"mem_usage"`
+	DiskUsage   float64   `json:"disk_usage"`
+	Timestamp   int64     `json:"timestamp"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+// Fresh 判断心跳是否在超时时间内
+func (hb *NodeHeartbeat) Fresh(now time.Time) bool {
+	return now.Sub(hb.ReceivedAt) <= NodeHeartbeatTimeout
+}
+
+// status 根据心跳内容计算节点状态
+func (hb *NodeHeartbeat) status() int {
+	if !hb.FrpsAlive {
+		return repository.NodeStatusOffline
+	}
+	if hb.CPUUsage >= degradedCPUUsage || hb.MemUsage >= degradedMemUsage || hb.DiskUsage >= degradedDiskUsage {
+		return repository.NodeStatusDegraded
+	}
+	return repository.NodeStatusOnline
+}
+
+// NodeHeartbeatService 节点心跳服务接口
+type NodeHeartbeatService interface {
+	Receive(ctx context.Context, node *repository.Node, hb *NodeHeartbeat) error
+	Get(ctx context.Context, nodeID int64) (*NodeHeartbeat, error)
+	CheckTimeouts(ctx context.Context) error
+}
+
+// nodeHeartbeatService 节点心跳服务实现
+type nodeHeartbeatService struct {
+	nodeService NodeService
+	redisCli    *redis.Client
+	logger      *logger.Logger
+}
+
+// NewNodeHeartbeatService 创建节点心跳服务实例
+func NewNodeHeartbeatService(nodeService NodeService, redisCli *redis.Client, logger *logger.Logger) NodeHeartbeatService {
+	return &nodeHeartbeatService{
+		nodeService: nodeService,
+		redisCli:    redisCli,
+		logger:      logger,
+	}
+}
+
+// nodeHeartbeatKey 节点最近一次心跳的缓存键
+func nodeHeartbeatKey(nodeID int64) string {
+	return fmt.Sprintf("node:heartbeat:%d", nodeID)
+}
+
+// Receive 保存心跳并根据上报内容更新节点状态和frps版本，待审核节点只记录不改状态
+func (s *nodeHeartbeatService) Receive(ctx context.Context, node *repository.Node, hb *NodeHeartbeat) error {
+	hb.ReceivedAt = time.Now()
+	data, err := json.Marshal(hb)
+	if err != nil {
+		return err
+	}
+	if err := s.redisCli.Set(ctx, nodeHeartbeatKey(node.ID), data, nodeHeartbeatRetention).Err(); err != nil {
+		return err
+	}
+
+	if hb.FrpsAlive {
+		if err := s.nodeService.UpdateFrpsVersion(ctx, node, hb.FrpsVersion); err != nil {
+			s.logger.Error("更新节点frps版本失败", "error", err, "node", node.NodeName)
+		}
+	}
+
+	if repository.NodeUnapproved(node.Status) || node.Status == repository.NodeStatusPaused {
+		return nil
+	}
+	return s.updateStatus(ctx, node, hb.status(), "heartbeat")
+}
+
+// Get 获取节点最近一次心跳，从未上报或已过保留期时返回nil
+func (s *nodeHeartbeatService) Get(ctx context.Context, nodeID int64) (*NodeHeartbeat, error) {
+	data, err := s.redisCli.Get(ctx, nodeHeartbeatKey(nodeID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var hb NodeHeartbeat
+	if err := json.Unmarshal(data, &hb); err != nil {
+		return nil, err
+	}
+	return &hb, nil
+}
+
+// CheckTimeouts 检查心跳超时的在线节点，退回TCP端口检查：端口可连接降级，否则离线
+func (s *nodeHeartbeatService) CheckTimeouts(ctx context.Context) error {
+	nodes, err := s.nodeService.GetAllNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, node := range nodes {
+		if node.Status != repository.NodeStatusOnline && node.Status != repository.NodeStatusDegraded {
+			continue
+		}
+
+		hb, err := s.Get(ctx, node.ID)
+		if err != nil {
+			s.logger.Error("获取节点心跳失败", "error", err, "node", node.NodeName)
+			continue
+		}
+		if hb == nil || hb.Fresh(now) {
+			continue
+		}
+
+		if err := s.updateStatus(ctx, node, FallbackNodeStatus(node, hb), "heartbeat_timeout"); err != nil {
+			s.logger.Error("更新节点状态失败", "error", err, "node", node.NodeName)
+		}
+	}
+	return nil
+}
+
+// updateStatus 更新节点状态并在变化时记录日志
+func (s *nodeHeartbeatService) updateStatus(ctx context.Context, node *repository.Node, status int, source string) error {
+	oldStatus := node.Status
+	if err := s.nodeService.UpdateStatus(ctx, node, status); err != nil {
+		return err
+	}
+	if oldStatus != status {
+		s.logger.Info("节点状态已变更", "node", node.NodeName, "from", oldStatus, "to", status, "source", source)
+	}
+	return nil
+}
+
+// FallbackNodeStatus 通过TCP端口检查判断节点状态
+// 曾上报过心跳的节点在心跳超时后即使端口可连接也只视为降级
+func FallbackNodeStatus(node *repository.Node, hb *NodeHeartbeat) int {
+	if !network.CheckPort(node.IP, node.FrpsPort) {
+		return repository.NodeStatusOffline
+	}
+	if hb != nil {
+		return repository.NodeStatusDegraded
+	}
+	return repository.NodeStatusOnline
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// nodeLoadTier 负载数据的一个存储层级，Resolution 为0表示原始数据
+type nodeLoadTier struct {
+	Name       string
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// nodeLoadTiers 负载数据按粒度由细到粗排列：原始数据保留2天，5分钟聚合保留30天，1小时聚合保留1年
+var nodeLoadTiers = []nodeLoadTier{
+	{Name: "raw", Resolution: 0, Retention: 48 * time.Hour},
+	{Name: "5m", Resolution: 5 * time.Minute, Retention: 30 * 24 * time.Hour},
+	{Name: "1h", Resolution: time.Hour, Retention: 365 * 24 * time.Hour},
+}
+
+// 图表查询限制
+const (
+	// maxNodeLoadChartPoints 自动选择粒度时单次返回的数据点上限
+	maxNodeLoadChartPoints = 1000
+	// nodeLoadRawInterval 估算原始数据点数时假定的上报间隔
+	nodeLoadRawInterval = time.Minute
+	// MaxNodeLoadChartRange 单次查询的最大时间跨度
+	MaxNodeLoadChartRange = 366 * 24 * time.Hour
+)
+
+// nodeLoadCacheTTL 最新负载的缓存时长，超过后视为没有负载数据
+const nodeLoadCacheTTL = 60 * time.Second
+
+// ErrInvalidNodeLoadResolution 不支持的图表粒度
+var ErrInvalidNodeLoadResolution = errors.New("无效的数据粒度，可选值为 raw、5m、1h")
+
+// NodeLoadInfo 节点代理上报的负载信息
+type NodeLoadInfo struct {
+	LoadScore         float64 `json:"load_score"`
+	CurrentConns      int     `json:"current_conns"`
+	PeakConns         int     `json:"peak_conns"`
+	CurrentTraffic    int64   `json:"current_traffic"`
+	PeakTraffic       int64   `json:"peak_traffic"`
+	CPUUsage          float64 `json:"cpu_usage"`
+	MemUsage          float64 `json:"mem_usage"`
+	ConnGrowthRate    float64 `json:"conn_growth_rate"`
+	TrafficGrowthRate float64 `json:"traffic_growth_rate"`
+	Timestamp         int64   `json:"timestamp"`
+}
+
+// NodeLoadChart 节点负载图表数据
+type NodeLoadChart struct {
+	NodeID     int64                       `json:"node_id"`
+	From       time.Time                   `json:"from"`
+	To         time.Time                   `json:"to"`
+	Resolution string                      `json:"resolution"`
+	Points     []*repository.NodeLoadPoint `json:"points"`
+}
+
+// NodeLoadService 节点负载时序数据服务接口
+type NodeLoadService interface {
+	Record(ctx context.Context, node *repository.Node, info *NodeLoadInfo) error
+	Latest(ctx context.Context, nodeID int64) (float64, bool, error)
+	Chart(ctx context.Context, nodeID int64, from, to time.Time, resolution string) (*NodeLoadChart, error)
+	Rollup(ctx context.Context, now time.Time) error
+	Cleanup(ctx context.Context, now time.Time) error
+}
+
+// nodeLoadService 节点负载时序数据服务实现
+type nodeLoadService struct {
+	nodeLoadRepo repository.NodeLoadRepository
+	redisCli     *redis.Client
+	logger       *logger.Logger
+}
+
+// NewNodeLoadService 创建节点负载时序数据服务实例
+func NewNodeLoadService(nodeLoadRepo repository.NodeLoadRepository, redisCli *redis.Client, logger *logger.Logger) NodeLoadService {
+	return &nodeLoadService{
+		nodeLoadRepo: nodeLoadRepo,
+		redisCli:     redisCli,
+		logger:       logger,
+	}
+}
+
+// NodeLoadCacheKey 节点最新负载百分比的缓存键
+func NodeLoadCacheKey(nodeID int64) string {
+	return fmt.Sprintf("node:load:%d", nodeID)
+}
+
+// Record 写入一条原始负载数据，并缓存最新的负载百分比供节点列表展示
+func (s *nodeLoadService) Record(ctx context.Context, node *repository.Node, info *NodeLoadInfo) error {
+	err := s.nodeLoadRepo.CreateSample(ctx, &repository.NodeLoadSample{
+		NodeID:            node.ID,
+		LoadScore:         info.LoadScore,
+		CurrentConns:      info.CurrentConns,
+		PeakConns:         info.PeakConns,
+		CurrentTraffic:    info.CurrentTraffic,
+		PeakTraffic:       info.PeakTraffic,
+		CPUUsage:          info.CPUUsage,
+		MemUsage:          info.MemUsage,
+		ConnGrowthRate:    info.ConnGrowthRate,
+		TrafficGrowthRate: info.TrafficGrowthRate,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 将负载评分转换为百分比字符串，如0.11118003913894325 -> "11.12%"
+	loadPercentage := fmt.Sprintf("%.2f%%", info.LoadScore*100)
+	if err := s.redisCli.Set(ctx, NodeLoadCacheKey(node.ID), loadPercentage, nodeLoadCacheTTL).Err(); err != nil {
+		s.logger.Error("缓存节点负载失败", "error", err, "nodeID", node.ID)
+	}
+	return nil
+}
+
+// Latest 获取节点最近上报的负载评分（0-1），缓存过期或从未上报时第二个返回值为false
+func (s *nodeLoadService) Latest(ctx context.Context, nodeID int64) (float64, bool, error) {
+	value, err := s.redisCli.Get(ctx, NodeLoadCacheKey(nodeID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	percentage, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("解析节点负载失败: %w", err)
+	}
+	return percentage / 100, true, nil
+}
+
+// Chart 获取节点在 [from, to) 内的负载数据
+// resolution 为空时自动选择保留期覆盖起始时间且数据点不超过上限的最细粒度
+func (s *nodeLoadService) Chart(ctx context.Context, nodeID int64, from, to time.Time, resolution string) (*NodeLoadChart, error) {
+	var tier *nodeLoadTier
+	if resolution != "" {
+		for i := range nodeLoadTiers {
+			if nodeLoadTiers[i].Name == resolution {
+				tier = &nodeLoadTiers[i]
+			}
+		}
+		if tier == nil {
+			return nil, ErrInvalidNodeLoadResolution
+		}
+	} else {
+		tier = chooseNodeLoadTier(from, to, time.Now())
+	}
+
+	var points []*repository.NodeLoadPoint
+	var err error
+	if tier.Resolution == 0 {
+		points, err = s.nodeLoadRepo.ListSamples(ctx, nodeID, from, to)
+	} else {
+		points, err = s.nodeLoadRepo.ListRollups(ctx, nodeID, int(tier.Resolution.Seconds()), from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if points == nil {
+		points = []*repository.NodeLoadPoint{}
+	}
+
+	return &NodeLoadChart{
+		NodeID:     nodeID,
+		From:       from,
+		To:         to,
+		Resolution: tier.Name,
+		Points:     points,
+	}, nil
+}
+
+// ParseNodeLoadChartRange 解析以Unix时间戳（秒）表示的图表时间范围，默认为最近24小时
+func ParseNodeLoadChartRange(fromStr, toStr string) (time.Time, time.Time, error) {
+	to := time.Now()
+	if toStr != "" {
+		ts, err := strconv.ParseInt(toStr, 10, 64)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("无效的结束时间")
+		}
+		to = time.Unix(ts, 0)
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if fromStr != "" {
+		ts, err := strconv.ParseInt(fromStr, 10, 64)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("无效的开始时间")
+		}
+		from = time.Unix(ts, 0)
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("开始时间必须早于结束时间")
+	}
+	if to.Sub(from) > MaxNodeLoadChartRange {
+		return time.Time{}, time.Time{}, errors.New("查询时间跨度不能超过366天")
+	}
+	return from, to, nil
+}
+
+// chooseNodeLoadTier 选择图表使用的存储层级
+func chooseNodeLoadTier(from, to, now time.Time) *nodeLoadTier {
+	span := to.Sub(from)
+	for i := range nodeLoadTiers {
+		tier := &nodeLoadTiers[i]
+		if from.Before(now.Add(-tier.Retention)) {
+			continue
+		}
+		interval := tier.Resolution
+		if interval == 0 {
+			interval = nodeLoadRawInterval
+		}
+		if span/interval <= maxNodeLoadChartPoints {
+			return tier
+		}
+	}
+	return &nodeLoadTiers[len(nodeLoadTiers)-1]
+}
+
+// Rollup 逐级降采样最近已结束的区间，多覆盖几个区间以容纳迟到的上报，重复执行结果不变
+func (s *nodeLoadService) Rollup(ctx context.Context, now time.Time) error {
+	var source *nodeLoadTier
+	for i := range nodeLoadTiers {
+		tier := &nodeLoadTiers[i]
+		if tier.Resolution == 0 {
+			source = tier
+			continue
+		}
+
+		to := now.Truncate(tier.Resolution)
+		from := to.Add(-3 * tier.Resolution)
+		resolution := int(tier.Resolution.Seconds())
+
+		var err error
+		if source.Resolution == 0 {
+			err = s.nodeLoadRepo.RollupSamples(ctx, resolution, from, to)
+		} else {
+			err = s.nodeLoadRepo.RollupRollups(ctx, int(source.Resolution.Seconds()), resolution, from, to)
+		}
+		if err != nil {
+			return fmt.Errorf("聚合%s负载数据失败: %w", tier.Name, err)
+		}
+		source = tier
+	}
+	return nil
+}
+
+// Cleanup 删除超过各层级保留期的负载数据
+func (s *nodeLoadService) Cleanup(ctx context.Context, now time.Time) error {
+	for _, tier := range nodeLoadTiers {
+		before := now.Add(-tier.Retention)
+
+		var deleted int64
+		var err error
+		if tier.Resolution == 0 {
+			deleted, err = s.nodeLoadRepo.DeleteSamplesBefore(ctx, before)
+		} else {
+			deleted, err = s.nodeLoadRepo.DeleteRollupsBefore(ctx, int(tier.Resolution.Seconds()), before)
+		}
+		if err != nil {
+			return fmt.Errorf("清理%s负载数据失败: %w", tier.Name, err)
+		}
+		if deleted > 0 {
+			s.logger.Info("已清理过期节点负载数据", "tier", tier.Name, "count", deleted)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/email"
+	"stellarfrp/pkg/logger"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 维护计划参数
+const (
+	// NodeMaintenanceNoticeLead 提前通知受影响用户的时长，开始时间不足该时长时创建后立即通知
+	NodeMaintenanceNoticeLead = 24 * time.Hour
+	// maxNodeMaintenanceDuration 单次维护的最长时长
+	maxNodeMaintenanceDuration = 7 * 24 * time.Hour
+	// maxNodeMaintenanceMessage 维护说明的最大长度
+	maxNodeMaintenanceMessage = 512
+)
+
+// 维护计划错误
+var (
+	ErrNodeMaintenanceNotFound = errors.New("维护计划不存在")
+	ErrNodeMaintenanceOverlap  = errors.New("该时间段与节点已有的维护计划重叠")
+)
+
+// NodeMaintenanceService 节点维护计划服务接口
+type NodeMaintenanceService interface {
+	Schedule(ctx context.Context, m *repository.NodeMaintenance) error
+	Cancel(ctx context.Context, id int64) (*repository.NodeMaintenance, error)
+	List(ctx context.Context, nodeID int64, offset, limit int) ([]*repository.NodeMaintenance, int, error)
+	GetActive(ctx context.Context, nodeID int64, now time.Time) (*repository.NodeMaintenance, error)
+	ActiveByNode(ctx context.Context, now time.Time) (map[int64]*repository.NodeMaintenance, error)
+	NotifyUpcoming(ctx context.Context, now time.Time) error
+}
+
+// nodeMaintenanceService 节点维护计划服务实现
+type nodeMaintenanceService struct {
+	maintenanceRepo   repository.NodeMaintenanceRepository
+	nodeService       NodeService
+	proxyService      ProxyService
+	userService       UserService
+	userNoticeService UserNoticeService
+	emailService      *email.Service
+	logger            *logger.Logger
+}
+
+// NewNodeMaintenanceService 创建节点维护计划服务实例
+func NewNodeMaintenanceService(
+	maintenanceRepo repository.NodeMaintenanceRepository,
+	nodeService NodeService,
+	proxyService ProxyService,
+	userService UserService,
+	userNoticeService UserNoticeService,
+	emailService *email.Service,
+	logger *logger.Logger,
+) NodeMaintenanceService {
+	return &nodeMaintenanceService{
+		maintenanceRepo:   maintenanceRepo,
+		nodeService:       nodeService,
+		proxyService:      proxyService,
+		userService:       userService,
+		userNoticeService: userNoticeService,
+		emailService:      emailService,
+		logger:            logger,
+	}
+}
+
+// Schedule 校验并创建维护计划，同一节点的维护时间段不能重叠
+func (s *nodeMaintenanceService) Schedule(ctx context.Context, m *repository.NodeMaintenance) error {
+	m.Message = strings.TrimSpace(m.Message)
+	if !m.StartAt.Before(m.EndAt) {
+		return errors.New("结束时间必须晚于开始时间")
+	}
+	if !m.EndAt.After(time.Now()) {
+		return errors.New("结束时间必须晚于当前时间")
+	}
+	if m.EndAt.Sub(m.StartAt) > maxNodeMaintenanceDuration {
+		return errors.New("单次维护时长不能超过7天")
+	}
+	if m.Message == "" {
+		return errors.New("维护说明不能为空")
+	}
+	if utf8.RuneCountInString(m.Message) > maxNodeMaintenanceMessage {
+		return fmt.Errorf("维护说明不能超过%d个字符", maxNodeMaintenanceMessage)
+	}
+
+	overlapping, err := s.maintenanceRepo.ListOverlapping(ctx, m.NodeID, m.StartAt, m.EndAt)
+	if err != nil {
+		return err
+	}
+	if len(overlapping) > 0 {
+		return ErrNodeMaintenanceOverlap
+	}
+	return s.maintenanceRepo.Create(ctx, m)
+}
+
+// Cancel 取消维护计划，已通知过用户且尚未结束时发送取消通知
+func (s *nodeMaintenanceService) Cancel(ctx context.Context, id int64) (*repository.NodeMaintenance, error) {
+	m, err := s.maintenanceRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil || m.Canceled {
+		return nil, ErrNodeMaintenanceNotFound
+	}
+	if err := s.maintenanceRepo.Cancel(ctx, id); err != nil {
+		return nil, err
+	}
+	m.Canceled = true
+
+	if m.NotifiedAt.Valid && m.EndAt.After(time.Now()) {
+		s.notify(ctx, m, "节点维护已取消", "原定的节点维护已取消，节点上的隧道可以正常使用。")
+	}
+	return m, nil
+}
+
+// List 分页获取维护计划，nodeID 为0时获取全部节点
+func (s *nodeMaintenanceService) List(ctx context.Context, nodeID int64, offset, limit int) ([]*repository.NodeMaintenance, int, error) {
+	list, total, err := s.maintenanceRepo.List(ctx, nodeID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if list == nil {
+		list = []*repository.NodeMaintenance{}
+	}
+	return list, total, nil
+}
+
+// GetActive 获取节点当前生效的维护计划，不在维护中时返回nil
+func (s *nodeMaintenanceService) GetActive(ctx context.Context, nodeID int64, now time.Time) (*repository.NodeMaintenance, error) {
+	active, err := s.ActiveByNode(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	return active[nodeID], nil
+}
+
+// ActiveByNode 获取当前生效的维护计划，按节点ID索引
+func (s *nodeMaintenanceService) ActiveByNode(ctx context.Context, now time.Time) (map[int64]*repository.NodeMaintenance, error) {
+	list, err := s.maintenanceRepo.ListActive(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[int64]*repository.NodeMaintenance, len(list))
+	for _, m := range list {
+		if _, ok := active[m.NodeID]; !ok {
+			active[m.NodeID] = m
+		}
+	}
+	return active, nil
+}
+
+// NotifyUpcoming 向即将开始维护的节点上有隧道的用户发送邮件和站内通知，每个维护计划只通知一次
+func (s *nodeMaintenanceService) NotifyUpcoming(ctx context.Context, now time.Time) error {
+	list, err := s.maintenanceRepo.ListToNotify(ctx, now, now.Add(NodeMaintenanceNoticeLead))
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// 发送前先标记，避免发送中途超时或多实例并发导致重复通知
+		claimed, err := s.maintenanceRepo.MarkNotified(ctx, m.ID, now)
+		if err != nil {
+			s.logger.Error("记录维护通知状态失败", "error", err, "maintenanceID", m.ID)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		s.notify(ctx, m, "节点维护通知", "您的隧道所在节点将进行维护，维护期间该节点上的隧道无法启动，维护结束后自动恢复。")
+	}
+	return nil
+}
+
+// notify 向节点上有隧道的全部用户发送站内通知和邮件，单个用户失败只记录日志
+func (s *nodeMaintenanceService) notify(ctx context.Context, m *repository.NodeMaintenance, title, summary string) {
+	node, err := s.nodeService.GetByID(ctx, m.NodeID)
+	if err != nil {
+		s.logger.Error("获取维护节点失败", "error", err, "nodeID", m.NodeID)
+		return
+	}
+	proxies, err := s.proxyService.ListByNode(ctx, m.NodeID)
+	if err != nil {
+		s.logger.Error("获取节点隧道失败", "error", err, "nodeID", m.NodeID)
+		return
+	}
+
+	lines := []string{
+		summary,
+		fmt.Sprintf("节点名称：%s", node.NodeName),
+		fmt.Sprintf("维护时间：%s 至 %s", m.StartAt.Format("2006-01-02 15:04"), m.EndAt.Format("2006-01-02 15:04")),
+		fmt.Sprintf("维护说明：%s", m.Message),
+	}
+	content := strings.Join(lines, "\n")
+	subject := fmt.Sprintf("StellarFrp - %s：%s", title, node.NodeName)
+
+	notified := make(map[string]bool)
+	for _, proxy := range proxies {
+		if notified[proxy.Username] {
+			continue
+		}
+		notified[proxy.Username] = true
+
+		if err := s.userNoticeService.Notify(ctx, proxy.Username, title, content); err != nil {
+			s.logger.Error("发送维护站内通知失败", "error", err, "username", proxy.Username)
+		}
+
+		user, err := s.userService.GetByUsername(ctx, proxy.Username)
+		if err != nil || user == nil || user.Email == "" {
+			continue
+		}
+		if err := s.emailService.SendNotification(user.Email, user.Username, subject, title, lines); err != nil {
+			s.logger.Error("发送维护通知邮件失败", "error", err, "username", user.Username)
+		}
+	}
+	s.logger.Info("已发送节点维护通知", "node", node.NodeName, "maintenanceID", m.ID, "users", len(notified))
+}
+
+// NodeMaintenanceReason 维护期间拒绝隧道启动时返回给客户端的原因
+func NodeMaintenanceReason(m *repository.NodeMaintenance) string {
+	return fmt.Sprintf("节点维护中，预计 %s 恢复：%s", m.EndAt.Format("2006-01-02 15:04"), m.Message)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"strings"
+	"unicode/utf8"
+)
+
+// 节点元数据限制
+const (
+	maxNodeTags        = 10
+	maxNodeTagLength   = 16
+	maxNodeMetaLength  = 32
+	nodeUngroupedLabel = "未分类"
+)
+
+// NodeISPs 支持的线路运营商类型
+var NodeISPs = map[string]bool{
+	"telecom": true, // 电信
+	"unicom":  true, // 联通
+	"mobile":  true, // 移动
+	"bgp":     true, // BGP多线
+	"other":   true, // 其他或海外线路
+}
+
+// NodeGroupFields 节点列表支持的分组字段
+var NodeGroupFields = map[string]bool{
+	"region":    true,
+	"city":      true,
+	"isp":       true,
+	"bandwidth": true,
+	"tag":       true,
+}
+
+// ErrInvalidNodeGroupField 不支持的分组字段
+var ErrInvalidNodeGroupField = errors.New("无效的分组字段，可选值为 region、city、isp、bandwidth、tag")
+
+// NodeFilter 节点列表筛选条件，字段为空表示不限制
+type NodeFilter struct {
+	Region        string
+	City          string
+	ISP           string
+	BandwidthTier string
+	Tag           string
+}
+
+// Match 判断节点是否满足筛选条件，比较时忽略大小写
+func (f *NodeFilter) Match(node *repository.Node) bool {
+	if f.Region != "" && !strings.EqualFold(node.Region, f.Region) {
+		return false
+	}
+	if f.City != "" && !strings.EqualFold(node.City, f.City) {
+		return false
+	}
+	if f.ISP != "" && !strings.EqualFold(node.ISP, f.ISP) {
+		return false
+	}
+	if f.BandwidthTier != "" && !strings.EqualFold(node.BandwidthTier, f.BandwidthTier) {
+		return false
+	}
+	if f.Tag != "" {
+		for _, tag := range ParseNodeTags(node) {
+			if strings.EqualFold(tag, f.Tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// ParseNodeTags 解析节点标签，配置为空或无法解析时返回空数组
+func ParseNodeTags(node *repository.Node) []string {
+	tags := []string{}
+	if node.Tags == "" {
+		return tags
+	}
+	if err := json.Unmarshal([]byte(node.Tags), &tags); err != nil {
+		return []string{}
+	}
+	return tags
+}
+
+// FormatNodeTags 规范化标签（去空白、转小写、去重）并格式化为JSON字符串
+func FormatNodeTags(tags []string) (string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxNodeTagLength {
+			return "", fmt.Errorf("标签 %s 超过%d个字符", tag, maxNodeTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxNodeTags {
+		return "", fmt.Errorf("标签数量不能超过%d个", maxNodeTags)
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ValidateNodeMeta 校验节点地区、城市、运营商和带宽档位
+func ValidateNodeMeta(node *repository.Node) error {
+	if node.ISP != "" && !NodeISPs[node.ISP] {
+		return errors.New("无效的线路运营商，可选值为 telecom、unicom、mobile、bgp、other")
+	}
+	for name, value := range map[string]string{"地区": node.Region, "城市": node.City, "带宽档位": node.BandwidthTier} {
+		if utf8.RuneCountInString(value) > maxNodeMetaLength {
+			return fmt.Errorf("%s不能超过%d个字符", name, maxNodeMetaLength)
+		}
+	}
+	return nil
+}
+
+// NodeGroupKeys 获取节点在指定分组字段下所属的分组，按标签分组时节点可属于多个分组
+func NodeGroupKeys(node *repository.Node, field string) []string {
+	var keys []string
+	switch field {
+	case "region":
+		keys = []string{node.Region}
+	case "city":
+		keys = []string{node.City}
+	case "isp":
+		keys = []string{node.ISP}
+	case "bandwidth":
+		keys = []string{node.BandwidthTier}
+	case "tag":
+		keys = ParseNodeTags(node)
+	}
+	if len(keys) == 0 || (len(keys) == 1 && keys[0] == "") {
+		return []string{nodeUngroupedLabel}
+	}
+	return keys
+}
+
+// GroupNodeIDs 将节点ID按分组字段归类，组内保持节点原有顺序
+func GroupNodeIDs(nodes []*repository.Node, field string) map[string][]int64 {
+	groups := make(map[string][]int64)
+	for _, node := range nodes {
+		for _, key := range NodeGroupKeys(node, field) {
+			groups[key] = append(groups[key], node.ID)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 推荐评分权重，满分100
+const (
+	recommendStatusWeight = 30
+	recommendLoadWeight   = 30
+	recommendPortWeight   = 20
+	recommendRegionWeight = 10
+	recommendISPWeight    = 10
+)
+
+// NodeRecommendRequest 节点推荐条件，均为可选
+type NodeRecommendRequest struct {
+	ProxyType  string
+	RemotePort int
+	Region     string
+	ISP        string
+}
+
+// NodeRecommendation 节点推荐结果，Reasons 说明各项得分
+type NodeRecommendation struct {
+	NodeID         int64    `json:"node_id"`
+	NodeName       string   `json:"node_name"`
+	Region         string   `json:"region"`
+	ISP            string   `json:"isp"`
+	Status         string   `json:"status"`
+	Score          float64  `json:"score"`
+	Load           *float64 `json:"load"`
+	RemainingPorts *int     `json:"remaining_ports"`
+	Reasons        []string `json:"reasons"`
+}
+
+// NodeRecommendService 节点推荐服务接口
+type NodeRecommendService interface {
+	Recommend(ctx context.Context, user *repository.User, req *NodeRecommendRequest) ([]*NodeRecommendation, error)
+}
+
+// nodeRecommendService 节点推荐服务实现
+type nodeRecommendService struct {
+	nodeService        NodeService
+	proxyService       ProxyService
+	nodeLoadService    NodeLoadService
+	maintenanceService NodeMaintenanceService
+	capacityService    NodeCapacityService
+	logger             *logger.Logger
+}
+
+// NewNodeRecommendService 创建节点推荐服务实例
+func NewNodeRecommendService(
+	nodeService NodeService,
+	proxyService ProxyService,
+	nodeLoadService NodeLoadService,
+	maintenanceService NodeMaintenanceService,
+	capacityService NodeCapacityService,
+	logger *logger.Logger,
+) NodeRecommendService {
+	return &nodeRecommendService{
+		nodeService:        nodeService,
+		proxyService:       proxyService,
+		nodeLoadService:    nodeLoadService,
+		maintenanceService: maintenanceService,
+		capacityService:    capacityService,
+		logger:             logger,
+	}
+}
+
+// Recommend 对用户可访问的节点评分并按得分从高到低返回
+// 离线、待审核、维护中、不支持所需协议、容量已满或端口已用尽的节点不参与推荐
+func (s *nodeRecommendService) Recommend(ctx context.Context, user *repository.User, req *NodeRecommendRequest) ([]*NodeRecommendation, error) {
+	nodes, err := s.nodeService.GetAccessibleNodes(ctx, user.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("获取可访问节点失败: %w", err)
+	}
+
+	maintenances, err := s.maintenanceService.ActiveByNode(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("获取节点维护计划失败: %w", err)
+	}
+
+	results := make([]*NodeRecommendation, 0, len(nodes))
+	for _, node := range nodes {
+		if maintenances[node.ID] != nil {
+			continue
+		}
+		rec, err := s.score(ctx, node, req)
+		if err != nil {
+			s.logger.Error("节点推荐评分失败", "error", err, "node", node.NodeName)
+			continue
+		}
+		if rec != nil {
+			results = append(results, rec)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].NodeID < results[j].NodeID
+	})
+	return results, nil
+}
+
+// score 计算单个节点的推荐得分，节点不可用时返回nil
+func (s *nodeRecommendService) score(ctx context.Context, node *repository.Node, req *NodeRecommendRequest) (*NodeRecommendation, error) {
+	rec := &NodeRecommendation{
+		NodeID:   node.ID,
+		NodeName: node.NodeName,
+		Region:   node.Region,
+		ISP:      node.ISP,
+	}
+	var score float64
+
+	switch node.Status {
+	case repository.NodeStatusOnline:
+		rec.Status = "online"
+		score += recommendStatusWeight
+		rec.Reasons = append(rec.Reasons, "节点在线")
+	case repository.NodeStatusDegraded:
+		rec.Status = "degraded"
+		score += recommendStatusWeight / 3
+		rec.Reasons = append(rec.Reasons, "节点处于降级状态，可用但稳定性较差")
+	default:
+		return nil, nil
+	}
+
+	if req.ProxyType != "" {
+		allowed, err := NodeAllowsType(node, req.ProxyType)
+		if err != nil || !allowed {
+			return nil, nil
+		}
+	}
+
+	reason, err := s.capacityService.CheckProxy(ctx, node, req.ProxyType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		return nil, nil
+	}
+
+	load, ok, err := s.nodeLoadService.Latest(ctx, node.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		load = math.Min(math.Max(load, 0), 1)
+		percentage := math.Round(load*10000) / 100
+		rec.Load = &percentage
+		score += recommendLoadWeight * (1 - load)
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("当前负载 %.2f%%", percentage))
+	} else {
+		score += recommendLoadWeight / 2
+		rec.Reasons = append(rec.Reasons, "暂无负载数据，按中等负载计算")
+	}
+
+	switch req.ProxyType {
+	case "tcp", "udp":
+		minPort, maxPort, err := ParsePortRange(node.PortRange)
+		if err != nil {
+			return nil, nil
+		}
+		if req.RemotePort != 0 {
+			if req.RemotePort < minPort || req.RemotePort > maxPort {
+				return nil, nil
+			}
+			used, err := s.proxyService.IsRemotePortUsed(ctx, node.ID, req.ProxyType, strconv.Itoa(req.RemotePort))
+			if err != nil {
+				return nil, err
+			}
+			if used {
+				return nil, nil
+			}
+		}
+
+		used, err := s.proxyService.CountRemotePorts(ctx, node.ID, req.ProxyType)
+		if err != nil {
+			return nil, err
+		}
+		total := maxPort - minPort + 1
+		remaining := total - used
+		if remaining <= 0 {
+			return nil, nil
+		}
+		rec.RemainingPorts = &remaining
+		score += recommendPortWeight * float64(remaining) / float64(total)
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("剩余 %d/%d 个%s端口", remaining, total, strings.ToUpper(req.ProxyType)))
+	case "":
+		score += recommendPortWeight / 2
+		rec.Reasons = append(rec.Reasons, "未指定协议，端口余量按一半计分")
+	default:
+		score += recommendPortWeight
+		rec.Reasons = append(rec.Reasons, strings.ToUpper(req.ProxyType)+"隧道无需占用独立端口")
+	}
+
+	if req.Region != "" {
+		if node.Region != "" && strings.EqualFold(node.Region, req.Region) {
+			score += recommendRegionWeight
+			rec.Reasons = append(rec.Reasons, "位于偏好地区 "+node.Region)
+		} else {
+			rec.Reasons = append(rec.Reasons, "不在偏好地区")
+		}
+	}
+	if req.ISP != "" {
+		if node.ISP != "" && (strings.EqualFold(node.ISP, req.ISP) || strings.EqualFold(node.ISP, "bgp")) {
+			score += recommendISPWeight
+			rec.Reasons = append(rec.Reasons, "线路匹配偏好运营商 "+req.ISP)
+		} else {
+			rec.Reasons = append(rec.Reasons, "线路与偏好运营商不一致")
+		}
+	}
+
+	rec.Score = math.Round(score*100) / 100
+	return rec, nil
+}
+
+// NodeAllowsType 判断节点是否开放指定协议类型，开放类型配置无法解析时返回错误
+func NodeAllowsType(node *repository.Node, proxyType string) (bool, error) {
+	var allowedTypes []string
+	if err := json.Unmarshal([]byte(node.AllowedTypes), &allowedTypes); err != nil {
+		return false, err
+	}
+	for _, t := range allowedTypes {
+		if strings.EqualFold(t, proxyType) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
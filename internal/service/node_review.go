@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/email"
+	"stellarfrp/pkg/logger"
+	"strings"
+	"unicode/utf8"
+)
+
+// 节点审核操作
+const (
+	NodeReviewSubmit         = "submit"          // 捐赠者提交节点
+	NodeReviewResubmit       = "resubmit"        // 捐赠者修改后重新提交
+	NodeReviewApprove        = "approve"         // 管理员审核通过
+	NodeReviewReject         = "reject"          // 管理员拒绝
+	NodeReviewRequestChanges = "request_changes" // 管理员要求修改
+)
+
+// 审核参数
+const (
+	maxNodeReviewReason = 512
+	maxNodeReviewNote   = 2000
+)
+
+// ErrNodeReviewInvalidState 节点当前状态不允许执行该审核操作
+var ErrNodeReviewInvalidState = errors.New("节点当前状态不允许该操作")
+
+// nodeReviewTransitions 审核操作允许的起始状态及操作后的状态
+var nodeReviewTransitions = map[string]struct {
+	from []int
+	to   int
+}{
+	NodeReviewApprove:        {from: []int{repository.NodeStatusPending}, to: repository.NodeStatusOnline},
+	NodeReviewReject:         {from: []int{repository.NodeStatusPending, repository.NodeStatusChangesRequested}, to: repository.NodeStatusRejected},
+	NodeReviewRequestChanges: {from: []int{repository.NodeStatusPending}, to: repository.NodeStatusChangesRequested},
+}
+
+// NodeReviewService 捐赠节点审核服务接口
+type NodeReviewService interface {
+	Submit(ctx context.Context, node *repository.Node, fromStatus int, note string) error
+	Resubmit(ctx context.Context, node *repository.Node, note string) error
+	Review(ctx context.Context, node *repository.Node, action string, reviewerID int64, note, reason string) (*repository.NodeReview, error)
+	List(ctx context.Context, nodeID int64) ([]*repository.NodeReview, error)
+	Latest(ctx context.Context, nodeID int64) (*repository.NodeReview, error)
+}
+
+// nodeReviewService 捐赠节点审核服务实现
+type nodeReviewService struct {
+	reviewRepo        repository.NodeReviewRepository
+	nodeService       NodeService
+	userService       UserService
+	userNoticeService UserNoticeService
+	emailService      *email.Service
+	logger            *logger.Logger
+}
+
+// NewNodeReviewService 创建捐赠节点审核服务实例
+func NewNodeReviewService(
+	reviewRepo repository.NodeReviewRepository,
+	nodeService NodeService,
+	userService UserService,
+	userNoticeService UserNoticeService,
+	emailService *email.Service,
+	logger *logger.Logger,
+) NodeReviewService {
+	return &nodeReviewService{
+		reviewRepo:        reviewRepo,
+		nodeService:       nodeService,
+		userService:       userService,
+		userNoticeService: userNoticeService,
+		emailService:      emailService,
+		logger:            logger,
+	}
+}
+
+// Submit 记录捐赠者提交审核，节点状态已由调用方置为待审核
+// fromStatus 为待审核时记为首次提交，否则记为重新提交
+func (s *nodeReviewService) Submit(ctx context.Context, node *repository.Node, fromStatus int, note string) error {
+	action := NodeReviewResubmit
+	if fromStatus == repository.NodeStatusPending {
+		action = NodeReviewSubmit
+	}
+	return s.reviewRepo.Create(ctx, &repository.NodeReview{
+		NodeID:     node.ID,
+		Action:     action,
+		FromStatus: fromStatus,
+		ToStatus:   node.Status,
+		Note:       note,
+	})
+}
+
+// Resubmit 捐赠者按要求修改后重新提交审核，仅需修改状态的节点可以重新提交
+func (s *nodeReviewService) Resubmit(ctx context.Context, node *repository.Node, note string) error {
+	if node.Status != repository.NodeStatusChangesRequested {
+		return ErrNodeReviewInvalidState
+	}
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxNodeReviewNote {
+		return fmt.Errorf("说明不能超过%d个字符", maxNodeReviewNote)
+	}
+
+	from := node.Status
+	if err := s.nodeService.UpdateStatus(ctx, node, repository.NodeStatusPending); err != nil {
+		return err
+	}
+	return s.Submit(ctx, node, from, note)
+}
+
+// Review 管理员审核节点：校验状态流转，更新节点状态，保存审核记录并通知捐赠者
+// 拒绝和要求修改时必须填写原因，原因对捐赠者可见，备注仅管理员可见
+func (s *nodeReviewService) Review(ctx context.Context, node *repository.Node, action string, reviewerID int64, note, reason string) (*repository.NodeReview, error) {
+	transition, ok := nodeReviewTransitions[action]
+	if !ok {
+		return nil, fmt.Errorf("无效的审核操作 %s", action)
+	}
+	allowed := false
+	for _, status := range transition.from {
+		if node.Status == status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, ErrNodeReviewInvalidState
+	}
+
+	note = strings.TrimSpace(note)
+	reason = strings.TrimSpace(reason)
+	if action != NodeReviewApprove && reason == "" {
+		return nil, errors.New("拒绝或要求修改时必须填写原因")
+	}
+	if utf8.RuneCountInString(reason) > maxNodeReviewReason {
+		return nil, fmt.Errorf("原因不能超过%d个字符", maxNodeReviewReason)
+	}
+	if utf8.RuneCountInString(note) > maxNodeReviewNote {
+		return nil, fmt.Errorf("备注不能超过%d个字符", maxNodeReviewNote)
+	}
+
+	review := &repository.NodeReview{
+		NodeID:     node.ID,
+		Action:     action,
+		FromStatus: node.Status,
+		ToStatus:   transition.to,
+		Note:       note,
+		Reason:     reason,
+	}
+	if reviewerID > 0 {
+		review.ReviewerID = sql.NullInt64{Int64: reviewerID, Valid: true}
+	}
+
+	if err := s.nodeService.UpdateStatus(ctx, node, transition.to); err != nil {
+		return nil, err
+	}
+	if err := s.reviewRepo.Create(ctx, review); err != nil {
+		return nil, err
+	}
+
+	go s.notify(context.Background(), node, review)
+	return review, nil
+}
+
+// List 获取节点的审核记录
+func (s *nodeReviewService) List(ctx context.Context, nodeID int64) ([]*repository.NodeReview, error) {
+	return s.reviewRepo.ListByNode(ctx, nodeID)
+}
+
+// Latest 获取节点最近一次审核记录，没有记录时返回nil
+func (s *nodeReviewService) Latest(ctx context.Context, nodeID int64) (*repository.NodeReview, error) {
+	return s.reviewRepo.GetLatest(ctx, nodeID)
+}
+
+// notify 通过站内通知和邮件告知捐赠者审核结果
+func (s *nodeReviewService) notify(ctx context.Context, node *repository.Node, review *repository.NodeReview) {
+	if !node.OwnerID.Valid {
+		return
+	}
+	user, err := s.userService.GetByID(ctx, node.OwnerID.Int64)
+	if err != nil || user == nil {
+		s.logger.Error("获取节点所有者失败", "error", err, "nodeID", node.ID)
+		return
+	}
+
+	var title, summary string
+	switch review.Action {
+	case NodeReviewApprove:
+		title = "节点审核通过"
+		summary = "您捐赠的节点已通过审核，现已对用户开放。感谢您的贡献！"
+	case NodeReviewReject:
+		title = "节点审核未通过"
+		summary = "很遗憾，您捐赠的节点未通过审核。"
+	case NodeReviewRequestChanges:
+		title = "节点需要修改"
+		summary = "管理员要求您修改捐赠的节点，修改完成后请在节点管理中重新提交审核。"
+	default:
+		return
+	}
+
+	lines := []string{summary, fmt.Sprintf("节点名称：%s", node.NodeName)}
+	if review.Reason != "" {
+		lines = append(lines, fmt.Sprintf("原因：%s", review.Reason))
+	}
+
+	if err := s.userNoticeService.Notify(ctx, user.Username, title, strings.Join(lines, "\n")); err != nil {
+		s.logger.Error("发送审核站内通知失败", "error", err, "username", user.Username)
+	}
+	if user.Email == "" {
+		return
+	}
+	subject := fmt.Sprintf("StellarFrp - %s：%s", title, node.NodeName)
+	if err := s.emailService.SendNotification(user.Email, user.Username, subject, title, lines); err != nil {
+		s.logger.Error("发送审核通知邮件失败", "error", err, "username", user.Username)
+	}
+}
@@ -0,0 +1,502 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 贡献奖励类型
+const (
+	NodeRewardTraffic = "traffic" // 增加流量配额，单位字节
+	NodeRewardTunnel  = "tunnel"  // 增加隧道数量
+	NodeRewardGroup   = "group"   // 临时升级用户组，单位天
+)
+
+// 贡献奖励流水状态
+const (
+	NodeRewardStatusCredited   = "credited"    // 已发放
+	NodeRewardStatusExpired    = "expired"     // 用户组升级已到期
+	NodeRewardStatusClawedBack = "clawed_back" // 已追回
+	NodeRewardStatusFailed     = "failed"      // 发放失败
+)
+
+// 贡献奖励参数
+const (
+	// nodeUptimeTTL 节点在线采样数据的保留时长
+	nodeUptimeTTL = 8 * 24 * time.Hour
+	// maxNodeRewardDuration 用户组升级单次发放的最长天数
+	maxNodeRewardDuration = 31
+)
+
+// ErrNodeRewardRuleNotFound 奖励规则不存在
+var ErrNodeRewardRuleNotFound = errors.New("奖励规则不存在")
+
+// NodeRewardService 捐赠节点贡献奖励服务接口
+type NodeRewardService interface {
+	SampleUptime(ctx context.Context, now time.Time) error
+	Uptime(ctx context.Context, nodeID int64, date time.Time) (float64, error)
+	Settle(ctx context.Context, date time.Time) (int, error)
+	ExpireUpgrades(ctx context.Context, now time.Time) error
+	Clawback(ctx context.Context, node *repository.Node, reason string, operatorID int64, block bool) (int, error)
+	ListRules(ctx context.Context) ([]*repository.NodeRewardRule, error)
+	SaveRule(ctx context.Context, rule *repository.NodeRewardRule) error
+	DeleteRule(ctx context.Context, id int64) error
+	ListCredits(ctx context.Context, userID, nodeID int64, offset, limit int) ([]*repository.NodeRewardCredit, int, error)
+}
+
+// nodeRewardService 捐赠节点贡献奖励服务实现
+type nodeRewardService struct {
+	rewardRepo        repository.NodeRewardRepository
+	groupRepo         repository.GroupRepository
+	nodeService       NodeService
+	userService       UserService
+	userNoticeService UserNoticeService
+	redisClient       *redis.Client
+	logger            *logger.Logger
+}
+
+// NewNodeRewardService 创建捐赠节点贡献奖励服务实例
+func NewNodeRewardService(
+	rewardRepo repository.NodeRewardRepository,
+	groupRepo repository.GroupRepository,
+	nodeService NodeService,
+	userService UserService,
+	userNoticeService UserNoticeService,
+	redisClient *redis.Client,
+	logger *logger.Logger,
+) NodeRewardService {
+	return &nodeRewardService{
+		rewardRepo:        rewardRepo,
+		groupRepo:         groupRepo,
+		nodeService:       nodeService,
+		userService:       userService,
+		userNoticeService: userNoticeService,
+		redisClient:       redisClient,
+		logger:            logger,
+	}
+}
+
+// nodeUptimeKey 节点某天在线采样计数的缓存键
+func nodeUptimeKey(date time.Time) string {
+	return "node:uptime:" + date.Format("2006-01-02")
+}
+
+// SampleUptime 对捐赠节点做一次在线采样，在线和降级计为可用，暂停和离线计为不可用，待审核节点不采样
+func (s *nodeRewardService) SampleUptime(ctx context.Context, now time.Time) error {
+	nodes, err := s.nodeService.GetAllNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := nodeUptimeKey(now)
+	pipe := s.redisClient.Pipeline()
+	for _, node := range nodes {
+		if !node.OwnerID.Valid || repository.NodeUnapproved(node.Status) {
+			continue
+		}
+		id := strconv.FormatInt(node.ID, 10)
+		pipe.HIncrBy(ctx, key, id+":total", 1)
+		if node.Status == repository.NodeStatusOnline || node.Status == repository.NodeStatusDegraded {
+			pipe.HIncrBy(ctx, key, id+":up", 1)
+		}
+	}
+	pipe.Expire(ctx, key, nodeUptimeTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Uptime 获取节点某天的在线率（百分比），没有采样时为0
+func (s *nodeRewardService) Uptime(ctx context.Context, nodeID int64, date time.Time) (float64, error) {
+	id := strconv.FormatInt(nodeID, 10)
+	values, err := s.redisClient.HMGet(ctx, nodeUptimeKey(date), id+":total", id+":up").Result()
+	if err != nil {
+		return 0, err
+	}
+	total, _ := strconv.ParseInt(fmt.Sprint(values[0]), 10, 64)
+	up, _ := strconv.ParseInt(fmt.Sprint(values[1]), 10, 64)
+	if total <= 0 {
+		return 0, nil
+	}
+	return float64(up) * 100 / float64(total), nil
+}
+
+// Settle 按启用的规则为捐赠节点的所有者结算指定日期的贡献奖励，返回发放的流水数量
+// 同一节点同一规则同一天只发放一次，可以重复执行
+func (s *nodeRewardService) Settle(ctx context.Context, date time.Time) (int, error) {
+	rules, err := s.rewardRepo.ListRules(ctx, true)
+	if err != nil {
+		return 0, fmt.Errorf("获取奖励规则失败: %w", err)
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	nodes, err := s.nodeService.GetAllNodes(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取节点列表失败: %w", err)
+	}
+
+	credited := 0
+	for _, node := range nodes {
+		if !node.OwnerID.Valid || repository.NodeUnapproved(node.Status) || node.RewardBlocked {
+			continue
+		}
+
+		traffic, err := s.nodeService.GetDailyTraffic(ctx, node, date, 1)
+		if err != nil {
+			s.logger.Error("获取节点流量失败", "error", err, "nodeID", node.ID)
+			continue
+		}
+		served := traffic[0].TrafficIn + traffic[0].TrafficOut
+		uptime, err := s.Uptime(ctx, node.ID, date)
+		if err != nil {
+			s.logger.Error("获取节点在线率失败", "error", err, "nodeID", node.ID)
+			continue
+		}
+
+		var summaries []string
+		for _, rule := range rules {
+			if served < rule.MinTraffic || uptime < rule.MinUptime {
+				continue
+			}
+			credit, err := s.creditRule(ctx, node, rule, date, served, uptime)
+			if err != nil {
+				s.logger.Error("发放贡献奖励失败", "error", err, "nodeID", node.ID, "ruleID", rule.ID)
+				continue
+			}
+			if credit != nil {
+				credited++
+				summaries = append(summaries, rule.Name+"："+FormatNodeReward(credit.RewardType, credit.Amount))
+			}
+		}
+
+		if len(summaries) > 0 {
+			s.notifyOwner(ctx, node.OwnerID.Int64, "节点贡献奖励已发放",
+				fmt.Sprintf("感谢您捐赠的节点 %s，%s 承载流量 %s、在线率 %.2f%%，获得奖励：%s",
+					node.NodeName, date.Format("2006-01-02"), formatRewardBytes(served), uptime, strings.Join(summaries, "；")))
+		}
+	}
+
+	s.logger.Info("贡献奖励结算完成", "date", date.Format("2006-01-02"), "credited", credited)
+	return credited, nil
+}
+
+// creditRule 按单条规则为节点所有者发放奖励，已发放或达到月度上限时返回nil
+func (s *nodeRewardService) creditRule(ctx context.Context, node *repository.Node, rule *repository.NodeRewardRule, date time.Time, served int64, uptime float64) (*repository.NodeRewardCredit, error) {
+	exists, err := s.rewardRepo.CreditExists(ctx, node.ID, rule.ID, date)
+	if err != nil || exists {
+		return nil, err
+	}
+
+	user, err := s.userService.GetByID(ctx, node.OwnerID.Int64)
+	if err != nil {
+		return nil, fmt.Errorf("获取节点所有者失败: %w", err)
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	amount := rule.RewardValue
+	if rule.RewardType == NodeRewardGroup {
+		amount = int64(rule.DurationDays)
+		if user.GroupID != rule.BaseGroupID && user.GroupID != rule.RewardValue {
+			return nil, nil
+		}
+		// 已永久拥有目标用户组时无需升级，否则会把永久用户组变成限时并在到期后被降级
+		if user.GroupID == rule.RewardValue && user.GroupTime == nil {
+			return nil, nil
+		}
+	}
+	if rule.MonthlyCap > 0 {
+		monthStart := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+		used, err := s.rewardRepo.SumCredited(ctx, user.ID, rule.ID, monthStart, monthStart.AddDate(0, 1, -1))
+		if err != nil {
+			return nil, fmt.Errorf("统计月度奖励失败: %w", err)
+		}
+		amount = min(amount, rule.MonthlyCap-used)
+	}
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	credit := &repository.NodeRewardCredit{
+		NodeID:     node.ID,
+		UserID:     user.ID,
+		RuleID:     rule.ID,
+		RewardDate: date,
+		RewardType: rule.RewardType,
+		Amount:     amount,
+		Traffic:    served,
+		Uptime:     uptime,
+		Status:     NodeRewardStatusCredited,
+	}
+
+	switch rule.RewardType {
+	case NodeRewardTraffic:
+		quota := amount
+		if user.TrafficQuota != nil {
+			quota += *user.TrafficQuota
+		}
+		user.TrafficQuota = &quota
+	case NodeRewardTunnel:
+		tunnels := int(amount)
+		if user.TunnelCount != nil {
+			tunnels += *user.TunnelCount
+		}
+		user.TunnelCount = &tunnels
+	case NodeRewardGroup:
+		now := time.Now()
+		expiresAt := now
+		if user.GroupID == rule.RewardValue && user.GroupTime != nil && user.GroupTime.After(now) {
+			expiresAt = *user.GroupTime
+		}
+		expiresAt = expiresAt.AddDate(0, 0, int(amount))
+		groupID, prevGroupID := rule.RewardValue, rule.BaseGroupID
+		credit.GroupID, credit.PrevGroupID, credit.ExpiresAt = &groupID, &prevGroupID, &expiresAt
+		user.GroupID = groupID
+		user.GroupTime = &expiresAt
+	default:
+		return nil, fmt.Errorf("未知的奖励类型 %s", rule.RewardType)
+	}
+
+	// 先写流水再更新用户，唯一索引保证同一天不会重复发放
+	if err := s.rewardRepo.CreateCredit(ctx, credit); err != nil {
+		return nil, fmt.Errorf("保存奖励流水失败: %w", err)
+	}
+	if err := s.userService.Update(ctx, user); err != nil {
+		if statusErr := s.rewardRepo.UpdateCreditStatus(ctx, credit.ID, NodeRewardStatusFailed, "更新用户失败", nil); statusErr != nil {
+			s.logger.Error("更新奖励流水状态失败", "error", statusErr, "creditID", credit.ID)
+		}
+		return nil, fmt.Errorf("更新用户失败: %w", err)
+	}
+	return credit, nil
+}
+
+// ExpireUpgrades 处理到期的用户组升级奖励，用户仍在升级后的用户组且未被续期时恢复原用户组
+func (s *nodeRewardService) ExpireUpgrades(ctx context.Context, now time.Time) error {
+	credits, err := s.rewardRepo.ListExpiredUpgrades(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, credit := range credits {
+		user, err := s.userService.GetByID(ctx, credit.UserID)
+		if err != nil || user == nil {
+			s.logger.Error("获取奖励用户失败", "error", err, "creditID", credit.ID)
+			continue
+		}
+		// 用户组已被改为永久时不再恢复
+		if credit.GroupID != nil && credit.PrevGroupID != nil && user.GroupID == *credit.GroupID &&
+			user.GroupTime != nil && !user.GroupTime.After(now) {
+			user.GroupID = *credit.PrevGroupID
+			user.GroupTime = nil
+			if err := s.userService.Update(ctx, user); err != nil {
+				s.logger.Error("恢复用户组失败", "error", err, "creditID", credit.ID, "userID", user.ID)
+				continue
+			}
+			s.logger.Info("贡献奖励用户组升级到期", "userID", user.ID, "group", *credit.GroupID, "restored", *credit.PrevGroupID)
+		}
+		if err := s.rewardRepo.UpdateCreditStatus(ctx, credit.ID, NodeRewardStatusExpired, "", nil); err != nil {
+			s.logger.Error("更新奖励流水状态失败", "error", err, "creditID", credit.ID)
+		}
+	}
+	return nil
+}
+
+// Clawback 追回节点已发放且仍有效的奖励，block 为true时同时停止向该节点发放奖励，返回追回的流水数量
+func (s *nodeRewardService) Clawback(ctx context.Context, node *repository.Node, reason string, operatorID int64, block bool) (int, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return 0, errors.New("请填写追回原因")
+	}
+
+	credits, err := s.rewardRepo.ListCreditedByNode(ctx, node.ID)
+	if err != nil {
+		return 0, fmt.Errorf("获取奖励流水失败: %w", err)
+	}
+
+	now := time.Now()
+	clawed := 0
+	var total []string
+	for _, credit := range credits {
+		user, err := s.userService.GetByID(ctx, credit.UserID)
+		if err != nil || user == nil {
+			s.logger.Error("获取奖励用户失败", "error", err, "creditID", credit.ID)
+			continue
+		}
+
+		switch credit.RewardType {
+		case NodeRewardTraffic:
+			if user.TrafficQuota != nil {
+				quota := max(*user.TrafficQuota-credit.Amount, 0)
+				user.TrafficQuota = &quota
+			}
+		case NodeRewardTunnel:
+			if user.TunnelCount != nil {
+				tunnels := max(*user.TunnelCount-int(credit.Amount), 0)
+				user.TunnelCount = &tunnels
+			}
+		case NodeRewardGroup:
+			if credit.GroupID != nil && user.GroupID == *credit.GroupID && user.GroupTime != nil {
+				groupTime := user.GroupTime.AddDate(0, 0, -int(credit.Amount))
+				user.GroupTime = &groupTime
+				if !groupTime.After(now) && credit.PrevGroupID != nil {
+					user.GroupID = *credit.PrevGroupID
+					user.GroupTime = nil
+				}
+			}
+		}
+
+		if err := s.userService.Update(ctx, user); err != nil {
+			s.logger.Error("追回奖励时更新用户失败", "error", err, "creditID", credit.ID, "userID", user.ID)
+			continue
+		}
+		if err := s.rewardRepo.UpdateCreditStatus(ctx, credit.ID, NodeRewardStatusClawedBack, reason, &operatorID); err != nil {
+			s.logger.Error("更新奖励流水状态失败", "error", err, "creditID", credit.ID)
+			continue
+		}
+		clawed++
+		total = append(total, FormatNodeReward(credit.RewardType, credit.Amount))
+	}
+
+	if block && !node.RewardBlocked {
+		if err := s.nodeService.SetRewardBlocked(ctx, node, true); err != nil {
+			return clawed, fmt.Errorf("停止发放节点奖励失败: %w", err)
+		}
+	}
+
+	s.logger.Info("已追回节点贡献奖励", "nodeID", node.ID, "count", clawed, "operator", operatorID, "block", block)
+	if clawed > 0 && node.OwnerID.Valid {
+		s.notifyOwner(ctx, node.OwnerID.Int64, "节点贡献奖励已追回",
+			fmt.Sprintf("您捐赠的节点 %s 的贡献奖励已被管理员追回（%s），原因：%s", node.NodeName, strings.Join(total, "、"), reason))
+	}
+	return clawed, nil
+}
+
+// ListRules 获取全部奖励规则
+func (s *nodeRewardService) ListRules(ctx context.Context) ([]*repository.NodeRewardRule, error) {
+	return s.rewardRepo.ListRules(ctx, false)
+}
+
+// SaveRule 校验并保存奖励规则，ID为0时创建
+func (s *nodeRewardService) SaveRule(ctx context.Context, rule *repository.NodeRewardRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return errors.New("规则名称不能为空")
+	}
+	if rule.MinTraffic < 0 || rule.MonthlyCap < 0 {
+		return errors.New("流量门槛和月度上限不能为负数")
+	}
+	if rule.MinUptime < 0 || rule.MinUptime > 100 {
+		return errors.New("在线率门槛需在0到100之间")
+	}
+
+	switch rule.RewardType {
+	case NodeRewardTraffic, NodeRewardTunnel:
+		if rule.RewardValue <= 0 {
+			return errors.New("奖励数值必须大于0")
+		}
+		rule.BaseGroupID, rule.DurationDays = 0, 0
+	case NodeRewardGroup:
+		if rule.DurationDays <= 0 || rule.DurationDays > maxNodeRewardDuration {
+			return fmt.Errorf("用户组升级天数需在1到%d之间", maxNodeRewardDuration)
+		}
+		if rule.RewardValue == rule.BaseGroupID {
+			return errors.New("升级的目标用户组不能与原用户组相同")
+		}
+		for _, groupID := range []int64{rule.RewardValue, rule.BaseGroupID} {
+			group, err := s.groupRepo.GetByID(ctx, groupID)
+			if err != nil || group == nil {
+				return fmt.Errorf("用户组 %d 不存在", groupID)
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的奖励类型 %s", rule.RewardType)
+	}
+
+	if rule.ID == 0 {
+		return s.rewardRepo.CreateRule(ctx, rule)
+	}
+	existing, err := s.rewardRepo.GetRule(ctx, rule.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrNodeRewardRuleNotFound
+	}
+	return s.rewardRepo.UpdateRule(ctx, rule)
+}
+
+// DeleteRule 删除奖励规则
+func (s *nodeRewardService) DeleteRule(ctx context.Context, id int64) error {
+	rule, err := s.rewardRepo.GetRule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return ErrNodeRewardRuleNotFound
+	}
+	return s.rewardRepo.DeleteRule(ctx, id)
+}
+
+// ListCredits 分页获取奖励流水
+func (s *nodeRewardService) ListCredits(ctx context.Context, userID, nodeID int64, offset, limit int) ([]*repository.NodeRewardCredit, int, error) {
+	return s.rewardRepo.ListCredits(ctx, userID, nodeID, offset, limit)
+}
+
+// notifyOwner 向节点所有者发送站内通知，失败只记录日志
+func (s *nodeRewardService) notifyOwner(ctx context.Context, userID int64, title, content string) {
+	user, err := s.userService.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		s.logger.Error("获取节点所有者失败", "error", err, "userID", userID)
+		return
+	}
+	if err := s.userNoticeService.Notify(ctx, user.Username, title, content); err != nil {
+		s.logger.Error("发送贡献奖励通知失败", "error", err, "username", user.Username)
+	}
+}
+
+// FormatNodeReward 格式化奖励数量
+func FormatNodeReward(rewardType string, amount int64) string {
+	switch rewardType {
+	case NodeRewardTraffic:
+		return "流量 " + formatRewardBytes(amount)
+	case NodeRewardTunnel:
+		return fmt.Sprintf("隧道 %d 条", amount)
+	case NodeRewardGroup:
+		return fmt.Sprintf("用户组升级 %d 天", amount)
+	default:
+		return strconv.FormatInt(amount, 10)
+	}
+}
+
+// formatRewardBytes 格式化流量大小为带单位的字符串
+func formatRewardBytes(bytes int64) string {
+	const (
+		KB = 1024
+		MB = 1024 * KB
+		GB = 1024 * MB
+		TB = 1024 * GB
+	)
+
+	switch {
+	case bytes < KB:
+		return fmt.Sprintf("%d B", bytes)
+	case bytes < MB:
+		return fmt.Sprintf("%.2f KB", float64(bytes)/KB)
+	case bytes < GB:
+		return fmt.Sprintf("%.2f MB", float64(bytes)/MB)
+	case bytes < TB:
+		return fmt.Sprintf("%.2f GB", float64(bytes)/GB)
+	default:
+		return fmt.Sprintf("%.2f TB", float64(bytes)/TB)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/repository/repositorytest"
+	"testing"
+	"time"
+)
+
+// fakeNodeTrafficRepo 保存在内存中的节点流量仓库
+type fakeNodeTrafficRepo struct {
+	repository.NodeTrafficRepository
+	logs []*repository.NodeTrafficLog
+}
+
+func (r *fakeNodeTrafficRepo) Create(ctx context.Context, traffic *repository.NodeTrafficLog) error {
+	traffic.ID = int64(len(r.logs) + 1)
+	r.logs = append(r.logs, traffic)
+	return nil
+}
+
+func (r *fakeNodeTrafficRepo) GetLastRecord(ctx context.Context, nodeName string) (*repository.NodeTrafficLog, error) {
+	var last *repository.NodeTrafficLog
+	for _, log := range r.logs {
+		if log.NodeName == nodeName {
+			last = log
+		}
+	}
+	return last, nil
+}
+
+func (r *fakeNodeTrafficRepo) GetDailyRecord(ctx context.Context, nodeName string, date string) (*repository.NodeTrafficLog, error) {
+	for _, log := range r.logs {
+		if log.NodeName == nodeName && trafficLogDate(log) == date {
+			return log, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeNodeTrafficRepo) UpdateRecord(ctx context.Context, id int64, trafficIn, trafficOut int64, onlineCount int) error {
+	for _, log := range r.logs {
+		if log.ID == id {
+			log.TrafficIn, log.TrafficOut, log.OnlineCount = trafficIn, trafficOut, onlineCount
+		}
+	}
+	return nil
+}
+
+func (r *fakeNodeTrafficRepo) ListByDateRange(ctx context.Context, nodeName string, startDate, endDate string) ([]*repository.NodeTrafficLog, error) {
+	var list []*repository.NodeTrafficLog
+	for _, log := range r.logs {
+		if date := trafficLogDate(log); date >= startDate && date <= endDate {
+			list = append(list, log)
+		}
+	}
+	return list, nil
+}
+
+func TestGetDailyTraffic(t *testing.T) {
+	repo := &fakeNodeTrafficRepo{logs: []*repository.NodeTrafficLog{
+		{RecordDate: "2024-06-01", TrafficIn: 1000, TrafficOut: 5000, OnlineCount: 2},
+		{RecordDate: "2024-06-02T00:00:00Z", TrafficIn: 1500, TrafficOut: 5200, OnlineCount: 3},
+		// 06-03 定时任务未执行
+		{RecordDate: "2024-06-04", TrafficIn: 9000, TrafficOut: 9000, OnlineCount: 4},
+		// frps重启后累计值归零
+		{RecordDate: "2024-06-05", TrafficIn: 100, TrafficOut: 100, OnlineCount: 1},
+	}}
+	s := NewNodeService(nil, repo, nil)
+	end, _ := time.Parse("2006-01-02", "2024-06-05")
+
+	got, err := s.GetDailyTraffic(context.Background(), &repository.Node{NodeName: "n1"}, end, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NodeDailyTraffic{
+		{Date: "2024-06-01", OnlineCount: 2},
+		{Date: "2024-06-02", TrafficIn: 500, TrafficOut: 200, OnlineCount: 3},
+		{Date: "2024-06-03"},
+		{Date: "2024-06-04", OnlineCount: 4},
+		{Date: "2024-06-05", OnlineCount: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if *got[i] != want[i] {
+			t.Errorf("day %d = %+v, want %+v", i, *got[i], want[i])
+		}
+	}
+}
+
+func TestUpdateFrpsVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := repositorytest.NewNodeRepository(&repository.Node{ID: 1, FrpsVersion: "0.51.3"})
+	s := NewNodeService(repo, nil, nil)
+	node, _ := repo.GetByID(ctx, 1)
+
+	// 识别失败的空版本不覆盖已有记录，版本变化时写库并同步到节点对象
+	for _, version := range []string{"", "0.51.3", "0.61.1"} {
+		if err := s.UpdateFrpsVersion(ctx, node, version); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored, _ := repo.GetByID(ctx, 1)
+	if node.FrpsVersion != "0.61.1" || stored.FrpsVersion != "0.61.1" {
+		t.Errorf("FrpsVersion = %q, stored %q; want 0.61.1", node.FrpsVersion, stored.FrpsVersion)
+	}
+}
@@ -2,13 +2,9 @@ package service
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
 	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/frps"
 	"stellarfrp/pkg/logger"
-	"stellarfrp/pkg/network"
 	"time"
 )
 
@@ -20,25 +16,32 @@ type NodeTrafficService interface {
 
 // nodeTrafficService 节点流量服务实现
 type nodeTrafficService struct {
-	nodeRepo        repository.NodeRepository
-	nodeTrafficRepo repository.NodeTrafficRepository
-	logger          *logger.Logger
+	nodeRepo         repository.NodeRepository
+	nodeTrafficRepo  repository.NodeTrafficRepository
+	frpsClient       *frps.Client
+	heartbeatService NodeHeartbeatService
+	logger           *logger.Logger
 }
 
 // NewNodeTrafficService 创建节点流量服务实例
 func NewNodeTrafficService(
 	nodeRepo repository.NodeRepository,
 	nodeTrafficRepo repository.NodeTrafficRepository,
+	frpsClient *frps.Client,
+	heartbeatService NodeHeartbeatService,
 	logger *logger.Logger,
 ) NodeTrafficService {
 	return &nodeTrafficService{
-		nodeRepo:        nodeRepo,
-		nodeTrafficRepo: nodeTrafficRepo,
-		logger:          logger,
+		nodeRepo:         nodeRepo,
+		nodeTrafficRepo:  nodeTrafficRepo,
+		frpsClient:       frpsClient,
+		heartbeatService: heartbeatService,
+		logger:           logger,
 	}
 }
 
-// CheckNodeStatus 检查节点服务器状态并更新
+// CheckNodeStatus 对未上报心跳或心跳已超时的节点进行TCP端口检查并更新状态
+// 心跳正常的节点由心跳决定状态，此处跳过
 func (s *nodeTrafficService) CheckNodeStatus(ctx context.Context) error {
 	// 获取所有节点信息
 	nodes, err := s.nodeRepo.List(ctx, 0, 10000)
@@ -47,43 +50,35 @@ func (s *nodeTrafficService) CheckNodeStatus(ctx context.Context) error {
 		return err
 	}
 
-	// 遍历所有节点，检查连接状态
+	now := time.Now()
 	for _, node := range nodes {
-		// 跳过待审核状态(2)的节点
-		if node.Status == 2 {
+		// 跳过待审核和所有者暂停的节点
+		if repository.NodeUnapproved(node.Status) || node.Status == repository.NodeStatusPaused {
 			continue
 		}
 
-		ip := node.IP
-		port := node.FrpsPort
-		status := node.Status
-
-		// 检查端口是否可以连接
-		isAvailable := network.CheckPort(ip, port)
-
-		// 如果节点可用但状态不是work(1)，则更新为work
-		if isAvailable {
-			if status != 1 {
-				node.Status = 1 // 在线状态
-				err := s.nodeRepo.Update(ctx, node)
-				if err != nil {
-					s.logger.Error("Failed to update node status", "node", node.NodeName, "error", err)
-					continue
-				}
-				s.logger.Info("Node status updated to work", "node", node.NodeName, "ip", ip, "port", port)
-			}
-		} else {
-			// 如果节点不可用，更新为close(0)
-			if status != 0 {
-				node.Status = 0 // 离线状态
-				err := s.nodeRepo.Update(ctx, node)
-				if err != nil {
-					s.logger.Error("Failed to update node status", "node", node.NodeName, "error", err)
-					continue
-				}
-				s.logger.Info("Node status updated to close", "node", node.NodeName, "ip", ip, "port", port)
-			}
+		hb, err := s.heartbeatService.Get(ctx, node.ID)
+		if err != nil {
+			s.logger.Error("Failed to get node heartbeat", "node", node.NodeName, "error", err)
+		}
+		if hb != nil && hb.Fresh(now) {
+			continue
+		}
+
+		status := FallbackNodeStatus(node, hb)
+		if status != repository.NodeStatusOffline {
+			s.detectFrpsVersion(ctx, node)
+		}
+		if status == node.Status {
+			continue
 		}
+
+		if err := s.nodeRepo.UpdateStatus(ctx, node.ID, status); err != nil {
+			s.logger.Error("Failed to update node status", "node", node.NodeName, "error", err)
+			continue
+		}
+		s.logger.Info("Node status updated", "node", node.NodeName, "ip", node.IP, "port", node.FrpsPort, "from", node.Status, "to", status)
+		node.Status = status
 	}
 
 	return nil
@@ -103,8 +98,8 @@ func (s *nodeTrafficService) RecordNodeTraffic(ctx context.Context) error {
 
 	// 为每个节点记录流量信息
 	for _, node := range nodes {
-		// 跳过离线节点和待审核节点
-		if node.Status == 0 || node.Status == 2 {
+		// 跳过离线节点和未通过审核的节点
+		if node.Status == repository.NodeStatusOffline || repository.NodeUnapproved(node.Status) {
 			continue
 		}
 
@@ -198,52 +193,11 @@ type NodeNetworkInfo struct {
 
 // getNodeNetworkInfo 获取节点的网络信息
 func (s *nodeTrafficService) getNodeNetworkInfo(ctx context.Context, node *repository.Node) (*NodeNetworkInfo, error) {
-	// 实际通过API请求获取节点的网络信息
-
-	// 构建节点API URL
-	apiURL := node.URL + "/api/serverinfo"
-
-	// 创建HTTP客户端并设置超时
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	// 创建请求
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %w", err)
-	}
-
-	// 设置Basic认证
-	req.SetBasicAuth(node.User, node.Token)
-
-	// 发送请求
-	resp, err := client.Do(req)
+	nodeInfo, err := s.frpsClient.ServerInfo(ctx, NodeEndpoint(node))
 	if err != nil {
-		return nil, fmt.Errorf("发送请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
-	}
-
-	// 解析JSON响应
-	var nodeInfo struct {
-		Version         string         `json:"version"`
-		BindPort        int            `json:"bindPort"`
-		TotalTrafficIn  int64          `json:"totalTrafficIn"`
-		TotalTrafficOut int64          `json:"totalTrafficOut"`
-		CurConns        int            `json:"curConns"`
-		ClientCounts    int            `json:"clientCounts"`
-		ProxyTypeCount  map[string]int `json:"proxyTypeCount"`
-	}
-
-	if err := json.Unmarshal(body, &nodeInfo); err != nil {
-		return nil, fmt.Errorf("解析响应数据失败: %w", err)
+		return nil, err
 	}
+	s.recordFrpsVersion(ctx, node, nodeInfo.Version)
 
 	// 返回提取的网络信息
 	return &NodeNetworkInfo{
@@ -252,3 +206,26 @@ func (s *nodeTrafficService) getNodeNetworkInfo(ctx context.Context, node *repos
 		OnlineCount:     nodeInfo.ClientCounts,
 	}, nil
 }
+
+// detectFrpsVersion 通过serverinfo识别节点的frps版本，失败时保留原有记录
+func (s *nodeTrafficService) detectFrpsVersion(ctx context.Context, node *repository.Node) {
+	info, err := s.frpsClient.ServerInfo(ctx, NodeEndpoint(node))
+	if err != nil {
+		s.logger.Warn("Failed to detect frps version", "node", node.NodeName, "error", err)
+		return
+	}
+	s.recordFrpsVersion(ctx, node, info.Version)
+}
+
+// recordFrpsVersion 节点frps版本发生变化时写入数据库
+func (s *nodeTrafficService) recordFrpsVersion(ctx context.Context, node *repository.Node, version string) {
+	if version == "" || version == node.FrpsVersion {
+		return
+	}
+	if err := s.nodeRepo.UpdateFrpsVersion(ctx, node.ID, version); err != nil {
+		s.logger.Error("Failed to update frps version", "node", node.NodeName, "error", err)
+		return
+	}
+	s.logger.Info("Node frps version changed", "node", node.NodeName, "from", node.FrpsVersion, "to", version)
+	node.FrpsVersion = version
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/internal/repository/repositorytest"
+	"stellarfrp/pkg/frps"
+	"stellarfrp/pkg/frps/frpstest"
+	"stellarfrp/pkg/logger"
+	"testing"
+	"time"
+)
+
+func newTestFrpsServer(t *testing.T, info frps.ServerInfo) *frpstest.Server {
+	t.Helper()
+	s := frpstest.NewServer("admin", "password")
+	t.Cleanup(s.Close)
+	s.SetServerInfo(info)
+	return s
+}
+
+func testNode(id int64, name string, status int, s *frpstest.Server) *repository.Node {
+	return &repository.Node{ID: id, NodeName: name, Status: status, URL: s.URL, User: s.User, Token: s.Token}
+}
+
+func TestRecordNodeTraffic(t *testing.T) {
+	online := newTestFrpsServer(t, frps.ServerInfo{Version: "0.61.1", TotalTrafficIn: 300, TotalTrafficOut: 400, ClientCounts: 2})
+	offline := newTestFrpsServer(t, frps.ServerInfo{Version: "0.61.1", TotalTrafficIn: 1})
+	failing := newTestFrpsServer(t, frps.ServerInfo{Version: "0.61.1", TotalTrafficIn: 1})
+	failing.FailNext(1, http.StatusInternalServerError)
+
+	nodeRepo := repositorytest.NewNodeRepository(
+		testNode(1, "n1", repository.NodeStatusOnline, online),
+		testNode(2, "n2", repository.NodeStatusOffline, offline),
+		testNode(3, "n3", repository.NodeStatusOnline, failing),
+	)
+	trafficRepo := &fakeNodeTrafficRepo{}
+	client := frps.NewClient(frps.Options{Timeout: time.Second, BreakerThreshold: 5, BreakerCooldown: time.Minute})
+	s := NewNodeTrafficService(nodeRepo, trafficRepo, client, nil, logger.NewLogger("error"))
+
+	if err := s.RecordNodeTraffic(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 离线节点不请求，请求失败的节点跳过且不影响其他节点
+	if len(trafficRepo.logs) != 1 {
+		t.Fatalf("logs = %+v, want only n1", trafficRepo.logs)
+	}
+	log := trafficRepo.logs[0]
+	if log.NodeName != "n1" || log.TrafficIn != 300 || log.TrafficOut != 400 || log.OnlineCount != 2 {
+		t.Errorf("log = %+v", log)
+	}
+	if log.RecordDate != time.Now().Format("2006-01-02") {
+		t.Errorf("RecordDate = %q", log.RecordDate)
+	}
+	// 识别到的版本写入节点，后续请求按版本选择解析器
+	for id, want := range map[int64]string{1: "0.61.1", 2: "", 3: ""} {
+		if node, _ := nodeRepo.GetByID(context.Background(), id); node.FrpsVersion != want {
+			t.Errorf("node %d FrpsVersion = %q, want %q", id, node.FrpsVersion, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/frps"
+	"stellarfrp/pkg/logger"
+	"stellarfrp/pkg/network"
+	"stellarfrp/pkg/secret"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 节点校验触发方式
+const (
+	NodeValidationTriggerSubmit  = "submit"  // 用户提交捐赠时
+	NodeValidationTriggerRecheck = "recheck" // 管理员审核时重新校验
+)
+
+// 节点校验检查项
+const (
+	NodeCheckAddress    = "address"    // 节点IP为可访问的公网地址
+	NodeCheckServerInfo = "serverinfo" // 使用提交的凭据访问面板 /api/serverinfo
+	NodeCheckBindPort   = "bind_port"  // 面板返回的监听端口与提交的服务端口一致
+	NodeCheckFrpsPort   = "frps_port"  // frps服务端口可以建立TCP连接
+	NodeCheckPlugin     = "plugin"     // frps已使用节点插件密钥回调面板
+)
+
+// 校验参数
+const (
+	nodeValidationTimeout     = 8 * time.Second
+	nodeValidationHistorySize = 20
+	// nodePluginSeenTTL 插件回调记录的保留时长
+	nodePluginSeenTTL = 30 * 24 * time.Hour
+)
+
+// NodeValidationService 捐赠节点自动校验服务接口
+type NodeValidationService interface {
+	Validate(ctx context.Context, node *repository.Node, trigger string, operatorID int64) (*repository.NodeValidation, error)
+	Latest(ctx context.Context, nodeID int64) (*repository.NodeValidation, error)
+	List(ctx context.Context, nodeID int64) ([]*repository.NodeValidation, error)
+	RecordPluginCallback(ctx context.Context, nodeID int64) error
+}
+
+// nodeValidationService 捐赠节点自动校验服务实现
+type nodeValidationService struct {
+	validationRepo repository.NodeValidationRepository
+	frpsClient     *frps.Client
+	redisClient    *redis.Client
+	logger         *logger.Logger
+}
+
+// NewNodeValidationService 创建捐赠节点自动校验服务实例
+// 节点地址由用户提交，使用只允许连接公网地址且不重试的独立客户端
+func NewNodeValidationService(validationRepo repository.NodeValidationRepository, redisClient *redis.Client, keyring *secret.Keyring, logger *logger.Logger) NodeValidationService {
+	opts := frps.DefaultOptions()
+	opts.Timeout = nodeValidationTimeout
+	opts.MaxRetries = 0
+	opts.PublicOnly = true
+	opts.DecryptToken = keyring.Decrypt
+
+	return &nodeValidationService{
+		validationRepo: validationRepo,
+		frpsClient:     frps.NewClient(opts),
+		redisClient:    redisClient,
+		logger:         logger,
+	}
+}
+
+// nodePluginSeenKey 节点最近一次插件回调时间的缓存键
+func nodePluginSeenKey(nodeID int64) string {
+	return fmt.Sprintf("node:plugin:seen:%d", nodeID)
+}
+
+// Validate 依次检查节点地址、面板凭据、服务端口和插件回调，结果保存为校验记录
+func (s *nodeValidationService) Validate(ctx context.Context, node *repository.Node, trigger string, operatorID int64) (*repository.NodeValidation, error) {
+	var checks []repository.NodeValidationCheck
+	add := func(name string, passed bool, detail string) {
+		checks = append(checks, repository.NodeValidationCheck{Name: name, Passed: passed, Detail: detail})
+	}
+
+	ip, err := resolvePublicIP(ctx, node.IP)
+	if err != nil {
+		add(NodeCheckAddress, false, err.Error())
+	} else {
+		add(NodeCheckAddress, true, "节点地址 "+ip)
+	}
+
+	info, err := s.frpsClient.ServerInfo(ctx, NodeEndpoint(node))
+	switch {
+	case err == nil:
+		add(NodeCheckServerInfo, true, "面板访问成功，frps版本 "+info.Version)
+	case isUnauthorized(err):
+		add(NodeCheckServerInfo, false, "面板拒绝访问，请检查用户名和Token")
+	default:
+		add(NodeCheckServerInfo, false, "无法访问面板: "+err.Error())
+	}
+
+	switch {
+	case info == nil:
+		add(NodeCheckBindPort, false, "面板不可用，无法核对服务端口")
+	case info.BindPort == 0:
+		add(NodeCheckBindPort, true, "面板未返回监听端口，跳过核对")
+	case info.BindPort != node.FrpsPort:
+		add(NodeCheckBindPort, false, fmt.Sprintf("面板显示 frps 监听端口为 %d，与提交的 %d 不一致", info.BindPort, node.FrpsPort))
+	default:
+		add(NodeCheckBindPort, true, fmt.Sprintf("监听端口 %d 与提交一致", info.BindPort))
+	}
+
+	if ip == "" {
+		add(NodeCheckFrpsPort, false, "节点地址不可用，跳过端口检查")
+	} else if result := network.ProbeTCP(ctx, ip, node.FrpsPort, nodeValidationTimeout); result.Success {
+		add(NodeCheckFrpsPort, true, fmt.Sprintf("端口 %d 可连接，耗时 %dms", node.FrpsPort, result.Latency.Milliseconds()))
+	} else {
+		add(NodeCheckFrpsPort, false, fmt.Sprintf("端口 %d 无法连接: %s", node.FrpsPort, result.Error))
+	}
+
+	if node.PluginSecret == "" {
+		add(NodeCheckPlugin, false, "节点尚未生成插件密钥，请使用面板生成的 frps 配置")
+	} else if seenAt, err := s.pluginSeenAt(ctx, node.ID); err != nil {
+		return nil, err
+	} else if seenAt.IsZero() {
+		add(NodeCheckPlugin, false, "尚未收到该节点的插件回调，请确认 frps 已使用面板生成的配置并有客户端登录过")
+	} else {
+		add(NodeCheckPlugin, true, "最近一次插件回调于 "+seenAt.Format("2006-01-02 15:04:05"))
+	}
+
+	v := &repository.NodeValidation{
+		NodeID:  node.ID,
+		Passed:  true,
+		Trigger: trigger,
+		Results: checks,
+	}
+	for _, check := range checks {
+		v.Passed = v.Passed && check.Passed
+	}
+	if operatorID > 0 {
+		v.OperatorID.Int64, v.OperatorID.Valid = operatorID, true
+	}
+	data, err := json.Marshal(checks)
+	if err != nil {
+		return nil, err
+	}
+	v.Checks = string(data)
+
+	if err := s.validationRepo.Create(ctx, v); err != nil {
+		return nil, fmt.Errorf("保存节点校验记录失败: %w", err)
+	}
+	s.logger.Info("捐赠节点校验完成", "nodeID", node.ID, "passed", v.Passed, "trigger", trigger)
+	return v, nil
+}
+
+// Latest 获取节点最近一次校验记录，没有记录时返回nil
+func (s *nodeValidationService) Latest(ctx context.Context, nodeID int64) (*repository.NodeValidation, error) {
+	v, err := s.validationRepo.GetLatest(ctx, nodeID)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	parseNodeValidationChecks(v)
+	return v, nil
+}
+
+// List 获取节点最近的校验记录
+func (s *nodeValidationService) List(ctx context.Context, nodeID int64) ([]*repository.NodeValidation, error) {
+	list, err := s.validationRepo.ListByNode(ctx, nodeID, nodeValidationHistorySize)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []*repository.NodeValidation{}
+	}
+	for _, v := range list {
+		parseNodeValidationChecks(v)
+	}
+	return list, nil
+}
+
+// RecordPluginCallback 记录节点携带正确密钥的插件回调，作为插件配置指向面板的依据
+func (s *nodeValidationService) RecordPluginCallback(ctx context.Context, nodeID int64) error {
+	return s.redisClient.Set(ctx, nodePluginSeenKey(nodeID), time.Now().Unix(), nodePluginSeenTTL).Err()
+}
+
+// pluginSeenAt 获取节点最近一次插件回调时间，没有记录时返回零值
+func (s *nodeValidationService) pluginSeenAt(ctx context.Context, nodeID int64) (time.Time, error) {
+	ts, err := s.redisClient.Get(ctx, nodePluginSeenKey(nodeID)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("获取插件回调记录失败: %w", err)
+	}
+	return time.Unix(ts, 0), nil
+}
+
+// parseNodeValidationChecks 解析校验记录中的检查结果
+func parseNodeValidationChecks(v *repository.NodeValidation) {
+	v.Results = []repository.NodeValidationCheck{}
+	if v.Checks != "" {
+		json.Unmarshal([]byte(v.Checks), &v.Results)
+	}
+}
+
+// resolvePublicIP 解析节点地址，要求解析结果均为公网地址，返回第一个地址
+func resolvePublicIP(ctx context.Context, host string) (string, error) {
+	if host == "" {
+		return "", errors.New("节点地址为空")
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("无法解析节点地址 %s", host)
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("节点地址 %s 没有解析结果", host)
+	}
+	for _, addr := range addrs {
+		if !network.IsPublicIP(addr.IP) {
+			return "", fmt.Errorf("节点地址 %s 不是公网地址", host)
+		}
+	}
+	return addrs[0].IP.String(), nil
+}
+
+// isUnauthorized 判断面板是否因凭据错误拒绝访问
+func isUnauthorized(err error) bool {
+	var statusErr *frps.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"stellarfrp/internal/repository"
 	"stellarfrp/internal/utils"
+	"stellarfrp/pkg/frps"
 	"stellarfrp/pkg/logger"
 	"time"
 
@@ -21,39 +22,71 @@ type ProxyService interface {
 	GetByUsernameWithPagination(ctx context.Context, username string, offset, limit int) ([]*repository.Proxy, error)
 	GetByUsernameAndName(ctx context.Context, username, proxyName string) (*repository.Proxy, error)
 	Update(ctx context.Context, proxy *repository.Proxy) error
+	UpdateBy(ctx context.Context, proxy *repository.Proxy, operator ProxyOperator) error
 	Delete(ctx context.Context, id int64) error
 	List(ctx context.Context, offset, limit int) ([]*repository.Proxy, error)
 	ListByStatus(ctx context.Context, status string, offset, limit int) ([]*repository.Proxy, error)
+	ListByNode(ctx context.Context, nodeID int64) ([]*repository.Proxy, error)
 	Count(ctx context.Context) (int, error)
 	CountByStatus(ctx context.Context, status string) (int, error)
 	IsRemotePortUsed(ctx context.Context, nodeID int64, proxyType string, remotePort string) (bool, error)
+	CountRemotePorts(ctx context.Context, nodeID int64, proxyType string) (int, error)
+	CountByNodeGroupByType(ctx context.Context, nodeID int64) (map[string]int, error)
+	ListOnlineRunIDs(ctx context.Context, nodeID int64) ([]string, error)
 	GetUserProxyCount(ctx context.Context, username string) (int, error)
 	CheckUserNodeAccess(ctx context.Context, username string, nodeID int64) (bool, error)
+	ListVersions(ctx context.Context, proxyID int64, offset, limit int) ([]*repository.ProxyVersion, int, error)
+	GetVersion(ctx context.Context, proxyID int64, version int) (*repository.ProxyVersion, error)
+	KickProxy(ctx context.Context, proxy *repository.Proxy, reason string) error
+	ListEvents(ctx context.Context, proxyID int64, offset, limit int) ([]*repository.ProxyEvent, int, error)
+	CleanupEvents(ctx context.Context, before time.Time) (int64, error)
+	GetUptime(ctx context.Context, proxyID int64) ([]ProxyUptime, error)
 }
 
 // proxyService 隧道服务实现
 type proxyService struct {
-	proxyRepo   repository.ProxyRepository
-	nodeService NodeService
-	userService UserService
-	redisCli    *redis.Client
-	logger      *logger.Logger
+	proxyRepo         repository.ProxyRepository
+	proxyVersionRepo  repository.ProxyVersionRepository
+	proxyEventRepo    repository.ProxyEventRepository
+	proxyScheduleRepo repository.ProxyScheduleRepository
+	proxyAlertRepo    repository.ProxyAlertRepository
+	proxyProbeRepo    repository.ProxyProbeRepository
+	nodeService       NodeService
+	userService       UserService
+	frpsClient        *frps.Client
+	streamHub         ProxyStreamHub
+	redisCli          *redis.Client
+	logger            *logger.Logger
 }
 
 // NewProxyService 创建隧道服务实例
 func NewProxyService(
 	proxyRepo repository.ProxyRepository,
+	proxyVersionRepo repository.ProxyVersionRepository,
+	proxyEventRepo repository.ProxyEventRepository,
+	proxyScheduleRepo repository.ProxyScheduleRepository,
+	proxyAlertRepo repository.ProxyAlertRepository,
+	proxyProbeRepo repository.ProxyProbeRepository,
 	nodeService NodeService,
 	userService UserService,
+	frpsClient *frps.Client,
+	streamHub ProxyStreamHub,
 	redisCli *redis.Client,
 	logger *logger.Logger,
 ) ProxyService {
 	return &proxyService{
-		proxyRepo:   proxyRepo,
-		nodeService: nodeService,
-		userService: userService,
-		redisCli:    redisCli,
-		logger:      logger,
+		proxyRepo:         proxyRepo,
+		proxyVersionRepo:  proxyVersionRepo,
+		proxyEventRepo:    proxyEventRepo,
+		proxyScheduleRepo: proxyScheduleRepo,
+		proxyAlertRepo:    proxyAlertRepo,
+		proxyProbeRepo:    proxyProbeRepo,
+		nodeService:       nodeService,
+		userService:       userService,
+		frpsClient:        frpsClient,
+		streamHub:         streamHub,
+		redisCli:          redisCli,
+		logger:            logger,
 	}
 }
 
@@ -105,6 +138,15 @@ func (s *proxyService) Create(ctx context.Context, proxy *repository.Proxy) (int
 		return 0, err
 	}
 
+	// 记录初始配置版本
+	created := *proxy
+	created.ID = id
+	s.recordVersion(ctx, &created, []string{}, ProxyOperator{
+		Type:   ProxyOperatorUser,
+		Name:   proxy.Username,
+		Action: ProxyVersionActionCreate,
+	})
+
 	// 创建成功后清除用户隧道缓存
 	s.clearUserProxiesCache(ctx, proxy.Username)
 	return id, nil
@@ -198,8 +240,13 @@ func (s *proxyService) GetByUsernameAndName(ctx context.Context, username, proxy
 	return s.proxyRepo.GetByUsernameAndName(ctx, username, proxyName)
 }
 
-// Update 更新隧道
+// Update 更新隧道，配置变更将以系统身份记录版本
 func (s *proxyService) Update(ctx context.Context, proxy *repository.Proxy) error {
+	return s.UpdateBy(ctx, proxy, systemProxyOperator)
+}
+
+// UpdateBy 更新隧道，配置字段发生变化时按操作人记录版本快照
+func (s *proxyService) UpdateBy(ctx context.Context, proxy *repository.Proxy, operator ProxyOperator) error {
 	// 获取原隧道信息，用于后续可能的缓存处理
 	oldProxy, err := s.proxyRepo.GetByID(ctx, proxy.ID)
 	if err != nil {
@@ -211,6 +258,17 @@ func (s *proxyService) Update(ctx context.Context, proxy *repository.Proxy) erro
 		return err
 	}
 
+	s.recordStatusEvent(ctx, oldProxy, proxy, operator)
+
+	// 仅在配置发生变化时记录版本，状态上报等运行时更新不产生版本
+	if oldProxy != nil {
+		changedFields := NewProxyConfigSnapshot(proxy).Diff(NewProxyConfigSnapshot(oldProxy))
+		if len(changedFields) > 0 {
+			s.ensureBaselineVersion(ctx, oldProxy)
+			s.recordVersion(ctx, proxy, changedFields, operator)
+		}
+	}
+
 	// 清除用户隧道缓存
 	s.clearUserProxiesCache(ctx, proxy.Username)
 
@@ -235,6 +293,22 @@ func (s *proxyService) Delete(ctx context.Context, id int64) error {
 		return err
 	}
 
+	if err := s.proxyVersionRepo.DeleteByProxyID(ctx, id); err != nil {
+		s.logger.Error("删除隧道配置版本失败", "error", err, "proxyID", id)
+	}
+	if err := s.proxyEventRepo.DeleteByProxyID(ctx, id); err != nil {
+		s.logger.Error("删除隧道事件失败", "error", err, "proxyID", id)
+	}
+	if err := s.proxyScheduleRepo.DeleteByProxyID(ctx, id); err != nil {
+		s.logger.Error("删除隧道时段配置失败", "error", err, "proxyID", id)
+	}
+	if err := s.proxyAlertRepo.DeleteByProxyID(ctx, id); err != nil {
+		s.logger.Error("删除隧道告警配置失败", "error", err, "proxyID", id)
+	}
+	if err := s.proxyProbeRepo.DeleteByProxyID(ctx, id); err != nil {
+		s.logger.Error("删除隧道探测记录失败", "error", err, "proxyID", id)
+	}
+
 	// 清除相关缓存
 	if proxy != nil {
 		s.clearUserProxiesCache(ctx, proxy.Username)
@@ -253,6 +327,11 @@ func (s *proxyService) ListByStatus(ctx context.Context, status string, offset,
 	return s.proxyRepo.ListByStatus(ctx, status, offset, limit)
 }
 
+// ListByNode 获取节点下的全部隧道
+func (s *proxyService) ListByNode(ctx context.Context, nodeID int64) ([]*repository.Proxy, error) {
+	return s.proxyRepo.ListByNode(ctx, nodeID)
+}
+
 // Count 获取隧道总数
 func (s *proxyService) Count(ctx context.Context) (int, error) {
 	return s.proxyRepo.Count(ctx)
@@ -268,6 +347,21 @@ func (s *proxyService) IsRemotePortUsed(ctx context.Context, nodeID int64, proxy
 	return s.proxyRepo.IsRemotePortUsed(ctx, nodeID, proxyType, remotePort)
 }
 
+// CountRemotePorts 统计节点下指定协议类型已占用的远程端口数量
+func (s *proxyService) CountRemotePorts(ctx context.Context, nodeID int64, proxyType string) (int, error) {
+	return s.proxyRepo.CountRemotePorts(ctx, nodeID, proxyType)
+}
+
+// CountByNodeGroupByType 按协议类型统计节点下的隧道数量
+func (s *proxyService) CountByNodeGroupByType(ctx context.Context, nodeID int64) (map[string]int, error) {
+	return s.proxyRepo.CountByNodeGroupByType(ctx, nodeID)
+}
+
+// ListOnlineRunIDs 获取节点上在线客户端的运行ID
+func (s *proxyService) ListOnlineRunIDs(ctx context.Context, nodeID int64) ([]string, error) {
+	return s.proxyRepo.ListOnlineRunIDs(ctx, nodeID)
+}
+
 // GetUserProxyCount 获取用户的隧道数量
 func (s *proxyService) GetUserProxyCount(ctx context.Context, username string) (int, error) {
 	// 尝试从缓存获取
@@ -327,3 +421,32 @@ func (s *proxyService) CheckUserNodeAccess(ctx context.Context, username string,
 	// 使用工具函数检查用户组ID是否在节点的权限组列表中
 	return utils.IsGroupInPermission(effectiveGroupID, node.Permission)
 }
+
+// KickProxy 通过节点API踢下隧道所在的客户端，并以指定原因将隧道标记为离线
+func (s *proxyService) KickProxy(ctx context.Context, proxy *repository.Proxy, reason string) error {
+	if proxy.RunID == "" {
+		return nil
+	}
+
+	node, err := s.nodeService.GetByID(ctx, proxy.Node)
+	if err != nil {
+		return fmt.Errorf("获取节点信息失败: %w", err)
+	}
+	if node == nil {
+		return fmt.Errorf("节点不存在: %d", proxy.Node)
+	}
+
+	if err := s.frpsClient.KickClient(ctx, NodeEndpoint(node), proxy.RunID); err != nil {
+		return fmt.Errorf("发送关闭隧道请求失败: %w", err)
+	}
+
+	proxy.RunID = ""
+	proxy.Status = "offline"
+	proxy.LastUpdate = time.Now().Format("2006-01-02 15:04:05")
+	if err := s.UpdateBy(ctx, proxy, ProxyOperator{Type: ProxyOperatorSystem, Reason: reason}); err != nil {
+		return fmt.Errorf("更新隧道状态失败: %w", err)
+	}
+	s.redisCli.Del(ctx, ProxyStatusSnapshotKey(proxy.ID))
+
+	return nil
+}
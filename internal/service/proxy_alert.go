@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/email"
+	"stellarfrp/pkg/logger"
+	"stellarfrp/pkg/secret"
+	"stellarfrp/pkg/webhook"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 告警规则取值范围
+const (
+	maxAlertOfflineMinutes = 1440
+	maxAlertFlapThreshold  = 60
+)
+
+// flapWindow 频繁掉线的统计窗口
+const flapWindow = time.Hour
+
+// proxyAlertTestCooldown 同一隧道两次发送测试告警的最小间隔
+const proxyAlertTestCooldown = time.Minute
+
+// ErrProxyAlertTestTooFrequent 测试告警发送过于频繁
+var ErrProxyAlertTestTooFrequent = errors.New("测试告警发送过于频繁，请稍后重试")
+
+// 告警事件类型
+const (
+	ProxyAlertEventOffline    = "proxy.offline"
+	ProxyAlertEventRecovered  = "proxy.recovered"
+	ProxyAlertEventFlapping   = "proxy.flapping"
+	ProxyAlertEventStabilized = "proxy.stabilized"
+	ProxyAlertEventTest       = "proxy.test"
+)
+
+// userInitiatedReasons 由用户主动操作导致的下线，不计入告警
+var userInitiatedReasons = map[string]bool{
+	ProxyEventReasonUserDisabled: true,
+	ProxyEventReasonUserKick:     true,
+	ProxyEventReasonScheduleKick: true,
+}
+
+// ProxyAlertSettings 隧道告警配置
+type ProxyAlertSettings struct {
+	ProxyID        int64  `json:"proxy_id"`
+	OfflineMinutes int    `json:"offline_minutes"`
+	FlapThreshold  int    `json:"flap_threshold"`
+	NotifyEmail    bool   `json:"notify_email"`
+	WebhookURL     string `json:"webhook_url"`
+	WebhookSecret  string `json:"webhook_secret"`
+	OfflineAlerted bool   `json:"offline_alerted"`
+	FlapAlerted    bool   `json:"flap_alerted"`
+}
+
+// ProxyAlertPayload 发送到 Webhook 的告警内容
+type ProxyAlertPayload struct {
+	Event        string     `json:"event"`
+	ProxyID      int64      `json:"proxy_id"`
+	ProxyName    string     `json:"proxy_name"`
+	NodeID       int64      `json:"node_id"`
+	Status       string     `json:"status"`
+	OfflineSince *time.Time `json:"offline_since,omitempty"`
+	FlapCount    int        `json:"flap_count,omitempty"`
+	Message      string     `json:"message"`
+	Time         time.Time  `json:"time"`
+}
+
+// ProxyAlertService 隧道离线告警服务接口
+type ProxyAlertService interface {
+	Get(ctx context.Context, proxy *repository.Proxy) (*ProxyAlertSettings, error)
+	Save(ctx context.Context, proxy *repository.Proxy, settings *ProxyAlertSettings, resetSecret bool) (*ProxyAlertSettings, error)
+	SendTest(ctx context.Context, proxy *repository.Proxy) error
+	Evaluate(ctx context.Context, now time.Time)
+	RotateSecrets(ctx context.Context) (int, error)
+}
+
+// proxyAlertService 隧道离线告警服务实现
+type proxyAlertService struct {
+	alertRepo      repository.ProxyAlertRepository
+	proxyEventRepo repository.ProxyEventRepository
+	proxyService   ProxyService
+	userService    UserService
+	emailService   *email.Service
+	webhookSender  *webhook.Sender
+	keyring        *secret.Keyring
+	redisCli       *redis.Client
+	logger         *logger.Logger
+}
+
+// NewProxyAlertService 创建隧道离线告警服务实例
+func NewProxyAlertService(
+	alertRepo repository.ProxyAlertRepository,
+	proxyEventRepo repository.ProxyEventRepository,
+	proxyService ProxyService,
+	userService UserService,
+	emailService *email.Service,
+	webhookSender *webhook.Sender,
+	keyring *secret.Keyring,
+	redisCli *redis.Client,
+	logger *logger.Logger,
+) ProxyAlertService {
+	return &proxyAlertService{
+		alertRepo:      alertRepo,
+		proxyEventRepo: proxyEventRepo,
+		proxyService:   proxyService,
+		userService:    userService,
+		emailService:   emailService,
+		webhookSender:  webhookSender,
+		keyring:        keyring,
+		redisCli:       redisCli,
+		logger:         logger,
+	}
+}
+
+// Get 获取隧道的告警配置，未配置时返回关闭状态的默认值
+func (s *proxyAlertService) Get(ctx context.Context, proxy *repository.Proxy) (*ProxyAlertSettings, error) {
+	alert, err := s.alertRepo.GetByProxyID(ctx, proxy.ID)
+	if err != nil {
+		return nil, err
+	}
+	if alert == nil {
+		return &ProxyAlertSettings{ProxyID: proxy.ID}, nil
+	}
+	webhookSecret, err := s.keyring.Decrypt(alert.WebhookSecret)
+	if err != nil {
+		return nil, fmt.Errorf("解密Webhook签名密钥失败: %w", err)
+	}
+	return &ProxyAlertSettings{
+		ProxyID:        alert.ProxyID,
+		OfflineMinutes: alert.OfflineMinutes,
+		FlapThreshold:  alert.FlapThreshold,
+		NotifyEmail:    alert.NotifyEmail,
+		WebhookURL:     alert.WebhookURL,
+		WebhookSecret:  webhookSecret,
+		OfflineAlerted: alert.OfflineAlertedAt.Valid,
+		FlapAlerted:    alert.FlapAlertedAt.Valid,
+	}, nil
+}
+
+// Save 保存隧道的告警配置，首次设置 Webhook 或要求重置时生成新的签名密钥
+func (s *proxyAlertService) Save(ctx context.Context, proxy *repository.Proxy, settings *ProxyAlertSettings, resetSecret bool) (*ProxyAlertSettings, error) {
+	if err := ValidateProxyAlertSettings(settings); err != nil {
+		return nil, err
+	}
+
+	current, err := s.Get(ctx, proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := current.WebhookSecret
+	if settings.WebhookURL != "" && (secret == "" || resetSecret) {
+		secret, err = webhook.GenerateSecret()
+		if err != nil {
+			return nil, fmt.Errorf("生成签名密钥失败: %w", err)
+		}
+	}
+
+	err = s.alertRepo.Upsert(ctx, &repository.ProxyAlert{
+		ProxyID:        proxy.ID,
+		Username:       proxy.Username,
+		OfflineMinutes: settings.OfflineMinutes,
+		FlapThreshold:  settings.FlapThreshold,
+		NotifyEmail:    settings.NotifyEmail,
+		WebhookURL:     settings.WebhookURL,
+		WebhookSecret:  secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, proxy)
+}
+
+// SendTest 向已配置的通知方式发送一条测试告警，同一隧道每分钟最多发送一次
+func (s *proxyAlertService) SendTest(ctx context.Context, proxy *repository.Proxy) error {
+	alert, err := s.alertRepo.GetByProxyID(ctx, proxy.ID)
+	if err != nil {
+		return err
+	}
+	if alert == nil || (!alert.NotifyEmail && alert.WebhookURL == "") {
+		return errors.New("请先开启邮件通知或填写Webhook地址")
+	}
+
+	key := fmt.Sprintf("proxy:alert:test:%d", proxy.ID)
+	ok, err := s.redisCli.SetNX(ctx, key, 1, proxyAlertTestCooldown).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrProxyAlertTestTooFrequent
+	}
+
+	payload := s.newPayload(ProxyAlertEventTest, proxy, time.Now())
+	payload.Message = fmt.Sprintf("这是隧道 %s 的测试告警", proxy.ProxyName)
+	return s.deliver(ctx, alert, payload)
+}
+
+// Evaluate 检查所有开启告警的隧道，发送离线、频繁掉线及恢复通知
+func (s *proxyAlertService) Evaluate(ctx context.Context, now time.Time) {
+	alerts, err := s.alertRepo.ListActive(ctx)
+	if err != nil {
+		s.logger.Error("获取隧道告警配置失败", "error", err)
+		return
+	}
+
+	for _, alert := range alerts {
+		if ctx.Err() != nil {
+			return
+		}
+
+		proxy, err := s.proxyService.GetByID(ctx, alert.ProxyID)
+		if err != nil {
+			s.logger.Error("获取隧道信息失败", "error", err, "proxyID", alert.ProxyID)
+			continue
+		}
+		if proxy == nil {
+			continue
+		}
+
+		if err := s.evaluateOffline(ctx, alert, proxy, now); err != nil {
+			s.logger.Error("检查隧道离线告警失败", "error", err, "proxyID", proxy.ID)
+		}
+		if err := s.evaluateFlapping(ctx, alert, proxy, now); err != nil {
+			s.logger.Error("检查隧道频繁掉线告警失败", "error", err, "proxyID", proxy.ID)
+		}
+	}
+}
+
+// evaluateOffline 离线超过设定时长时告警一次，隧道重新上线后发送恢复通知
+func (s *proxyAlertService) evaluateOffline(ctx context.Context, alert *repository.ProxyAlert, proxy *repository.Proxy, now time.Time) error {
+	if proxy.Status == "online" {
+		if !alert.OfflineAlertedAt.Valid {
+			return nil
+		}
+		return s.notifyRecovered(ctx, alert, proxy, now)
+	}
+
+	last, err := s.proxyEventRepo.GetLastBefore(ctx, proxy.ID, now.Add(time.Second))
+	if err != nil {
+		return err
+	}
+	if last == nil || last.Status == "online" {
+		return nil
+	}
+
+	if alert.OfflineAlertedAt.Valid {
+		// 告警后隧道曾经恢复又再次离线，两次检查之间错过了上线状态，先补发恢复通知
+		if last.CreatedAt.After(alert.OfflineAlertedAt.Time) {
+			return s.notifyRecovered(ctx, alert, proxy, now)
+		}
+		return nil
+	}
+
+	if alert.OfflineMinutes <= 0 || userInitiatedReasons[last.Reason] {
+		return nil
+	}
+	if now.Sub(last.CreatedAt) < time.Duration(alert.OfflineMinutes)*time.Minute {
+		return nil
+	}
+
+	offlineSince := last.CreatedAt
+	payload := s.newPayload(ProxyAlertEventOffline, proxy, now)
+	payload.OfflineSince = &offlineSince
+	payload.Message = fmt.Sprintf("隧道 %s 已离线超过 %d 分钟（自 %s 起）",
+		proxy.ProxyName, alert.OfflineMinutes, offlineSince.Format("2006-01-02 15:04:05"))
+
+	s.send(ctx, alert, payload)
+	return s.alertRepo.UpdateOfflineAlertedAt(ctx, proxy.ID, sql.NullTime{Time: now, Valid: true})
+}
+
+// notifyRecovered 发送离线恢复通知并清除离线告警状态
+func (s *proxyAlertService) notifyRecovered(ctx context.Context, alert *repository.ProxyAlert, proxy *repository.Proxy, now time.Time) error {
+	payload := s.newPayload(ProxyAlertEventRecovered, proxy, now)
+	payload.Message = fmt.Sprintf("隧道 %s 已恢复在线", proxy.ProxyName)
+
+	s.send(ctx, alert, payload)
+	return s.alertRepo.UpdateOfflineAlertedAt(ctx, proxy.ID, sql.NullTime{})
+}
+
+// evaluateFlapping 一小时内掉线次数超过阈值时告警一次，回落到阈值以内且在线后发送恢复通知
+func (s *proxyAlertService) evaluateFlapping(ctx context.Context, alert *repository.ProxyAlert, proxy *repository.Proxy, now time.Time) error {
+	if alert.FlapThreshold <= 0 && !alert.FlapAlertedAt.Valid {
+		return nil
+	}
+
+	events, err := s.proxyEventRepo.ListByProxyIDSince(ctx, proxy.ID, now.Add(-flapWindow))
+	if err != nil {
+		return err
+	}
+	drops := 0
+	for _, e := range events {
+		if e.Status != "online" && !userInitiatedReasons[e.Reason] {
+			drops++
+		}
+	}
+
+	flapping := alert.FlapThreshold > 0 && drops > alert.FlapThreshold
+	switch {
+	case flapping && !alert.FlapAlertedAt.Valid:
+		payload := s.newPayload(ProxyAlertEventFlapping, proxy, now)
+		payload.FlapCount = drops
+		payload.Message = fmt.Sprintf("隧道 %s 在过去一小时内掉线 %d 次，超过设定的 %d 次",
+			proxy.ProxyName, drops, alert.FlapThreshold)
+
+		s.send(ctx, alert, payload)
+		return s.alertRepo.UpdateFlapAlertedAt(ctx, proxy.ID, sql.NullTime{Time: now, Valid: true})
+	case !flapping && alert.FlapAlertedAt.Valid && proxy.Status == "online":
+		payload := s.newPayload(ProxyAlertEventStabilized, proxy, now)
+		payload.FlapCount = drops
+		payload.Message = fmt.Sprintf("隧道 %s 已恢复稳定，过去一小时内掉线 %d 次", proxy.ProxyName, drops)
+
+		s.send(ctx, alert, payload)
+		return s.alertRepo.UpdateFlapAlertedAt(ctx, proxy.ID, sql.NullTime{})
+	}
+	return nil
+}
+
+// newPayload 构建告警内容的公共字段
+func (s *proxyAlertService) newPayload(event string, proxy *repository.Proxy, now time.Time) *ProxyAlertPayload {
+	return &ProxyAlertPayload{
+		Event:     event,
+		ProxyID:   proxy.ID,
+		ProxyName: proxy.ProxyName,
+		NodeID:    proxy.Node,
+		Status:    proxy.Status,
+		Time:      now,
+	}
+}
+
+// RotateSecrets 使用当前密钥重新加密所有Webhook签名密钥，返回更新的配置数
+func (s *proxyAlertService) RotateSecrets(ctx context.Context) (int, error) {
+	return s.alertRepo.RotateSecrets(ctx)
+}
+
+// send 发送告警，失败只记录日志；告警状态照常更新，避免通知渠道故障时每分钟重复发送
+func (s *proxyAlertService) send(ctx context.Context, alert *repository.ProxyAlert, payload *ProxyAlertPayload) {
+	if err := s.deliver(ctx, alert, payload); err != nil {
+		s.logger.Error("发送隧道告警失败", "error", err, "proxyID", payload.ProxyID, "event", payload.Event)
+		return
+	}
+	s.logger.Info("已发送隧道告警", "proxyID", payload.ProxyID, "event", payload.Event)
+}
+
+// deliver 通过邮件和 Webhook 发送告警，任一渠道失败时返回错误
+func (s *proxyAlertService) deliver(ctx context.Context, alert *repository.ProxyAlert, payload *ProxyAlertPayload) error {
+	var errs []error
+
+	if alert.NotifyEmail {
+		if err := s.sendEmail(ctx, alert, payload); err != nil {
+			errs = append(errs, fmt.Errorf("邮件: %w", err))
+		}
+	}
+
+	if alert.WebhookURL != "" {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		webhookSecret, err := s.keyring.Decrypt(alert.WebhookSecret)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Webhook: 解密签名密钥失败: %w", err))
+		} else if err := s.webhookSender.Send(ctx, alert.WebhookURL, webhookSecret, payload.Event, body); err != nil {
+			errs = append(errs, fmt.Errorf("Webhook: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sendEmail 发送告警邮件
+func (s *proxyAlertService) sendEmail(ctx context.Context, alert *repository.ProxyAlert, payload *ProxyAlertPayload) error {
+	user, err := s.userService.GetByUsername(ctx, alert.Username)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Email == "" {
+		return errors.New("用户未绑定邮箱")
+	}
+
+	title := proxyAlertTitles[payload.Event]
+	lines := []string{
+		payload.Message,
+		fmt.Sprintf("隧道名称：%s", payload.ProxyName),
+		fmt.Sprintf("隧道ID：%d", payload.ProxyID),
+		fmt.Sprintf("通知时间：%s", payload.Time.Format("2006-01-02 15:04:05")),
+	}
+	subject := fmt.Sprintf("StellarFrp - %s：%s", title, payload.ProxyName)
+	return s.emailService.SendNotification(user.Email, user.Username, subject, title, lines)
+}
+
+// proxyAlertTitles 告警事件对应的邮件标题
+var proxyAlertTitles = map[string]string{
+	ProxyAlertEventOffline:    "隧道离线告警",
+	ProxyAlertEventRecovered:  "隧道已恢复",
+	ProxyAlertEventFlapping:   "隧道频繁掉线告警",
+	ProxyAlertEventStabilized: "隧道已恢复稳定",
+	ProxyAlertEventTest:       "测试告警",
+}
+
+// ValidateProxyAlertSettings 校验告警配置
+func ValidateProxyAlertSettings(settings *ProxyAlertSettings) error {
+	if settings.OfflineMinutes < 0 || settings.OfflineMinutes > maxAlertOfflineMinutes {
+		return fmt.Errorf("离线告警时长应为0-%d分钟", maxAlertOfflineMinutes)
+	}
+	if settings.FlapThreshold < 0 || settings.FlapThreshold > maxAlertFlapThreshold {
+		return fmt.Errorf("掉线次数阈值应为0-%d次", maxAlertFlapThreshold)
+	}
+	if settings.WebhookURL != "" {
+		if err := webhook.ValidateURL(settings.WebhookURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
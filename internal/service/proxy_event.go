@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"math"
+	"stellarfrp/internal/repository"
+	"time"
+)
+
+// 隧道状态变更原因
+const (
+	ProxyEventReasonPluginNewProxy   = "plugin_new_proxy"
+	ProxyEventReasonPluginCloseProxy = "plugin_close_proxy"
+	ProxyEventReasonReconciler       = "reconciler"
+	ProxyEventReasonScheduleKick     = "schedule_kick"
+	ProxyEventReasonUserDisabled     = "user_disabled"
+	ProxyEventReasonUserKick         = "user_kick"
+	ProxyEventReasonAdminKick        = "admin_kick"
+	ProxyEventReasonTrafficExhausted = "traffic_exhausted"
+)
+
+// ProxyEventRetention 隧道事件保留时长，不短于在线率统计的最长窗口
+const ProxyEventRetention = 30 * 24 * time.Hour
+
+// uptimeWindows 在线率统计窗口
+var uptimeWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// ProxyUptime 隧道在指定窗口内的在线率
+// Percent 为已追踪时长内的在线百分比，窗口内没有任何状态记录时为nil
+type ProxyUptime struct {
+	Window         string   `json:"window"`
+	Percent        *float64 `json:"percent"`
+	OnlineSeconds  int64    `json:"online_seconds"`
+	TrackedSeconds int64    `json:"tracked_seconds"`
+}
+
+// recordStatusEvent 在隧道状态发生变化时写入事件并推送给订阅者，失败只记录日志不影响主流程
+func (s *proxyService) recordStatusEvent(ctx context.Context, oldProxy, proxy *repository.Proxy, operator ProxyOperator) {
+	if oldProxy == nil || oldProxy.Status == proxy.Status {
+		return
+	}
+
+	reason := operator.Reason
+	if reason == "" {
+		reason = operator.Type
+	}
+
+	err := s.proxyEventRepo.Create(ctx, &repository.ProxyEvent{
+		ProxyID:  proxy.ID,
+		NodeID:   proxy.Node,
+		Username: proxy.Username,
+		Status:   proxy.Status,
+		Reason:   reason,
+	})
+	if err != nil {
+		s.logger.Error("写入隧道事件失败", "error", err, "proxyID", proxy.ID)
+	}
+
+	s.streamHub.Publish(proxy.Username, ProxyStreamEvent{
+		Type: ProxyStreamEventStatus,
+		Data: ProxyStatusChange{
+			ProxyID:   proxy.ID,
+			ProxyName: proxy.ProxyName,
+			NodeID:    proxy.Node,
+			Status:    proxy.Status,
+			Reason:    reason,
+			Time:      time.Now(),
+		},
+	})
+}
+
+// ListEvents 获取隧道上下线事件（按时间倒序）
+func (s *proxyService) ListEvents(ctx context.Context, proxyID int64, offset, limit int) ([]*repository.ProxyEvent, int, error) {
+	events, err := s.proxyEventRepo.ListByProxyID(ctx, proxyID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.proxyEventRepo.CountByProxyID(ctx, proxyID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// CleanupEvents 删除过期的隧道事件，每个隧道保留过期前的最后一条事件
+func (s *proxyService) CleanupEvents(ctx context.Context, before time.Time) (int64, error) {
+	return s.proxyEventRepo.DeleteBefore(ctx, before)
+}
+
+// GetUptime 计算隧道在24小时、7天、30天内的在线率
+func (s *proxyService) GetUptime(ctx context.Context, proxyID int64) ([]ProxyUptime, error) {
+	now := time.Now()
+	since := now.Add(-uptimeWindows[len(uptimeWindows)-1].Duration)
+
+	events, err := s.proxyEventRepo.ListByProxyIDSince(ctx, proxyID, since)
+	if err != nil {
+		return nil, err
+	}
+	initial, err := s.proxyEventRepo.GetLastBefore(ctx, proxyID, since)
+	if err != nil {
+		return nil, err
+	}
+	if initial != nil {
+		events = append([]*repository.ProxyEvent{initial}, events...)
+	}
+
+	uptimes := make([]ProxyUptime, 0, len(uptimeWindows))
+	for _, w := range uptimeWindows {
+		online, tracked := computeUptime(events, now.Add(-w.Duration), now)
+		uptime := ProxyUptime{
+			Window:         w.Name,
+			OnlineSeconds:  int64(online.Seconds()),
+			TrackedSeconds: int64(tracked.Seconds()),
+		}
+		if tracked > 0 {
+			percent := math.Round(online.Seconds()/tracked.Seconds()*10000) / 100
+			uptime.Percent = &percent
+		}
+		uptimes = append(uptimes, uptime)
+	}
+	return uptimes, nil
+}
+
+// computeUptime 根据按时间正序排列的事件计算 [start, end] 内的在线时长与已追踪时长
+// 窗口开始前的最后一条事件决定初始状态；没有时从窗口内第一条事件开始追踪
+func computeUptime(events []*repository.ProxyEvent, start, end time.Time) (time.Duration, time.Duration) {
+	var online, tracked time.Duration
+	state := ""
+	cursor := start
+
+	for _, e := range events {
+		if !e.CreatedAt.After(start) {
+			state = e.Status
+			continue
+		}
+		if state != "" {
+			d := e.CreatedAt.Sub(cursor)
+			tracked += d
+			if state == "online" {
+				online += d
+			}
+		}
+		cursor = e.CreatedAt
+		state = e.Status
+	}
+
+	if state != "" && end.After(cursor) {
+		d := end.Sub(cursor)
+		tracked += d
+		if state == "online" {
+			online += d
+		}
+	}
+
+	return online, tracked
+}
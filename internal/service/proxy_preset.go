@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/logger"
+)
+
+// maxProxyPresets 每个用户可保存的隧道预设数量上限
+const maxProxyPresets = 20
+
+// 隧道预设相关错误
+var (
+	ErrProxyPresetLimit      = errors.New("最多只能保存20个隧道预设")
+	ErrProxyPresetNameExists = errors.New("已存在同名预设，请更换预设名称")
+)
+
+// ProxyPresetService 隧道预设服务接口
+type ProxyPresetService interface {
+	Create(ctx context.Context, preset *repository.ProxyPreset) (int64, error)
+	GetByID(ctx context.Context, id int64) (*repository.ProxyPreset, error)
+	ListByUsername(ctx context.Context, username string) ([]*repository.ProxyPreset, error)
+	Update(ctx context.Context, preset *repository.ProxyPreset) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// proxyPresetService 隧道预设服务实现
+type proxyPresetService struct {
+	presetRepo repository.ProxyPresetRepository
+	logger     *logger.Logger
+}
+
+// NewProxyPresetService 创建隧道预设服务实例
+func NewProxyPresetService(presetRepo repository.ProxyPresetRepository, logger *logger.Logger) ProxyPresetService {
+	return &proxyPresetService{
+		presetRepo: presetRepo,
+		logger:     logger,
+	}
+}
+
+// Create 创建隧道预设，检查数量上限与名称唯一性
+func (s *proxyPresetService) Create(ctx context.Context, preset *repository.ProxyPreset) (int64, error) {
+	count, err := s.presetRepo.CountByUsername(ctx, preset.Username)
+	if err != nil {
+		return 0, err
+	}
+	if count >= maxProxyPresets {
+		return 0, ErrProxyPresetLimit
+	}
+
+	existing, err := s.presetRepo.GetByUsernameAndName(ctx, preset.Username, preset.Name)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		return 0, ErrProxyPresetNameExists
+	}
+
+	return s.presetRepo.Create(ctx, preset)
+}
+
+// GetByID 根据ID获取隧道预设
+func (s *proxyPresetService) GetByID(ctx context.Context, id int64) (*repository.ProxyPreset, error) {
+	return s.presetRepo.GetByID(ctx, id)
+}
+
+// ListByUsername 获取用户的全部隧道预设
+func (s *proxyPresetService) ListByUsername(ctx context.Context, username string) ([]*repository.ProxyPreset, error) {
+	return s.presetRepo.ListByUsername(ctx, username)
+}
+
+// Update 更新隧道预设，检查名称唯一性
+func (s *proxyPresetService) Update(ctx context.Context, preset *repository.ProxyPreset) error {
+	existing, err := s.presetRepo.GetByUsernameAndName(ctx, preset.Username, preset.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != preset.ID {
+		return ErrProxyPresetNameExists
+	}
+
+	return s.presetRepo.Update(ctx, preset)
+}
+
+// Delete 删除隧道预设
+func (s *proxyPresetService) Delete(ctx context.Context, id int64) error {
+	return s.presetRepo.Delete(ctx, id)
+}
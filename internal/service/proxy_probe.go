@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/logger"
+	"stellarfrp/pkg/network"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 探测参数
+const (
+	proxyProbeTimeout     = 5 * time.Second
+	proxyProbeConcurrency = 16
+	proxyProbePageSize    = 500
+	maxProbeErrorLength   = 255
+)
+
+// probeableTypes 支持公网探测的隧道类型
+var probeableTypes = map[string]bool{"tcp": true, "http": true, "https": true}
+
+// ProxyProbeRetention 探测记录保留时长
+const ProxyProbeRetention = 7 * 24 * time.Hour
+
+// ProxyProbeSummary 一段时间内的探测汇总
+// SuccessRate 为成功次数百分比，AvgLatencyMs 只统计成功的探测，没有记录时均为nil
+type ProxyProbeSummary struct {
+	Total        int                    `json:"total"`
+	Success      int                    `json:"success"`
+	SuccessRate  *float64               `json:"success_rate"`
+	AvgLatencyMs *int                   `json:"avg_latency_ms"`
+	Last         *repository.ProxyProbe `json:"last"`
+}
+
+// ProxyProbeService 隧道公网可达性探测服务接口
+type ProxyProbeService interface {
+	ProbeOnline(ctx context.Context)
+	GetHistory(ctx context.Context, proxyID int64, since time.Time) ([]*repository.ProxyProbe, *ProxyProbeSummary, error)
+	Cleanup(ctx context.Context, before time.Time) (int64, error)
+}
+
+// proxyProbeService 隧道公网可达性探测服务实现
+type proxyProbeService struct {
+	probeRepo    repository.ProxyProbeRepository
+	proxyService ProxyService
+	nodeService  NodeService
+	logger       *logger.Logger
+}
+
+// NewProxyProbeService 创建隧道公网可达性探测服务实例
+func NewProxyProbeService(
+	probeRepo repository.ProxyProbeRepository,
+	proxyService ProxyService,
+	nodeService NodeService,
+	logger *logger.Logger,
+) ProxyProbeService {
+	return &proxyProbeService{
+		probeRepo:    probeRepo,
+		proxyService: proxyService,
+		nodeService:  nodeService,
+		logger:       logger,
+	}
+}
+
+// ProbeOnline 探测所有在线的TCP、HTTP、HTTPS隧道的公网入口并记录结果
+func (s *proxyProbeService) ProbeOnline(ctx context.Context) {
+	nodes := make(map[int64]*repository.Node)
+	jobs := make(chan *repository.Proxy)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i := 0; i < proxyProbeConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for proxy := range jobs {
+				mu.Lock()
+				node := nodes[proxy.Node]
+				mu.Unlock()
+				s.probe(ctx, proxy, node)
+			}
+		}()
+	}
+
+	for offset := 0; ctx.Err() == nil; offset += proxyProbePageSize {
+		proxies, err := s.proxyService.ListByStatus(ctx, "online", offset, proxyProbePageSize)
+		if err != nil {
+			s.logger.Error("获取在线隧道列表失败", "error", err)
+			break
+		}
+
+		for _, proxy := range proxies {
+			if !probeableTypes[proxy.ProxyType] {
+				continue
+			}
+			if _, ok := nodes[proxy.Node]; !ok {
+				node, err := s.nodeService.GetByID(ctx, proxy.Node)
+				if err != nil {
+					s.logger.Error("获取节点信息失败", "error", err, "nodeID", proxy.Node)
+				}
+				mu.Lock()
+				nodes[proxy.Node] = node
+				mu.Unlock()
+			}
+
+			select {
+			case jobs <- proxy:
+			case <-ctx.Done():
+			}
+		}
+
+		if len(proxies) < proxyProbePageSize {
+			break
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+}
+
+// probe 探测单个隧道并写入记录
+func (s *proxyProbeService) probe(ctx context.Context, proxy *repository.Proxy, node *repository.Node) {
+	target := ProbeTarget(proxy, node)
+	if target == "" || ctx.Err() != nil {
+		return
+	}
+
+	var result network.ProbeResult
+	if proxy.ProxyType == "tcp" {
+		host, portStr, _ := net.SplitHostPort(target)
+		port, _ := strconv.Atoi(portStr)
+		result = network.ProbeTCP(ctx, host, port, proxyProbeTimeout)
+	} else {
+		result = network.ProbeHTTP(ctx, target, proxyProbeTimeout)
+	}
+
+	errMsg := result.Error
+	if len(errMsg) > maxProbeErrorLength {
+		errMsg = errMsg[:maxProbeErrorLength]
+	}
+
+	err := s.probeRepo.Create(ctx, &repository.ProxyProbe{
+		ProxyID:    proxy.ID,
+		Target:     target,
+		Success:    result.Success,
+		LatencyMs:  int(result.Latency.Milliseconds()),
+		StatusCode: result.StatusCode,
+		Error:      errMsg,
+	})
+	if err != nil {
+		s.logger.Error("写入隧道探测记录失败", "error", err, "proxyID", proxy.ID)
+	}
+}
+
+// GetHistory 获取隧道指定时间之后的探测记录与汇总
+func (s *proxyProbeService) GetHistory(ctx context.Context, proxyID int64, since time.Time) ([]*repository.ProxyProbe, *ProxyProbeSummary, error) {
+	probes, err := s.probeRepo.ListByProxyIDSince(ctx, proxyID, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	if probes == nil {
+		probes = []*repository.ProxyProbe{}
+	}
+	return probes, summarizeProbes(probes), nil
+}
+
+// Cleanup 删除过期的探测记录
+func (s *proxyProbeService) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	return s.probeRepo.DeleteBefore(ctx, before)
+}
+
+// summarizeProbes 汇总按时间正序排列的探测记录
+func summarizeProbes(probes []*repository.ProxyProbe) *ProxyProbeSummary {
+	summary := &ProxyProbeSummary{Total: len(probes)}
+	if len(probes) == 0 {
+		return summary
+	}
+
+	var latencyTotal int
+	for _, p := range probes {
+		if p.Success {
+			summary.Success++
+			latencyTotal += p.LatencyMs
+		}
+	}
+
+	rate := math.Round(float64(summary.Success)/float64(summary.Total)*10000) / 100
+	summary.SuccessRate = &rate
+	if summary.Success > 0 {
+		avg := latencyTotal / summary.Success
+		summary.AvgLatencyMs = &avg
+	}
+	summary.Last = probes[len(probes)-1]
+	return summary
+}
+
+// ProbeTarget 获取隧道的公网探测地址：TCP隧道为 节点地址:远程端口，HTTP(S)隧道为 域名URL
+// 节点为空或隧道类型不支持探测时返回空字符串
+func ProbeTarget(proxy *repository.Proxy, node *repository.Node) string {
+	switch proxy.ProxyType {
+	case "http", "https":
+		if proxy.Domain == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s://%s", proxy.ProxyType, proxy.Domain)
+	case "tcp":
+		if node == nil {
+			return ""
+		}
+		host := node.IP
+		if host == "" && node.Host.Valid {
+			host = node.Host.String
+		}
+		port, err := strconv.Atoi(proxy.RemotePort)
+		if host == "" || err != nil || port <= 0 {
+			return ""
+		}
+		return net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	return ""
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// maxScheduleWindows 单个隧道允许配置的时段数量上限
+const maxScheduleWindows = 20
+
+// ScheduleWindow 每周允许在线的时段，按服务器本地时区计算
+// Days 为星期几（0为周日），Start/End 为 HH:MM；End 不大于 Start 时表示跨越零点
+type ScheduleWindow struct {
+	Days  []int  `json:"days"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// ProxyScheduleInfo 隧道的禁用状态与在线时段
+type ProxyScheduleInfo struct {
+	ProxyID  int64            `json:"proxy_id"`
+	Disabled bool             `json:"disabled"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+// ProxyScheduleService 隧道禁用与在线时段服务接口
+type ProxyScheduleService interface {
+	Get(ctx context.Context, proxyID int64) (*ProxyScheduleInfo, error)
+	SetDisabled(ctx context.Context, proxyID int64, disabled bool) error
+	SetWindows(ctx context.Context, proxyID int64, windows []ScheduleWindow) error
+	CheckAllowed(ctx context.Context, proxyID int64, now time.Time) (bool, string, error)
+	ListRestrictedOnline(ctx context.Context) ([]*ProxyScheduleInfo, error)
+}
+
+// proxyScheduleService 隧道禁用与在线时段服务实现
+type proxyScheduleService struct {
+	scheduleRepo repository.ProxyScheduleRepository
+	logger       *logger.Logger
+}
+
+// NewProxyScheduleService 创建隧道禁用与在线时段服务实例
+func NewProxyScheduleService(scheduleRepo repository.ProxyScheduleRepository, logger *logger.Logger) ProxyScheduleService {
+	return &proxyScheduleService{
+		scheduleRepo: scheduleRepo,
+		logger:       logger,
+	}
+}
+
+// Get 获取隧道的时段配置，未配置时返回不限制的默认值
+func (s *proxyScheduleService) Get(ctx context.Context, proxyID int64) (*ProxyScheduleInfo, error) {
+	schedule, err := s.scheduleRepo.GetByProxyID(ctx, proxyID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return &ProxyScheduleInfo{ProxyID: proxyID, Windows: []ScheduleWindow{}}, nil
+	}
+	return s.toInfo(schedule), nil
+}
+
+// SetDisabled 设置隧道禁用状态
+func (s *proxyScheduleService) SetDisabled(ctx context.Context, proxyID int64, disabled bool) error {
+	info, err := s.Get(ctx, proxyID)
+	if err != nil {
+		return err
+	}
+	info.Disabled = disabled
+	return s.save(ctx, info)
+}
+
+// SetWindows 设置隧道每周允许在线的时段，传入空列表表示取消限制
+func (s *proxyScheduleService) SetWindows(ctx context.Context, proxyID int64, windows []ScheduleWindow) error {
+	if err := ValidateScheduleWindows(windows); err != nil {
+		return err
+	}
+	info, err := s.Get(ctx, proxyID)
+	if err != nil {
+		return err
+	}
+	info.Windows = windows
+	return s.save(ctx, info)
+}
+
+// CheckAllowed 检查隧道当前是否允许上线，不允许时返回原因
+func (s *proxyScheduleService) CheckAllowed(ctx context.Context, proxyID int64, now time.Time) (bool, string, error) {
+	info, err := s.Get(ctx, proxyID)
+	if err != nil {
+		return false, "", err
+	}
+	if info.Disabled {
+		return false, "disabled", nil
+	}
+	if !InScheduleWindows(info.Windows, now) {
+		return false, "out_of_window", nil
+	}
+	return true, "", nil
+}
+
+// ListRestrictedOnline 获取当前在线且设置了禁用或时段限制的隧道
+func (s *proxyScheduleService) ListRestrictedOnline(ctx context.Context) ([]*ProxyScheduleInfo, error) {
+	schedules, err := s.scheduleRepo.ListRestrictedOnline(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*ProxyScheduleInfo, 0, len(schedules))
+	for _, schedule := range schedules {
+		infos = append(infos, s.toInfo(schedule))
+	}
+	return infos, nil
+}
+
+// save 保存时段配置
+func (s *proxyScheduleService) save(ctx context.Context, info *ProxyScheduleInfo) error {
+	windows := info.Windows
+	if windows == nil {
+		windows = []ScheduleWindow{}
+	}
+	windowsBytes, err := json.Marshal(windows)
+	if err != nil {
+		return err
+	}
+	return s.scheduleRepo.Upsert(ctx, &repository.ProxySchedule{
+		ProxyID:  info.ProxyID,
+		Disabled: info.Disabled,
+		Windows:  string(windowsBytes),
+	})
+}
+
+// toInfo 解析数据库中的时段配置，解析失败时视为不限制并记录日志
+func (s *proxyScheduleService) toInfo(schedule *repository.ProxySchedule) *ProxyScheduleInfo {
+	info := &ProxyScheduleInfo{
+		ProxyID:  schedule.ProxyID,
+		Disabled: schedule.Disabled,
+		Windows:  []ScheduleWindow{},
+	}
+	if schedule.Windows != "" {
+		if err := json.Unmarshal([]byte(schedule.Windows), &info.Windows); err != nil {
+			s.logger.Error("解析隧道在线时段失败", "error", err, "proxyID", schedule.ProxyID)
+			info.Windows = []ScheduleWindow{}
+		}
+	}
+	return info
+}
+
+// ValidateScheduleWindows 校验在线时段配置
+func ValidateScheduleWindows(windows []ScheduleWindow) error {
+	if len(windows) > maxScheduleWindows {
+		return fmt.Errorf("最多只能设置%d个时段", maxScheduleWindows)
+	}
+	for i, w := range windows {
+		if len(w.Days) == 0 {
+			return fmt.Errorf("第%d个时段未选择星期", i+1)
+		}
+		for _, d := range w.Days {
+			if d < 0 || d > 6 {
+				return fmt.Errorf("第%d个时段的星期取值应为0-6", i+1)
+			}
+		}
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return fmt.Errorf("第%d个时段的开始时间格式错误，应为HH:MM", i+1)
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return fmt.Errorf("第%d个时段的结束时间格式错误，应为HH:MM", i+1)
+		}
+		if start == end {
+			return fmt.Errorf("第%d个时段的开始时间与结束时间不能相同", i+1)
+		}
+	}
+	return nil
+}
+
+// InScheduleWindows 判断给定时间是否落在任一时段内，未配置时段时始终返回true
+func InScheduleWindows(windows []ScheduleWindow, now time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	today := int(now.Weekday())
+	yesterday := (today + 6) % 7
+
+	for _, w := range windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			continue
+		}
+
+		if start < end {
+			if containsDay(w.Days, today) && minute >= start && minute < end {
+				return true
+			}
+			continue
+		}
+
+		// 跨零点的时段：当天开始后的部分，以及前一天开始延续到今天的部分
+		if containsDay(w.Days, today) && minute >= start {
+			return true
+		}
+		if containsDay(w.Days, yesterday) && minute < end {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock 将 HH:MM 解析为当天的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// containsDay 判断星期列表中是否包含指定星期
+func containsDay(days []int, day int) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
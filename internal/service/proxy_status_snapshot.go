@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"stellarfrp/pkg/logger"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ProxyStatusPollInterval 隧道状态轮询间隔
+const ProxyStatusPollInterval = 15 * time.Second
+
+// proxyStatusSnapshotTTL 快照过期时间，轮询器停止或节点长时间不可达时快照自然失效
+const proxyStatusSnapshotTTL = 2 * time.Minute
+
+// ProxyStatusSnapshot 轮询器从节点采集的隧道实时状态快照
+type ProxyStatusSnapshot struct {
+	ProxyID         int64     `json:"proxy_id"`
+	ProxyName       string    `json:"proxy_name"`
+	Username        string    `json:"username"`
+	NodeID          int64     `json:"node_id"`
+	NodeName        string    `json:"node_name"`
+	Status          string    `json:"status"`
+	CurConns        int       `json:"cur_conns"`
+	TodayTrafficIn  int64     `json:"today_traffic_in"`
+	TodayTrafficOut int64     `json:"today_traffic_out"`
+	LastStartTime   string    `json:"last_start_time"`
+	LastCloseTime   string    `json:"last_close_time"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ProxyStatusSnapshotKey 生成隧道状态快照的缓存键
+func ProxyStatusSnapshotKey(proxyID int64) string {
+	return fmt.Sprintf("proxy:snapshot:%d", proxyID)
+}
+
+// ProxyStatusSnapshotService 隧道状态快照服务接口
+type ProxyStatusSnapshotService interface {
+	Save(ctx context.Context, snapshots []*ProxyStatusSnapshot) error
+	Get(ctx context.Context, proxyID int64) (*ProxyStatusSnapshot, error)
+	GetMany(ctx context.Context, proxyIDs []int64) (map[int64]*ProxyStatusSnapshot, error)
+	Delete(ctx context.Context, proxyID int64) error
+}
+
+// proxyStatusSnapshotService 隧道状态快照服务实现
+type proxyStatusSnapshotService struct {
+	redisCli *redis.Client
+	logger   *logger.Logger
+}
+
+// NewProxyStatusSnapshotService 创建隧道状态快照服务实例
+func NewProxyStatusSnapshotService(redisCli *redis.Client, logger *logger.Logger) ProxyStatusSnapshotService {
+	return &proxyStatusSnapshotService{
+		redisCli: redisCli,
+		logger:   logger,
+	}
+}
+
+// Save 批量写入隧道状态快照
+func (s *proxyStatusSnapshotService) Save(ctx context.Context, snapshots []*ProxyStatusSnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	pipe := s.redisCli.Pipeline()
+	for _, snapshot := range snapshots {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, ProxyStatusSnapshotKey(snapshot.ProxyID), data, proxyStatusSnapshotTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Get 获取单个隧道的状态快照，不存在时返回nil
+func (s *proxyStatusSnapshotService) Get(ctx context.Context, proxyID int64) (*ProxyStatusSnapshot, error) {
+	snapshots, err := s.GetMany(ctx, []int64{proxyID})
+	if err != nil {
+		return nil, err
+	}
+	return snapshots[proxyID], nil
+}
+
+// GetMany 批量获取隧道状态快照，结果中不包含没有快照的隧道
+func (s *proxyStatusSnapshotService) GetMany(ctx context.Context, proxyIDs []int64) (map[int64]*ProxyStatusSnapshot, error) {
+	result := make(map[int64]*ProxyStatusSnapshot, len(proxyIDs))
+	if len(proxyIDs) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(proxyIDs))
+	for i, id := range proxyIDs {
+		keys[i] = ProxyStatusSnapshotKey(id)
+	}
+
+	values, err := s.redisCli.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var snapshot ProxyStatusSnapshot
+		if err := json.Unmarshal([]byte(str), &snapshot); err != nil {
+			s.logger.Error("解析隧道状态快照失败", "error", err, "proxyID", proxyIDs[i])
+			continue
+		}
+		result[proxyIDs[i]] = &snapshot
+	}
+	return result, nil
+}
+
+// Delete 删除隧道状态快照，用于隧道被关闭后立即反映离线状态
+func (s *proxyStatusSnapshotService) Delete(ctx context.Context, proxyID int64) error {
+	return s.redisCli.Del(ctx, ProxyStatusSnapshotKey(proxyID)).Err()
+}
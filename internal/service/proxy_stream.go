@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// 推送事件类型，站内通知复用隧道推送连接
+const (
+	ProxyStreamEventStatus = "status"
+	ProxyStreamEventStats  = "stats"
+	ProxyStreamEventNotice = "notice"
+)
+
+// maxProxyStreamSubscribers 每个用户同时保持的推送连接上限
+const maxProxyStreamSubscribers = 5
+
+// proxyStreamBufferSize 每个连接的事件缓冲区大小，消费过慢时丢弃新事件
+const proxyStreamBufferSize = 32
+
+// ErrProxyStreamLimit 推送连接数已达上限
+var ErrProxyStreamLimit = errors.New("推送连接数已达上限，请关闭其他页面后重试")
+
+// ProxyStreamEvent 推送给用户的隧道事件
+type ProxyStreamEvent struct {
+	Type string
+	Data interface{}
+}
+
+// ProxyStatusChange 隧道上下线事件
+type ProxyStatusChange struct {
+	ProxyID   int64     `json:"proxyId"`
+	ProxyName string    `json:"proxyName"`
+	NodeID    int64     `json:"nodeId"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason"`
+	Time      time.Time `json:"time"`
+}
+
+// ProxyStreamStats 隧道实时流量与连接数
+type ProxyStreamStats struct {
+	ProxyID         int64     `json:"proxyId"`
+	ProxyName       string    `json:"proxyName"`
+	Status          string    `json:"status"`
+	CurConns        int       `json:"curConns"`
+	TodayTrafficIn  int64     `json:"todayTrafficIn"`
+	TodayTrafficOut int64     `json:"todayTrafficOut"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// NewProxyStreamStats 由状态快照构建推送数据
+func NewProxyStreamStats(snapshot *ProxyStatusSnapshot) ProxyStreamStats {
+	return ProxyStreamStats{
+		ProxyID:         snapshot.ProxyID,
+		ProxyName:       snapshot.ProxyName,
+		Status:          snapshot.Status,
+		CurConns:        snapshot.CurConns,
+		TodayTrafficIn:  snapshot.TodayTrafficIn,
+		TodayTrafficOut: snapshot.TodayTrafficOut,
+		UpdatedAt:       snapshot.UpdatedAt,
+	}
+}
+
+// ProxyStreamHub 隧道事件推送中心，按用户分发事件
+type ProxyStreamHub interface {
+	// Subscribe 订阅用户的隧道事件，返回事件通道与取消订阅函数
+	Subscribe(username string) (<-chan ProxyStreamEvent, func(), error)
+	// Publish 向用户的所有订阅者推送事件，不会阻塞
+	Publish(username string, event ProxyStreamEvent)
+	// HasSubscribers 判断用户当前是否有订阅者
+	HasSubscribers(username string) bool
+}
+
+// proxyStreamSubscriber 单个推送连接
+type proxyStreamSubscriber struct {
+	ch chan ProxyStreamEvent
+}
+
+// proxyStreamHub 隧道事件推送中心实现
+type proxyStreamHub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*proxyStreamSubscriber]struct{}
+}
+
+// NewProxyStreamHub 创建隧道事件推送中心实例
+func NewProxyStreamHub() ProxyStreamHub {
+	return &proxyStreamHub{
+		subscribers: make(map[string]map[*proxyStreamSubscriber]struct{}),
+	}
+}
+
+// Subscribe 订阅用户的隧道事件
+func (h *proxyStreamHub) Subscribe(username string) (<-chan ProxyStreamEvent, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subscribers[username]
+	if len(subs) >= maxProxyStreamSubscribers {
+		return nil, nil, ErrProxyStreamLimit
+	}
+	if subs == nil {
+		subs = make(map[*proxyStreamSubscriber]struct{})
+		h.subscribers[username] = subs
+	}
+
+	sub := &proxyStreamSubscriber{ch: make(chan ProxyStreamEvent, proxyStreamBufferSize)}
+	subs[sub] = struct{}{}
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers[username], sub)
+			if len(h.subscribers[username]) == 0 {
+				delete(h.subscribers, username)
+			}
+			close(sub.ch)
+		})
+	}
+	return sub.ch, cancel, nil
+}
+
+// Publish 推送事件，订阅者缓冲区已满时丢弃该事件
+func (h *proxyStreamHub) Publish(username string, event ProxyStreamEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers[username] {
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// HasSubscribers 判断用户当前是否有订阅者
+func (h *proxyStreamHub) HasSubscribers(username string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[username]) > 0
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"stellarfrp/internal/repository"
+	"strconv"
+	"strings"
+)
+
+// 隧道字段校验错误码
+const (
+	ProxyErrRequired          = "required"
+	ProxyErrNodeNotFound      = "node_not_found"
+	ProxyErrNodeForbidden     = "node_forbidden"
+	ProxyErrNodeImmutable     = "node_immutable"
+	ProxyErrNodeConfigInvalid = "node_config_invalid"
+	ProxyErrTypeNotAllowed    = "type_not_allowed"
+	ProxyErrPortInUse         = "port_in_use"
+	ProxyErrPortOutOfRange    = "port_out_of_range"
+	ProxyErrPortMismatch      = "port_mismatch"
+	ProxyErrDomainRequired    = "domain_required"
+	ProxyErrNameDuplicated    = "name_duplicated"
+	ProxyErrTunnelLimit       = "tunnel_limit"
+	ProxyErrNodeFull          = "node_full"
+	ProxyErrNodePaused        = "node_paused"
+)
+
+// ProxyFieldError 隧道字段级校验错误
+type ProxyFieldError struct {
+	Field string `json:"field"`
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+}
+
+// ProxyValidationInput 待校验的隧道参数
+type ProxyValidationInput struct {
+	NodeID     int64
+	ProxyName  string
+	ProxyType  string
+	LocalIP    string
+	LocalPort  int
+	RemotePort int
+	Domain     string
+	// Existing 为更新时的原隧道，创建时为nil
+	Existing *repository.Proxy
+}
+
+// ProxyValidator 隧道创建与编辑的统一校验器
+type ProxyValidator struct {
+	proxyService    ProxyService
+	nodeService     NodeService
+	userService     UserService
+	capacityService NodeCapacityService
+}
+
+// NewProxyValidator 创建隧道校验器实例
+func NewProxyValidator(proxyService ProxyService, nodeService NodeService, userService UserService, capacityService NodeCapacityService) *ProxyValidator {
+	return &ProxyValidator{
+		proxyService:    proxyService,
+		nodeService:     nodeService,
+		userService:     userService,
+		capacityService: capacityService,
+	}
+}
+
+// Validate 执行全部校验规则（含隧道数量上限），一次性返回所有问题
+func (v *ProxyValidator) Validate(ctx context.Context, user *repository.User, input *ProxyValidationInput) ([]ProxyFieldError, error) {
+	errs, err := v.ValidateConfig(ctx, user, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Existing == nil {
+		remaining, err := v.RemainingTunnels(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		if remaining <= 0 {
+			errs = append(errs, ProxyFieldError{Field: "", Code: ProxyErrTunnelLimit, Msg: "您已达到可创建的数量上限"})
+		}
+	}
+
+	return errs, nil
+}
+
+// ValidateConfig 校验隧道配置本身：节点权限、协议类型、节点容量、端口、域名与名称唯一性
+func (v *ProxyValidator) ValidateConfig(ctx context.Context, user *repository.User, input *ProxyValidationInput) ([]ProxyFieldError, error) {
+	var errs []ProxyFieldError
+	add := func(field, code, msg string) {
+		errs = append(errs, ProxyFieldError{Field: field, Code: code, Msg: msg})
+	}
+
+	if input.NodeID == 0 {
+		add("nodeId", ProxyErrRequired, "请选择节点")
+	}
+	if input.ProxyName == "" {
+		add("proxyName", ProxyErrRequired, "隧道名称不能为空")
+	}
+	if input.ProxyType == "" {
+		add("proxyType", ProxyErrRequired, "隧道类型不能为空")
+	}
+	if input.LocalIP == "" {
+		add("localIp", ProxyErrRequired, "本地IP不能为空")
+	}
+	if input.LocalPort == 0 {
+		add("localPort", ProxyErrRequired, "本地端口不能为空")
+	}
+
+	isHTTP := input.ProxyType == "http" || input.ProxyType == "https"
+
+	// 节点相关校验
+	var node *repository.Node
+	if input.NodeID != 0 {
+		if input.Existing != nil && input.Existing.Node != input.NodeID {
+			msg := "该隧道不属于请求中指定的节点"
+			if originalNode, err := v.nodeService.GetByID(ctx, input.Existing.Node); err == nil {
+				msg = "该隧道属于 " + originalNode.NodeName + " 节点，不能修改为其他节点"
+			}
+			add("nodeId", ProxyErrNodeImmutable, msg)
+		} else if n, err := v.nodeService.GetByID(ctx, input.NodeID); err != nil {
+			add("nodeId", ProxyErrNodeNotFound, "节点不存在或已下线")
+		} else {
+			nodes, err := v.nodeService.GetAccessibleNodes(ctx, user.GroupID)
+			if err != nil {
+				return nil, fmt.Errorf("获取节点权限失败: %w", err)
+			}
+			for _, accessible := range nodes {
+				if accessible.ID == n.ID {
+					node = n
+					break
+				}
+			}
+			if node == nil {
+				add("nodeId", ProxyErrNodeForbidden, "您没有权限使用该节点")
+			} else if node.Status == repository.NodeStatusPaused && input.Existing == nil {
+				add("nodeId", ProxyErrNodePaused, "节点已被所有者暂停，请更换节点")
+			} else if repository.NodeUnapproved(node.Status) && input.Existing == nil {
+				add("nodeId", ProxyErrNodeNotFound, "节点不存在或已下线")
+			}
+		}
+	}
+
+	if node != nil && input.ProxyType != "" {
+		typeAllowed, err := NodeAllowsType(node, input.ProxyType)
+		if err != nil {
+			add("nodeId", ProxyErrNodeConfigInvalid, "节点开放类型配置错误")
+		} else if !typeAllowed {
+			add("proxyType", ProxyErrTypeNotAllowed, "该节点不支持 "+input.ProxyType+" 类型的隧道")
+		}
+
+		reason, err := v.capacityService.CheckProxy(ctx, node, input.ProxyType, input.Existing)
+		if err != nil {
+			return nil, fmt.Errorf("检查节点容量失败: %w", err)
+		}
+		if reason != "" {
+			add("nodeId", ProxyErrNodeFull, reason)
+		}
+	}
+
+	// 端口与域名校验
+	remotePortStr := strconv.Itoa(input.RemotePort)
+	if isHTTP {
+		if input.Domain == "" {
+			add("domain", ProxyErrDomainRequired, "HTTP/HTTPS类型的隧道必须填写域名")
+		}
+
+		expectedPort := 80
+		if input.ProxyType == "https" {
+			expectedPort = 443
+		}
+		if input.RemotePort != expectedPort {
+			add("remotePort", ProxyErrPortMismatch, input.ProxyType+"类型的隧道远程端口必须为"+strconv.Itoa(expectedPort))
+		}
+	} else if input.ProxyType == "tcp" || input.ProxyType == "udp" {
+		if input.RemotePort == 0 {
+			add("remotePort", ProxyErrRequired, "TCP/UDP类型的隧道必须填写远程端口")
+		} else if node != nil {
+			minPort, maxPort, err := ParsePortRange(node.PortRange)
+			if err != nil {
+				add("nodeId", ProxyErrNodeConfigInvalid, "节点端口范围配置错误")
+			} else if input.RemotePort < minPort || input.RemotePort > maxPort {
+				add("remotePort", ProxyErrPortOutOfRange, "远程端口必须在"+node.PortRange+"范围内")
+			}
+		}
+	}
+
+	if !isHTTP && input.RemotePort != 0 && input.NodeID != 0 && input.ProxyType != "" {
+		isUsed, err := v.proxyService.IsRemotePortUsed(ctx, input.NodeID, input.ProxyType, remotePortStr)
+		if err != nil {
+			return nil, fmt.Errorf("检查端口占用失败: %w", err)
+		}
+		ownPort := input.Existing != nil &&
+			input.Existing.RemotePort == remotePortStr &&
+			input.Existing.Node == input.NodeID &&
+			input.Existing.ProxyType == input.ProxyType
+		if isUsed && !ownPort {
+			add("remotePort", ProxyErrPortInUse, "该节点下已有相同协议类型的隧道使用了端口 "+remotePortStr+"，请更换端口")
+		}
+	}
+
+	// 名称唯一性校验
+	if input.ProxyName != "" && (input.Existing == nil || input.Existing.ProxyName != input.ProxyName) {
+		other, err := v.proxyService.GetByUsernameAndName(ctx, user.Username, input.ProxyName)
+		if err != nil {
+			return nil, fmt.Errorf("检查隧道名称失败: %w", err)
+		}
+		if other != nil {
+			add("proxyName", ProxyErrNameDuplicated, "您已有同名隧道，请更换隧道名称")
+		}
+	}
+
+	return errs, nil
+}
+
+// RemainingTunnels 获取用户还可以创建的隧道数量
+func (v *ProxyValidator) RemainingTunnels(ctx context.Context, user *repository.User) (int, error) {
+	proxyCount, err := v.proxyService.GetUserProxyCount(ctx, user.Username)
+	if err != nil {
+		return 0, fmt.Errorf("获取隧道数量失败: %w", err)
+	}
+
+	tunnelLimit, err := v.userService.GetGroupTunnelLimit(ctx, user.GroupID)
+	if err != nil {
+		return 0, fmt.Errorf("获取用户组隧道限制失败: %w", err)
+	}
+
+	if user.TunnelCount != nil {
+		tunnelLimit += *user.TunnelCount
+	}
+
+	return tunnelLimit - proxyCount, nil
+}
+
+// ParsePortRange 解析节点端口范围，如 10000-20000
+func ParsePortRange(portRange string) (int, int, error) {
+	parts := strings.Split(portRange, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("端口范围格式错误: %s", portRange)
+	}
+
+	minPort, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("端口范围格式错误: %w", err)
+	}
+	maxPort, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("端口范围格式错误: %w", err)
+	}
+
+	return minPort, maxPort, nil
+}
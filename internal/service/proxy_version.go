@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"stellarfrp/internal/repository"
+	"strconv"
+)
+
+// 隧道配置变更操作人类型
+const (
+	ProxyOperatorUser   = "user"
+	ProxyOperatorAdmin  = "admin"
+	ProxyOperatorSystem = "system"
+)
+
+// 隧道配置变更动作
+const (
+	ProxyVersionActionCreate   = "create"
+	ProxyVersionActionUpdate   = "update"
+	ProxyVersionActionImport   = "import"
+	ProxyVersionActionRollback = "rollback"
+)
+
+// ProxyOperator 隧道变更的操作人信息
+type ProxyOperator struct {
+	Type   string
+	Name   string
+	Action string
+	Remark string
+	// Reason 状态变更原因，用于隧道上下线事件记录
+	Reason string
+}
+
+// systemProxyOperator 未指定操作人时使用的系统操作人
+var systemProxyOperator = ProxyOperator{Type: ProxyOperatorSystem, Action: ProxyVersionActionUpdate}
+
+// ProxyConfigSnapshot 隧道配置快照，仅包含用户可编辑的配置字段
+type ProxyConfigSnapshot struct {
+	ProxyName         string `json:"proxy_name"`
+	ProxyType         string `json:"proxy_type"`
+	LocalIP           string `json:"local_ip"`
+	LocalPort         int    `json:"local_port"`
+	UseEncryption     string `json:"use_encryption"`
+	UseCompression    string `json:"use_compression"`
+	Domain            string `json:"domain"`
+	HostHeaderRewrite string `json:"host_header_rewrite"`
+	RemotePort        string `json:"remote_port"`
+	HeaderXFromWhere  string `json:"header_x_from_where"`
+	Node              int64  `json:"node"`
+}
+
+// NewProxyConfigSnapshot 从隧道生成配置快照
+func NewProxyConfigSnapshot(proxy *repository.Proxy) *ProxyConfigSnapshot {
+	return &ProxyConfigSnapshot{
+		ProxyName:         proxy.ProxyName,
+		ProxyType:         proxy.ProxyType,
+		LocalIP:           proxy.LocalIP,
+		LocalPort:         proxy.LocalPort,
+		UseEncryption:     proxy.UseEncryption,
+		UseCompression:    proxy.UseCompression,
+		Domain:            proxy.Domain,
+		HostHeaderRewrite: proxy.HostHeaderRewrite,
+		RemotePort:        proxy.RemotePort,
+		HeaderXFromWhere:  proxy.HeaderXFromWhere,
+		Node:              proxy.Node,
+	}
+}
+
+// ParseProxyConfigSnapshot 解析版本记录中的配置快照
+func ParseProxyConfigSnapshot(version *repository.ProxyVersion) (*ProxyConfigSnapshot, error) {
+	var snapshot ProxyConfigSnapshot
+	if err := json.Unmarshal([]byte(version.Snapshot), &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// ApplyTo 将快照中的配置写回隧道，状态、运行ID等运行时字段保持不变
+func (s *ProxyConfigSnapshot) ApplyTo(proxy *repository.Proxy) {
+	proxy.ProxyName = s.ProxyName
+	proxy.ProxyType = s.ProxyType
+	proxy.LocalIP = s.LocalIP
+	proxy.LocalPort = s.LocalPort
+	proxy.UseEncryption = s.UseEncryption
+	proxy.UseCompression = s.UseCompression
+	proxy.Domain = s.Domain
+	proxy.HostHeaderRewrite = s.HostHeaderRewrite
+	proxy.RemotePort = s.RemotePort
+	proxy.HeaderXFromWhere = s.HeaderXFromWhere
+	proxy.Node = s.Node
+}
+
+// ValidationInput 将快照转换为校验参数，用于回滚前重新执行当前的校验规则
+func (s *ProxyConfigSnapshot) ValidationInput(existing *repository.Proxy) *ProxyValidationInput {
+	remotePort, _ := strconv.Atoi(s.RemotePort)
+	return &ProxyValidationInput{
+		NodeID:     s.Node,
+		ProxyName:  s.ProxyName,
+		ProxyType:  s.ProxyType,
+		LocalIP:    s.LocalIP,
+		LocalPort:  s.LocalPort,
+		RemotePort: remotePort,
+		Domain:     s.Domain,
+		Existing:   existing,
+	}
+}
+
+// Diff 返回与另一个快照相比发生变化的字段名
+func (s *ProxyConfigSnapshot) Diff(other *ProxyConfigSnapshot) []string {
+	changed := []string{}
+	if other == nil {
+		return changed
+	}
+	if s.ProxyName != other.ProxyName {
+		changed = append(changed, "proxy_name")
+	}
+	if s.ProxyType != other.ProxyType {
+		changed = append(changed, "proxy_type")
+	}
+	if s.LocalIP != other.LocalIP {
+		changed = append(changed, "local_ip")
+	}
+	if s.LocalPort != other.LocalPort {
+		changed = append(changed, "local_port")
+	}
+	if s.UseEncryption != other.UseEncryption {
+		changed = append(changed, "use_encryption")
+	}
+	if s.UseCompression != other.UseCompression {
+		changed = append(changed, "use_compression")
+	}
+	if s.Domain != other.Domain {
+		changed = append(changed, "domain")
+	}
+	if s.HostHeaderRewrite != other.HostHeaderRewrite {
+		changed = append(changed, "host_header_rewrite")
+	}
+	if s.RemotePort != other.RemotePort {
+		changed = append(changed, "remote_port")
+	}
+	if s.HeaderXFromWhere != other.HeaderXFromWhere {
+		changed = append(changed, "header_x_from_where")
+	}
+	if s.Node != other.Node {
+		changed = append(changed, "node")
+	}
+	return changed
+}
+
+// ProxyVersionDetail 对外展示的版本记录
+type ProxyVersionDetail struct {
+	Version       int                  `json:"version"`
+	Action        string               `json:"action"`
+	OperatorType  string               `json:"operator_type"`
+	Operator      string               `json:"operator"`
+	Remark        string               `json:"remark"`
+	ChangedFields []string             `json:"changed_fields"`
+	Snapshot      *ProxyConfigSnapshot `json:"snapshot"`
+	CreatedAt     string               `json:"created_at"`
+}
+
+// NewProxyVersionDetail 将版本记录转换为展示结构，快照或字段解析失败时对应字段留空
+func NewProxyVersionDetail(version *repository.ProxyVersion) *ProxyVersionDetail {
+	detail := &ProxyVersionDetail{
+		Version:       version.Version,
+		Action:        version.Action,
+		OperatorType:  version.OperatorType,
+		Operator:      version.Operator,
+		Remark:        version.Remark,
+		ChangedFields: []string{},
+		CreatedAt:     version.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if snapshot, err := ParseProxyConfigSnapshot(version); err == nil {
+		detail.Snapshot = snapshot
+	}
+	_ = json.Unmarshal([]byte(version.ChangedFields), &detail.ChangedFields)
+	return detail
+}
+
+// recordVersion 写入隧道配置版本记录，失败只记录日志不影响主流程
+func (s *proxyService) recordVersion(ctx context.Context, proxy *repository.Proxy, changedFields []string, operator ProxyOperator) {
+	snapshotBytes, err := json.Marshal(NewProxyConfigSnapshot(proxy))
+	if err != nil {
+		s.logger.Error("序列化隧道配置快照失败", "error", err, "proxyID", proxy.ID)
+		return
+	}
+	changedBytes, err := json.Marshal(changedFields)
+	if err != nil {
+		s.logger.Error("序列化隧道变更字段失败", "error", err, "proxyID", proxy.ID)
+		return
+	}
+
+	if operator.Action == "" {
+		operator.Action = ProxyVersionActionUpdate
+	}
+
+	err = s.proxyVersionRepo.Create(ctx, &repository.ProxyVersion{
+		ProxyID:       proxy.ID,
+		Username:      proxy.Username,
+		Snapshot:      string(snapshotBytes),
+		ChangedFields: string(changedBytes),
+		Action:        operator.Action,
+		OperatorType:  operator.Type,
+		Operator:      operator.Name,
+		Remark:        operator.Remark,
+	})
+	if err != nil {
+		s.logger.Error("写入隧道配置版本失败", "error", err, "proxyID", proxy.ID)
+	}
+}
+
+// ensureBaselineVersion 为功能上线前创建的隧道补记变更前的基线版本，保证首次编辑后仍可回滚
+func (s *proxyService) ensureBaselineVersion(ctx context.Context, oldProxy *repository.Proxy) {
+	count, err := s.proxyVersionRepo.CountByProxyID(ctx, oldProxy.ID)
+	if err != nil {
+		s.logger.Error("获取隧道配置版本数量失败", "error", err, "proxyID", oldProxy.ID)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	s.recordVersion(ctx, oldProxy, []string{}, ProxyOperator{
+		Type:   ProxyOperatorSystem,
+		Action: ProxyVersionActionCreate,
+		Remark: "历史配置基线",
+	})
+}
+
+// ListVersions 获取隧道配置版本列表（按版本号倒序）
+func (s *proxyService) ListVersions(ctx context.Context, proxyID int64, offset, limit int) ([]*repository.ProxyVersion, int, error) {
+	versions, err := s.proxyVersionRepo.ListByProxyID(ctx, proxyID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.proxyVersionRepo.CountByProxyID(ctx, proxyID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return versions, total, nil
+}
+
+// GetVersion 获取隧道的指定配置版本
+func (s *proxyService) GetVersion(ctx context.Context, proxyID int64, version int) (*repository.ProxyVersion, error) {
+	return s.proxyVersionRepo.GetByVersion(ctx, proxyID, version)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"stellarfrp/internal/repository"
+	"stellarfrp/pkg/logger"
+	"time"
+)
+
+// UserNoticeService 用户站内通知服务接口
+type UserNoticeService interface {
+	Notify(ctx context.Context, username, title, content string) error
+	List(ctx context.Context, username string, offset, limit int) ([]*repository.UserNotice, int, int, error)
+	MarkRead(ctx context.Context, username string, ids []int64) error
+}
+
+// userNoticeService 用户站内通知服务实现
+type userNoticeService struct {
+	noticeRepo repository.UserNoticeRepository
+	streamHub  ProxyStreamHub
+	logger     *logger.Logger
+}
+
+// NewUserNoticeService 创建用户站内通知服务实例
+func NewUserNoticeService(noticeRepo repository.UserNoticeRepository, streamHub ProxyStreamHub, logger *logger.Logger) UserNoticeService {
+	return &userNoticeService{
+		noticeRepo: noticeRepo,
+		streamHub:  streamHub,
+		logger:     logger,
+	}
+}
+
+// Notify 写入站内通知，用户在线时同时通过推送连接实时送达
+func (s *userNoticeService) Notify(ctx context.Context, username, title, content string) error {
+	notice := &repository.UserNotice{
+		Username: username,
+		Title:    title,
+		Content:  content,
+	}
+	if err := s.noticeRepo.Create(ctx, notice); err != nil {
+		return err
+	}
+	s.streamHub.Publish(username, ProxyStreamEvent{Type: ProxyStreamEventNotice, Data: notice})
+	return nil
+}
+
+// List 分页获取用户的站内通知，同时返回总数与未读数量
+func (s *userNoticeService) List(ctx context.Context, username string, offset, limit int) ([]*repository.UserNotice, int, int, error) {
+	notices, total, err := s.noticeRepo.ListByUsername(ctx, username, offset, limit)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if notices == nil {
+		notices = []*repository.UserNotice{}
+	}
+	unread, err := s.noticeRepo.CountUnread(ctx, username)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return notices, total, unread, nil
+}
+
+// MarkRead 将通知标记为已读，ids 为空时标记全部
+func (s *userNoticeService) MarkRead(ctx context.Context, username string, ids []int64) error {
+	if len(ids) == 0 {
+		return s.noticeRepo.MarkAllRead(ctx, username, time.Now())
+	}
+	return s.noticeRepo.MarkRead(ctx, username, ids, time.Now())
+}
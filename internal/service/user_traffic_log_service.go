@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"stellarfrp/internal/repository"
 	"stellarfrp/internal/types"
+	"stellarfrp/pkg/frps"
 	"stellarfrp/pkg/logger"
 	"strings"
 	"sync"
@@ -36,7 +36,7 @@ type userTrafficLogService struct {
 	nodeRepo        repository.NodeRepository
 	userTrafficRepo repository.UserTrafficLogRepository
 	redisClient     *redis.Client
-	httpClient      *http.Client
+	frpsClient      *frps.Client
 	logger          *logger.Logger
 }
 
@@ -45,13 +45,14 @@ func NewUserTrafficLogService(
 	nodeRepo repository.NodeRepository,
 	userTrafficRepo repository.UserTrafficLogRepository,
 	redisClient *redis.Client,
+	frpsClient *frps.Client,
 	logger *logger.Logger,
 ) UserTrafficLogService {
 	return &userTrafficLogService{
 		nodeRepo:        nodeRepo,
 		userTrafficRepo: userTrafficRepo,
 		redisClient:     redisClient,
-		httpClient:      &http.Client{Timeout: apiTimeout},
+		frpsClient:      frpsClient,
 		logger:          logger,
 	}
 }
@@ -179,7 +180,7 @@ func (s *userTrafficLogService) collectNodeTraffic(ctx context.Context, node *re
 			defer wg.Done()
 
 			// 发送API请求获取流量数据
-			data, err := s.getProxyTraffic(ctx, node.URL, node.User, node.Token, proxyType)
+			proxies, err := s.frpsClient.ListProxies(ctx, NodeEndpoint(node), proxyType)
 			if err != nil {
 				s.logger.Error("获取节点流量数据失败",
 					"node", node.NodeName,
@@ -189,7 +190,7 @@ func (s *userTrafficLogService) collectNodeTraffic(ctx context.Context, node *re
 			}
 
 			// 处理返回的数据
-			for _, proxy := range data.Proxies {
+			for _, proxy := range proxies {
 				if proxy.Name != "" {
 					username := extractUsername(proxy.Name)
 					// 计算进出流量总和
@@ -207,31 +208,6 @@ func (s *userTrafficLogService) collectNodeTraffic(ctx context.Context, node *re
 	return nodeUserTraffic
 }
 
-// getProxyTraffic 获取指定类型的代理流量数据
-func (s *userTrafficLogService) getProxyTraffic(ctx context.Context, url, user, token, proxyType string) (*types.ProxyResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
-		url+"/api/proxy/"+proxyType, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// 添加基本认证
-	req.SetBasicAuth(user, token)
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var response types.ProxyResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, err
-	}
-
-	return &response, nil
-}
-
 // extractUsername 从代理名称中提取用户名
 func extractUsername(name string) string {
 	if idx := strings.Index(name, "."); idx > 0 {
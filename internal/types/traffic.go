@@ -11,15 +11,3 @@ type UserTrafficLog struct {
 	HistoryTraffic string    `db:"history_traffic" json:"history_traffic"`
 	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
 }
-
-// ProxyTrafficInfo 代理流量信息
-type ProxyTrafficInfo struct {
-	Name            string `json:"name"`
-	TodayTrafficIn  int64  `json:"todayTrafficIn"`
-	TodayTrafficOut int64  `json:"todayTrafficOut"`
-}
-
-// ProxyResponse API响应
-type ProxyResponse struct {
-	Proxies []ProxyTrafficInfo `json:"proxies"`
-}
@@ -32,6 +32,8 @@ const (
 	TypeResetPassword EmailType = "reset_password"
 	// TypeWelcome 欢迎邮件
 	TypeWelcome EmailType = "register_success"
+	// TypeNotification 通用通知邮件
+	TypeNotification EmailType = "notification"
 )
 
 // EmailData 邮件数据
@@ -42,6 +44,8 @@ type EmailData struct {
 	ExpireTime  time.Time // 过期时间
 	ProductName string    // 产品名称
 	UserName    string    // 用户名
+	Title       string    // 通知标题
+	Lines       []string  // 通知内容，每项一段
 }
 
 // Service 邮件服务
@@ -226,3 +230,16 @@ func (s *Service) SendWelcomeEmail(to, userName string) error {
 
 	return s.SendEmail(TypeWelcome, data)
 }
+
+// SendNotification 发送通知邮件
+func (s *Service) SendNotification(to, userName, subject, title string, lines []string) error {
+	data := EmailData{
+		To:       to,
+		UserName: userName,
+		Subject:  subject,
+		Title:    title,
+		Lines:    lines,
+	}
+
+	return s.SendEmail(TypeNotification, data)
+}
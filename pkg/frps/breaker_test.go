@@ -0,0 +1,88 @@
+package frps
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	var b breaker
+	now := time.Now()
+
+	b.failure(now, 3, time.Minute)
+	b.failure(now, 3, time.Minute)
+	if b.open(now) || !b.allow(now) {
+		t.Fatal("熔断器在达到阈值前不应熔断")
+	}
+
+	b.failure(now, 3, time.Minute)
+	if !b.open(now) {
+		t.Fatal("连续失败达到阈值后应熔断")
+	}
+	if b.allow(now.Add(30 * time.Second)) {
+		t.Fatal("冷却期内不应放行请求")
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	var b breaker
+	now := time.Now()
+
+	b.failure(now, 2, time.Minute)
+	b.success()
+	b.failure(now, 2, time.Minute)
+	if b.open(now) {
+		t.Fatal("成功请求后应重新计算连续失败次数")
+	}
+}
+
+func TestBreakerHalfOpenAllowsSingleProbe(t *testing.T) {
+	var b breaker
+	now := time.Now()
+	cooldown := time.Minute
+
+	b.failure(now, 1, cooldown)
+	after := now.Add(cooldown)
+	if b.open(after) {
+		t.Fatal("冷却期结束后不应处于熔断状态")
+	}
+	if !b.allow(after) {
+		t.Fatal("冷却期结束后应放行一个探测请求")
+	}
+	if b.allow(after) {
+		t.Fatal("探测请求未结束时不应放行其他请求")
+	}
+
+	b.release()
+	if !b.allow(after) {
+		t.Fatal("探测请求取消后应重新放行探测请求")
+	}
+}
+
+func TestBreakerProbeFailureReopens(t *testing.T) {
+	var b breaker
+	now := time.Now()
+	cooldown := time.Minute
+
+	for i := 0; i < 3; i++ {
+		b.failure(now, 3, cooldown)
+	}
+	after := now.Add(cooldown)
+	if !b.allow(after) {
+		t.Fatal("冷却期结束后应放行一个探测请求")
+	}
+
+	// 探测失败时无需再次达到阈值即重新熔断
+	b.failure(after, 100, cooldown)
+	if !b.open(after) || b.allow(after) {
+		t.Fatal("探测请求失败后应重新熔断")
+	}
+	if !b.allow(after.Add(cooldown)) {
+		t.Fatal("新的冷却期结束后应再次放行探测请求")
+	}
+
+	b.success()
+	if b.open(after.Add(cooldown)) || !b.allow(after.Add(cooldown)) {
+		t.Fatal("探测请求成功后应恢复")
+	}
+}
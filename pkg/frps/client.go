@@ -0,0 +1,233 @@
+package frps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"stellarfrp/pkg/network"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponseSize 节点响应体读取上限
+const maxResponseSize = 8 << 20
+
+// Options 客户端配置
+type Options struct {
+	// Timeout 单次请求默认超时
+	Timeout time.Duration
+	// MaxRetries 请求失败后的最大重试次数
+	MaxRetries int
+	// RetryBackoff 首次重试前的等待时间，之后每次翻倍
+	RetryBackoff time.Duration
+	// BreakerThreshold 连续失败多少次后熔断
+	BreakerThreshold int
+	// BreakerCooldown 熔断持续时间
+	BreakerCooldown time.Duration
+	// PublicOnly 只允许连接公网地址，用于请求用户提交的未审核节点
+	PublicOnly bool
+	// DecryptToken 发送请求前解密节点密码，节点密码加密存储时设置，为空时直接使用
+	DecryptToken func(token string) (string, error)
+}
+
+// DefaultOptions 默认客户端配置
+func DefaultOptions() Options {
+	return Options{
+		Timeout:          10 * time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     200 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// Client frps Dashboard API 客户端，所有节点共享连接池，每个节点独立熔断
+type Client struct {
+	httpClient *http.Client
+	opts       Options
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// NewClient 创建 frps API 客户端
+func NewClient(opts Options) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 200
+	transport.MaxIdleConnsPerHost = 8
+	transport.IdleConnTimeout = 90 * time.Second
+	if opts.PublicOnly {
+		transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, Control: network.PublicOnlyControl}).DialContext
+	}
+
+	return &Client{
+		httpClient: &http.Client{Transport: transport},
+		opts:       opts,
+		breakers:   make(map[string]*breaker),
+	}
+}
+
+// ServerInfo 获取节点服务器信息，按响应中的版本号选择解析器
+func (c *Client) ServerInfo(ctx context.Context, ep Endpoint) (*ServerInfo, error) {
+	body, err := c.do(ctx, ep, http.MethodGet, "/api/serverinfo", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var head struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(body, &head); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	info, err := decoderFor(head.Version).decodeServerInfo(body)
+	if err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	return info, nil
+}
+
+// ListProxies 获取节点上指定类型的所有隧道状态，按节点记录的版本号选择解析器
+func (c *Client) ListProxies(ctx context.Context, ep Endpoint, proxyType string) ([]ProxyStats, error) {
+	body, err := c.do(ctx, ep, http.MethodGet, "/api/proxy/"+url.PathEscape(proxyType), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies, err := decoderFor(ep.Version).decodeProxies(body)
+	if err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	return proxies, nil
+}
+
+// KickClient 踢下指定运行ID的客户端
+func (c *Client) KickClient(ctx context.Context, ep Endpoint, runID string) error {
+	payload, err := json.Marshal(map[string]string{"runid": runID})
+	if err != nil {
+		return fmt.Errorf("构建请求体失败: %w", err)
+	}
+	_, err = c.do(ctx, ep, http.MethodPost, "/api/client/kick", payload)
+	return err
+}
+
+// CircuitOpen 判断节点当前是否处于熔断状态
+func (c *Client) CircuitOpen(ep Endpoint) bool {
+	return c.breakerFor(ep).open(time.Now())
+}
+
+// breakerFor 获取节点对应的熔断器
+func (c *Client) breakerFor(ep Endpoint) *breaker {
+	key := strings.TrimRight(ep.URL, "/")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[key]
+	if !ok {
+		b = &breaker{}
+		c.breakers[key] = b
+	}
+	return b
+}
+
+// do 发送请求，网络错误与5xx按指数退避重试，并按最终结果更新熔断器
+func (c *Client) do(ctx context.Context, ep Endpoint, method, path string, payload []byte) ([]byte, error) {
+	if c.opts.DecryptToken != nil {
+		token, err := c.opts.DecryptToken(ep.Token)
+		if err != nil {
+			return nil, fmt.Errorf("解密节点凭据失败: %w", err)
+		}
+		ep.Token = token
+	}
+
+	b := c.breakerFor(ep)
+	if !b.allow(time.Now()) {
+		return nil, ErrCircuitOpen
+	}
+
+	backoff := c.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		body, err := c.doOnce(ctx, ep, method, path, payload)
+		if err == nil {
+			b.success()
+			return body, nil
+		}
+
+		// 4xx 说明节点可达，只是请求本身有问题
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			b.success()
+			return nil, err
+		}
+
+		if ctx.Err() != nil {
+			b.release()
+			return nil, err
+		}
+
+		if attempt >= c.opts.MaxRetries {
+			b.failure(time.Now(), c.opts.BreakerThreshold, c.opts.BreakerCooldown)
+			return nil, err
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			b.release()
+			return nil, err
+		}
+	}
+}
+
+// doOnce 发送单次请求并返回响应体
+func (c *Client) doOnce(ctx context.Context, ep Endpoint, method, path string, payload []byte) ([]byte, error) {
+	timeout := ep.Timeout
+	if timeout <= 0 {
+		timeout = c.opts.Timeout
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, method, strings.TrimRight(ep.URL, "/")+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.SetBasicAuth(ep.User, ep.Token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求节点失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		preview := strings.TrimSpace(string(body))
+		if len(preview) > 200 {
+			preview = preview[:200]
+		}
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: preview}
+	}
+	return body, nil
+}
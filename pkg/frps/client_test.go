@@ -0,0 +1,227 @@
+package frps_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"stellarfrp/pkg/frps"
+	"stellarfrp/pkg/frps/frpstest"
+	"testing"
+	"time"
+)
+
+const testBackoff = 20 * time.Millisecond
+
+func newTestClient(maxRetries, threshold int, cooldown time.Duration) *frps.Client {
+	return frps.NewClient(frps.Options{
+		Timeout:          time.Second,
+		MaxRetries:       maxRetries,
+		RetryBackoff:     testBackoff,
+		BreakerThreshold: threshold,
+		BreakerCooldown:  cooldown,
+	})
+}
+
+func newTestServer(t *testing.T) *frpstest.Server {
+	t.Helper()
+	s := frpstest.NewServer("admin", "password")
+	t.Cleanup(s.Close)
+	s.SetServerInfo(frps.ServerInfo{Version: "0.61.1", BindPort: 7000})
+	return s
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	s := newTestServer(t)
+	client := newTestClient(2, 5, time.Minute)
+
+	s.FailNext(2, http.StatusBadGateway)
+	start := time.Now()
+	info, err := client.ServerInfo(context.Background(), s.Endpoint())
+	if err != nil {
+		t.Fatalf("重试后应成功: %v", err)
+	}
+	if info.BindPort != 7000 {
+		t.Errorf("BindPort = %d, want 7000", info.BindPort)
+	}
+	// 两次重试分别等待 backoff 和 2*backoff
+	if elapsed := time.Since(start); elapsed < 3*testBackoff {
+		t.Errorf("重试间隔应按指数退避, elapsed = %v", elapsed)
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	s := newTestServer(t)
+	client := newTestClient(2, 5, time.Minute)
+
+	s.FailNext(4, http.StatusInternalServerError)
+	_, err := client.ServerInfo(context.Background(), s.Endpoint())
+	var statusErr *frps.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("err = %v, want 500 StatusError", err)
+	}
+
+	// 首次请求加两次重试共消耗3个注入故障，用不重试的客户端确认剩余1个
+	probe := newTestClient(0, 5, time.Minute)
+	if _, err := probe.ServerInfo(context.Background(), s.Endpoint()); err == nil {
+		t.Fatal("应还剩一个注入故障")
+	}
+	if _, err := probe.ServerInfo(context.Background(), s.Endpoint()); err != nil {
+		t.Fatalf("注入故障耗尽后应成功: %v", err)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	s := newTestServer(t)
+	client := newTestClient(2, 1, time.Minute)
+
+	s.FailNext(2, http.StatusBadRequest)
+	for i := 0; i < 2; i++ {
+		_, err := client.ServerInfo(context.Background(), s.Endpoint())
+		var statusErr *frps.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+			t.Fatalf("第%d次请求 err = %v, want 400 StatusError", i+1, err)
+		}
+	}
+	if client.CircuitOpen(s.Endpoint()) {
+		t.Error("4xx 说明节点可达，不应熔断")
+	}
+	if _, err := client.ServerInfo(context.Background(), s.Endpoint()); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestClientRetriesNetworkErrors(t *testing.T) {
+	s := newTestServer(t)
+	ep := s.Endpoint()
+	s.Close()
+	client := newTestClient(2, 5, time.Minute)
+
+	start := time.Now()
+	_, err := client.ServerInfo(context.Background(), ep)
+	if err == nil {
+		t.Fatal("节点不可达时应返回错误")
+	}
+	var statusErr *frps.StatusError
+	if errors.As(err, &statusErr) {
+		t.Fatalf("err = %v, want network error", err)
+	}
+	if elapsed := time.Since(start); elapsed < 3*testBackoff {
+		t.Errorf("网络错误应按指数退避重试, elapsed = %v", elapsed)
+	}
+}
+
+func TestClientStopsRetryingWhenCanceled(t *testing.T) {
+	s := newTestServer(t)
+	client := frps.NewClient(frps.Options{
+		Timeout:          time.Second,
+		MaxRetries:       5,
+		RetryBackoff:     time.Minute,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Minute,
+	})
+
+	s.FailNext(1, http.StatusServiceUnavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.ServerInfo(ctx, s.Endpoint()); err == nil {
+		t.Fatal("取消后应返回错误")
+	}
+	if client.CircuitOpen(s.Endpoint()) {
+		t.Error("调用方取消的请求不应计入熔断")
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	s := newTestServer(t)
+	cooldown := 100 * time.Millisecond
+	client := newTestClient(0, 2, cooldown)
+	ep := s.Endpoint()
+
+	s.FailNext(2, http.StatusInternalServerError)
+	for i := 0; i < 2; i++ {
+		if _, err := client.ServerInfo(context.Background(), ep); err == nil {
+			t.Fatalf("第%d次请求应失败", i+1)
+		}
+	}
+	if !client.CircuitOpen(ep) {
+		t.Fatal("连续失败达到阈值后应熔断")
+	}
+
+	// 熔断期间不请求节点，注入的故障不会被消耗
+	s.FailNext(1, http.StatusInternalServerError)
+	if _, err := client.ServerInfo(context.Background(), ep); !errors.Is(err, frps.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+
+	// 冷却期过后放行探测请求，探测失败立即重新熔断
+	time.Sleep(cooldown)
+	if client.CircuitOpen(ep) {
+		t.Fatal("冷却期过后不应处于熔断状态")
+	}
+	var statusErr *frps.StatusError
+	if _, err := client.ServerInfo(context.Background(), ep); !errors.As(err, &statusErr) {
+		t.Fatalf("探测请求 err = %v, want StatusError", err)
+	}
+	if !client.CircuitOpen(ep) {
+		t.Fatal("探测请求失败后应重新熔断")
+	}
+
+	// 再次冷却后探测成功，熔断恢复
+	time.Sleep(cooldown)
+	if _, err := client.ServerInfo(context.Background(), ep); err != nil {
+		t.Fatalf("探测请求应成功: %v", err)
+	}
+	if client.CircuitOpen(ep) {
+		t.Fatal("探测成功后应恢复")
+	}
+}
+
+func TestClientBreakerPerNode(t *testing.T) {
+	bad := newTestServer(t)
+	good := newTestServer(t)
+	client := newTestClient(0, 1, time.Minute)
+
+	bad.FailNext(1, http.StatusInternalServerError)
+	if _, err := client.ServerInfo(context.Background(), bad.Endpoint()); err == nil {
+		t.Fatal("请求应失败")
+	}
+	if !client.CircuitOpen(bad.Endpoint()) {
+		t.Fatal("故障节点应熔断")
+	}
+	if _, err := client.ServerInfo(context.Background(), good.Endpoint()); err != nil {
+		t.Fatalf("其他节点不应受影响: %v", err)
+	}
+}
+
+func TestClientDecryptToken(t *testing.T) {
+	s := newTestServer(t)
+	client := frps.NewClient(frps.Options{
+		Timeout:          time.Second,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Minute,
+		DecryptToken: func(token string) (string, error) {
+			if token != "sealed" {
+				return "", errors.New("unexpected token")
+			}
+			return s.Token, nil
+		},
+	})
+
+	ep := s.Endpoint()
+	ep.Token = "sealed"
+	if _, err := client.ServerInfo(context.Background(), ep); err != nil {
+		t.Fatalf("应使用解密后的密码请求: %v", err)
+	}
+}
+
+func TestClientKickClient(t *testing.T) {
+	s := newTestServer(t)
+	client := newTestClient(0, 5, time.Minute)
+
+	if err := client.KickClient(context.Background(), s.Endpoint(), "run-1"); err != nil {
+		t.Fatal(err)
+	}
+	if kicked := s.Kicked(); len(kicked) != 1 || kicked[0] != "run-1" {
+		t.Errorf("Kicked = %v", kicked)
+	}
+}
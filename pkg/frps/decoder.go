@@ -0,0 +1,191 @@
+package frps
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// camelCaseSince frps 自 0.52.0 起 Dashboard API 字段改为驼峰命名，此前为下划线命名
+var camelCaseSince = Version{Major: 0, Minor: 52, Patch: 0}
+
+// Version frps 版本号
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion 解析 frps 版本号，兼容 "v0.61.1"、"0.61.1-dev" 等写法
+func ParseVersion(s string) (Version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+ "); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, false
+	}
+
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, false
+		}
+		nums[i] = n
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, true
+}
+
+// Less 判断版本是否低于另一个版本
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+func (v Version) String() string {
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Patch)
+}
+
+// decoder 将不同版本 frps 的响应统一解析为相同结构
+type decoder interface {
+	decodeServerInfo(body []byte) (*ServerInfo, error)
+	decodeProxies(body []byte) ([]ProxyStats, error)
+}
+
+// decoderFor 根据 frps 版本选择解析器，版本未知时根据响应字段自动识别
+func decoderFor(version string) decoder {
+	v, ok := ParseVersion(version)
+	if !ok {
+		return autoDecoder{}
+	}
+	if v.Less(camelCaseSince) {
+		return snakeDecoder{}
+	}
+	return camelDecoder{}
+}
+
+// camelDecoder 0.52.0 及以后版本，字段为驼峰命名
+type camelDecoder struct{}
+
+func (camelDecoder) decodeServerInfo(body []byte) (*ServerInfo, error) {
+	var info ServerInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (camelDecoder) decodeProxies(body []byte) ([]ProxyStats, error) {
+	var resp struct {
+		Proxies []ProxyStats `json:"proxies"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Proxies, nil
+}
+
+// snakeDecoder 0.52.0 以前版本，字段为下划线命名
+type snakeDecoder struct{}
+
+func (snakeDecoder) decodeServerInfo(body []byte) (*ServerInfo, error) {
+	var raw struct {
+		Version         string         `json:"version"`
+		BindPort        int            `json:"bind_port"`
+		TotalTrafficIn  int64          `json:"total_traffic_in"`
+		TotalTrafficOut int64          `json:"total_traffic_out"`
+		CurConns        int            `json:"cur_conns"`
+		ClientCounts    int            `json:"client_counts"`
+		ProxyTypeCount  map[string]int `json:"proxy_type_count"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	return &ServerInfo{
+		Version:         raw.Version,
+		BindPort:        raw.BindPort,
+		TotalTrafficIn:  raw.TotalTrafficIn,
+		TotalTrafficOut: raw.TotalTrafficOut,
+		CurConns:        raw.CurConns,
+		ClientCounts:    raw.ClientCounts,
+		ProxyTypeCount:  raw.ProxyTypeCount,
+	}, nil
+}
+
+func (snakeDecoder) decodeProxies(body []byte) ([]ProxyStats, error) {
+	var resp struct {
+		Proxies []struct {
+			Name            string `json:"name"`
+			Status          string `json:"status"`
+			CurConns        int    `json:"cur_conns"`
+			TodayTrafficIn  int64  `json:"today_traffic_in"`
+			TodayTrafficOut int64  `json:"today_traffic_out"`
+			LastStartTime   string `json:"last_start_time"`
+			LastCloseTime   string `json:"last_close_time"`
+		} `json:"proxies"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	proxies := make([]ProxyStats, 0, len(resp.Proxies))
+	for _, p := range resp.Proxies {
+		proxies = append(proxies, ProxyStats{
+			Name:            p.Name,
+			Status:          p.Status,
+			CurConns:        p.CurConns,
+			TodayTrafficIn:  p.TodayTrafficIn,
+			TodayTrafficOut: p.TodayTrafficOut,
+			LastStartTime:   p.LastStartTime,
+			LastCloseTime:   p.LastCloseTime,
+		})
+	}
+	return proxies, nil
+}
+
+// autoDecoder 版本未知时，根据响应中出现的字段名选择解析器
+type autoDecoder struct{}
+
+func (autoDecoder) decodeServerInfo(body []byte) (*ServerInfo, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	if hasSnakeFields(fields, "client_counts", "total_traffic_in", "cur_conns") {
+		return snakeDecoder{}.decodeServerInfo(body)
+	}
+	return camelDecoder{}.decodeServerInfo(body)
+}
+
+func (autoDecoder) decodeProxies(body []byte) ([]ProxyStats, error) {
+	var resp struct {
+		Proxies []map[string]json.RawMessage `json:"proxies"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	for _, fields := range resp.Proxies {
+		if hasSnakeFields(fields, "today_traffic_in", "cur_conns", "last_start_time") {
+			return snakeDecoder{}.decodeProxies(body)
+		}
+	}
+	return camelDecoder{}.decodeProxies(body)
+}
+
+// hasSnakeFields 判断响应中是否出现了任一下划线命名的字段
+func hasSnakeFields(fields map[string]json.RawMessage, names ...string) bool {
+	for _, name := range names {
+		if _, ok := fields[name]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package frps
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	camelServerInfo = `{"version":"0.61.1","bindPort":7000,"totalTrafficIn":100,"totalTrafficOut":200,"curConns":3,"clientCounts":2,"proxyTypeCount":{"tcp":4}}`
+	snakeServerInfo = `{"version":"0.51.3","bind_port":7000,"total_traffic_in":100,"total_traffic_out":200,"cur_conns":3,"client_counts":2,"proxy_type_count":{"tcp":4}}`
+	camelProxies    = `{"proxies":[{"name":"web","status":"online","curConns":1,"todayTrafficIn":10,"todayTrafficOut":20,"lastStartTime":"06-01 10:00:00","lastCloseTime":""}]}`
+	snakeProxies    = `{"proxies":[{"name":"web","status":"online","cur_conns":1,"today_traffic_in":10,"today_traffic_out":20,"last_start_time":"06-01 10:00:00","last_close_time":""}]}`
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+		ok   bool
+	}{
+		{"0.61.1", Version{0, 61, 1}, true},
+		{"v0.52.0", Version{0, 52, 0}, true},
+		{"0.51.3-dev", Version{0, 51, 3}, true},
+		{" 0.48 ", Version{0, 48, 0}, true},
+		{"", Version{}, false},
+		{"1", Version{}, false},
+		{"0.a.1", Version{}, false},
+		{"0.1.2.3", Version{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseVersion(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDecoderFor(t *testing.T) {
+	tests := []struct {
+		version string
+		want    decoder
+	}{
+		{"0.51.3", snakeDecoder{}},
+		{"v0.51.9", snakeDecoder{}},
+		{"0.52.0", camelDecoder{}},
+		{"0.61.1", camelDecoder{}},
+		{"", autoDecoder{}},
+		{"unknown", autoDecoder{}},
+	}
+	for _, tt := range tests {
+		if got := decoderFor(tt.version); reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+			t.Errorf("decoderFor(%q) = %T, want %T", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeServerInfo(t *testing.T) {
+	want := ServerInfo{
+		BindPort:        7000,
+		TotalTrafficIn:  100,
+		TotalTrafficOut: 200,
+		CurConns:        3,
+		ClientCounts:    2,
+		ProxyTypeCount:  map[string]int{"tcp": 4},
+	}
+	tests := []struct {
+		name    string
+		dec     decoder
+		body    string
+		version string
+	}{
+		{"camel", camelDecoder{}, camelServerInfo, "0.61.1"},
+		{"snake", snakeDecoder{}, snakeServerInfo, "0.51.3"},
+		{"auto camel", autoDecoder{}, camelServerInfo, "0.61.1"},
+		{"auto snake", autoDecoder{}, snakeServerInfo, "0.51.3"},
+	}
+	for _, tt := range tests {
+		got, err := tt.dec.decodeServerInfo([]byte(tt.body))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		expected := want
+		expected.Version = tt.version
+		if !reflect.DeepEqual(*got, expected) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *got, expected)
+		}
+	}
+}
+
+func TestDecodeProxies(t *testing.T) {
+	want := []ProxyStats{{
+		Name:            "web",
+		Status:          "online",
+		CurConns:        1,
+		TodayTrafficIn:  10,
+		TodayTrafficOut: 20,
+		LastStartTime:   "06-01 10:00:00",
+	}}
+	tests := []struct {
+		name string
+		dec  decoder
+		body string
+	}{
+		{"camel", camelDecoder{}, camelProxies},
+		{"snake", snakeDecoder{}, snakeProxies},
+		{"auto camel", autoDecoder{}, camelProxies},
+		{"auto snake", autoDecoder{}, snakeProxies},
+	}
+	for _, tt := range tests {
+		got, err := tt.dec.decodeProxies([]byte(tt.body))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestSnakeDecoderIgnoresCamelFields(t *testing.T) {
+	// 按版本选错解析器时字段为零值，说明必须按版本选择解析器
+	got, err := snakeDecoder{}.decodeProxies([]byte(camelProxies))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].TodayTrafficIn != 0 || got[0].CurConns != 0 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestAutoDecoderEmptyProxies(t *testing.T) {
+	got, err := autoDecoder{}.decodeProxies([]byte(`{"proxies":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %+v, want empty", got)
+	}
+}
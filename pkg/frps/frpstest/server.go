@@ -0,0 +1,180 @@
+// Package frpstest 提供一个内存中的 frps Dashboard API 模拟服务，便于离线调试依赖节点API的功能
+package frpstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"stellarfrp/pkg/frps"
+	"strings"
+	"sync"
+)
+
+// Server 模拟的 frps Dashboard API 服务
+type Server struct {
+	*httptest.Server
+
+	User  string
+	Token string
+
+	mu         sync.Mutex
+	serverInfo frps.ServerInfo
+	proxies    map[string][]frps.ProxyStats
+	kicked     []string
+	failNext   int
+	failStatus int
+	legacy     bool
+}
+
+// NewServer 启动模拟服务，使用给定的 Basic 认证信息
+func NewServer(user, token string) *Server {
+	s := &Server{
+		User:    user,
+		Token:   token,
+		proxies: make(map[string][]frps.ProxyStats),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/serverinfo", s.handleServerInfo)
+	mux.HandleFunc("/api/proxy/", s.handleProxies)
+	mux.HandleFunc("/api/client/kick", s.handleKick)
+	s.Server = httptest.NewServer(s.authenticate(mux))
+	return s
+}
+
+// Endpoint 返回指向模拟服务的连接信息
+func (s *Server) Endpoint() frps.Endpoint {
+	return frps.Endpoint{URL: s.URL, User: s.User, Token: s.Token}
+}
+
+// SetServerInfo 设置 /api/serverinfo 的返回值
+func (s *Server) SetServerInfo(info frps.ServerInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serverInfo = info
+}
+
+// SetProxies 设置 /api/proxy/{type} 的返回值
+func (s *Server) SetProxies(proxyType string, proxies []frps.ProxyStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.proxies[proxyType] = proxies
+}
+
+// Kicked 返回已被踢下的运行ID
+func (s *Server) Kicked() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.kicked...)
+}
+
+// SetLegacy 切换为 0.52.0 以前版本的下划线命名响应
+func (s *Server) SetLegacy(legacy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.legacy = legacy
+}
+
+// FailNext 让接下来的 n 个请求返回指定状态码，用于模拟节点故障
+func (s *Server) FailNext(n, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+	s.failStatus = status
+}
+
+// authenticate 校验 Basic 认证并处理注入的故障
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, token, ok := r.BasicAuth()
+		if !ok || user != s.User || token != s.Token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		s.mu.Lock()
+		if s.failNext > 0 {
+			s.failNext--
+			status := s.failStatus
+			s.mu.Unlock()
+			http.Error(w, "injected failure", status)
+			return
+		}
+		s.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleServerInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	info := s.serverInfo
+	legacy := s.legacy
+	s.mu.Unlock()
+
+	if legacy {
+		writeJSON(w, map[string]interface{}{
+			"version":           info.Version,
+			"bind_port":         info.BindPort,
+			"total_traffic_in":  info.TotalTrafficIn,
+			"total_traffic_out": info.TotalTrafficOut,
+			"cur_conns":         info.CurConns,
+			"client_counts":     info.ClientCounts,
+			"proxy_type_count":  info.ProxyTypeCount,
+		})
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *Server) handleProxies(w http.ResponseWriter, r *http.Request) {
+	proxyType := strings.TrimPrefix(r.URL.Path, "/api/proxy/")
+
+	s.mu.Lock()
+	proxies := append([]frps.ProxyStats{}, s.proxies[proxyType]...)
+	legacy := s.legacy
+	s.mu.Unlock()
+
+	if legacy {
+		items := make([]map[string]interface{}, 0, len(proxies))
+		for _, p := range proxies {
+			items = append(items, map[string]interface{}{
+				"name":              p.Name,
+				"status":            p.Status,
+				"cur_conns":         p.CurConns,
+				"today_traffic_in":  p.TodayTrafficIn,
+				"today_traffic_out": p.TodayTrafficOut,
+				"last_start_time":   p.LastStartTime,
+				"last_close_time":   p.LastCloseTime,
+			})
+		}
+		writeJSON(w, map[string]interface{}{"proxies": items})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"proxies": proxies})
+}
+
+func (s *Server) handleKick(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RunID string `json:"runid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RunID == "" {
+		http.Error(w, "invalid runid", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.kicked = append(s.kicked, req.RunID)
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,58 @@
+package frps
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Endpoint 节点 Dashboard API 的连接信息
+type Endpoint struct {
+	URL   string
+	User  string
+	Token string
+	// Version 节点的 frps 版本，为空时根据响应字段自动识别
+	Version string
+	// Timeout 单次请求超时，为0时使用客户端默认值
+	Timeout time.Duration
+}
+
+// ServerInfo /api/serverinfo 返回的节点信息，已按版本统一字段
+type ServerInfo struct {
+	Version         string         `json:"version"`
+	BindPort        int            `json:"bindPort"`
+	TotalTrafficIn  int64          `json:"totalTrafficIn"`
+	TotalTrafficOut int64          `json:"totalTrafficOut"`
+	CurConns        int            `json:"curConns"`
+	ClientCounts    int            `json:"clientCounts"`
+	ProxyTypeCount  map[string]int `json:"proxyTypeCount"`
+}
+
+// ProxyStats /api/proxy/{type} 返回的单个隧道状态，已按版本统一字段
+type ProxyStats struct {
+	Name            string `json:"name"`
+	Status          string `json:"status"`
+	CurConns        int    `json:"curConns"`
+	TodayTrafficIn  int64  `json:"todayTrafficIn"`
+	TodayTrafficOut int64  `json:"todayTrafficOut"`
+	LastStartTime   string `json:"lastStartTime"`
+	LastCloseTime   string `json:"lastCloseTime"`
+}
+
+// ErrCircuitOpen 节点连续失败次数过多，熔断期间直接拒绝请求
+var ErrCircuitOpen = errors.New("节点API熔断中")
+
+// StatusError 节点返回了非200状态码
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("节点返回错误, status: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// retryable 5xx 视为节点临时故障可重试，4xx 为请求本身的问题
+func (e *StatusError) retryable() bool {
+	return e.StatusCode >= 500
+}
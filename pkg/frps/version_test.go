@@ -0,0 +1,51 @@
+package frps_test
+
+import (
+	"context"
+	"stellarfrp/pkg/frps"
+	"testing"
+	"time"
+)
+
+func TestClientDecodesByVersion(t *testing.T) {
+	proxies := []frps.ProxyStats{{Name: "web", Status: "online", CurConns: 2, TodayTrafficIn: 10, TodayTrafficOut: 20}}
+	// ServerInfo 按响应中的版本号选择解析器，ListProxies 按节点记录的版本号选择，为空时自动识别
+	tests := []struct {
+		name          string
+		legacy        bool
+		serverVersion string
+		version       string
+	}{
+		{"camel", false, "0.61.1", "0.61.1"},
+		{"snake", true, "0.51.3", "0.51.3"},
+		{"auto camel", false, "dev", ""},
+		{"auto snake", true, "dev", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.SetLegacy(tt.legacy)
+			s.SetServerInfo(frps.ServerInfo{Version: tt.serverVersion, ClientCounts: 3, TotalTrafficIn: 100})
+			s.SetProxies("tcp", proxies)
+			client := newTestClient(0, 5, time.Minute)
+
+			info, err := client.ServerInfo(context.Background(), s.Endpoint())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.ClientCounts != 3 || info.TotalTrafficIn != 100 {
+				t.Errorf("ServerInfo = %+v", info)
+			}
+
+			ep := s.Endpoint()
+			ep.Version = tt.version
+			got, err := client.ListProxies(context.Background(), ep, "tcp")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0] != proxies[0] {
+				t.Errorf("ListProxies = %+v, want %+v", got, proxies)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package network
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// ErrForbiddenAddress 目标地址为内网、回环等不允许访问的地址
+var ErrForbiddenAddress = errors.New("不允许访问内网地址")
+
+// IsPublicIP 判断是否为可访问的公网地址
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	// 100.64.0.0/10 运营商级NAT地址
+	if v4 := ip.To4(); v4 != nil && v4[0] == 100 && v4[1]&0xc0 == 64 {
+		return false
+	}
+	return true
+}
+
+// PublicOnlyControl 用于 net.Dialer.Control，在建立连接前校验解析后的地址，防止通过域名解析访问内网
+func PublicOnlyControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
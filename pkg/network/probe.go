@@ -0,0 +1,79 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ProbeResult 单次探测结果
+type ProbeResult struct {
+	Success    bool
+	Latency    time.Duration
+	StatusCode int
+	Error      string
+}
+
+// ProbeTCP 探测TCP端口是否可连接，并记录建立连接的耗时
+func ProbeTCP(ctx context.Context, host string, port int, timeout time.Duration) ProbeResult {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := net.Dialer{Timeout: timeout}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Latency: latency, Error: err.Error()}
+	}
+	conn.Close()
+
+	return ProbeResult{Success: true, Latency: latency}
+}
+
+// probeHTTPClient HTTP探测客户端，不跟随重定向、不校验证书，只关心公网入口能否响应
+// 域名由用户填写，只允许连接解析到公网地址的目标
+var probeHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext:       (&net.Dialer{Timeout: 5 * time.Second, Control: PublicOnlyControl}).DialContext,
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// ProbeHTTP 请求指定地址并记录首字节耗时，5xx 视为失败
+// frps 在本地服务不可用时会返回 502/504，借此区分客户端在线但本地服务异常的情况
+func ProbeHTTP(ctx context.Context, url string, timeout time.Duration) ProbeResult {
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return ProbeResult{Error: err.Error()}
+	}
+	req.Header.Set("User-Agent", "StellarFrp-Probe")
+
+	start := time.Now()
+	resp, err := probeHTTPClient.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Latency: latency, Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	result := ProbeResult{Latency: latency, StatusCode: resp.StatusCode}
+	if resp.StatusCode >= 500 {
+		result.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return result
+	}
+	result.Success = true
+	return result
+}
@@ -0,0 +1,160 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix 密文前缀，格式为 "enc:v1:<密钥ID>:<base64(nonce+密文)>"
+const prefix = "enc:v1:"
+
+// 加解密错误
+var (
+	ErrNoKey      = errors.New("未配置加密密钥")
+	ErrUnknownKey = errors.New("密文使用的密钥不存在")
+	ErrMalformed  = errors.New("密文格式错误")
+)
+
+// Keyring 应用层加密密钥环，使用AES-256-GCM
+// 第一个密钥用于加密，其余密钥只用于解密轮换前写入的数据
+type Keyring struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// GenerateKey 生成 "id:base64密钥" 格式的随机密钥配置，可直接写入 SECRET_KEYS
+func GenerateKey(id string) (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKeyring 解析密钥配置，格式为 "id:base64密钥"，多个密钥用逗号分隔，第一个为当前密钥
+// 密钥为32字节随机数据，可用 openssl rand -base64 32 生成；配置为空时返回未启用加密的密钥环
+func ParseKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{aeads: make(map[string]cipher.AEAD)}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(item, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("密钥配置格式错误，应为 id:base64密钥")
+		}
+		if _, exists := k.aeads[id]; exists {
+			return nil, fmt.Errorf("密钥ID %s 重复", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("密钥 %s 不是有效的base64: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("密钥 %s 长度应为32字节", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[id] = aead
+		if k.current == "" {
+			k.current = id
+		}
+	}
+	return k, nil
+}
+
+// Enabled 是否配置了加密密钥
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.current != ""
+}
+
+// CurrentKeyID 当前用于加密的密钥ID
+func (k *Keyring) CurrentKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.current
+}
+
+// IsEncrypted 判断值是否为密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt 使用当前密钥加密，空字符串和已加密的值原样返回，未配置密钥时返回明文
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || IsEncrypted(plaintext) || !k.Enabled() {
+		return plaintext, nil
+	}
+
+	aead := k.aeads[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.current))
+	return prefix + k.current + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密密文，非密文（加密启用前写入的数据）原样返回
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if !k.Enabled() {
+		return "", ErrNoKey
+	}
+
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", ErrMalformed
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 判断值是否需要用当前密钥重新加密：明文或使用旧密钥加密的密文
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" || !k.Enabled() {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	return !strings.HasPrefix(value, prefix+k.current+":")
+}
+
+// Rotate 使用当前密钥重新加密明文或旧密钥加密的密文，无需轮换的值原样返回
+func (k *Keyring) Rotate(value string) (string, error) {
+	if !k.NeedsRotation(value) {
+		return value, nil
+	}
+	plaintext, err := k.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(plaintext)
+}
@@ -0,0 +1,217 @@
+package secret
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T, id string) string {
+	t.Helper()
+	spec, err := GenerateKey(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func mustParse(t *testing.T, spec string) *Keyring {
+	t.Helper()
+	k, err := ParseKeyring(spec)
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	return k
+}
+
+func TestParseKeyring(t *testing.T) {
+	k1, k2 := newKey(t, "k1"), newKey(t, "k2")
+
+	k := mustParse(t, " "+k2+" , "+k1+",")
+	if !k.Enabled() || k.CurrentKeyID() != "k2" {
+		t.Errorf("Enabled = %v, CurrentKeyID = %q; want true, k2", k.Enabled(), k.CurrentKeyID())
+	}
+
+	empty := mustParse(t, "")
+	if empty.Enabled() || empty.CurrentKeyID() != "" {
+		t.Error("空配置不应启用加密")
+	}
+
+	short := "k1:" + base64.StdEncoding.EncodeToString([]byte("too short"))
+	for _, spec := range []string{"nokey", ":abc", "k1:not base64!", short, k1 + "," + k1} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("ParseKeyring(%q) 应返回错误", spec)
+		}
+	}
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	k := mustParse(t, newKey(t, "k1"))
+
+	for _, plain := range []string{"password", "中文密码", strings.Repeat("x", 1000)} {
+		sealed, err := k.Encrypt(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(sealed) || !strings.HasPrefix(sealed, "enc:v1:k1:") {
+			t.Fatalf("密文格式错误: %q", sealed)
+		}
+		if strings.Contains(sealed, plain) {
+			t.Fatal("密文中不应包含明文")
+		}
+		got, err := k.Decrypt(sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != plain {
+			t.Errorf("Decrypt = %q, want %q", got, plain)
+		}
+	}
+
+	// 每次加密使用随机nonce，相同明文的密文不同
+	a, _ := k.Encrypt("same")
+	b, _ := k.Encrypt("same")
+	if a == b {
+		t.Error("相同明文的密文不应相同")
+	}
+}
+
+func TestPassThrough(t *testing.T) {
+	k := mustParse(t, newKey(t, "k1"))
+
+	if got, _ := k.Encrypt(""); got != "" {
+		t.Errorf("空字符串应原样返回, got %q", got)
+	}
+	sealed, _ := k.Encrypt("password")
+	if got, _ := k.Encrypt(sealed); got != sealed {
+		t.Error("已加密的值不应重复加密")
+	}
+	if got, err := k.Decrypt("plaintext"); err != nil || got != "plaintext" {
+		t.Errorf("明文应原样返回, got %q, %v", got, err)
+	}
+
+	for _, disabled := range []*Keyring{nil, mustParse(t, "")} {
+		if got, err := disabled.Encrypt("password"); err != nil || got != "password" {
+			t.Errorf("未配置密钥时应返回明文, got %q, %v", got, err)
+		}
+		if got, err := disabled.Decrypt("password"); err != nil || got != "password" {
+			t.Errorf("未配置密钥时明文应原样返回, got %q, %v", got, err)
+		}
+		if _, err := disabled.Decrypt(sealed); !errors.Is(err, ErrNoKey) {
+			t.Errorf("未配置密钥时解密密文 err = %v, want ErrNoKey", err)
+		}
+	}
+}
+
+func TestDecryptAfterRotation(t *testing.T) {
+	k1, k2 := newKey(t, "k1"), newKey(t, "k2")
+	old := mustParse(t, k1)
+	sealed, err := old.Encrypt("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 新密钥放在最前面，旧密钥保留用于解密
+	rotated := mustParse(t, k2+","+k1)
+	got, err := rotated.Decrypt(sealed)
+	if err != nil || got != "password" {
+		t.Fatalf("轮换后应能解密旧密钥密文, got %q, %v", got, err)
+	}
+
+	resealed, err := rotated.Rotate(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resealed, "enc:v1:k2:") {
+		t.Fatalf("应使用新密钥重新加密: %q", resealed)
+	}
+
+	// 删除旧密钥后只能解密新密钥的密文
+	current := mustParse(t, k2)
+	if got, err := current.Decrypt(resealed); err != nil || got != "password" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	if _, err := current.Decrypt(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	k := mustParse(t, newKey(t, "k1"))
+	sealed, _ := k.Encrypt("password")
+	payload := strings.TrimPrefix(sealed, "enc:v1:k1:")
+
+	tests := []struct {
+		name  string
+		value string
+		want  error
+	}{
+		{"unknown key", "enc:v1:k9:" + payload, ErrUnknownKey},
+		{"missing key id", "enc:v1:" + payload, ErrMalformed},
+		{"bad base64", "enc:v1:k1:!!!", ErrMalformed},
+		{"too short", "enc:v1:k1:" + base64.RawURLEncoding.EncodeToString([]byte("abc")), ErrMalformed},
+	}
+	for _, tt := range tests {
+		if _, err := k.Decrypt(tt.value); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// 篡改密文或更换密钥ID都无法通过认证
+	tampered := sealed[:len(sealed)-2] + "AA"
+	if tampered == sealed {
+		tampered = sealed[:len(sealed)-2] + "BB"
+	}
+	if _, err := k.Decrypt(tampered); err == nil {
+		t.Error("篡改后的密文不应解密成功")
+	}
+	other := mustParse(t, newKey(t, "k1"))
+	if _, err := other.Decrypt(sealed); err == nil {
+		t.Error("同ID不同密钥不应解密成功")
+	}
+}
+
+func TestNeedsRotation(t *testing.T) {
+	k1, k2 := newKey(t, "k1"), newKey(t, "k2")
+	old := mustParse(t, k1)
+	oldSealed, _ := old.Encrypt("password")
+
+	k := mustParse(t, k2+","+k1)
+	current, _ := k.Encrypt("password")
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"empty", "", false},
+		{"plaintext", "password", true},
+		{"old key", oldSealed, true},
+		{"current key", current, false},
+	}
+	for _, tt := range tests {
+		if got := k.NeedsRotation(tt.value); got != tt.want {
+			t.Errorf("%s: NeedsRotation = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if mustParse(t, "").NeedsRotation("password") {
+		t.Error("未配置密钥时不需要轮换")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	k := mustParse(t, newKey(t, "k2"))
+
+	sealed, err := k.Rotate("password")
+	if err != nil || !strings.HasPrefix(sealed, "enc:v1:k2:") {
+		t.Fatalf("明文应被加密, got %q, %v", sealed, err)
+	}
+	if again, err := k.Rotate(sealed); err != nil || again != sealed {
+		t.Errorf("当前密钥的密文应原样返回, got %q, %v", again, err)
+	}
+	if _, err := k.Rotate("enc:v1:k9:abc"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("err = %v, want ErrUnknownKey", err)
+	}
+}
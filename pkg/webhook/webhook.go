@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"stellarfrp/pkg/network"
+	"strconv"
+	"time"
+)
+
+// 签名相关请求头
+const (
+	HeaderTimestamp = "X-StellarFrp-Timestamp"
+	HeaderSignature = "X-StellarFrp-Signature"
+	HeaderEvent     = "X-StellarFrp-Event"
+)
+
+// maxURLLength Webhook 地址长度上限
+const maxURLLength = 512
+
+// ErrForbiddenAddress 目标地址为内网、回环等不允许访问的地址
+var ErrForbiddenAddress = errors.New("不允许向内网地址发送Webhook")
+
+// ValidateURL 校验用户填写的 Webhook 地址
+func ValidateURL(raw string) error {
+	if len(raw) > maxURLLength {
+		return fmt.Errorf("Webhook地址长度不能超过%d个字符", maxURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("Webhook地址格式错误")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("Webhook地址仅支持http或https")
+	}
+	if u.User != nil {
+		return errors.New("Webhook地址不能包含用户名或密码")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !network.IsPublicIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// GenerateSecret 生成随机签名密钥
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Sign 计算签名，签名内容为 "时间戳.请求体"，结果为 "sha256=" 加十六进制摘要
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Sender Webhook 发送器，只允许连接公网地址
+type Sender struct {
+	httpClient *http.Client
+}
+
+// NewSender 创建 Webhook 发送器
+func NewSender(timeout time.Duration) *Sender {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: network.PublicOnlyControl,
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Sender{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			// 不跟随重定向，以首次响应的状态码为准
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send 发送签名后的 JSON 请求，非2xx响应视为失败
+func (s *Sender) Send(ctx context.Context, target, secret, event string, body []byte) error {
+	if err := ValidateURL(target); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "StellarFrp-Webhook")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求Webhook失败: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook返回错误, status: %d", resp.StatusCode)
+	}
+	return nil
+}